@@ -0,0 +1,77 @@
+// Command clean-images strips metadata (EXIF including GPS, XMP, IPTC and comments) from car
+// images uploaded before it was stripped on upload, and rotates them to their EXIF orientation.
+//
+// Cleaned copies are saved to the image store (IMAGE_STORAGE, see env.example) and replace the
+// originals one image at a time, so the tool can be stopped and run again at any point; once
+// every image is cleaned it finds nothing to do.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/uzimpp/CarJai/backend/config"
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/storage"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 50, "Number of images to load per batch")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Strips metadata from and auto-orients car images uploaded before that was done on upload\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	imageStore, err := storage.New(config.LoadImageStorageConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize image storage: %v", err)
+	}
+
+	dbConfig := config.LoadDatabaseConfig()
+	db, err := sql.Open("postgres", dbConfig.GetConnectionString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	database := models.NewDatabase(db)
+	carService := services.NewCarService(
+		models.NewCarRepository(database),
+		models.NewCarImageRepository(database),
+		models.NewInspectionRepository(database),
+		models.NewCarColorRepository(database),
+		models.NewCarFuelRepository(database),
+		models.NewMarketPriceRepository(database),
+		models.NewCarRevisionRepository(database),
+		imageStore,
+	)
+
+	// Stop between images on Ctrl+C; every image cleaned so far stays cleaned
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Cleaning image metadata")
+	cleaned, failed, err := carService.CleanExistingImages(ctx, *batchSize)
+	if err != nil {
+		log.Printf("Stopped: %v", err)
+	}
+
+	log.Printf("Done: %d cleaned, %d failed", cleaned, failed)
+	if err != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,77 @@
+// Command hash-images computes the perceptual hash of car images uploaded before photos were
+// hashed on upload, and flags the duplicate photos it finds across sellers' listings.
+//
+// Only cleaned images are hashed, so run clean-images first. Images are hashed one at a time
+// and the hash is saved as it is computed, so the tool can be stopped and run again at any
+// point; once every image is hashed it finds nothing to do.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/uzimpp/CarJai/backend/config"
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/storage"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 50, "Number of images to load per batch")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Hashes car images uploaded before perceptual hashing and flags duplicate photos\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	imageStore, err := storage.New(config.LoadImageStorageConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize image storage: %v", err)
+	}
+
+	dbConfig := config.LoadDatabaseConfig()
+	db, err := sql.Open("postgres", dbConfig.GetConnectionString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	database := models.NewDatabase(db)
+	carService := services.NewCarService(
+		models.NewCarRepository(database),
+		models.NewCarImageRepository(database),
+		models.NewInspectionRepository(database),
+		models.NewCarColorRepository(database),
+		models.NewCarFuelRepository(database),
+		models.NewMarketPriceRepository(database),
+		models.NewCarRevisionRepository(database),
+		imageStore,
+	)
+
+	// Stop between images on Ctrl+C; every hash saved so far stays saved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Printf("Hashing images for duplicate photo checks")
+	hashed, failed, err := carService.HashExistingImages(ctx, *batchSize)
+	if err != nil {
+		log.Printf("Stopped: %v", err)
+	}
+
+	log.Printf("Done: %d hashed, %d failed", hashed, failed)
+	if err != nil || failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,91 @@
+// Command migrate-images moves car images still stored in the database (car_images.image_data)
+// into the configured image store (IMAGE_STORAGE, see env.example).
+//
+// Images are copied one at a time and only then cleared from the database, so the tool can
+// be stopped and run again at any point. Postgres does not give the space back on its own;
+// run VACUUM FULL car_images afterwards to reclaim it.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/uzimpp/CarJai/backend/config"
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/storage"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 100, "Number of images to load per batch")
+	limit := flag.Int("limit", 0, "Maximum number of images to process (0 for all)")
+	dryRun := flag.Bool("dry-run", false, "Only count the images that would be moved")
+
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options]\n\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Moves car images stored in the database into the image store\n\n")
+		fmt.Fprintf(os.Stderr, "Options:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	storageConfig := config.LoadImageStorageConfig()
+	imageStore, err := storage.New(storageConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize image storage: %v", err)
+	}
+
+	dbConfig := config.LoadDatabaseConfig()
+	db, err := sql.Open("postgres", dbConfig.GetConnectionString())
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	database := models.NewDatabase(db)
+	carService := services.NewCarService(
+		models.NewCarRepository(database),
+		models.NewCarImageRepository(database),
+		models.NewInspectionRepository(database),
+		models.NewCarColorRepository(database),
+		models.NewCarFuelRepository(database),
+		models.NewMarketPriceRepository(database),
+		models.NewCarRevisionRepository(database),
+		imageStore,
+	)
+
+	// Stop between images on Ctrl+C; everything moved so far stays moved
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *dryRun {
+		log.Printf("Dry run: counting images that would be moved to %s storage", storageConfig.Backend)
+	} else {
+		log.Printf("Moving images to %s storage", storageConfig.Backend)
+	}
+	result, err := carService.MigrateDatabaseImages(ctx, *batchSize, *limit, *dryRun, log.Printf)
+	if err != nil {
+		log.Printf("Stopped: %v", err)
+	}
+
+	if *dryRun {
+		log.Printf("%d images (%d bytes) would be moved", result.Moved, result.Bytes)
+	} else {
+		log.Printf("Done: %d moved (%d bytes), %d skipped, %d failed", result.Moved, result.Bytes, result.Skipped, result.Failed)
+		if result.Moved > 0 {
+			log.Printf("Run VACUUM FULL car_images to reclaim the database space")
+		}
+	}
+	if err != nil || result.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -44,6 +44,15 @@ type AppConfig struct {
 	FrontendURL                  string
 	// Cookie security configuration
 	CookieSecure bool // If true, cookies require HTTPS (Secure flag)
+	// Optional "similar cars" weight overrides, e.g. "brand=2,model=3,price=4"
+	SimilarCarWeights string
+	// If true, listings with a photo matching one on another seller's active listing can't be published
+	BlockDuplicatePhotos bool
+	// Origins allowed to call POST /api/valuation: CORSAllowedOrigins plus the sites embedding
+	// the valuation widget
+	ValuationAllowedOrigins []string
+	// Reverse proxies (IPs or CIDRs) whose X-Forwarded-For and X-Real-IP headers rate limits trust
+	TrustedProxies []string
 }
 
 // LoadAppConfig loads application configuration from environment variables
@@ -87,6 +96,14 @@ func LoadAppConfig() *AppConfig {
 		FrontendURL:                  utils.GetEnv("FRONTEND_URL"),
 		// Cookie security - use COOKIE_SECURE env var if set, otherwise default based on environment
 		CookieSecure: getCookieSecureSetting(),
+		// Similar cars weights (optional, so use os.Getenv)
+		SimilarCarWeights: os.Getenv("SIMILAR_CAR_WEIGHTS"),
+		// Duplicate photo publish check (optional, defaults to false)
+		BlockDuplicatePhotos: utils.GetEnvAsBool("BLOCK_DUPLICATE_PHOTOS"),
+		// Valuation widget origins (optional, so use os.Getenv)
+		ValuationAllowedOrigins: append(append([]string{}, allowedOrigins...), parseCORSOrigins(os.Getenv("VALUATION_WIDGET_ORIGINS"))...),
+		// Trusted proxies (optional, defaults to the loopback and private ranges)
+		TrustedProxies: parseTrustedProxies(getTrustedProxiesSetting()),
 	}
 }
 
@@ -133,3 +150,34 @@ func parseCORSOrigins(corsOrigins string) []string {
 	}
 	return allowedOrigins
 }
+
+// defaultTrustedProxies are trusted when TRUSTED_PROXIES is not set: the loopback and private
+// ranges, where the Next.js frontend (which proxies /api/*) and Docker networks live
+const defaultTrustedProxies = "127.0.0.0/8,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,::1/128,fc00::/7"
+
+// getTrustedProxiesSetting returns TRUSTED_PROXIES, or defaultTrustedProxies when it is not set.
+// Setting it to an empty value trusts no proxy.
+func getTrustedProxiesSetting() string {
+	if proxies, ok := os.LookupEnv("TRUSTED_PROXIES"); ok {
+		return proxies
+	}
+	return defaultTrustedProxies
+}
+
+// parseTrustedProxies parses comma-separated proxy IPs and CIDRs into a slice, skipping invalid entries
+func parseTrustedProxies(proxies string) []string {
+	list := strings.Split(proxies, ",")
+	trusted := make([]string, 0, len(list))
+	for _, proxy := range list {
+		trimmedProxy := strings.TrimSpace(proxy)
+		if trimmedProxy == "" {
+			continue
+		}
+		if err := utils.ValidateIPAddress(trimmedProxy); err != nil {
+			log.Printf("Ignoring invalid TRUSTED_PROXIES entry %q: %v", trimmedProxy, err)
+			continue
+		}
+		trusted = append(trusted, trimmedProxy)
+	}
+	return trusted
+}
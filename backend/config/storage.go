@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/storage"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// DefaultImageStorageDir is where the local backend keeps images when IMAGE_STORAGE_DIR is not set
+const DefaultImageStorageDir = "data/images"
+
+// LoadImageStorageConfig loads car image storage settings from environment variables.
+// All are optional: images are kept on the local filesystem unless IMAGE_STORAGE=s3.
+func LoadImageStorageConfig() storage.Config {
+	cfg := storage.Config{
+		Backend:  strings.ToLower(strings.TrimSpace(os.Getenv("IMAGE_STORAGE"))),
+		LocalDir: os.Getenv("IMAGE_STORAGE_DIR"),
+		S3: storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("S3_REGION"),
+			Bucket:          os.Getenv("S3_BUCKET"),
+			AccessKeyID:     os.Getenv("S3_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("S3_SECRET_ACCESS_KEY"),
+			PathStyle:       utils.GetEnvAsBool("S3_PATH_STYLE"),
+		},
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = storage.BackendLocal
+	}
+	if cfg.LocalDir == "" {
+		cfg.LocalDir = DefaultImageStorageDir
+	}
+	return cfg
+}
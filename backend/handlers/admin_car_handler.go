@@ -1,117 +1,276 @@
-package handlers
-
-import (
-	"encoding/json"
-	"net/http"
-	"strconv"
-	"strings"
-
-	"github.com/uzimpp/CarJai/backend/models"
-	"github.com/uzimpp/CarJai/backend/services"
-	"github.com/uzimpp/CarJai/backend/utils"
-)
-
-// AdminCarHandler handles admin operations on cars
-type AdminCarHandler struct {
-	carService *services.CarService
-}
-
-// NewAdminCarHandler creates a new handler for admin-car operations
-func NewAdminCarHandler(carService *services.CarService) *AdminCarHandler {
-	return &AdminCarHandler{
-		carService: carService,
-	}
-}
-
-// GetCars handles GET /admin/cars
-func (h *AdminCarHandler) GetCars(w http.ResponseWriter, r *http.Request) {
-	cars, err := h.carService.GetManagedCars()
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, "Failed to retrieve cars")
-		return
-	}
-
-	response := models.AdminCarsListResponse{
-		Cars:  *cars,
-		Total: len(*cars),
-	}
-	utils.WriteJSON(w, http.StatusOK, response, "")
-}
-
-// UpdateCar handles PATCH /admin/cars/:id
-func (h *AdminCarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
-	// Extract car ID from URL path
-	parts := strings.Split(r.URL.Path, "/")
-	idStr := parts[len(parts)-1]
-	carID, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
-		return
-	}
-
-	var req models.AdminUpdateCarRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Call the service
-	updatedCar, err := h.carService.UpdateCarByAdmin(carID, req)
-	if err != nil {
-		if strings.Contains(err.Error(), "car not found") {
-			utils.WriteError(w, http.StatusNotFound, err.Error())
-		} else {
-			utils.WriteError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	// Return the updated car's public data
-	utils.WriteJSON(w, http.StatusOK, updatedCar, "")
-}
-
-// CreateCar handles POST /admin/cars
-func (h *AdminCarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
-	var req models.AdminCreateCarRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	newCar, err := h.carService.CreateCarByAdmin(req)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			utils.WriteError(w, http.StatusBadRequest, err.Error())
-		} else {
-			utils.WriteError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusCreated, newCar, "")
-}
-
-// DeleteCar handles DELETE /admin/cars/:id
-func (h *AdminCarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
-	// Extract car ID from URL path
-	parts := strings.Split(r.URL.Path, "/")
-	idStr := parts[len(parts)-1]
-	carID, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
-		return
-	}
-
-	// Call the service
-	err = h.carService.DeleteCarByAdmin(carID)
-	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			utils.WriteError(w, http.StatusNotFound, err.Error())
-		} else {
-			utils.WriteError(w, http.StatusInternalServerError, err.Error())
-		}
-		return
-	}
-
-	utils.WriteJSON(w, http.StatusOK, nil, "Car deleted successfully")
-}
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// AdminCarHandler handles admin operations on cars
+type AdminCarHandler struct {
+	carService *services.CarService
+}
+
+// NewAdminCarHandler creates a new handler for admin-car operations
+func NewAdminCarHandler(carService *services.CarService) *AdminCarHandler {
+	return &AdminCarHandler{
+		carService: carService,
+	}
+}
+
+// GetCars handles GET /admin/cars
+func (h *AdminCarHandler) GetCars(w http.ResponseWriter, r *http.Request) {
+	cars, err := h.carService.GetManagedCars()
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to retrieve cars")
+		return
+	}
+
+	response := models.AdminCarsListResponse{
+		Cars:  *cars,
+		Total: len(*cars),
+	}
+	utils.WriteJSON(w, http.StatusOK, response, "")
+}
+
+// UpdateCar handles PATCH /admin/cars/:id
+func (h *AdminCarHandler) UpdateCar(w http.ResponseWriter, r *http.Request) {
+	// Extract car ID from URL path
+	parts := strings.Split(r.URL.Path, "/")
+	idStr := parts[len(parts)-1]
+	carID, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	adminID, err := strconv.Atoi(r.Header.Get("X-Admin-ID"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid admin ID")
+		return
+	}
+
+	var req models.AdminUpdateCarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	// Call the service
+	updatedCar, err := h.carService.UpdateCarByAdmin(carID, req, adminID)
+	if err != nil {
+		if strings.Contains(err.Error(), "car not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		} else if strings.Contains(err.Error(), "cannot publish") || strings.Contains(err.Error(), "invalid status") ||
+			strings.Contains(err.Error(), "cannot change status") || strings.Contains(err.Error(), "cannot set status") {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	// Return the updated car's public data
+	utils.WriteJSON(w, http.StatusOK, updatedCar, "")
+}
+
+// CreateCar handles POST /admin/cars
+func (h *AdminCarHandler) CreateCar(w http.ResponseWriter, r *http.Request) {
+	adminID, err := strconv.Atoi(r.Header.Get("X-Admin-ID"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid admin ID")
+		return
+	}
+
+	var req models.AdminCreateCarRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	newCar, err := h.carService.CreateCarByAdmin(req, adminID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, newCar, "")
+}
+
+// DeleteCar handles DELETE /admin/cars/:id
+func (h *AdminCarHandler) DeleteCar(w http.ResponseWriter, r *http.Request) {
+	// Extract car ID from URL path
+	parts := strings.Split(r.URL.Path, "/")
+	idStr := parts[len(parts)-1]
+	carID, err := strconv.Atoi(idStr)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	// Call the service
+	err = h.carService.DeleteCarByAdmin(carID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil, "Car deleted successfully")
+}
+
+// GetDuplicatePhotos handles GET /admin/cars/{id}/duplicate-photos
+// Lists the car's photos that nearly match photos on other sellers' listings.
+func (h *AdminCarHandler) GetDuplicatePhotos(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimSuffix(r.URL.Path, "/duplicate-photos"), "/")
+	carID, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	matches, err := h.carService.GetDuplicatePhotos(carID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Car not found")
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to retrieve duplicate photos")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, matches, "")
+}
+
+// GetRevisions handles GET /admin/cars/{id}/revisions
+func (h *AdminCarHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	carID, _, err := extractRevisionPathIDs(r.URL.Path)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	revisions, err := h.carService.GetCarRevisions(carID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Car not found")
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to retrieve revisions")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, revisions, "")
+}
+
+// DiffRevisions handles GET /admin/cars/{id}/revisions/diff?from={revisionId|published}&to={revisionId}
+// Without "to" the latest revision is used; without "from" the revision before "to".
+// from=published diffs against the first published revision, showing edits made after publishing.
+func (h *AdminCarHandler) DiffRevisions(w http.ResponseWriter, r *http.Request) {
+	carID, _, err := extractRevisionPathIDs(r.URL.Path)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	query := r.URL.Query()
+	toID := 0
+	if v := query.Get("to"); v != "" {
+		if toID, err = strconv.Atoi(v); err != nil || toID <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid 'to' revision ID")
+			return
+		}
+	}
+
+	fromID := 0
+	switch v := query.Get("from"); v {
+	case "":
+	case "published":
+		if fromID, err = h.carService.GetFirstPublishedRevisionID(carID); err != nil {
+			if strings.Contains(err.Error(), "no published revision") {
+				utils.WriteError(w, http.StatusNotFound, "Car has not been published")
+			} else {
+				utils.WriteError(w, http.StatusInternalServerError, "Failed to retrieve revisions")
+			}
+			return
+		}
+	default:
+		if fromID, err = strconv.Atoi(v); err != nil || fromID <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid 'from' revision ID")
+			return
+		}
+	}
+
+	diff, err := h.carService.DiffCarRevisions(carID, fromID, toID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Revision not found")
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to diff revisions")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, diff, "")
+}
+
+// RevertRevision handles POST /admin/cars/{id}/revisions/{revisionId}/revert
+func (h *AdminCarHandler) RevertRevision(w http.ResponseWriter, r *http.Request) {
+	adminID, err := strconv.Atoi(r.Header.Get("X-Admin-ID"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid admin ID")
+		return
+	}
+
+	carID, revisionID, err := extractRevisionPathIDs(r.URL.Path)
+	if err != nil || revisionID == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car or revision ID")
+		return
+	}
+
+	revision, err := h.carService.RevertCarToRevision(carID, revisionID, adminID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		} else {
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, revision, "Car reverted successfully")
+}
+
+// extractRevisionPathIDs extracts the car ID and, if present, the revision ID from paths like
+// /admin/cars/{id}/revisions and /admin/cars/{id}/revisions/{revisionId}/revert
+func extractRevisionPathIDs(path string) (carID, revisionID int, err error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part != "revisions" || i == 0 {
+			continue
+		}
+		if carID, err = strconv.Atoi(parts[i-1]); err != nil {
+			return 0, 0, fmt.Errorf("invalid car ID: %w", err)
+		}
+		if i+1 < len(parts) && parts[i+1] != "diff" {
+			if revisionID, err = strconv.Atoi(parts[i+1]); err != nil {
+				return 0, 0, fmt.Errorf("invalid revision ID: %w", err)
+			}
+		}
+		return carID, revisionID, nil
+	}
+	return 0, 0, fmt.Errorf("invalid path format")
+}
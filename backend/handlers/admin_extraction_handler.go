@@ -2,12 +2,15 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/uzimpp/CarJai/backend/models"
@@ -27,7 +30,16 @@ func NewAdminExtractionHandler(es *services.ExtractionService) *AdminExtractionH
 	}
 }
 
-// ImportMarketPrices handles the PDF upload, extracts data, and commits directly to database.
+// marketPriceFileExtensions maps the content types of accepted market price files to their
+// extension, for uploads whose filename lacks one
+var marketPriceFileExtensions = map[string]string{
+	"application/pdf": ".pdf",
+	"text/csv":        ".csv",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": ".xlsx",
+}
+
+// ImportMarketPrices handles the PDF, CSV or XLSX upload, extracts data, and commits directly to database.
+// With dryRun=true the file is only read and a preview of the import is returned.
 func (h *AdminExtractionHandler) ImportMarketPrices(w http.ResponseWriter, r *http.Request) {
 	// --- File Upload Handling ---
 	err := r.ParseMultipartForm(50 << 20) // 50 MB
@@ -36,23 +48,38 @@ func (h *AdminExtractionHandler) ImportMarketPrices(w http.ResponseWriter, r *ht
 		utils.WriteError(w, http.StatusBadRequest, "Error processing uploaded file: "+err.Error())
 		return
 	}
-	file, fileHeader, err := r.FormFile("marketPricePdf")
+	// marketPricePdf is the original field name, kept for existing clients
+	file, fileHeader, err := r.FormFile("marketPriceFile")
+	if err == http.ErrMissingFile {
+		file, fileHeader, err = r.FormFile("marketPricePdf")
+	}
 	if err != nil {
 		log.Printf("Error retrieving file from form: %v", err)
-		utils.WriteError(w, http.StatusBadRequest, "PDF file ('marketPricePdf' field) is required.")
+		utils.WriteError(w, http.StatusBadRequest, "Market price file ('marketPriceFile' field) is required.")
 		return
 	}
 	defer file.Close()
-	if fileHeader.Header.Get("Content-Type") != "application/pdf" && filepath.Ext(fileHeader.Filename) != ".pdf" {
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".pdf" && ext != ".csv" && ext != ".xlsx" {
+		ext = marketPriceFileExtensions[fileHeader.Header.Get("Content-Type")]
+	}
+	if ext == "" {
 		log.Printf("Invalid file type uploaded: %s", fileHeader.Header.Get("Content-Type"))
-		utils.WriteError(w, http.StatusBadRequest, "Invalid file type. Only PDF is allowed.")
+		utils.WriteError(w, http.StatusBadRequest, "Invalid file type. Only PDF, CSV and XLSX are allowed.")
 		return
 	}
+	dryRun := false
+	if v := r.FormValue("dryRun"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid dryRun, expected true or false")
+			return
+		}
+	}
 
 	tempDir := os.TempDir()
-	tempFileName := fmt.Sprintf("market_price_upload_%d%s", time.Now().UnixNano(), filepath.Ext(fileHeader.Filename))
+	tempFileName := fmt.Sprintf("market_price_upload_%d%s", time.Now().UnixNano(), ext)
 	tempFilePath := filepath.Join(tempDir, tempFileName)
-	log.Printf("Saving uploaded PDF to temporary file: %s", tempFilePath)
+	log.Printf("Saving uploaded file to temporary file: %s", tempFilePath)
 	tempFile, err := os.Create(tempFilePath)
 	if err != nil {
 		log.Printf("Error creating temporary file: %v", err)
@@ -78,25 +105,62 @@ func (h *AdminExtractionHandler) ImportMarketPrices(w http.ResponseWriter, r *ht
 	}
 	// --- End File Upload Handling ---
 
+	// --- Dry Run ---
+	if dryRun {
+		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute)
+		defer cancel()
+		preview, err := h.ExtractionService.PreviewMarketPriceImport(ctx, tempFilePath)
+		if err != nil {
+			log.Printf("ERROR during market price preview of %s: %v", tempFilePath, err)
+			if errors.Is(err, models.ErrInvalidMarketPriceFile) {
+				utils.WriteError(w, http.StatusBadRequest, err.Error())
+			} else {
+				utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Preview failed: %v", err))
+			}
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, preview, "Dry run: nothing was imported")
+		return
+	}
+
+	// --- Version Options ---
+	// The import becomes a new version, active unless activate=false so it can be reviewed first
+	source, err := marketPriceVersionSource(r, fileHeader.Filename)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	// --- Call Import Service (Extract + Commit) ---
 	log.Printf("Starting market price import (extraction + database commit) for file: %s", tempFilePath)
 	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Minute) // Longer timeout for DB operations
 	defer cancel()
 
-	inserted, updated, importErr := h.ExtractionService.ImportMarketPricesFromPDF(ctx, tempFilePath)
+	version, rowErrors, importErr := h.ExtractionService.ImportMarketPriceFile(ctx, tempFilePath, source)
 	if importErr != nil {
 		log.Printf("ERROR during market price import from %s: %v", tempFilePath, importErr)
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Import failed: %v", importErr))
+		switch {
+		case len(rowErrors) > 0:
+			// Report each invalid row so the spreadsheet can be fixed
+			utils.WriteErrorWithFields(w, http.StatusBadRequest, importErr.Error(), map[string]interface{}{"rowErrors": rowErrors})
+		case errors.Is(importErr, models.ErrMarketPriceFileImported):
+			utils.WriteError(w, http.StatusConflict, importErr.Error())
+		case errors.Is(importErr, models.ErrInvalidMarketPriceFile):
+			utils.WriteError(w, http.StatusBadRequest, importErr.Error())
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Import failed: %v", importErr))
+		}
 		return
 	}
 
-	log.Printf("Market price import from %s completed successfully. Inserted: %d, Updated: %d", tempFilePath, inserted, updated)
+	log.Printf("Market price import from %s completed successfully as version %d. Inserted: %d, Updated: %d", tempFilePath, version.ID, version.InsertedCount, version.UpdatedCount)
 
 	// --- Respond with Success ---
 	response := models.MarketPriceImportResponse{
 		Message:       "Market prices imported successfully.",
-		InsertedCount: inserted,
-		UpdatedCount:  updated,
+		InsertedCount: version.InsertedCount,
+		UpdatedCount:  version.UpdatedCount,
+		Version:       version,
 	}
 	utils.WriteJSON(w, http.StatusOK, response, "")
 	log.Println("Admin ImportMarketPrices request processed successfully.")
@@ -118,3 +182,122 @@ func (h *AdminExtractionHandler) GetMarketPrices(w http.ResponseWriter, r *http.
 	log.Printf("Successfully retrieved %d market prices.", len(prices))
 	utils.WriteJSON(w, http.StatusOK, prices, "")
 }
+
+// marketPriceVersionSource reads the version options of an import form: effectiveFrom
+// (YYYY-MM-DD, defaults to today) and activate (defaults to true)
+func marketPriceVersionSource(r *http.Request, filename string) (models.MarketPriceVersionSource, error) {
+	source := models.MarketPriceVersionSource{Filename: filepath.Base(filename), Activate: true}
+	if adminID, err := strconv.Atoi(r.Header.Get("X-Admin-ID")); err == nil {
+		source.UploadedBy = &adminID
+	}
+	if v := r.FormValue("effectiveFrom"); v != "" {
+		effectiveFrom, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return source, fmt.Errorf("invalid effectiveFrom, expected YYYY-MM-DD")
+		}
+		source.EffectiveFrom = &effectiveFrom
+	}
+	if v := r.FormValue("activate"); v != "" {
+		activate, err := strconv.ParseBool(v)
+		if err != nil {
+			return source, fmt.Errorf("invalid activate, expected true or false")
+		}
+		source.Activate = activate
+	}
+	return source, nil
+}
+
+// GetMarketPriceVersions handles GET /admin/market-price/versions
+func (h *AdminExtractionHandler) GetMarketPriceVersions(w http.ResponseWriter, r *http.Request) {
+	versions, err := h.ExtractionService.GetMarketPriceVersions()
+	if err != nil {
+		log.Printf("ERROR fetching market price versions: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch market price versions")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, versions, "")
+}
+
+// ActivateMarketPriceVersion handles POST /admin/market-price/versions/{id}/activate
+func (h *AdminExtractionHandler) ActivateMarketPriceVersion(w http.ResponseWriter, r *http.Request) {
+	// Path: /admin/market-price/versions/{id}/activate
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid version ID")
+		return
+	}
+	versionID, err := strconv.Atoi(parts[len(parts)-2])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid version ID")
+		return
+	}
+
+	version, err := h.ExtractionService.ActivateMarketPriceVersion(versionID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("ERROR activating market price version %d: %v", versionID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to activate market price version")
+		return
+	}
+	log.Printf("Market price version %d activated by admin %s", versionID, r.Header.Get("X-Admin-ID"))
+	utils.WriteJSON(w, http.StatusOK, version, "Market price version activated")
+}
+
+// RollbackMarketPriceVersion handles POST /admin/market-price/versions/rollback
+func (h *AdminExtractionHandler) RollbackMarketPriceVersion(w http.ResponseWriter, r *http.Request) {
+	version, err := h.ExtractionService.RollbackMarketPriceVersion()
+	if err != nil {
+		if strings.Contains(err.Error(), "no earlier") {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		log.Printf("ERROR rolling back market price version: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to roll back market price version")
+		return
+	}
+	log.Printf("Market prices rolled back to version %d by admin %s", version.ID, r.Header.Get("X-Admin-ID"))
+	utils.WriteJSON(w, http.StatusOK, version, "Market prices rolled back")
+}
+
+// DiffMarketPriceVersions handles GET /admin/market-price/versions/diff?from={id}&to={id}
+func (h *AdminExtractionHandler) DiffMarketPriceVersions(w http.ResponseWriter, r *http.Request) {
+	fromID, errFrom := strconv.Atoi(r.URL.Query().Get("from"))
+	toID, errTo := strconv.Atoi(r.URL.Query().Get("to"))
+	if errFrom != nil || errTo != nil {
+		utils.WriteError(w, http.StatusBadRequest, "from and to version IDs are required")
+		return
+	}
+
+	diff, err := h.ExtractionService.DiffMarketPriceVersions(fromID, toID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		log.Printf("ERROR diffing market price versions %d and %d: %v", fromID, toID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to compare market price versions")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, diff, "")
+}
+
+// GetMarketPriceHistory handles GET /admin/market-price/history?brand=&model=&submodel=&year=
+func (h *AdminExtractionHandler) GetMarketPriceHistory(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	year, _ := strconv.Atoi(query.Get("year"))
+
+	history, err := h.ExtractionService.GetMarketPriceHistory(query.Get("brand"), query.Get("model"), query.Get("submodel"), year)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid request") {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		log.Printf("ERROR fetching market price history: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to fetch market price history")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, history, "")
+}
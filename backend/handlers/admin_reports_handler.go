@@ -66,10 +66,22 @@ func (h *AdminReportsHandler) ListReports(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Load duplicate photos of every reported car in one query
+	var carIDs []int
+	for _, report := range reports {
+		if report.ReportType == "car" && report.CarID != nil {
+			carIDs = append(carIDs, *report.CarID)
+		}
+	}
+	duplicatePhotos, err := h.carService.GetDuplicatePhotosBatch(carIDs)
+	if err != nil {
+		utils.AppLogger.Error("Failed to load duplicate photos of reported cars: " + err.Error())
+	}
+
 	// Convert to admin response format
 	adminReports := make([]models.AdminReportResponse, 0, len(reports))
 	for _, report := range reports {
-		adminReport := h.convertToAdminReport(report)
+		adminReport := h.convertToAdminReport(report, duplicatePhotos)
 		adminReports = append(adminReports, adminReport)
 	}
 
@@ -212,8 +224,9 @@ func (h *AdminReportsHandler) RemoveCar(w http.ResponseWriter, r *http.Request)
 }
 
 // convertToAdminReport converts a backend Report to AdminReportResponse
-// This function handles missing data gracefully by using placeholder values
-func (h *AdminReportsHandler) convertToAdminReport(report models.Report) models.AdminReportResponse {
+// This function handles missing data gracefully by using placeholder values.
+// duplicatePhotos holds the preloaded duplicate photo matches of reported cars, keyed by car ID.
+func (h *AdminReportsHandler) convertToAdminReport(report models.Report, duplicatePhotos map[int][]models.DuplicatePhotoMatch) models.AdminReportResponse {
 	adminReport := models.AdminReportResponse{
 		ID:          report.ID,
 		Reason:      report.Topic,
@@ -289,6 +302,9 @@ func (h *AdminReportsHandler) convertToAdminReport(report models.Report) models.
 				adminReport.TargetCarTitle = &defaultTitle
 			}
 		}
+
+		// Photos reused from other sellers' listings are a common sign of a fake listing
+		adminReport.DuplicatePhotos = duplicatePhotos[*report.CarID]
 	}
 
 	// Get resolved info
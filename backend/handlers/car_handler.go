@@ -2,10 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/uzimpp/CarJai/backend/middleware"
 	"github.com/uzimpp/CarJai/backend/models"
@@ -57,18 +59,15 @@ func (h *CarHandler) GetPriceEstimate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Get price estimation from service
-	price, err := h.carService.EstimateCarPrice(carID)
+	estimate, err := h.carService.ExplainCarPrice(carID)
 	if err != nil {
 		// Don't return 500, just indicate estimation is unavailable
 		utils.WriteError(w, http.StatusOK, err.Error()) // e.g., "estimation unavailable"
 		return
 	}
 
-	// Return estimated price
-	response := models.EstimatedPriceResponse{
-		EstimatedPrice: price,
-	}
-	utils.WriteJSON(w, http.StatusOK, response, "")
+	// Return estimated price with its breakdown
+	utils.WriteJSON(w, http.StatusOK, estimate, "")
 }
 
 // CreateCar handles POST /api/cars
@@ -127,9 +126,9 @@ func (h *CarHandler) GetCar(w http.ResponseWriter, r *http.Request) {
 	// 	isOwner = (carWithImages.Car.SellerID == userID)
 	// }
 
-	// Only allow access to active cars for public users
+	// Only allow access to published (active or reserved) cars for public users
 	// Owners can access their cars regardless of status (draft, sold, deleted)
-	if carWithImages.Car.Status != "active" {
+	if !services.IsPublishedStatus(carWithImages.Car.Status) {
 		utils.WriteError(w, http.StatusNotFound, "Car not found")
 		return
 	}
@@ -151,7 +150,7 @@ func (h *CarHandler) GetCar(w http.ResponseWriter, r *http.Request) {
 	enrichedImages := make([]models.CarImageMetadata, len(carWithImages.Images))
 	for i, img := range carWithImages.Images {
 		enrichedImages[i] = img
-		enrichedImages[i].URL = fmt.Sprintf("/api/cars/images/%d", img.ID)
+		enrichedImages[i].URL = services.CarImageURL(img.ID, img.ContentHash, "")
 	}
 
 	// Get seller contacts
@@ -160,12 +159,19 @@ func (h *CarHandler) GetCar(w http.ResponseWriter, r *http.Request) {
 		sellerContacts = contacts
 	}
 
+	// Get published price changes (best effort)
+	priceHistory := []models.CarPriceChange{}
+	if history, err := h.carService.GetCarPriceHistory(carID); err == nil {
+		priceHistory = history
+	}
+
 	// Return response with proper types
 	response := models.CarDetailResponse{
 		Car:            display.CarDisplay,
 		Images:         enrichedImages,
 		Inspection:     display.InspectionDisplay,
 		SellerContacts: sellerContacts,
+		PriceHistory:   priceHistory,
 	}
 	utils.WriteJSON(w, http.StatusOK, response, "")
 }
@@ -192,8 +198,32 @@ func (h *CarHandler) GetMyCars(w http.ResponseWriter, r *http.Request) {
 		lang = "en"
 	}
 
+	// Keyset pagination is opt-in (cursor or limit); without it the full list is returned
+	after, limit, paginated, err := parseCarListCursor(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	if paginated {
+		listItems, nextCursor, err := h.carService.GetCarListItemsBySellerIDPage(userID, lang, nil, after, limit)
+		if err != nil {
+			if errors.Is(err, models.ErrInvalidCursor) {
+				utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get cars: %v", err))
+			return
+		}
+		utils.WriteJSON(w, http.StatusOK, models.CursorCarListingData{
+			Cars:       listItems,
+			Limit:      limit,
+			NextCursor: nextCursor,
+		}, "")
+		return
+	}
+
 	// Get user's cars as lightweight list items (always translated for display)
-	listItems, err := h.carService.GetCarListItemsBySellerID(userID, lang, "")
+	listItems, err := h.carService.GetCarListItemsBySellerID(userID, lang, nil)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get cars: %v", err))
 		return
@@ -202,8 +232,266 @@ func (h *CarHandler) GetMyCars(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusOK, listItems, "")
 }
 
+// ExportMyCars handles GET /api/cars/my/export?format=csv|json|xlsx
+func (h *CarHandler) ExportMyCars(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	isSeller, err := h.userService.IsSeller(userID)
+	if err != nil || !isSeller {
+		utils.WriteError(w, http.StatusForbidden, "Only sellers can export their car listings")
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.CarExportCSV
+	}
+	contentType, ok := services.CarExportContentType(format)
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid format (allowed: csv, json, xlsx)")
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	// Headers are only sent with the first byte of the export, so a failure before
+	// anything is written can still be reported as an error response
+	out := &exportResponseWriter{
+		w:           w,
+		contentType: contentType,
+		filename:    fmt.Sprintf("carjai-inventory-%s.%s", time.Now().Format("20060102"), format),
+	}
+	if err := h.carService.ExportSellerCars(userID, lang, format, out); err != nil {
+		if !out.started {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to export cars: %v", err))
+			return
+		}
+		utils.AppLogger.WithField("seller_id", userID).Error("Inventory export aborted: " + err.Error())
+	}
+}
+
+// exportResponseWriter sets download headers on the first write
+type exportResponseWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	filename    string
+	started     bool
+}
+
+func (e *exportResponseWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.contentType)
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+		e.w.Header().Set("Cache-Control", "no-store")
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
+
 // SearchCars handles GET /api/cars/search (public)
 func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
+	req, page, limit := parseSearchCarsRequest(r)
+
+	// Parse keyset cursor (takes precedence over page) and optional count
+	if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+		after, err := models.DecodeCarCursor(cursor)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		req.After = after
+	}
+	if r.URL.Query().Get("count") == "false" {
+		req.SkipCount = true
+	}
+
+	// Distance sort measures from the signed-in buyer's province, else from near_province
+	if req.SortBy == "distance" {
+		req.OriginProvinceID = h.buyerProvinceID(r)
+		if req.OriginProvinceID == nil {
+			req.OriginProvinceID = req.NearProvinceID
+		}
+	}
+
+	// Get language preference (default to English)
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	// Search cars as lightweight list items (optimized for browse/search)
+	listItems, total, nextCursor, err := h.carService.SearchActiveCarsAsListItems(req, lang)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			utils.WriteError(w, http.StatusBadRequest, "Cursor does not match the requested sort")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search cars: %v", err))
+		return
+	}
+
+	response := models.PaginatedCarListingData{
+		Cars:       listItems,
+		Total:      total,
+		Page:       page,
+		Limit:      limit,
+		NextCursor: nextCursor,
+	}
+	utils.WriteJSON(w, http.StatusOK, response, "")
+}
+
+// GetSimilarCars handles GET /api/cars/{id}/similar (public)
+func (h *CarHandler) GetSimilarCars(w http.ResponseWriter, r *http.Request) {
+	carID, err := utils.ExtractIDFromPath(r.URL.Path, "/api/cars/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	limit := 8
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 24 {
+			limit = l
+		}
+	}
+
+	// Get language preference (default to English)
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	listItems, err := h.carService.GetSimilarCarListItems(carID, lang, limit)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Car not found")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get similar cars: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, listItems, "")
+}
+
+// CompareCars handles GET /api/cars/compare?ids=1,2,3 - Side-by-side comparison of active cars (public)
+func (h *CarHandler) CompareCars(w http.ResponseWriter, r *http.Request) {
+	var carIDs []int
+	for _, part := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := strconv.Atoi(part)
+		if err != nil || id <= 0 {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid car ID: "+part)
+			return
+		}
+		carIDs = append(carIDs, id)
+	}
+
+	// Get language preference (default to English)
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	comparison, err := h.carService.CompareCars(carIDs, lang)
+	if err != nil {
+		if errors.Is(err, models.ErrInvalidComparison) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to compare cars: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, comparison, "")
+}
+
+// buyerProvinceID returns the province ID saved in the signed-in buyer's profile, or nil
+// when the request is anonymous or the buyer has no (recognised) province
+func (h *CarHandler) buyerProvinceID(r *http.Request) *int {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok || h.profileService == nil {
+		return nil
+	}
+
+	buyer, err := h.profileService.GetBuyerByUserID(userID)
+	if err != nil || buyer == nil || buyer.Province == nil || *buyer.Province == "" {
+		return nil
+	}
+
+	provinceID, err := h.carService.LookupProvinceIDByName(*buyer.Province)
+	if err != nil {
+		return nil
+	}
+	return provinceID
+}
+
+// GetSearchFacets handles GET /api/cars/facets (public)
+// Accepts the same filters as /api/cars/search; pagination and sorting are ignored
+func (h *CarHandler) GetSearchFacets(w http.ResponseWriter, r *http.Request) {
+	req, _, _ := parseSearchCarsRequest(r)
+
+	// Get language preference (default to English)
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	facets, err := h.carService.GetSearchFacets(req, lang)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get search facets: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, facets, "")
+}
+
+// parseCarListCursor reads the optional cursor and limit query parameters of a seller car list
+// paginated is false when neither is provided, meaning the caller should return the full list
+func parseCarListCursor(r *http.Request) (after *models.CarCursor, limit int, paginated bool, err error) {
+	query := r.URL.Query()
+	cursor := query.Get("cursor")
+	limitStr := query.Get("limit")
+	if cursor == "" && limitStr == "" {
+		return nil, 0, false, nil
+	}
+
+	limit = 20
+	if l, convErr := strconv.Atoi(limitStr); convErr == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+
+	if cursor != "" {
+		after, err = models.DecodeCarCursor(cursor)
+		if err != nil {
+			return nil, 0, false, err
+		}
+	}
+
+	return after, limit, true, nil
+}
+
+// maxSearchRadiusKm caps the radius_km search parameter (Thailand spans ~1,650 km)
+const maxSearchRadiusKm = 2000
+
+// parseSearchCarsRequest builds a SearchCarsRequest from the search query parameters
+// Returns the request along with the parsed page and limit
+func parseSearchCarsRequest(r *http.Request) (*models.SearchCarsRequest, int, int) {
 	// Parse query parameters
 	query := r.URL.Query()
 
@@ -256,6 +544,19 @@ func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse region and near-province filters
+	req.Region = query.Get("region")
+	if nearStr := query.Get("near_province"); nearStr != "" {
+		if nearID, err := strconv.Atoi(nearStr); err == nil && nearID > 0 {
+			req.NearProvinceID = &nearID
+		}
+	}
+	if radiusStr := query.Get("radius_km"); radiusStr != "" {
+		if radiusKm, err := strconv.Atoi(radiusStr); err == nil && radiusKm > 0 && radiusKm <= maxSearchRadiusKm {
+			req.RadiusKm = &radiusKm
+		}
+	}
+
 	// Parse type filters
 	if bodyTypes := query["bodyType"]; len(bodyTypes) > 0 {
 		req.BodyTypeCodes = bodyTypes
@@ -286,9 +587,18 @@ func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Parse recently reduced filter
+	req.RecentlyReduced = query.Get("recentlyReduced") == "true"
+
+	// Reserved cars are hidden from search unless asked for
+	req.IncludeReserved = query.Get("includeReserved") == "true"
+
 	// Parse sorting
+	// "sort" is accepted as an alias of sortBy (e.g. sort=relevance)
 	if sortBy := query.Get("sortBy"); sortBy != "" {
 		req.SortBy = sortBy
+	} else if sortBy := query.Get("sort"); sortBy != "" {
+		req.SortBy = sortBy
 	}
 	if sortOrder := query.Get("sortOrder"); sortOrder != "" {
 		req.SortOrder = sortOrder
@@ -312,26 +622,7 @@ func (h *CarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
 	req.Limit = limit
 	req.Offset = (page - 1) * limit
 
-	// Get language preference (default to English)
-	lang := r.URL.Query().Get("lang")
-	if lang == "" {
-		lang = "en"
-	}
-
-	// Search cars as lightweight list items (optimized for browse/search)
-	listItems, total, err := h.carService.SearchActiveCarsAsListItems(req, lang)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to search cars: %v", err))
-		return
-	}
-
-	response := models.PaginatedCarListingData{
-		Cars:  listItems,
-		Total: total,
-		Page:  page,
-		Limit: limit,
-	}
-	utils.WriteJSON(w, http.StatusOK, response, "")
+	return req, page, limit
 }
 
 // UpdateCar handles PUT /api/cars/{id}
@@ -591,7 +882,7 @@ func (h *CarHandler) UploadCarImages(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Upload images
-	uploadedImages, err := h.carService.UploadCarImages(carID, userID, files, isAdmin)
+	uploadedImages, results, err := h.carService.UploadCarImages(carID, userID, files, isAdmin)
 	if err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			utils.WriteError(w, http.StatusForbidden, err.Error())
@@ -601,7 +892,17 @@ func (h *CarHandler) UploadCarImages(w http.ResponseWriter, r *http.Request) {
 			utils.WriteError(w, http.StatusNotFound, "Car not found")
 			return
 		}
-		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidImageUpload) {
+			status = http.StatusBadRequest
+		}
+		if results == nil {
+			utils.WriteError(w, status, err.Error())
+			return
+		}
+
+		// Report each file so the client can show which ones to fix
+		utils.WriteErrorWithFields(w, status, err.Error(), map[string]interface{}{"results": results})
 		return
 	}
 
@@ -609,6 +910,7 @@ func (h *CarHandler) UploadCarImages(w http.ResponseWriter, r *http.Request) {
 		CarID:         carID,
 		UploadedCount: len(uploadedImages),
 		Images:        uploadedImages,
+		Results:       results,
 	}
 	utils.WriteJSON(w, http.StatusCreated, response, "")
 }
@@ -616,7 +918,7 @@ func (h *CarHandler) UploadCarImages(w http.ResponseWriter, r *http.Request) {
 // HandleImageByID handles /api/cars/images/{id} - GET public, DELETE authenticated
 func (h *CarHandler) HandleImageByID(w http.ResponseWriter, r *http.Request, authMiddleware *middleware.UserAuthMiddleware) {
 	switch r.Method {
-	case http.MethodGet:
+	case http.MethodGet, http.MethodHead:
 		// Public: Get image data
 		h.GetCarImage(w, r)
 	case http.MethodDelete:
@@ -627,7 +929,9 @@ func (h *CarHandler) HandleImageByID(w http.ResponseWriter, r *http.Request, aut
 	}
 }
 
-// GetCarImage handles GET /api/cars/images/{id}
+// GetCarImage handles GET /api/cars/images/{id}?size=thumb|card|full|original&v={version}
+// Responses carry a strong ETag and support conditional and Range requests. Versioned URLs
+// (see services.CarImageURL) never change content, so they are cached as immutable.
 func (h *CarHandler) GetCarImage(w http.ResponseWriter, r *http.Request) {
 	// Extract image ID
 	imageID, err := utils.ExtractIDFromPath(r.URL.Path, "/api/cars/images/")
@@ -636,8 +940,42 @@ func (h *CarHandler) GetCarImage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get image
-	image, err := h.carService.GetCarImage(imageID)
+	size := r.URL.Query().Get("size")
+	image, err := h.carService.GetCarImageForServing(r.Context(), imageID, size)
+	if err != nil {
+		if strings.Contains(err.Error(), "invalid image size") {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Image not found")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError,
+			fmt.Sprintf("Failed to get image: %v", err),
+		)
+		return
+	}
+
+	// Unversioned URLs are revalidated, since an image's data can change (e.g. when an
+	// older image is cleaned of metadata)
+	if version := r.URL.Query().Get("v"); version != "" && version == services.CarImageVersion(image) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, no-cache")
+	}
+	etag := services.CarImageETag(image, size)
+	if etag != "" {
+		w.Header().Set("ETag", etag)
+		// Answer revalidations without opening the image
+		if utils.ETagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	// Open the image (or one of its resized derivatives)
+	served, data, err := h.carService.OpenCarImageSize(r.Context(), image, size)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			utils.WriteError(w, http.StatusNotFound, "Image not found")
@@ -648,13 +986,13 @@ func (h *CarHandler) GetCarImage(w http.ResponseWriter, r *http.Request) {
 		)
 		return
 	}
+	defer data.Close()
 
-	// Set content type and write image data
-	w.Header().Set("Content-Type", image.ImageType)
-	w.Header().Set("Content-Length", strconv.Itoa(len(image.ImageData)))
-	w.Header().Set("Cache-Control", "public, max-age=86400") // Cache for 1 day
-	w.WriteHeader(http.StatusOK)
-	w.Write(image.ImageData)
+	// ServeContent handles If-Modified-Since, Range and If-Range (using the ETag). The
+	// modification time is when the stored data last changed, not the upload time, since
+	// cleaning an older image rewrites it; derivatives are regenerated from that data.
+	w.Header().Set("Content-Type", served.ImageType)
+	http.ServeContent(w, r, "", served.DataUpdated, data)
 }
 
 // DeleteCarImage handles DELETE /api/cars/images/{id}
@@ -828,7 +1166,8 @@ func (h *CarHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 
 	// Parse request body
 	var req struct {
-		Status string `json:"status"`
+		Status           string `json:"status"`
+		ReservationHours *int   `json:"reservationHours"` // Only when reserving
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
@@ -841,30 +1180,56 @@ func (h *CarHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		isAdmin = true
 	}
 
-	// If changing to active, validate publish readiness
-	if req.Status == "active" {
-		ready, issues := h.carService.ValidatePublish(carID)
-		if !ready {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Cannot publish car: %v", issues))
+	// Update status via UpdateCar (which validates publish readiness when publishing)
+	updateReq := models.UpdateCarRequest{
+		Status:           &req.Status,
+		ReservationHours: req.ReservationHours,
+	}
+
+	if err := h.carService.UpdateCar(carID, userID, &updateReq, isAdmin); err != nil {
+		if strings.Contains(err.Error(), "unauthorized") {
+			utils.WriteError(w, http.StatusForbidden, err.Error())
 			return
 		}
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	// Update status via UpdateCar
-	updateReq := models.UpdateCarRequest{
-		Status: &req.Status,
+	utils.WriteJSON(w, http.StatusOK, nil, "Status updated successfully")
+}
+
+// RenewCar handles POST /api/cars/{id}/renew - Start a new listing period (owner-only)
+func (h *CarHandler) RenewCar(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return
 	}
 
-	if err := h.carService.UpdateCar(carID, userID, &updateReq, isAdmin); err != nil {
+	carID, err := utils.ExtractIDFromPath(r.URL.Path, "/api/cars/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid car ID")
+		return
+	}
+
+	car, err := h.carService.RenewCar(carID, userID)
+	if err != nil {
 		if strings.Contains(err.Error(), "unauthorized") {
 			utils.WriteError(w, http.StatusForbidden, err.Error())
 			return
 		}
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Car not found")
+			return
+		}
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil, "Status updated successfully")
+	utils.WriteJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    car.Status,
+		"expiresAt": car.ExpiresAt,
+	}, "Listing renewed successfully")
 }
 
 // UploadBook handles POST /api/cars/{id}/book - Upload vehicle registration book to existing car
@@ -1092,7 +1457,7 @@ func (h *CarHandler) RestoreProgress(w http.ResponseWriter, r *http.Request) {
 	enrichedImages := make([]models.CarImageMetadata, len(carWithImages.Images))
 	for i, img := range carWithImages.Images {
 		enrichedImages[i] = img
-		enrichedImages[i].URL = fmt.Sprintf("/api/cars/images/%d", img.ID)
+		enrichedImages[i].URL = services.CarImageURL(img.ID, img.ContentHash, "")
 	}
 
 	// Return response with proper types
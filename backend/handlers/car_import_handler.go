@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// maxCarImportUpload caps an import upload (CSV plus images zip)
+const maxCarImportUpload = 1 << 30
+
+// CarImportHandler handles bulk listing import endpoints
+type CarImportHandler struct {
+	importService *services.CarImportService
+	userService   *services.UserService
+}
+
+// NewCarImportHandler creates a new car import handler
+func NewCarImportHandler(importService *services.CarImportService, userService *services.UserService) *CarImportHandler {
+	return &CarImportHandler{importService: importService, userService: userService}
+}
+
+// requireSeller returns the authenticated user's ID, writing an error response
+// unless the user is a seller
+func (h *CarImportHandler) requireSeller(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "Unauthorized")
+		return 0, false
+	}
+
+	isSeller, err := h.userService.IsSeller(userID)
+	if err != nil || !isSeller {
+		utils.WriteError(w, http.StatusForbidden, "Only sellers can import cars")
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// StartImport handles POST /api/cars/import
+func (h *CarImportHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireSeller(w, r)
+	if !ok {
+		return
+	}
+
+	// Files beyond 32MB are buffered to disk by the multipart parser
+	r.Body = http.MaxBytesReader(w, r.Body, maxCarImportUpload)
+	if err := r.ParseMultipartForm(32 << 20); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Failed to parse multipart form")
+		return
+	}
+
+	csvFile, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "CSV file is required")
+		return
+	}
+	defer csvFile.Close()
+
+	var images io.Reader
+	if imagesFile, _, err := r.FormFile("images"); err == nil {
+		defer imagesFile.Close()
+		images = imagesFile
+	}
+
+	publish := r.FormValue("publish") == "true"
+
+	job, err := h.importService.StartImport(userID, csvFile, images, publish)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrInvalidCarImport):
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		case errors.Is(err, models.ErrCarImportInProgress):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to start import")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusAccepted, job, "Import started")
+}
+
+// GetImport handles GET /api/cars/import/{id}
+func (h *CarImportHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireSeller(w, r)
+	if !ok {
+		return
+	}
+
+	jobID, err := utils.ExtractIDFromPath(r.URL.Path, "/api/cars/import/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid import ID")
+		return
+	}
+
+	job, err := h.importService.GetImport(jobID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			utils.WriteError(w, http.StatusNotFound, "Import not found")
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to get import")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, job, "")
+}
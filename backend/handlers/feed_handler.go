@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// FeedHandler handles the home feed endpoint
+type FeedHandler struct {
+	feedService *services.FeedService
+}
+
+// NewFeedHandler creates a new feed handler
+func NewFeedHandler(feedService *services.FeedService) *FeedHandler {
+	return &FeedHandler{feedService: feedService}
+}
+
+// GetFeed handles GET /api/feed (optional auth)
+func (h *FeedHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	page := 1
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	limit := 20
+	if limitStr := query.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	// Get language preference (default to English)
+	lang := query.Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+
+	// Anonymous users (userID 0) get the trending feed
+	userID, _ := middleware.GetUserIDFromContext(r)
+
+	feed, err := h.feedService.GetFeed(userID, lang, page, limit)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to get feed: %v", err))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, feed, "")
+}
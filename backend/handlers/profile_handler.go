@@ -150,16 +150,29 @@ func (h *ProfileHandler) GetSellerProfile(w http.ResponseWriter, r *http.Request
 		contacts = []models.SellerContact{}
 	}
 
-	// Get seller's active cars only (lightweight list items only)
+	// Get seller's published cars only, reserved ones included (lightweight list items only)
 	// Get language preference (default to English)
 	lang := r.URL.Query().Get("lang")
 	if lang == "" {
 		lang = "en"
 	}
-	cars, err := h.carService.GetCarListItemsBySellerID(sellerID, lang, "active")
+	// Keyset pagination is opt-in (cursor or limit); without it all published cars are returned
+	after, limit, paginated, err := parseCarListCursor(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+		return
+	}
+	var cars []models.CarListItem
+	nextCursor := ""
+	if paginated {
+		cars, nextCursor, err = h.carService.GetCarListItemsBySellerIDPage(sellerID, lang, services.PublishedStatuses, after, limit)
+	} else {
+		cars, err = h.carService.GetCarListItemsBySellerID(sellerID, lang, services.PublishedStatuses)
+	}
 	if err != nil {
 		// Return seller without cars if cars fetch fails
 		cars = []models.CarListItem{}
+		nextCursor = ""
 	}
 
 	// Get sold cars count
@@ -173,6 +186,7 @@ func (h *ProfileHandler) GetSellerProfile(w http.ResponseWriter, r *http.Request
 		Seller:        *seller,
 		Contacts:      contacts,
 		Cars:          cars,
+		NextCursor:    nextCursor,
 		SoldCarsCount: soldCarsCount,
 	}
 	utils.WriteJSON(w, http.StatusOK, response, "")
@@ -25,8 +25,10 @@ type ReferenceOption struct {
 
 // ProvinceOption represents a province with ID and name
 type ProvinceOption struct {
-	ID    int    `json:"id"`
-	Label string `json:"label"`
+	ID          int    `json:"id"`
+	Label       string `json:"label"`
+	Region      string `json:"region"`      // English region name, used as the search "region" filter value
+	RegionLabel string `json:"regionLabel"` // Region name in the requested language
 }
 
 // ReferenceData contains all dropdown options
@@ -193,7 +195,7 @@ func (h *ReferenceHandler) getDrivetrains(lang string) ([]ReferenceOption, error
 
 // GetBrands handles GET /api/reference-data/brands
 func (h *ReferenceHandler) GetBrands(w http.ResponseWriter, r *http.Request) {
-	query := `SELECT DISTINCT brand FROM market_price WHERE brand IS NOT NULL AND brand != '' ORDER BY brand;`
+	query := `SELECT DISTINCT brand FROM market_price WHERE brand IS NOT NULL AND brand != '' AND version_id = (SELECT id FROM market_price_versions WHERE is_active) ORDER BY brand;`
 
 	rows, err := h.db.Query(query)
 	if err != nil {
@@ -224,7 +226,7 @@ func (h *ReferenceHandler) GetModels(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `SELECT DISTINCT model FROM market_price WHERE brand = $1 AND model IS NOT NULL AND model != '' ORDER BY model;`
+	query := `SELECT DISTINCT model FROM market_price WHERE brand = $1 AND model IS NOT NULL AND model != '' AND version_id = (SELECT id FROM market_price_versions WHERE is_active) ORDER BY model;`
 	rows, err := h.db.Query(query, brand)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to query models: "+err.Error())
@@ -255,7 +257,7 @@ func (h *ReferenceHandler) GetSubModels(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	query := `SELECT DISTINCT sub_model FROM market_price WHERE brand = $1 AND model = $2 AND sub_model IS NOT NULL AND sub_model != '' ORDER BY sub_model;`
+	query := `SELECT DISTINCT sub_model FROM market_price WHERE brand = $1 AND model = $2 AND sub_model IS NOT NULL AND sub_model != '' AND version_id = (SELECT id FROM market_price_versions WHERE is_active) ORDER BY sub_model;`
 	rows, err := h.db.Query(query, brand, model)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to query submodels: "+err.Error())
@@ -279,10 +281,12 @@ func (h *ReferenceHandler) GetSubModels(w http.ResponseWriter, r *http.Request)
 
 func (h *ReferenceHandler) getProvinces(lang string) ([]ProvinceOption, error) {
 	nameCol := "name_en"
+	regionCol := "region_en"
 	if lang == "th" {
 		nameCol = "name_th"
+		regionCol = "region_th"
 	}
-	query := "SELECT id, " + nameCol + " FROM provinces ORDER BY " + nameCol
+	query := "SELECT id, " + nameCol + ", COALESCE(region_en, ''), COALESCE(" + regionCol + ", '') FROM provinces ORDER BY " + nameCol
 	rows, err := h.db.Query(query)
 	if err != nil {
 		return nil, err
@@ -292,7 +296,7 @@ func (h *ReferenceHandler) getProvinces(lang string) ([]ProvinceOption, error) {
 	var provinces []ProvinceOption
 	for rows.Next() {
 		var opt ProvinceOption
-		if err := rows.Scan(&opt.ID, &opt.Label); err != nil {
+		if err := rows.Scan(&opt.ID, &opt.Label, &opt.Region, &opt.RegionLabel); err != nil {
 			return nil, err
 		}
 		provinces = append(provinces, opt)
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// SavedSearchHandler handles saved search and inbox endpoints
+type SavedSearchHandler struct {
+	savedSearchService *services.SavedSearchService
+	userService        *services.UserService
+}
+
+// NewSavedSearchHandler creates a new saved search handler
+func NewSavedSearchHandler(savedSearchService *services.SavedSearchService, userService *services.UserService) *SavedSearchHandler {
+	return &SavedSearchHandler{savedSearchService: savedSearchService, userService: userService}
+}
+
+// requireBuyer returns the authenticated user's ID, writing an error response
+// unless the user has the buyer role
+func (h *SavedSearchHandler) requireBuyer(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := middleware.GetUserIDFromContext(r)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return 0, false
+	}
+
+	token, ok := middleware.GetTokenFromContext(r)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "Authentication required")
+		return 0, false
+	}
+	me, err := h.userService.GetCurrentUser(token)
+	if err != nil || !me.Roles.Buyer {
+		utils.WriteError(w, http.StatusForbidden, "Access denied: buyer role required")
+		return 0, false
+	}
+
+	return userID, true
+}
+
+// writeSavedSearchError maps saved search service errors to HTTP responses
+func writeSavedSearchError(w http.ResponseWriter, err error) {
+	if strings.Contains(err.Error(), "not found") {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if strings.Contains(err.Error(), "failed to") {
+		utils.WriteError(w, http.StatusInternalServerError, "Failed to process saved search")
+		return
+	}
+	utils.WriteError(w, http.StatusBadRequest, err.Error())
+}
+
+// ListSavedSearches handles GET /api/saved-searches
+func (h *SavedSearchHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	searches, err := h.savedSearchService.GetSavedSearches(userID)
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, searches, "")
+}
+
+// CreateSavedSearch handles POST /api/saved-searches
+func (h *SavedSearchHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ss, err := h.savedSearchService.CreateSavedSearch(userID, req)
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, ss, "Saved search created")
+}
+
+// GetSavedSearch handles GET /api/saved-searches/{id}
+func (h *SavedSearchHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := utils.ExtractIDFromPath(r.URL.Path, "/api/saved-searches/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+
+	ss, err := h.savedSearchService.GetSavedSearch(userID, id)
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, ss, "")
+}
+
+// UpdateSavedSearch handles PUT /api/saved-searches/{id}
+func (h *SavedSearchHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := utils.ExtractIDFromPath(r.URL.Path, "/api/saved-searches/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+
+	var req models.SavedSearchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	ss, err := h.savedSearchService.UpdateSavedSearch(userID, id, req)
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, ss, "Saved search updated")
+}
+
+// DeleteSavedSearch handles DELETE /api/saved-searches/{id}
+func (h *SavedSearchHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	id, err := utils.ExtractIDFromPath(r.URL.Path, "/api/saved-searches/")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid saved search ID")
+		return
+	}
+
+	if err := h.savedSearchService.DeleteSavedSearch(userID, id); err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil, "Saved search deleted")
+}
+
+// GetInbox handles GET /api/saved-searches/inbox
+func (h *SavedSearchHandler) GetInbox(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	// Get language preference (default to English)
+	lang := r.URL.Query().Get("lang")
+	if lang == "" {
+		lang = "en"
+	}
+	unreadOnly := r.URL.Query().Get("unread") == "true"
+
+	inbox, err := h.savedSearchService.GetInbox(userID, unreadOnly, lang)
+	if err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, inbox, "")
+}
+
+// MarkInboxRead handles POST /api/saved-searches/inbox/read
+func (h *SavedSearchHandler) MarkInboxRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := h.requireBuyer(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MarkInboxReadRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+
+	if err := h.savedSearchService.MarkInboxRead(userID, req.IDs); err != nil {
+		writeSavedSearchError(w, err)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, nil, "Inbox marked as read")
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// ValuationHandler handles price estimates for cars that are not listed
+type ValuationHandler struct {
+	carService *services.CarService
+}
+
+// NewValuationHandler creates a new valuation handler
+func NewValuationHandler(carService *services.CarService) *ValuationHandler {
+	return &ValuationHandler{carService: carService}
+}
+
+// maxValuationRequestSize limits the size of a valuation request body
+const maxValuationRequestSize = 4 << 10
+
+// Valuate handles POST /api/valuation (public)
+func (h *ValuationHandler) Valuate(w http.ResponseWriter, r *http.Request) {
+	var req models.ValuationRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxValuationRequestSize)).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	estimate, err := h.carService.EstimateSpecPrice(req)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid request"):
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+		case strings.Contains(err.Error(), "estimation unavailable"):
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, "Failed to estimate price")
+		}
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, estimate, "")
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/uzimpp/CarJai/backend/models"
 	"github.com/uzimpp/CarJai/backend/routes"
 	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/storage"
 	"github.com/uzimpp/CarJai/backend/utils"
 )
 
@@ -31,6 +32,9 @@ func main() {
 	// Initialize services
 	services := initializeServices(db, appConfig)
 
+	// Rate limits trust forwarding headers only from the configured proxies
+	middleware.SetTrustedProxies(appConfig.TrustedProxies)
+
 	// Setup routers
 	routers := setupRoutes(services, appConfig, db)
 
@@ -57,6 +61,9 @@ type ServiceContainer struct {
 	Profile     *services.ProfileService
 	Car         *services.CarService
 	Favourite   *services.FavouriteService
+	SavedSearch *services.SavedSearchService
+	CarImport   *services.CarImportService
+	Feed        *services.FeedService
 	Report      *services.ReportService
 	Maintenance *services.MaintenanceService
 	OCR         *services.OCRService
@@ -84,7 +91,9 @@ func initializeServices(db *sql.DB, appConfig *config.AppConfig) *ServiceContain
 	carColorRepo := models.NewCarColorRepository(database)
 	carFuelRepo := models.NewCarFuelRepository(database)
 	marketPriceRepo := models.NewMarketPriceRepository(database)
+	carRevisionRepo := models.NewCarRevisionRepository(database)
 	favouriteRepo := models.NewFavouriteRepository(database)
+	savedSearchRepo := models.NewSavedSearchRepository(database)
 	reportRepo := models.NewReportRepository(database)
 	// Create JWT managers
 	userJWTManager := utils.NewJWTManager(
@@ -137,6 +146,14 @@ func initializeServices(db *sql.DB, appConfig *config.AppConfig) *ServiceContain
 	// Set profile service on user service (to avoid circular dependency)
 	userService.SetProfileService(profileService)
 
+	// Create the car image store (local disk or S3, see IMAGE_STORAGE)
+	imageStorageConfig := config.LoadImageStorageConfig()
+	imageStore, err := storage.New(imageStorageConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize image storage: %v", err)
+	}
+	log.Printf("✅ Image storage: %s", imageStorageConfig.Backend)
+
 	// Create car service with all repositories
 	carService := services.NewCarService(
 		carRepo,
@@ -145,9 +162,42 @@ func initializeServices(db *sql.DB, appConfig *config.AppConfig) *ServiceContain
 		carColorRepo,
 		carFuelRepo,
 		marketPriceRepo,
+		carRevisionRepo,
+		imageStore,
 	)
+	if appConfig.SimilarCarWeights != "" {
+		weights, err := models.ParseSimilarCarWeights(appConfig.SimilarCarWeights, carService.SimilarCarWeights())
+		if err != nil {
+			log.Printf("⚠️  Ignoring invalid SIMILAR_CAR_WEIGHTS: %v", err)
+		} else {
+			carService.SetSimilarCarWeights(weights)
+		}
+	}
+	carService.SetBlockDuplicatePhotos(appConfig.BlockDuplicatePhotos)
+	// Deleting a user also removes their cars' images from the image store
+	userService.SetCarService(carService)
 	// Create favourites service
 	favouriteService := services.NewFavouriteService(favouriteRepo, carService)
+	// Create home feed service
+	feedService := services.NewFeedService(carRepo, carService, profileService)
+	// Create saved search service (new-listing alerts are emailed through the email service)
+	savedSearchService := services.NewSavedSearchService(
+		savedSearchRepo,
+		userRepo,
+		carService,
+		emailService,
+		appConfig.FrontendURL,
+		utils.AppLogger,
+	)
+	// Publishing a listing alerts buyers whose saved searches match it
+	carService.SetSavedSearchService(savedSearchService)
+	// Create bulk listing import service
+	carImportService := services.NewCarImportService(models.NewCarImportRepository(database), carService)
+	if n, err := carImportService.FailInterruptedImports(); err != nil {
+		log.Printf("⚠️  Failed to fail interrupted car imports: %v", err)
+	} else if n > 0 {
+		log.Printf("⚠️  Marked %d interrupted car imports as failed", n)
+	}
 	// Create report service
 	reportService := services.NewReportService(reportRepo, carService, profileService, database)
 
@@ -158,7 +208,18 @@ func initializeServices(db *sql.DB, appConfig *config.AppConfig) *ServiceContain
 	recentViewsService := services.NewRecentViewsService(db, carService)
 
 	// Create extraction service
-	extractionService := services.NewExtractionService(db)
+	extractionService := services.NewExtractionService(db, marketPriceRepo)
+
+	// Create maintenance service (also sends the daily saved search digests and expires listings)
+	maintenanceService := services.NewMaintenanceService(
+		adminRepo,
+		sessionRepo,
+		ipWhitelistRepo,
+		carRepo,
+		utils.AppLogger,
+	)
+	maintenanceService.SetSavedSearchService(savedSearchService)
+	maintenanceService.SetListingLifecycle(carService, emailService, appConfig.FrontendURL)
 
 	return &ServiceContainer{
 		Admin: services.NewAdminService(
@@ -167,18 +228,15 @@ func initializeServices(db *sql.DB, appConfig *config.AppConfig) *ServiceContain
 			ipWhitelistRepo,
 			adminJWTManager,
 		),
-		User:      userService,
-		Profile:   profileService,
-		Car:       carService,
-		Favourite: favouriteService,
-		Report:    reportService,
-		Maintenance: services.NewMaintenanceService(
-			adminRepo,
-			sessionRepo,
-			ipWhitelistRepo,
-			carRepo,
-			utils.AppLogger,
-		),
+		User:        userService,
+		Profile:     profileService,
+		Car:         carService,
+		Favourite:   favouriteService,
+		Report:      reportService,
+		SavedSearch: savedSearchService,
+		CarImport:   carImportService,
+		Feed:        feedService,
+		Maintenance: maintenanceService,
 		OCR:         services.NewOCRService(appConfig.AigenAPIKey),
 		Scraper:     scraperService,
 		RecentViews: recentViewsService,
@@ -206,12 +264,25 @@ func setupRoutes(services *ServiceContainer, appConfig *config.AppConfig, db *sq
 		routes.CarRoutes(services.Car, services.User, services.Profile, services.OCR, services.Scraper, services.UserJWT, appConfig.CORSAllowedOrigins))
 	mux.Handle("/api/cars/",
 		routes.CarRoutes(services.Car, services.User, services.Profile, services.OCR, services.Scraper, services.UserJWT, appConfig.CORSAllowedOrigins))
+	// Bulk listing import routes (seller-authenticated)
+	mux.Handle("/api/cars/import",
+		routes.CarImportRoutes(services.CarImport, services.User, appConfig.CORSAllowedOrigins))
+	mux.Handle("/api/cars/import/",
+		routes.CarImportRoutes(services.CarImport, services.User, appConfig.CORSAllowedOrigins))
 
 	// Favourite routes
 	mux.Handle("/api/favorites",
 		routes.FavouritesRoutes(services.Favourite, services.User, appConfig.CORSAllowedOrigins))
 	mux.Handle("/api/favorites/",
 		routes.FavouritesRoutes(services.Favourite, services.User, appConfig.CORSAllowedOrigins))
+	// Home feed route (personalized when signed in)
+	mux.Handle("/api/feed",
+		routes.FeedRoutes(services.Feed, services.User, appConfig.CORSAllowedOrigins))
+	// Saved search routes (buyer-authenticated)
+	mux.Handle("/api/saved-searches",
+		routes.SavedSearchRoutes(services.SavedSearch, services.User, appConfig.CORSAllowedOrigins))
+	mux.Handle("/api/saved-searches/",
+		routes.SavedSearchRoutes(services.SavedSearch, services.User, appConfig.CORSAllowedOrigins))
 	// Report routes (user-authenticated)
 	mux.Handle("/api/reports",
 		routes.ReportRoutes(services.Report, services.User, appConfig.CORSAllowedOrigins))
@@ -231,6 +302,9 @@ func setupRoutes(services *ServiceContainer, appConfig *config.AppConfig, db *sq
 			appConfig,
 		),
 	)
+	// Public valuation route (also embedded as a widget on other sites)
+	mux.Handle("/api/valuation",
+		routes.ValuationRoutes(services.Car, appConfig.ValuationAllowedOrigins))
 	mux.Handle("/health",
 		routes.HealthRoutes(db, appConfig.CORSAllowedOrigins))
 	mux.Handle("/api/recent-views",
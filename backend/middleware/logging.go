@@ -64,7 +64,7 @@ func LoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Calculate metrics
 		duration := time.Since(start)
 		requestSize := getRequestSize(r)
-		responseSize := int64(responseBuffer.Len())
+		responseSize := wrapped.size
 
 		// Prepare response data for logging (only in development)
 		var responseData string
@@ -101,19 +101,30 @@ type responseWriter struct {
 	http.ResponseWriter
 	statusCode   int
 	responseData *bytes.Buffer
+	size         int64
 }
 
+// maxCapturedResponse caps how much of a response is kept for logging (bodies are
+// truncated to 1000 characters when logged), so streamed downloads are not buffered
+const maxCapturedResponse = 4096
+
 func (rw *responseWriter) WriteHeader(code int) {
 	rw.statusCode = code
 	rw.ResponseWriter.WriteHeader(code)
 }
 
 func (rw *responseWriter) Write(data []byte) (int, error) {
-	// Capture response data for logging
-	if rw.responseData != nil {
-		rw.responseData.Write(data)
+	// Capture the start of the response data for logging
+	if rw.responseData != nil && rw.responseData.Len() < maxCapturedResponse {
+		n := len(data)
+		if room := maxCapturedResponse - rw.responseData.Len(); n > room {
+			n = room
+		}
+		rw.responseData.Write(data[:n])
 	}
-	return rw.ResponseWriter.Write(data)
+	n, err := rw.ResponseWriter.Write(data)
+	rw.size += int64(n)
+	return n, err
 }
 
 // AdminLoggingMiddleware logs admin-specific requests with enhanced structured logging
@@ -144,7 +155,7 @@ func AdminLoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Calculate metrics
 		duration := time.Since(start)
 		requestSize := getRequestSize(r)
-		responseSize := int64(responseBuffer.Len())
+		responseSize := wrapped.size
 
 		// Prepare response data for logging (only in development)
 		var responseData string
@@ -212,7 +223,7 @@ func DetailedLoggingMiddleware(next http.HandlerFunc) http.HandlerFunc {
 		// Calculate metrics
 		duration := time.Since(start)
 		requestSize := getRequestSize(r)
-		responseSize := int64(responseBuffer.Len())
+		responseSize := wrapped.size
 
 		// Prepare response data for logging (only in development)
 		var responseData string
@@ -21,11 +21,26 @@ type RateLimiter struct {
 
 var (
 	// Singleton rate limiters to prevent goroutine leaks
-	loginRateLimiter    *RateLimiter
-	generalRateLimiter  *RateLimiter
-	rateLimiterInitOnce sync.Once
+	loginRateLimiter     *RateLimiter
+	generalRateLimiter   *RateLimiter
+	valuationRateLimiter *RateLimiter
+	rateLimiterInitOnce  sync.Once
+
+	// Proxies (IPs or CIDRs) whose X-Forwarded-For and X-Real-IP headers are honoured
+	trustedProxies []string
 )
 
+// SetTrustedProxies sets the proxies whose forwarding headers identify the client for rate
+// limiting. Without any, limits are keyed by the connection's address.
+func SetTrustedProxies(proxies []string) {
+	trustedProxies = proxies
+}
+
+// clientIP returns the IP address rate limits are keyed by
+func clientIP(r *http.Request) string {
+	return utils.ExtractClientIPBehindProxies(r.RemoteAddr, r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Real-IP"), trustedProxies)
+}
+
 // NewRateLimiter creates a new rate limiter
 func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
 	rl := &RateLimiter{
@@ -191,17 +206,7 @@ func RateLimitMiddleware(limiter *RateLimiter, keyFunc func(*http.Request) strin
 func IPBasedRateLimit(limit int, window time.Duration) func(http.HandlerFunc) http.HandlerFunc {
 	limiter := NewRateLimiter(limit, window)
 
-	return RateLimitMiddleware(limiter, func(r *http.Request) string {
-		// Use IP address as the key
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = forwarded
-		}
-		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-			ip = realIP
-		}
-		return ip
-	})
+	return RateLimitMiddleware(limiter, clientIP)
 }
 
 // initRateLimiters initializes singleton rate limiters (thread-safe)
@@ -211,6 +216,8 @@ func initRateLimiters() {
 		loginRateLimiter = NewRateLimiter(5, 15*time.Minute)
 		// General rate limiter: 100 requests per minute per IP
 		generalRateLimiter = NewRateLimiter(100, time.Minute)
+		// Valuation rate limiter: 20 estimates per minute per IP (public, embeddable)
+		valuationRateLimiter = NewRateLimiter(20, time.Minute)
 	})
 }
 
@@ -218,34 +225,22 @@ func initRateLimiters() {
 // Uses a singleton instance to prevent goroutine leaks
 func LoginRateLimit() func(http.HandlerFunc) http.HandlerFunc {
 	initRateLimiters()
-	return RateLimitMiddleware(loginRateLimiter, func(r *http.Request) string {
-		// Use IP address as the key
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = forwarded
-		}
-		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-			ip = realIP
-		}
-		return ip
-	})
+	return RateLimitMiddleware(loginRateLimiter, clientIP)
 }
 
 // GeneralRateLimit creates a general rate limiter for API endpoints
 // Uses a singleton instance to prevent goroutine leaks
 func GeneralRateLimit() func(http.HandlerFunc) http.HandlerFunc {
 	initRateLimiters()
-	return RateLimitMiddleware(generalRateLimiter, func(r *http.Request) string {
-		// Use IP address as the key
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = forwarded
-		}
-		if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
-			ip = realIP
-		}
-		return ip
-	})
+	return RateLimitMiddleware(generalRateLimiter, clientIP)
+}
+
+// ValuationRateLimit creates a rate limiter for the public valuation endpoint, separate from
+// the general limit so embedded widgets can't use up a visitor's API quota or vice versa.
+// Uses a singleton instance to prevent goroutine leaks
+func ValuationRateLimit() func(http.HandlerFunc) http.HandlerFunc {
+	initRateLimiters()
+	return RateLimitMiddleware(valuationRateLimiter, clientIP)
 }
 
 // StopAllRateLimiters stops all singleton rate limiters (for graceful shutdown)
@@ -257,4 +252,7 @@ func StopAllRateLimiters() {
 	if generalRateLimiter != nil {
 		generalRateLimiter.Stop()
 	}
+	if valuationRateLimiter != nil {
+		valuationRateLimiter.Stop()
+	}
 }
@@ -0,0 +1,12 @@
+-- Car Search Trigram Indexes
+
+-- Up
+-- Trigram indexes for fuzzy, typo-tolerant text search (pg_trgm is installed in 000_extensions.sql).
+-- The expression below must match carSearchDocumentSQL in models/car.go so the planner can use the index.
+CREATE INDEX IF NOT EXISTS idx_cars_search_document_trgm ON cars USING GIN (
+    (
+        COALESCE(brand_name, '') || ' ' || COALESCE(model_name, '') || ' ' || COALESCE(submodel_name, '')
+    ) gin_trgm_ops
+);
+
+CREATE INDEX IF NOT EXISTS idx_cars_description_trgm ON cars USING GIN (description gin_trgm_ops);
@@ -0,0 +1,42 @@
+-- Saved Searches and New-Match Alerts
+
+-- Up
+-- Buyers save search criteria under a name and get alerted about newly published matches
+CREATE TABLE saved_searches (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    criteria JSONB NOT NULL DEFAULT '{}'::jsonb, -- Search filters (same names as /api/cars/search query params)
+    email_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'instant' CHECK (
+        frequency IN ('instant', 'daily')
+    ),
+    last_digest_sent_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_searches_user_id ON saved_searches (user_id);
+
+CREATE TRIGGER update_saved_searches_updated_at
+    BEFORE UPDATE ON saved_searches
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Per-user inbox of cars that matched a saved search when they were published
+CREATE TABLE saved_search_matches (
+    id SERIAL PRIMARY KEY,
+    saved_search_id INTEGER NOT NULL REFERENCES saved_searches (id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    car_id INTEGER NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    emailed_at TIMESTAMP, -- NULL until included in an alert email
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (saved_search_id, car_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_matches_user_created ON saved_search_matches (user_id, created_at DESC);
+
+CREATE INDEX IF NOT EXISTS idx_saved_search_matches_unemailed ON saved_search_matches (saved_search_id)
+WHERE
+    emailed_at IS NULL;
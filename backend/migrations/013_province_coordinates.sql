@@ -0,0 +1,90 @@
+-- Province Coordinates for Region and Distance Search
+
+-- Up
+-- Approximate coordinates of each province's capital, used to compute distances between provinces
+ALTER TABLE provinces
+ADD COLUMN latitude DOUBLE PRECISION,
+ADD COLUMN longitude DOUBLE PRECISION;
+
+UPDATE provinces
+SET
+    latitude = coords.latitude,
+    longitude = coords.longitude
+FROM (
+        VALUES
+        ('Chiang Mai', 18.7883, 98.9853),
+        ('Chiang Rai', 19.9105, 99.8406),
+        ('Lamphun', 18.5745, 99.0087),
+        ('Lampang', 18.2888, 99.4909),
+        ('Mae Hong Son', 19.3020, 97.9654),
+        ('Nan', 18.7756, 100.7730),
+        ('Phayao', 19.1665, 99.9019),
+        ('Phrae', 18.1445, 100.1403),
+        ('Uttaradit', 17.6201, 100.0993),
+        ('Phitsanulok', 16.8211, 100.2659),
+        ('Sukhothai', 17.0056, 99.8264),
+        ('Phichit', 16.4419, 100.3488),
+        ('Kamphaeng Phet', 16.4828, 99.5227),
+        ('Tak', 16.8840, 99.1258),
+        ('Khon Kaen', 16.4322, 102.8236),
+        ('Kalasin', 16.4315, 103.5059),
+        ('Maha Sarakham', 16.1851, 103.3029),
+        ('Roi Et', 16.0538, 103.6520),
+        ('Yasothon', 15.7921, 104.1453),
+        ('Amnat Charoen', 15.8657, 104.6258),
+        ('Ubon Ratchathani', 15.2287, 104.8564),
+        ('Si Sa Ket', 15.1186, 104.3220),
+        ('Nakhon Ratchasima', 14.9799, 102.0978),
+        ('Buri Ram', 14.9930, 103.1029),
+        ('Surin', 14.8818, 103.4936),
+        ('Chaiyaphum', 15.8068, 102.0316),
+        ('Nong Bua Lam Phu', 17.2218, 102.4260),
+        ('Nong Khai', 17.8783, 102.7420),
+        ('Loei', 17.4860, 101.7223),
+        ('Sakon Nakhon', 17.1545, 104.1348),
+        ('Nakhon Phanom', 17.3920, 104.7695),
+        ('Mukdahan', 16.5425, 104.7235),
+        ('Bangkok', 13.7563, 100.5018),
+        ('Nonthaburi', 13.8621, 100.5144),
+        ('Pathum Thani', 14.0208, 100.5250),
+        ('Phra Nakhon Si Ayutthaya', 14.3532, 100.5689),
+        ('Saraburi', 14.5289, 100.9101),
+        ('Lop Buri', 14.7995, 100.6534),
+        ('Ang Thong', 14.5896, 100.4550),
+        ('Sing Buri', 14.8936, 100.3967),
+        ('Chai Nat', 15.1851, 100.1251),
+        ('Suphan Buri', 14.4745, 100.1177),
+        ('Nakhon Pathom', 13.8199, 100.0622),
+        ('Samut Sakhon', 13.5475, 100.2745),
+        ('Samut Prakan', 13.5991, 100.5998),
+        ('Samut Songkhram', 13.4098, 100.0023),
+        ('Ratchaburi', 13.5283, 99.8134),
+        ('Nakhon Nayok', 14.2069, 101.2131),
+        ('Kanchanaburi', 14.0228, 99.5328),
+        ('Phetchaburi', 13.1119, 99.9398),
+        ('Chon Buri', 13.3611, 100.9847),
+        ('Rayong', 12.6814, 101.2816),
+        ('Chanthaburi', 12.6113, 102.1039),
+        ('Trat', 12.2428, 102.5175),
+        ('Prachin Buri', 14.0509, 101.3717),
+        ('Sa Kaeo', 13.8240, 102.0646),
+        ('Prachuap Khiri Khan', 11.8126, 99.7957),
+        ('Chumphon', 10.4930, 99.1800),
+        ('Ranong', 9.9529, 98.6085),
+        ('Surat Thani', 9.1382, 99.3217),
+        ('Nakhon Si Thammarat', 8.4304, 99.9631),
+        ('Phatthalung', 7.6167, 100.0740),
+        ('Songkhla', 7.1898, 100.5954),
+        ('Satun', 6.6238, 100.0674),
+        ('Trang', 7.5645, 99.6239),
+        ('Krabi', 8.0863, 98.9063),
+        ('Phangnga', 8.4501, 98.5255),
+        ('Phuket', 7.8804, 98.3923),
+        ('Yala', 6.5411, 101.2804),
+        ('Pattani', 6.8696, 101.2501),
+        ('Narathiwat', 6.4255, 101.8253)
+    ) AS coords (name_en, latitude, longitude)
+WHERE
+    provinces.name_en = coords.name_en;
+
+CREATE INDEX IF NOT EXISTS idx_provinces_region_en ON provinces (region_en);
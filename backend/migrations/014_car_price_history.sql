@@ -0,0 +1,14 @@
+-- Car Price History
+
+-- Up
+-- One row per price change of a published (active) listing; the listed price before the
+-- first change is the old_price of the earliest row
+CREATE TABLE car_price_history (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    old_price INTEGER NOT NULL,
+    new_price INTEGER NOT NULL,
+    changed_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_price_history_car_changed ON car_price_history (car_id, changed_at DESC);
@@ -0,0 +1,62 @@
+-- Car Revisions
+
+-- Up
+-- Audit trail of listing edits: one row per change to a car, its colors, fuels or images,
+-- holding a full snapshot so any two revisions can be diffed and a listing reverted
+CREATE TABLE car_revisions (
+    id SERIAL PRIMARY KEY,
+    car_id INTEGER NOT NULL REFERENCES cars (id) ON DELETE CASCADE,
+    actor_type VARCHAR(10) NOT NULL CHECK (
+        actor_type IN ('seller', 'admin', 'system')
+    ),
+    actor_id INTEGER, -- users.id for seller, admins.id for admin; NULL for system or unknown
+    changed_fields JSONB NOT NULL DEFAULT '[]'::jsonb, -- Snapshot keys changed since the previous revision
+    snapshot JSONB NOT NULL, -- Listing state after the change (see models.CarSnapshot)
+    reverted_from_id INTEGER REFERENCES car_revisions (id) ON DELETE SET NULL, -- Set when created by an admin revert
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_revisions_car_id ON car_revisions (car_id, id DESC);
+
+-- Baseline revision for existing listings so their first tracked edit has something to diff against
+INSERT INTO
+    car_revisions (car_id, actor_type, snapshot)
+SELECT
+    c.id,
+    'system',
+    jsonb_build_object(
+        'status', c.status,
+        'brandName', c.brand_name,
+        'modelName', c.model_name,
+        'submodelName', c.submodel_name,
+        'chassisNumber', c.chassis_number,
+        'year', c.year,
+        'mileage', c.mileage,
+        'engineCc', c.engine_cc,
+        'seats', c.seats,
+        'doors', c.doors,
+        'prefix', c.prefix,
+        'number', c.number,
+        'provinceId', c.province_id,
+        'description', c.description,
+        'price', c.price,
+        'isFlooded', c.is_flooded,
+        'isHeavilyDamaged', c.is_heavily_damaged,
+        'conditionRating', c.condition_rating,
+        'bodyTypeCode', c.body_type_code,
+        'transmissionCode', c.transmission_code,
+        'drivetrainCode', c.drivetrain_code,
+        'colors', COALESCE(
+            (SELECT jsonb_agg(cc.color_code ORDER BY cc.position) FROM car_colors cc WHERE cc.car_id = c.id),
+            '[]'::jsonb
+        ),
+        'fuelTypes', COALESCE(
+            (SELECT jsonb_agg(cf.fuel_type_code ORDER BY cf.fuel_type_code) FROM car_fuel cf WHERE cf.car_id = c.id),
+            '[]'::jsonb
+        ),
+        'imageIds', COALESCE(
+            (SELECT jsonb_agg(ci.id ORDER BY ci.display_order, ci.uploaded_at) FROM car_images ci WHERE ci.car_id = c.id),
+            '[]'::jsonb
+        )
+    )
+FROM cars c;
@@ -0,0 +1,42 @@
+-- Listing Expiry
+
+-- Up
+-- Published listings expire after a fixed period unless the seller renews them
+ALTER TABLE cars
+ADD COLUMN expires_at TIMESTAMP, -- Set when published or renewed; NULL for drafts
+ADD COLUMN expiry_reminder_sent_at TIMESTAMP; -- Reset on renewal
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_status_check;
+
+ALTER TABLE cars
+ADD CONSTRAINT cars_status_check CHECK (
+    status IN (
+        'draft',
+        'active',
+        'sold',
+        'expired', -- Active listing that reached expires_at without renewal
+        'deleted' -- Soft delete
+    )
+);
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS check_chassis_for_active;
+
+ALTER TABLE cars
+ADD CONSTRAINT check_chassis_for_active CHECK (
+    status = 'draft'
+    OR (
+        status IN ('active', 'sold', 'expired', 'deleted')
+        AND chassis_number IS NOT NULL
+    )
+);
+
+-- Give listings that are already published a full period from now
+UPDATE cars
+SET
+    expires_at = NOW() + INTERVAL '60 days'
+WHERE
+    status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_cars_active_expires_at ON cars (expires_at)
+WHERE
+    status = 'active';
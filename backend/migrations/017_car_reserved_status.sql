@@ -0,0 +1,35 @@
+-- Reserved Status
+
+-- Up
+-- Sellers can hold a listing for a buyer; the reservation lapses back to active at reserved_until
+ALTER TABLE cars
+ADD COLUMN reserved_until TIMESTAMP; -- Set while status = 'reserved'
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS cars_status_check;
+
+ALTER TABLE cars
+ADD CONSTRAINT cars_status_check CHECK (
+    status IN (
+        'draft',
+        'active',
+        'reserved', -- Pending sale; still visible but hidden from search by default
+        'sold',
+        'expired', -- Active listing that reached expires_at without renewal
+        'deleted' -- Soft delete
+    )
+);
+
+ALTER TABLE cars DROP CONSTRAINT IF EXISTS check_chassis_for_active;
+
+ALTER TABLE cars
+ADD CONSTRAINT check_chassis_for_active CHECK (
+    status = 'draft'
+    OR (
+        status IN ('active', 'reserved', 'sold', 'expired', 'deleted')
+        AND chassis_number IS NOT NULL
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_cars_reserved_until ON cars (reserved_until)
+WHERE
+    status = 'reserved';
@@ -0,0 +1,29 @@
+-- Bulk Car Import Jobs
+
+-- Up
+-- Sellers upload a CSV of listings (plus an optional zip of images) that is imported in the
+-- background; the job row holds its progress and a per-row report
+CREATE TABLE car_import_jobs (
+    id SERIAL PRIMARY KEY,
+    seller_id INTEGER NOT NULL REFERENCES sellers (id) ON DELETE CASCADE,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (
+        status IN ('pending', 'running', 'completed', 'failed')
+    ),
+    publish BOOLEAN NOT NULL DEFAULT FALSE, -- Publish rows that pass publish validation
+    total_rows INTEGER NOT NULL DEFAULT 0,
+    processed_rows INTEGER NOT NULL DEFAULT 0,
+    created_count INTEGER NOT NULL DEFAULT 0, -- Rows saved as drafts
+    published_count INTEGER NOT NULL DEFAULT 0,
+    failed_count INTEGER NOT NULL DEFAULT 0,
+    report JSONB NOT NULL DEFAULT '[]'::jsonb, -- Per-row results (see models.CarImportRowResult)
+    error TEXT, -- Set when the whole job failed
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_import_jobs_seller_id ON car_import_jobs (seller_id, id DESC);
+
+-- A seller has at most one unfinished import; concurrent uploads conflict on this index
+CREATE UNIQUE INDEX IF NOT EXISTS idx_car_import_jobs_one_unfinished ON car_import_jobs (seller_id)
+WHERE status IN ('pending', 'running');
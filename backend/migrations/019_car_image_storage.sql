@@ -0,0 +1,21 @@
+-- Car Image Storage
+
+-- Up
+-- Image files move out of the database into the configured image store (local disk or S3).
+-- New images only have storage_key; older rows keep image_data until the migrate-images tool
+-- moves them (it sets storage_key and clears image_data).
+ALTER TABLE car_images
+ADD COLUMN storage_key VARCHAR(255); -- Object key, e.g. cars/12/3f2a...9c.jpg
+
+ALTER TABLE car_images ALTER COLUMN image_data DROP NOT NULL;
+
+ALTER TABLE car_images
+ADD CONSTRAINT chk_car_image_source CHECK (
+    image_data IS NOT NULL
+    OR storage_key IS NOT NULL
+);
+
+-- Rows still to migrate
+CREATE INDEX IF NOT EXISTS idx_car_images_unmigrated ON car_images (id)
+WHERE
+    storage_key IS NULL;
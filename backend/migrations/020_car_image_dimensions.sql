@@ -0,0 +1,14 @@
+-- Car Image Dimensions
+
+-- Up
+-- Uploaded images are stripped of metadata (EXIF including GPS) and auto-oriented, and
+-- their dimensions are recorded. Rows without dimensions were uploaded before that and are
+-- cleaned once by the clean-images command.
+ALTER TABLE car_images
+ADD COLUMN width INTEGER,
+ADD COLUMN height INTEGER;
+
+-- Rows still to clean
+CREATE INDEX IF NOT EXISTS idx_car_images_uncleaned ON car_images (id)
+WHERE
+    width IS NULL;
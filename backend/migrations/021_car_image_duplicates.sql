@@ -0,0 +1,44 @@
+-- Duplicate Car Photos
+
+-- Up
+-- Perceptual hash (64-bit dHash, see utils.DifferenceHash) of each image. Null until computed,
+-- and for WebP images, which cannot be decoded. Other cleaned rows without a hash were cleaned
+-- before hashes existed and are hashed once by the hash-images command.
+ALTER TABLE car_images ADD COLUMN phash BIGINT;
+
+-- The eight bytes of phash, each tagged with its position (position * 256 + byte). Hashes
+-- within 7 bits of each other differ in at most 7 bytes, so they share at least one tagged
+-- byte, and the GIN index finds the candidate matches without comparing every image.
+ALTER TABLE car_images ADD COLUMN phash_bands INTEGER[] GENERATED ALWAYS AS (
+    CASE WHEN phash IS NULL THEN NULL ELSE ARRAY[
+        (phash & 255)::int,
+        256 + ((phash >> 8) & 255)::int,
+        512 + ((phash >> 16) & 255)::int,
+        768 + ((phash >> 24) & 255)::int,
+        1024 + ((phash >> 32) & 255)::int,
+        1280 + ((phash >> 40) & 255)::int,
+        1536 + ((phash >> 48) & 255)::int,
+        1792 + ((phash >> 56) & 255)::int
+    ] END
+) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_car_images_phash_bands ON car_images USING GIN (phash_bands);
+
+CREATE INDEX IF NOT EXISTS idx_car_images_unhashed ON car_images (id)
+WHERE
+    phash IS NULL
+    AND width IS NOT NULL
+    AND image_type <> 'image/webp';
+
+-- Near-duplicate photos on listings of different sellers, recorded in both directions when
+-- an image is hashed
+CREATE TABLE car_image_duplicates (
+    image_id INTEGER NOT NULL REFERENCES car_images (id) ON DELETE CASCADE,
+    matched_image_id INTEGER NOT NULL REFERENCES car_images (id) ON DELETE CASCADE,
+    distance SMALLINT NOT NULL, -- Hamming distance between the hashes (0-64)
+    detected_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (image_id, matched_image_id),
+    CHECK (image_id <> matched_image_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_car_image_duplicates_matched_image_id ON car_image_duplicates (matched_image_id);
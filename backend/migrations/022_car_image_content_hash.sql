@@ -0,0 +1,6 @@
+-- Car Image Content Hash
+
+-- Up
+-- SHA-256 (hex) of each stored image. It is the image's ETag and versions its URLs, so they
+-- can be cached as immutable. Null for images stored before it existed until first served.
+ALTER TABLE car_images ADD COLUMN content_hash CHAR(64);
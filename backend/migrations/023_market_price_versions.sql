@@ -0,0 +1,90 @@
+-- Market Price Dataset Versions
+
+-- Up
+-- Each import creates a version holding a complete copy of the market prices: the rows of the
+-- version active at import time, updated with the imported rows. Exactly one version is active
+-- and answers price lookups, whatever its effective_from; activating an older one rolls back an
+-- import.
+CREATE TABLE market_price_versions (
+    id SERIAL PRIMARY KEY,
+    source_filename VARCHAR(255),
+    source_hash CHAR(64), -- SHA-256 of the imported file
+    row_count INTEGER NOT NULL DEFAULT 0, -- Rows in the version
+    imported_count INTEGER NOT NULL DEFAULT 0, -- Rows in the imported file
+    inserted_count INTEGER NOT NULL DEFAULT 0, -- Imported rows that were new
+    updated_count INTEGER NOT NULL DEFAULT 0, -- Imported rows that changed a price
+    uploaded_by INTEGER REFERENCES admins (id) ON DELETE SET NULL,
+    effective_from DATE NOT NULL DEFAULT CURRENT_DATE, -- When the prices apply from (informational)
+    is_active BOOLEAN NOT NULL DEFAULT FALSE,
+    activated_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_market_price_versions_active ON market_price_versions (is_active)
+WHERE
+    is_active;
+
+-- A file can only be imported once, even by concurrent uploads
+CREATE UNIQUE INDEX IF NOT EXISTS idx_market_price_versions_source_hash ON market_price_versions (source_hash)
+WHERE
+    source_hash IS NOT NULL;
+
+-- Every time a version became the active one. A rollback marks the current activation as
+-- rolled back and reactivates the version of the latest activation before it, so repeated
+-- rollbacks walk back through the versions that were live, skipping versions never activated.
+CREATE TABLE market_price_version_activations (
+    id SERIAL PRIMARY KEY,
+    version_id INTEGER NOT NULL REFERENCES market_price_versions (id) ON DELETE CASCADE,
+    activated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    rolled_back_at TIMESTAMP
+);
+
+-- Prices imported before versions existed become the first, active version
+INSERT INTO
+    market_price_versions (
+        source_filename,
+        row_count,
+        imported_count,
+        inserted_count,
+        is_active,
+        activated_at
+    )
+SELECT 'Imported before versioning', COUNT(*), COUNT(*), COUNT(*), TRUE, NOW()
+FROM market_price
+HAVING
+    COUNT(*) > 0;
+
+INSERT INTO
+    market_price_version_activations (version_id, activated_at)
+SELECT id, activated_at
+FROM market_price_versions
+WHERE
+    is_active;
+
+ALTER TABLE market_price
+ADD COLUMN version_id INTEGER REFERENCES market_price_versions (id) ON DELETE CASCADE;
+
+UPDATE market_price
+SET
+    version_id = (
+        SELECT id
+        FROM market_price_versions
+        WHERE
+            is_active
+    );
+
+ALTER TABLE market_price ALTER COLUMN version_id SET NOT NULL;
+
+ALTER TABLE market_price DROP CONSTRAINT market_price_unique;
+
+DROP INDEX IF EXISTS idx_market_price_brand_model_sub_model_year;
+
+ALTER TABLE market_price
+ADD CONSTRAINT market_price_unique UNIQUE (
+    version_id,
+    brand,
+    model,
+    sub_model,
+    year_start,
+    year_end
+);
@@ -0,0 +1,13 @@
+-- Car Image Data Updated At
+
+-- Up
+-- When each image's stored data last changed: on upload, and again when an older image is
+-- cleaned of metadata. It is the image's Last-Modified time, which uploaded_at cannot be as
+-- cleaning rewrites the data after upload.
+ALTER TABLE car_images ADD COLUMN data_updated_at TIMESTAMP;
+
+UPDATE car_images SET data_updated_at = uploaded_at WHERE data_updated_at IS NULL;
+
+ALTER TABLE car_images
+ALTER COLUMN data_updated_at SET DEFAULT NOW(),
+ALTER COLUMN data_updated_at SET NOT NULL;
@@ -2,9 +2,13 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Car represents a car listing
@@ -34,17 +38,48 @@ type Car struct {
 	ConditionRating  *int      `json:"conditionRating" db:"condition_rating"`
 	CreatedAt        time.Time `json:"createdAt" db:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Listing expiry (set when published or renewed; see ListingDuration)
+	ExpiresAt *time.Time `json:"expiresAt" db:"expires_at"`
+
+	// When a reservation lapses back to active (set only while status is "reserved")
+	ReservedUntil *time.Time `json:"reservedUntil" db:"reserved_until"`
 }
 
-// CarImage represents an image stored in the database
+// CarImage represents a car image. The file is kept in the image store under StorageKey;
+// images uploaded before the image store have their data in the database instead.
 type CarImage struct {
 	ID           int       `json:"id" db:"id"`
 	CarID        int       `json:"carId" db:"car_id"`
-	ImageData    []byte    `json:"-" db:"image_data"`
+	ImageData    []byte    `json:"-" db:"image_data"`  // Legacy, only set when StorageKey is nil (not loaded by GetCarImageByID)
+	StorageKey   *string   `json:"-" db:"storage_key"` // Image store object key
 	ImageType    string    `json:"imageType" db:"image_type"`
 	ImageSize    int       `json:"imageSize" db:"image_size"`
+	Width        *int      `json:"width" db:"width"`    // Null until the image is cleaned
+	Height       *int      `json:"height" db:"height"`  // Null until the image is cleaned
+	PHash        *int64    `json:"-" db:"phash"`        // Perceptual hash (utils.DifferenceHash), null if not decodable
+	ContentHash  *string   `json:"-" db:"content_hash"` // SHA-256 of the stored data, null until first served for older images
 	DisplayOrder int       `json:"displayOrder" db:"display_order"`
 	UploadedAt   time.Time `json:"uploadedAt" db:"uploaded_at"`
+	DataUpdated  time.Time `json:"-" db:"data_updated_at"` // When the stored data last changed (upload or cleaning)
+}
+
+// DuplicatePhotoMatch is a photo of a car that nearly matches a photo on another seller's
+// listing. URL fields are populated in services for API responses.
+type DuplicatePhotoMatch struct {
+	ImageID            int       `json:"imageId" db:"image_id"`
+	ImageURL           string    `json:"imageUrl" db:"-"`
+	ImageContentHash   *string   `json:"-" db:"image_content_hash"`
+	MatchedImageID     int       `json:"matchedImageId" db:"matched_image_id"`
+	MatchedImageURL    string    `json:"matchedImageUrl" db:"-"`
+	MatchedContentHash *string   `json:"-" db:"matched_content_hash"`
+	MatchedCarID       int       `json:"matchedCarId" db:"matched_car_id"`
+	MatchedCarStatus   string    `json:"matchedCarStatus" db:"matched_car_status"`
+	MatchedSellerID    int       `json:"matchedSellerId" db:"matched_seller_id"`
+	MatchedSellerName  *string   `json:"matchedSellerName" db:"matched_seller_name"`
+	MatchedUploadedAt  time.Time `json:"matchedUploadedAt" db:"matched_uploaded_at"` // Earlier uploads are more likely the original
+	Distance           int       `json:"distance" db:"distance"`                     // Differing hash bits, 0 for identical photos
+	DetectedAt         time.Time `json:"detectedAt" db:"detected_at"`
 }
 
 // CarImageMetadata represents image metadata without the actual image data
@@ -54,8 +89,11 @@ type CarImageMetadata struct {
 	CarID        int       `json:"carId" db:"car_id"`
 	ImageType    string    `json:"imageType" db:"image_type"`
 	ImageSize    int       `json:"imageSize" db:"image_size"`
+	Width        *int      `json:"width" db:"width"`
+	Height       *int      `json:"height" db:"height"`
 	DisplayOrder int       `json:"displayOrder" db:"display_order"`
 	UploadedAt   time.Time `json:"uploadedAt" db:"uploaded_at"`
+	ContentHash  *string   `json:"-" db:"content_hash"`  // Versions the image's URL
 	URL          string    `json:"url,omitempty" db:"-"` // Populated in handlers for API responses
 }
 
@@ -115,7 +153,8 @@ type UpdateCarRequest struct {
 	IsFlooded        *bool    `json:"isFlooded"`
 	IsHeavilyDamaged *bool    `json:"isHeavilyDamaged"`
 	ConditionRating  *int     `json:"conditionRating" validate:"omitempty,gte=1,lte=5"`
-	Status           *string  `json:"status" validate:"omitempty,oneof=draft active sold deleted"`
+	Status           *string  `json:"status" validate:"omitempty,oneof=draft active reserved sold deleted"`
+	ReservationHours *int     `json:"reservationHours,omitempty"` // How long to hold a reserved car (see MaxReservationDuration)
 	FuelCodes        []string `json:"fuelCodes,omitempty"`
 
 	// Text fields for frontend submission (backend maps to codes)
@@ -138,6 +177,8 @@ type AdminManagedCar struct {
 	SellerName   *string   `json:"soldBy" db:"seller_name"`
 	Price        *int      `json:"price" db:"price"`
 	Mileage      *int      `json:"mileage" db:"mileage"`
+	// Photos matching photos on other sellers' listings (see GET /admin/cars/{id}/duplicate-photos)
+	DuplicatePhotos int `json:"duplicatePhotos" db:"duplicate_photos"`
 }
 
 // AdminUpdateCarRequest defines the fields updatable by an admin
@@ -174,9 +215,62 @@ type StepStatus struct {
 	Step3 StepState `json:"step3"`
 }
 
-// EstimatedPriceResponse represents the estimated price response (API response only)
+// EstimatedPriceResponse represents the estimated price response with how it was reached (API response only)
 type EstimatedPriceResponse struct {
-	EstimatedPrice int64 `json:"estimatedPrice"`
+	EstimatedPrice   int64             `json:"estimatedPrice"`
+	LowPrice         int64             `json:"lowPrice"`  // Confidence range
+	HighPrice        int64             `json:"highPrice"` // Confidence range
+	BasePrice        int64             `json:"basePrice"` // Midpoint of the market price range
+	MarketPrice      *MarketPrice      `json:"marketPrice"`
+	AdjustmentFactor float64           `json:"adjustmentFactor"` // Multiplier applied to the base price
+	Adjustments      []PriceAdjustment `json:"adjustments"`
+	Match            *MarketPriceMatch `json:"match"` // How the car was matched to MarketPrice
+}
+
+// Market price match types, from most to least certain
+const (
+	MarketPriceMatchExact      = "exact"      // Same names, ignoring case
+	MarketPriceMatchNormalized = "normalized" // Same names once punctuation, spacing and brand aliases are ignored
+	MarketPriceMatchFuzzy      = "fuzzy"      // The most similar names
+)
+
+// MarketPriceMatch describes how a car's brand, model and submodel were matched to a market
+// price. Fuzzy matches may be a different trim, so the seller should confirm them.
+type MarketPriceMatch struct {
+	Type              string  `json:"type"`
+	Score             float64 `json:"score"` // 1 for exact and normalized matches, otherwise the similarity (0-1)
+	NeedsConfirmation bool    `json:"needsConfirmation"`
+}
+
+// ValuationRequest describes a car to value without listing it (API request only)
+type ValuationRequest struct {
+	BrandName        string `json:"brandName"`
+	ModelName        string `json:"modelName"`
+	SubmodelName     string `json:"submodelName"`
+	Year             int    `json:"year"`
+	Mileage          *int   `json:"mileage"`
+	ConditionRating  *int   `json:"conditionRating"` // 1-5
+	IsFlooded        bool   `json:"isFlooded"`
+	IsHeavilyDamaged bool   `json:"isHeavilyDamaged"`
+}
+
+// Factors of a price estimate adjustment
+const (
+	PriceFactorCondition        = "condition"
+	PriceFactorMileage          = "mileage"
+	PriceFactorFlooded          = "flooded"
+	PriceFactorHeavilyDamaged   = "heavilyDamaged"
+	PriceFactorInspectionPassed = "inspectionPassed" // Every inspection item passed
+	PriceFactorInspectionFailed = "inspectionFailed" // One failed or unrecorded inspection item
+)
+
+// PriceAdjustment is one factor's contribution to a price estimate (API response only)
+type PriceAdjustment struct {
+	Factor  string  `json:"factor"`
+	Item    string  `json:"item,omitempty"`  // Inspection item (InspectionResult JSON key, or "overallPass")
+	Value   *int    `json:"value,omitempty"` // Condition rating (1-5) or mileage per year (km)
+	Percent float64 `json:"percent"`         // Change to the adjustment factor in percentage points
+	Amount  int64   `json:"amount"`          // Change to the estimate in THB, before rounding
 }
 
 // CarIDResponse represents a simple car ID response (API response only)
@@ -194,9 +288,17 @@ type BookUploadErrorResponse struct {
 
 // MarketPriceImportResponse represents the response for market price import (API response only)
 type MarketPriceImportResponse struct {
-	Message       string `json:"message"`
-	InsertedCount int    `json:"inserted_count"`
-	UpdatedCount  int    `json:"updated_count"`
+	Message       string              `json:"message"`
+	InsertedCount int                 `json:"inserted_count"`
+	UpdatedCount  int                 `json:"updated_count"`
+	Version       *MarketPriceVersion `json:"version"` // The version created by the import
+}
+
+// MarketPriceRowError lists the problems of a market price spreadsheet row. A file with row
+// errors is not imported.
+type MarketPriceRowError struct {
+	Row    int      `json:"row"` // Spreadsheet row number; the header is row 1
+	Errors []string `json:"errors"`
 }
 
 // AdminCarsListResponse represents the response for admin cars list (API response only)
@@ -223,10 +325,18 @@ type ReviewResponse struct {
 
 // PaginatedCarListingData is used for search/browse endpoints (returns CarListItem) (API response only)
 type PaginatedCarListingData struct {
-	Cars  []CarListItem `json:"cars"`
-	Total int           `json:"total"`
-	Page  int           `json:"page"`
-	Limit int           `json:"limit"`
+	Cars       []CarListItem `json:"cars"`
+	Total      int           `json:"total"` // -1 when counting was skipped (count=false)
+	Page       int           `json:"page"`
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Keyset cursor for the next page; empty on the last page
+}
+
+// CursorCarListingData is a keyset-paginated list of cars (seller listings)
+type CursorCarListingData struct {
+	Cars       []CarListItem `json:"cars"`
+	Limit      int           `json:"limit"`
+	NextCursor string        `json:"nextCursor,omitempty"` // Keyset cursor for the next page; empty on the last page
 }
 
 // CarWithImages represents a car with its images and inspection (used in services)
@@ -244,6 +354,7 @@ type CarDetailResponse struct {
 	Images         []CarImageMetadata `json:"images"`                   // Images with URLs (URL populated in handler)
 	Inspection     interface{}        `json:"inspection"`               // InspectionDisplay from services (from services.InspectionDisplay)
 	SellerContacts []SellerContact    `json:"sellerContacts,omitempty"` // Optional seller contacts
+	PriceHistory   []CarPriceChange   `json:"priceHistory"`             // Published price changes, newest first
 }
 
 // CarListItem is a lightweight representation for car cards and lists
@@ -266,13 +377,42 @@ type CarListItem struct {
 	Colors          []string `json:"colors"`          // Display labels (e.g., ["White", "Gray"])
 	ConditionRating *int     `json:"conditionRating"` // Condition score (1-5)
 	ThumbnailURL    *string  `json:"thumbnailUrl"`    // Image URL for thumbnail (display order = 0)
+
+	// Price drop badge (from car_price_history)
+	PriceDropped  bool `json:"priceDropped"`            // Latest published price change was a drop
+	PreviousPrice *int `json:"previousPrice,omitempty"` // Price before that drop
+
+	// Listing expiry (only loaded for the seller's own listings)
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"`
+	ReservedUntil *time.Time `json:"reservedUntil,omitempty"`
 }
 
 // ImageUploadData represents the data returned after image upload (API response only)
 type ImageUploadData struct {
-	CarID         int                `json:"carId"`
-	UploadedCount int                `json:"uploadedCount"`
-	Images        []CarImageMetadata `json:"images"`
+	CarID         int                 `json:"carId"`
+	UploadedCount int                 `json:"uploadedCount"`
+	Images        []CarImageMetadata  `json:"images"`
+	Results       []ImageUploadResult `json:"results"`
+}
+
+// ErrInvalidImageUpload is returned when an image upload is rejected because of its files,
+// e.g. an invalid image or more images than a car can have
+var ErrInvalidImageUpload = errors.New("invalid image upload")
+
+// Statuses of the files of an image upload
+const (
+	ImageUploadStatusUploaded = "uploaded"
+	ImageUploadStatusInvalid  = "invalid" // Failed validation
+	ImageUploadStatusFailed   = "failed"  // Valid, but could not be saved
+	ImageUploadStatusSkipped  = "skipped" // Valid, but not saved because another file failed
+)
+
+// ImageUploadResult is the outcome of one file of an image upload, in upload order (API response only)
+type ImageUploadResult struct {
+	Filename string `json:"filename"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	ImageID  *int   `json:"imageId,omitempty"`
 }
 
 // CarRepository handles car-related database operations
@@ -329,7 +469,7 @@ func (r *CarRepository) GetCarByID(carID int) (*Car, error) {
 			year, mileage, engine_cc, seats, doors,
 			prefix, number, province_id, description, price,
 			is_flooded, is_heavily_damaged,
-			status, condition_rating, created_at, updated_at
+			status, condition_rating, created_at, updated_at, expires_at, reserved_until
 		FROM cars
 		WHERE id = $1`
 
@@ -339,7 +479,7 @@ func (r *CarRepository) GetCarByID(carID int) (*Car, error) {
 		&car.Year, &car.Mileage, &car.EngineCC, &car.Seats, &car.Doors,
 		&car.Prefix, &car.Number, &car.ProvinceID, &car.Description, &car.Price,
 		&car.IsFlooded, &car.IsHeavilyDamaged, &car.Status,
-		&car.ConditionRating, &car.CreatedAt, &car.UpdatedAt,
+		&car.ConditionRating, &car.CreatedAt, &car.UpdatedAt, &car.ExpiresAt, &car.ReservedUntil,
 	)
 
 	if err != nil {
@@ -420,7 +560,15 @@ func (r *CarRepository) GetManagedCars() (*[]AdminManagedCar, error) {
 			c.created_at,
 			u.name AS seller_name,
 			c.price,
-			c.mileage
+			c.mileage,
+			(
+				SELECT COUNT(DISTINCT d.image_id)
+				FROM car_image_duplicates d
+				JOIN car_images ci ON ci.id = d.image_id
+				JOIN car_images mi ON mi.id = d.matched_image_id
+				JOIN cars mc ON mc.id = mi.car_id
+				WHERE ci.car_id = c.id AND mc.status <> 'deleted'
+			) AS duplicate_photos
 		FROM
 			cars c
 		LEFT JOIN
@@ -448,6 +596,7 @@ func (r *CarRepository) GetManagedCars() (*[]AdminManagedCar, error) {
 			&car.SellerName,
 			&car.Price,
 			&car.Mileage,
+			&car.DuplicatePhotos,
 		)
 
 		if err != nil {
@@ -503,79 +652,6 @@ func (r *CarRepository) GetTopBrandsByCount() ([]BrandDataPoint, error) {
 	return results, nil
 }
 
-// UpdateCarByAdmin updates a car's details from the admin panel
-func (r *CarRepository) UpdateCarByAdmin(carID int, req AdminUpdateCarRequest) (*Car, error) {
-	// Build dynamic query
-	query := "UPDATE cars SET "
-	args := []interface{}{}
-	argNum := 1
-	updates := []string{}
-
-	if req.BrandName != nil {
-		updates = append(updates, fmt.Sprintf("brand_name = $%d", argNum))
-		args = append(args, *req.BrandName)
-		argNum++
-	}
-	if req.ModelName != nil {
-		updates = append(updates, fmt.Sprintf("model_name = $%d", argNum))
-		args = append(args, *req.ModelName)
-		argNum++
-	}
-	if req.SubmodelName != nil {
-		updates = append(updates, fmt.Sprintf("submodel_name = $%d", argNum))
-		args = append(args, *req.SubmodelName)
-		argNum++
-	}
-	if req.Year != nil {
-		updates = append(updates, fmt.Sprintf("year = $%d", argNum))
-		args = append(args, *req.Year)
-		argNum++
-	}
-	if req.Price != nil {
-		updates = append(updates, fmt.Sprintf("price = $%d", argNum))
-		args = append(args, *req.Price)
-		argNum++
-	}
-	if req.Mileage != nil {
-		updates = append(updates, fmt.Sprintf("mileage = $%d", argNum))
-		args = append(args, *req.Mileage)
-		argNum++
-	}
-	if req.Status != nil {
-		updates = append(updates, fmt.Sprintf("status = $%d", argNum))
-		args = append(args, *req.Status)
-		argNum++
-	}
-
-	if len(updates) == 0 {
-		return r.GetCarByID(carID)
-	}
-
-	updates = append(updates, fmt.Sprintf("updated_at = $%d", argNum))
-	args = append(args, time.Now())
-	argNum++
-
-	query += strings.Join(updates, ", ")
-	query += fmt.Sprintf(" WHERE id = $%d", argNum)
-	args = append(args, carID)
-
-	// Execute query
-	result, err := r.db.DB.Exec(query, args...)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute admin car update: %w", err)
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get rows affected: %w", err)
-	}
-	if rowsAffected == 0 {
-		return nil, fmt.Errorf("car not found")
-	}
-
-	return r.GetCarByID(carID)
-}
-
 // CreateCarByAdmin creates a new car listing associated with a seller
 func (r *CarRepository) CreateCarByAdmin(req AdminCreateCarRequest) (*Car, error) {
 	status := "draft"
@@ -652,24 +728,25 @@ func (r *CarRepository) CountCarsBySellerIDAndStatus(sellerID int, status string
 }
 
 // GetCarsBySellerID retrieves cars for a seller, optionally filtered by status
-// If status is empty string, returns all cars. Otherwise filters by the specified status.
-func (r *CarRepository) GetCarsBySellerID(sellerID int, status string) ([]Car, error) {
+// If statuses is empty, returns all cars. Otherwise returns cars with one of the statuses.
+func (r *CarRepository) GetCarsBySellerID(sellerID int, statuses []string) ([]Car, error) {
 	query := `
 		SELECT id, seller_id, body_type_code, transmission_code,
 			drivetrain_code, brand_name, model_name, submodel_name, 
 			chassis_number, year, mileage, engine_cc,
 			seats, doors, prefix, number, 
 			province_id, description, price, is_flooded, 
-			is_heavily_damaged, status, condition_rating, created_at, updated_at
+			is_heavily_damaged, status, condition_rating, created_at, updated_at, expires_at,
+			reserved_until
 		FROM cars
 		WHERE seller_id = $1`
 
 	args := []interface{}{sellerID}
 	argNum := 2
 
-	if status != "" {
-		query += fmt.Sprintf(" AND status = $%d", argNum)
-		args = append(args, status)
+	if len(statuses) > 0 {
+		query += fmt.Sprintf(" AND status = ANY($%d)", argNum)
+		args = append(args, pq.Array(statuses))
 	}
 
 	query += " ORDER BY created_at DESC"
@@ -689,7 +766,73 @@ func (r *CarRepository) GetCarsBySellerID(sellerID int, status string) ([]Car, e
 			&car.Year, &car.Mileage, &car.EngineCC, &car.Seats, &car.Doors,
 			&car.Prefix, &car.Number, &car.ProvinceID, &car.Description, &car.Price,
 			&car.IsFlooded, &car.IsHeavilyDamaged,
-			&car.Status, &car.ConditionRating, &car.CreatedAt, &car.UpdatedAt,
+			&car.Status, &car.ConditionRating, &car.CreatedAt, &car.UpdatedAt, &car.ExpiresAt,
+			&car.ReservedUntil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan car: %w", err)
+		}
+		cars = append(cars, car)
+	}
+
+	return cars, nil
+}
+
+// GetCarsBySellerIDPage retrieves one page of a seller's cars, newest first,
+// continuing after the given keyset cursor (nil for the first page)
+func (r *CarRepository) GetCarsBySellerIDPage(sellerID int, statuses []string, after *CarCursor, limit int) ([]Car, error) {
+	whereClauses := []string{"cars.seller_id = $1"}
+	args := []interface{}{sellerID}
+	argNum := 2
+
+	if len(statuses) > 0 {
+		whereClauses = append(whereClauses, fmt.Sprintf("cars.status = ANY($%d)", argNum))
+		args = append(args, pq.Array(statuses))
+		argNum++
+	}
+
+	sortBy, sortOrder := ResolveCarSort("created_at", "desc")
+	if after != nil {
+		if after.SortBy != sortBy || after.SortOrder != sortOrder {
+			return nil, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidCursor)
+		}
+		keysetSQL, keysetArgs := carKeysetSQL(after, argNum)
+		whereClauses = append(whereClauses, keysetSQL)
+		args = append(args, keysetArgs...)
+		argNum += len(keysetArgs)
+	}
+
+	query := fmt.Sprintf(`
+		SELECT cars.id, cars.seller_id, cars.body_type_code, cars.transmission_code,
+			cars.drivetrain_code, cars.brand_name, cars.model_name, cars.submodel_name,
+			cars.chassis_number, cars.year, cars.mileage, cars.engine_cc,
+			cars.seats, cars.doors, cars.prefix, cars.number,
+			cars.province_id, cars.description, cars.price, cars.is_flooded,
+			cars.is_heavily_damaged, cars.status, cars.condition_rating, cars.created_at, cars.updated_at,
+			cars.expires_at, cars.reserved_until
+		FROM cars
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d`, strings.Join(whereClauses, " AND "), carOrderBySQL(sortBy, sortOrder), argNum)
+	args = append(args, limit)
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cars by seller: %w", err)
+	}
+	defer rows.Close()
+
+	var cars []Car
+	for rows.Next() {
+		var car Car
+		err := rows.Scan(
+			&car.ID, &car.SellerID, &car.BodyTypeCode, &car.TransmissionCode, &car.DrivetrainCode,
+			&car.BrandName, &car.ModelName, &car.SubmodelName, &car.ChassisNumber,
+			&car.Year, &car.Mileage, &car.EngineCC, &car.Seats, &car.Doors,
+			&car.Prefix, &car.Number, &car.ProvinceID, &car.Description, &car.Price,
+			&car.IsFlooded, &car.IsHeavilyDamaged,
+			&car.Status, &car.ConditionRating, &car.CreatedAt, &car.UpdatedAt, &car.ExpiresAt,
+			&car.ReservedUntil,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan car: %w", err)
@@ -702,62 +845,163 @@ func (r *CarRepository) GetCarsBySellerID(sellerID int, status string) ([]Car, e
 
 // SearchCarsRequest represents search/filter parameters
 type SearchCarsRequest struct {
-	Query            string   // Search query for brand/model/description
-	MinPrice         *int     // Minimum price filter
-	MaxPrice         *int     // Maximum price filter
-	ProvinceID       *int     // Province filter
-	MinYear          *int     // Minimum year filter
-	MaxYear          *int     // Maximum year filter
-	MinMileage       *int     // Minimum mileage filter
-	MaxMileage       *int     // Maximum mileage filter
-	BodyTypeCodes    []string // Body type filters (codes like "PICKUP", "SUV")
-	TransmissionCode *string  // Transmission filter (code like "MANUAL", "AT")
-	DrivetrainCode   *string  // Drivetrain filter (code like "FWD", "AWD", "4WD")
-	FuelTypeCodes    []string // Fuel type filters (codes like "GASOLINE", "DIESEL")
-	ColorCodes       []string // Color filters (codes like "WHITE", "BLACK", "GRAY")
-	ConditionRating  *int     // Minimum condition rating filter (1-5)
-	SortBy           string   // Sort field: "price", "year", "mileage", "created_at", "condition_rating"
-	SortOrder        string   // Sort order: "asc" or "desc" (default: "desc")
-	Status           string   // Status filter (default: "active")
-	Limit            int      // Results per page (default: 20)
-	Offset           int      // Pagination offset (default: 0)
-}
-
-// GetActiveCars retrieves all active car listings with optional filters
-func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error) {
-	// Build WHERE clauses
+	Query            string     // Search query for brand/model/description
+	MinPrice         *int       // Minimum price filter
+	MaxPrice         *int       // Maximum price filter
+	ProvinceID       *int       // Province filter
+	Region           string     // Region filter (region_en such as "Central", or region_th)
+	NearProvinceID   *int       // Only cars within RadiusKm of this province
+	RadiusKm         *int       // Radius for NearProvinceID (default: DefaultSearchRadiusKm)
+	OriginProvinceID *int       // Province that "distance" sort measures from (e.g. the buyer's province)
+	MinYear          *int       // Minimum year filter
+	MaxYear          *int       // Maximum year filter
+	MinMileage       *int       // Minimum mileage filter
+	MaxMileage       *int       // Maximum mileage filter
+	BodyTypeCodes    []string   // Body type filters (codes like "PICKUP", "SUV")
+	TransmissionCode *string    // Transmission filter (code like "MANUAL", "AT")
+	DrivetrainCode   *string    // Drivetrain filter (code like "FWD", "AWD", "4WD")
+	FuelTypeCodes    []string   // Fuel type filters (codes like "GASOLINE", "DIESEL")
+	ColorCodes       []string   // Color filters (codes like "WHITE", "BLACK", "GRAY")
+	ConditionRating  *int       // Minimum condition rating filter (1-5)
+	RecentlyReduced  bool       // Only cars whose latest price change was a drop in the last RecentlyReducedDays
+	IncludeReserved  bool       // Also match reserved cars when Status is "active"
+	SortBy           string     // Sort field: "price", "year", "mileage", "created_at", "condition_rating", "relevance", "distance"
+	SortOrder        string     // Sort order: "asc" or "desc" (default: "desc")
+	Status           string     // Status filter (default: "active")
+	Limit            int        // Results per page (default: 20)
+	Offset           int        // Pagination offset (default: 0)
+	After            *CarCursor // Keyset cursor from the previous page; Offset is ignored when set
+	SkipCount        bool       // Skip the total count query (total is returned as -1)
+}
+
+// UsesRelevanceSort reports whether results are ordered by text search relevance
+// (relevance sort falls back to created_at without a search query)
+func (req *SearchCarsRequest) UsesRelevanceSort() bool {
+	return req.SortBy == "relevance" && NormalizeSearchQuery(req.Query) != ""
+}
+
+// UsesDistanceSort reports whether results are ordered by distance from OriginProvinceID
+// (distance sort falls back to created_at without an origin)
+func (req *SearchCarsRequest) UsesDistanceSort() bool {
+	return req.SortBy == "distance" && req.OriginProvinceID != nil
+}
+
+// DefaultSearchRadiusKm is the radius used by the near-province filter when none is given
+const DefaultSearchRadiusKm = 100
+
+// provinceDistanceKmSQL returns the great-circle (haversine) distance in km between
+// two aliased provinces rows; NULL when either has no coordinates
+func provinceDistanceKmSQL(from, to string) string {
+	return fmt.Sprintf(
+		"(12742 * ASIN(LEAST(1, SQRT(POWER(SIN(RADIANS(%[2]s.latitude - %[1]s.latitude) / 2), 2)"+
+			" + COS(RADIANS(%[1]s.latitude)) * COS(RADIANS(%[2]s.latitude))"+
+			" * POWER(SIN(RADIANS(%[2]s.longitude - %[1]s.longitude) / 2), 2)))))",
+		from, to,
+	)
+}
+
+// carSearchDocumentSQL is the text matched by fuzzy search. It must stay identical to the
+// expression indexed in migrations/011_car_search_trigram.sql so the trigram index is used.
+const carSearchDocumentSQL = "(COALESCE(cars.brand_name, '') || ' ' || COALESCE(cars.model_name, '') || ' ' || COALESCE(cars.submodel_name, ''))"
+
+// NormalizeSearchQuery trims a free-text search query and collapses inner whitespace
+func NormalizeSearchQuery(q string) string {
+	return strings.Join(strings.Fields(q), " ")
+}
+
+// escapeLikePattern escapes LIKE/ILIKE wildcards so user input is matched literally
+func escapeLikePattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Search filter keys; a facet passes its own key to buildSearchWhereClause so that
+// its filter is left out and the alternatives stay visible
+const (
+	searchFilterPrice        = "price"
+	searchFilterProvince     = "province"
+	searchFilterYear         = "year"
+	searchFilterBodyType     = "bodyType"
+	searchFilterTransmission = "transmission"
+	searchFilterDrivetrain   = "drivetrain"
+	searchFilterFuel         = "fuel"
+	searchFilterColor        = "color"
+)
+
+// searchWhereClause is the WHERE clause built from a SearchCarsRequest
+type searchWhereClause struct {
+	SQL          string        // Conditions joined with AND (without the WHERE keyword)
+	Args         []interface{} // Positional args referenced by SQL
+	NextArg      int           // Next free placeholder number
+	RelevanceSQL string        // Relevance score expression (empty without a text query)
+}
+
+// buildSearchWhereClause builds the WHERE clause shared by GetActiveCars and GetSearchFacets.
+// The filter named by exclude (one of the searchFilter* keys, or "" for none) is skipped.
+func buildSearchWhereClause(req *SearchCarsRequest, exclude string) searchWhereClause {
 	whereClauses := []string{"status = $1"}
+	if req.IncludeReserved && req.Status == "active" {
+		whereClauses[0] = "status IN ($1, 'reserved')"
+	}
 	args := []interface{}{req.Status}
 	argCounter := 2
 
-	if req.MinPrice != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("price >= $%d", argCounter))
-		args = append(args, *req.MinPrice)
-		argCounter++
-	}
+	if exclude != searchFilterPrice {
+		if req.MinPrice != nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("price >= $%d", argCounter))
+			args = append(args, *req.MinPrice)
+			argCounter++
+		}
 
-	if req.MaxPrice != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("price <= $%d", argCounter))
-		args = append(args, *req.MaxPrice)
-		argCounter++
+		if req.MaxPrice != nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("price <= $%d", argCounter))
+			args = append(args, *req.MaxPrice)
+			argCounter++
+		}
 	}
 
-	if req.ProvinceID != nil {
+	if req.ProvinceID != nil && exclude != searchFilterProvince {
 		whereClauses = append(whereClauses, fmt.Sprintf("province_id = $%d", argCounter))
 		args = append(args, *req.ProvinceID)
 		argCounter++
 	}
 
-	if req.MinYear != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("year >= $%d", argCounter))
-		args = append(args, *req.MinYear)
+	// Region filter (matches the English or Thai region name)
+	if region := strings.TrimSpace(req.Region); region != "" {
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"cars.province_id IN (SELECT id FROM provinces WHERE LOWER(region_en) = LOWER($%[1]d) OR region_th = $%[1]d)",
+			argCounter,
+		))
+		args = append(args, region)
 		argCounter++
 	}
 
-	if req.MaxYear != nil {
-		whereClauses = append(whereClauses, fmt.Sprintf("year <= $%d", argCounter))
-		args = append(args, *req.MaxYear)
-		argCounter++
+	// Near-province filter: the province itself plus every province within the radius
+	if req.NearProvinceID != nil {
+		radiusKm := DefaultSearchRadiusKm
+		if req.RadiusKm != nil {
+			radiusKm = *req.RadiusKm
+		}
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"cars.province_id IN (SELECT p.id FROM provinces p JOIN provinces origin ON origin.id = $%[1]d"+
+				" WHERE p.id = origin.id OR %[3]s <= $%[2]d)",
+			argCounter, argCounter+1, provinceDistanceKmSQL("origin", "p"),
+		))
+		args = append(args, *req.NearProvinceID, radiusKm)
+		argCounter += 2
+	}
+
+	if exclude != searchFilterYear {
+		if req.MinYear != nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("year >= $%d", argCounter))
+			args = append(args, *req.MinYear)
+			argCounter++
+		}
+
+		if req.MaxYear != nil {
+			whereClauses = append(whereClauses, fmt.Sprintf("year <= $%d", argCounter))
+			args = append(args, *req.MaxYear)
+			argCounter++
+		}
 	}
 
 	// Mileage filters
@@ -774,7 +1018,7 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 	}
 
 	// Body type filter (multiple values)
-	if len(req.BodyTypeCodes) > 0 {
+	if len(req.BodyTypeCodes) > 0 && exclude != searchFilterBodyType {
 		bodyTypePlaceholders := make([]string, len(req.BodyTypeCodes))
 		for i, bodyTypeCode := range req.BodyTypeCodes {
 			bodyTypePlaceholders[i] = fmt.Sprintf("$%d", argCounter)
@@ -784,13 +1028,13 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 		whereClauses = append(whereClauses, fmt.Sprintf("cars.body_type_code IN (%s)", strings.Join(bodyTypePlaceholders, ", ")))
 	}
 
-	if req.TransmissionCode != nil {
+	if req.TransmissionCode != nil && exclude != searchFilterTransmission {
 		whereClauses = append(whereClauses, fmt.Sprintf("cars.transmission_code = $%d", argCounter))
 		args = append(args, *req.TransmissionCode)
 		argCounter++
 	}
 
-	if req.DrivetrainCode != nil {
+	if req.DrivetrainCode != nil && exclude != searchFilterDrivetrain {
 		whereClauses = append(whereClauses, fmt.Sprintf("cars.drivetrain_code = $%d", argCounter))
 		args = append(args, *req.DrivetrainCode)
 		argCounter++
@@ -803,9 +1047,13 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 		argCounter++
 	}
 
-	// Fuel type filter (requires JOIN with car_fuel)
-	fuelJoin := ""
-	if len(req.FuelTypeCodes) > 0 {
+	// Recently reduced filter (latest published price change was a recent drop)
+	if req.RecentlyReduced {
+		whereClauses = append(whereClauses, recentlyReducedSQL)
+	}
+
+	// Fuel type filter (EXISTS keeps one row per car, so no DISTINCT is needed)
+	if len(req.FuelTypeCodes) > 0 && exclude != searchFilterFuel {
 		// Build IN clause for fuel types
 		fuelPlaceholders := make([]string, len(req.FuelTypeCodes))
 		for i, fuelCode := range req.FuelTypeCodes {
@@ -813,13 +1061,14 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 			args = append(args, fuelCode)
 			argCounter++
 		}
-		fuelJoin = " INNER JOIN car_fuel ON cars.id = car_fuel.car_id"
-		whereClauses = append(whereClauses, fmt.Sprintf("car_fuel.fuel_type_code IN (%s)", strings.Join(fuelPlaceholders, ",")))
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM car_fuel WHERE car_fuel.car_id = cars.id AND car_fuel.fuel_type_code IN (%s))",
+			strings.Join(fuelPlaceholders, ","),
+		))
 	}
 
-	// Color filter (requires JOIN with car_colors)
-	colorJoin := ""
-	if len(req.ColorCodes) > 0 {
+	// Color filter (EXISTS keeps one row per car, so no DISTINCT is needed)
+	if len(req.ColorCodes) > 0 && exclude != searchFilterColor {
 		// Build IN clause for colors
 		colorPlaceholders := make([]string, len(req.ColorCodes))
 		for i, colorCode := range req.ColorCodes {
@@ -827,79 +1076,107 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 			args = append(args, colorCode)
 			argCounter++
 		}
-		colorJoin = " INNER JOIN car_colors ON cars.id = car_colors.car_id"
-		whereClauses = append(whereClauses, fmt.Sprintf("car_colors.color_code IN (%s)", strings.Join(colorPlaceholders, ",")))
+		whereClauses = append(whereClauses, fmt.Sprintf(
+			"EXISTS (SELECT 1 FROM car_colors WHERE car_colors.car_id = cars.id AND car_colors.color_code IN (%s))",
+			strings.Join(colorPlaceholders, ","),
+		))
 	}
 
-	// Text search on brand/model/description in cars
-	if req.Query != "" {
+	// Text search: substring match or trigram word similarity (typo tolerant)
+	// on brand/model/submodel and description
+	relevanceSQL := ""
+	searchQuery := NormalizeSearchQuery(req.Query)
+	if searchQuery != "" {
+		queryArg := argCounter
+		patternArg := argCounter + 1
 		whereClauses = append(whereClauses, fmt.Sprintf(
-			"(cars.brand_name ILIKE $%d OR cars.model_name ILIKE $%d OR cars.description ILIKE $%d)",
-			argCounter, argCounter+1, argCounter+2,
+			"(%[1]s ILIKE $%[3]d OR cars.description ILIKE $%[3]d OR $%[2]d <%% %[1]s OR $%[2]d <%% cars.description)",
+			carSearchDocumentSQL, queryArg, patternArg,
 		))
-		searchPattern := "%" + req.Query + "%"
-		args = append(args, searchPattern, searchPattern, searchPattern)
-		argCounter += 3
+		// Exact substring hits get a tier bonus (2 for brand/model, 1 for description)
+		// on top of a similarity score in [0, 1], so typo matches always rank below them
+		relevanceSQL = fmt.Sprintf(
+			"(CASE WHEN %[1]s ILIKE $%[3]d THEN 2 WHEN cars.description ILIKE $%[3]d THEN 1 ELSE 0 END"+
+				" + GREATEST(word_similarity($%[2]d, %[1]s), word_similarity($%[2]d, COALESCE(cars.description, '')) * 0.5))",
+			carSearchDocumentSQL, queryArg, patternArg,
+		)
+		args = append(args, searchQuery, "%"+escapeLikePattern(searchQuery)+"%")
+		argCounter += 2
 	}
 
-	whereSQL := strings.Join(whereClauses, " AND ")
-
-	// Validate and set sort parameters
-	sortBy := req.SortBy
-	if sortBy == "" {
-		sortBy = "created_at" // Default sort
+	return searchWhereClause{
+		SQL:          strings.Join(whereClauses, " AND "),
+		Args:         args,
+		NextArg:      argCounter,
+		RelevanceSQL: relevanceSQL,
 	}
+}
 
-	// Validate sort field to prevent SQL injection
-	validSortFields := map[string]string{
-		"price":            "cars.price",
-		"year":             "cars.year",
-		"mileage":          "cars.mileage",
-		"created_at":       "cars.created_at",
-		"condition_rating": "cars.condition_rating",
-	}
-	sortField, ok := validSortFields[sortBy]
-	if !ok {
-		sortField = "cars.created_at" // Default to created_at if invalid
+// GetActiveCars retrieves all active car listings with optional filters.
+// Pages with req.After (keyset) when set, otherwise with req.Offset.
+// The returned total is -1 when req.SkipCount is set.
+func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error) {
+	where := buildSearchWhereClause(req, "")
+	whereSQL := where.SQL
+	args := where.Args
+	argCounter := where.NextArg
+	relevanceSQL := where.RelevanceSQL
+
+	// Count total results using the same WHERE clause and args as the page query
+	// (before the keyset predicate, so the total covers every page)
+	total := -1
+	if !req.SkipCount {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM cars WHERE %s", whereSQL)
+		if err := r.db.DB.QueryRow(countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count cars: %w", err)
+		}
 	}
 
-	sortOrder := req.SortOrder
-	if sortOrder != "asc" && sortOrder != "desc" {
-		sortOrder = "desc" // Default to descending
+	// Validate and set sort parameters
+	sortBy, sortOrder := ResolveCarSort(req.SortBy, req.SortOrder)
+	orderSQL := carOrderBySQL(sortBy, sortOrder)
+	if req.UsesRelevanceSort() {
+		// Best match first regardless of sortOrder; newest first among equally relevant listings
+		orderSQL = fmt.Sprintf("%s DESC, cars.created_at DESC, cars.id DESC", relevanceSQL)
 	}
 
-	// Count total results (with DISTINCT if joins are used)
-	countQuery := ""
-	joinClause := fuelJoin + colorJoin
-	if joinClause != "" {
-		countQuery = fmt.Sprintf("SELECT COUNT(DISTINCT cars.id) FROM cars%s WHERE %s", joinClause, whereSQL)
-	} else {
-		countQuery = fmt.Sprintf("SELECT COUNT(*) FROM cars WHERE %s", whereSQL)
-	}
-	var total int
-	err := r.db.DB.QueryRow(countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count cars: %w", err)
+	offset := req.Offset
+	if req.After != nil {
+		if req.UsesRelevanceSort() || req.UsesDistanceSort() || req.After.SortBy != sortBy || req.After.SortOrder != sortOrder {
+			return nil, 0, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalidCursor)
+		}
+		keysetSQL, keysetArgs := carKeysetSQL(req.After, argCounter)
+		whereSQL += " AND " + keysetSQL
+		args = append(args, keysetArgs...)
+		argCounter += len(keysetArgs)
+		offset = 0
+	}
+
+	if req.UsesDistanceSort() {
+		// Nearest first regardless of sortOrder; cars without a known location go last
+		orderSQL = fmt.Sprintf(
+			"(SELECT %s FROM provinces p JOIN provinces origin ON origin.id = $%d WHERE p.id = cars.province_id) ASC NULLS LAST,"+
+				" cars.created_at DESC, cars.id DESC",
+			provinceDistanceKmSQL("origin", "p"), argCounter,
+		)
+		args = append(args, *req.OriginProvinceID)
+		argCounter++
 	}
 
-	// Get paginated results (with DISTINCT if joins are used)
-	selectClause := "SELECT"
-	if joinClause != "" {
-		selectClause = "SELECT DISTINCT"
-	}
+	// Get paginated results
 	query := fmt.Sprintf(`
-        %s cars.id, cars.seller_id, cars.body_type_code, cars.transmission_code, cars.drivetrain_code,
+        SELECT cars.id, cars.seller_id, cars.body_type_code, cars.transmission_code, cars.drivetrain_code,
             cars.brand_name, cars.model_name, cars.submodel_name, cars.chassis_number,
             cars.year, cars.mileage, cars.engine_cc, cars.seats, cars.doors,
             cars.prefix, cars.number, cars.province_id, cars.description, cars.price,
             cars.is_flooded, cars.is_heavily_damaged,
             cars.status, cars.condition_rating, cars.created_at, cars.updated_at
-        FROM cars%s
+        FROM cars
         WHERE %s
-        ORDER BY %s %s
-        LIMIT $%d OFFSET $%d`, selectClause, joinClause, whereSQL, sortField, sortOrder, argCounter, argCounter+1)
+        ORDER BY %s
+        LIMIT $%d OFFSET $%d`, whereSQL, orderSQL, argCounter, argCounter+1)
 
-	args = append(args, req.Limit, req.Offset)
+	args = append(args, req.Limit, offset)
 
 	rows, err := r.db.DB.Query(query, args...)
 	if err != nil {
@@ -927,8 +1204,209 @@ func (r *CarRepository) GetActiveCars(req *SearchCarsRequest) ([]Car, int, error
 	return cars, total, nil
 }
 
+// SearchFacetCount is the number of matching cars for one filter value
+type SearchFacetCount struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// ProvinceFacetCount is the number of matching cars in one province
+type ProvinceFacetCount struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+// SearchFacetBucket is the number of matching cars in the range [Min, Max).
+// A nil bound means the bucket is open-ended on that side.
+type SearchFacetBucket struct {
+	Min   *int `json:"min"`
+	Max   *int `json:"max"`
+	Count int  `json:"count"`
+}
+
+// SearchFacets holds per-filter counts for the search sidebar.
+// Each facet is counted with every filter applied except its own.
+type SearchFacets struct {
+	Total         int                  `json:"total"`
+	BodyTypes     []SearchFacetCount   `json:"bodyTypes"`
+	Transmissions []SearchFacetCount   `json:"transmissions"`
+	Drivetrains   []SearchFacetCount   `json:"drivetrains"`
+	FuelTypes     []SearchFacetCount   `json:"fuelTypes"`
+	Colors        []SearchFacetCount   `json:"colors"`
+	Provinces     []ProvinceFacetCount `json:"provinces"`
+	PriceRanges   []SearchFacetBucket  `json:"priceRanges"`
+	YearRanges    []SearchFacetBucket  `json:"yearRanges"`
+}
+
+// Bucket edges for the price and year facets
+var (
+	SearchPriceBucketEdges = []int{200000, 300000, 500000, 700000, 1000000, 1500000, 2000000, 3000000}
+	SearchYearBucketEdges  = []int{2000, 2005, 2010, 2015, 2020}
+)
+
+// GetSearchFacets counts matching cars per filter value for the given search request.
+// Labels are left empty; they are filled in by the service layer.
+func (r *CarRepository) GetSearchFacets(req *SearchCarsRequest) (*SearchFacets, error) {
+	facets := &SearchFacets{}
+
+	where := buildSearchWhereClause(req, "")
+	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM cars WHERE %s", where.SQL)
+	if err := r.db.DB.QueryRow(countQuery, where.Args...).Scan(&facets.Total); err != nil {
+		return nil, fmt.Errorf("failed to count cars: %w", err)
+	}
+
+	var err error
+	if facets.BodyTypes, err = r.countSearchFacet(req, searchFilterBodyType, "cars.body_type_code", ""); err != nil {
+		return nil, err
+	}
+	if facets.Transmissions, err = r.countSearchFacet(req, searchFilterTransmission, "cars.transmission_code", ""); err != nil {
+		return nil, err
+	}
+	if facets.Drivetrains, err = r.countSearchFacet(req, searchFilterDrivetrain, "cars.drivetrain_code", ""); err != nil {
+		return nil, err
+	}
+	if facets.FuelTypes, err = r.countSearchFacet(req, searchFilterFuel, "car_fuel.fuel_type_code",
+		" INNER JOIN car_fuel ON car_fuel.car_id = cars.id"); err != nil {
+		return nil, err
+	}
+	if facets.Colors, err = r.countSearchFacet(req, searchFilterColor, "car_colors.color_code",
+		" INNER JOIN car_colors ON car_colors.car_id = cars.id"); err != nil {
+		return nil, err
+	}
+
+	provinceCounts, err := r.countSearchFacet(req, searchFilterProvince, "cars.province_id", "")
+	if err != nil {
+		return nil, err
+	}
+	facets.Provinces = make([]ProvinceFacetCount, 0, len(provinceCounts))
+	for _, pc := range provinceCounts {
+		id, err := strconv.Atoi(pc.Code)
+		if err != nil {
+			continue
+		}
+		facets.Provinces = append(facets.Provinces, ProvinceFacetCount{ID: id, Count: pc.Count})
+	}
+
+	if facets.PriceRanges, err = r.countSearchFacetBuckets(req, searchFilterPrice, "cars.price", SearchPriceBucketEdges); err != nil {
+		return nil, err
+	}
+	if facets.YearRanges, err = r.countSearchFacetBuckets(req, searchFilterYear, "cars.year", SearchYearBucketEdges); err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// CarMatchesSearch reports whether a single car satisfies the filters of a search request
+func (r *CarRepository) CarMatchesSearch(carID int, req *SearchCarsRequest) (bool, error) {
+	where := buildSearchWhereClause(req, "")
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM cars WHERE %s AND cars.id = $%d)", where.SQL, where.NextArg)
+	args := append(where.Args, carID)
+
+	var matches bool
+	if err := r.db.DB.QueryRow(query, args...).Scan(&matches); err != nil {
+		return false, fmt.Errorf("failed to match car against search: %w", err)
+	}
+	return matches, nil
+}
+
+// countSearchFacet groups matching cars by column with the facet's own filter excluded.
+// Results are ordered by count (highest first).
+func (r *CarRepository) countSearchFacet(req *SearchCarsRequest, exclude, column, join string) ([]SearchFacetCount, error) {
+	where := buildSearchWhereClause(req, exclude)
+	query := fmt.Sprintf(
+		"SELECT %[1]s::text, COUNT(*) FROM cars%[2]s WHERE %[3]s AND %[1]s IS NOT NULL GROUP BY %[1]s ORDER BY COUNT(*) DESC, %[1]s",
+		column, join, where.SQL,
+	)
+
+	rows, err := r.db.DB.Query(query, where.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s facet: %w", exclude, err)
+	}
+	defer rows.Close()
+
+	counts := []SearchFacetCount{}
+	for rows.Next() {
+		var fc SearchFacetCount
+		if err := rows.Scan(&fc.Code, &fc.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s facet: %w", exclude, err)
+		}
+		counts = append(counts, fc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s facet: %w", exclude, err)
+	}
+
+	return counts, nil
+}
+
+// countSearchFacetBuckets counts matching cars per range of column with the facet's own filter excluded.
+// Every bucket is returned, including empty ones.
+func (r *CarRepository) countSearchFacetBuckets(req *SearchCarsRequest, exclude, column string, edges []int) ([]SearchFacetBucket, error) {
+	where := buildSearchWhereClause(req, exclude)
+
+	edgeValues := make([]string, len(edges))
+	for i, edge := range edges {
+		edgeValues[i] = strconv.Itoa(edge)
+	}
+
+	// width_bucket returns 0 below the first edge and len(edges) at or above the last one
+	query := fmt.Sprintf(
+		"SELECT width_bucket(%[1]s, ARRAY[%[2]s]::int[]) AS bucket, COUNT(*) FROM cars WHERE %[3]s AND %[1]s IS NOT NULL GROUP BY bucket",
+		column, strings.Join(edgeValues, ","), where.SQL,
+	)
+
+	rows, err := r.db.DB.Query(query, where.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s facet: %w", exclude, err)
+	}
+	defer rows.Close()
+
+	buckets := NewSearchFacetBuckets(edges)
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan %s facet: %w", exclude, err)
+		}
+		if bucket >= 0 && bucket < len(buckets) {
+			buckets[bucket].Count = count
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating %s facet: %w", exclude, err)
+	}
+
+	return buckets, nil
+}
+
+// NewSearchFacetBuckets returns len(edges)+1 empty buckets: below the first edge,
+// between each pair of edges, and at or above the last edge
+func NewSearchFacetBuckets(edges []int) []SearchFacetBucket {
+	buckets := make([]SearchFacetBucket, len(edges)+1)
+	for i := range buckets {
+		if i > 0 {
+			lower := edges[i-1]
+			buckets[i].Min = &lower
+		}
+		if i < len(edges) {
+			upper := edges[i]
+			buckets[i].Max = &upper
+		}
+	}
+	return buckets
+}
+
 // UpdateCar updates a car listing
 func (r *CarRepository) UpdateCar(car *Car) error {
+	return r.updateCar(r.db.DB, car)
+}
+
+// updateCar runs the car UPDATE on a connection or transaction
+func (r *CarRepository) updateCar(exec sqlExecer, car *Car) error {
 	query := `
     	UPDATE cars SET
     		body_type_code = $2, transmission_code = $3, drivetrain_code = $4,
@@ -936,16 +1414,16 @@ func (r *CarRepository) UpdateCar(car *Car) error {
     		year = $9, mileage = $10, engine_cc = $11, seats = $12, doors = $13,
     		prefix = $14, number = $15, province_id = $16, description = $17, price = $18,
     		is_flooded = $19, is_heavily_damaged = $20,
-    		status = $21, condition_rating = $22
+    		status = $21, condition_rating = $22, reserved_until = $23
     	WHERE id = $1`
 
-	result, err := r.db.DB.Exec(query,
+	result, err := exec.Exec(query,
 		car.ID, car.BodyTypeCode, car.TransmissionCode, car.DrivetrainCode,
 		car.BrandName, car.ModelName, car.SubmodelName, car.ChassisNumber,
 		car.Year, car.Mileage, car.EngineCC, car.Seats, car.Doors,
 		car.Prefix, car.Number, car.ProvinceID, car.Description, car.Price,
 		car.IsFlooded, car.IsHeavilyDamaged,
-		car.Status, car.ConditionRating,
+		car.Status, car.ConditionRating, car.ReservedUntil,
 	)
 
 	if err != nil {
@@ -1251,6 +1729,49 @@ func (r *CarRepository) GetProvinceLabelByID(id int, lang string) (string, error
 	return label, err
 }
 
+// GetProvinceLabelsByIDs returns display labels for province IDs in a map
+func (r *CarRepository) GetProvinceLabelsByIDs(ids []int, lang string) (map[int]string, error) {
+	if len(ids) == 0 {
+		return make(map[int]string), nil
+	}
+
+	nameCol := "name_en"
+	if lang == "th" {
+		nameCol = "name_th"
+	}
+
+	// Build placeholders for IN clause
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf("SELECT id, %s FROM provinces WHERE id IN (%s)", nameCol, strings.Join(placeholders, ","))
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query province labels: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]string)
+	for rows.Next() {
+		var id int
+		var label string
+		if err := rows.Scan(&id, &label); err != nil {
+			return nil, fmt.Errorf("failed to scan province label: %w", err)
+		}
+		result[id] = label
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating province labels: %w", err)
+	}
+
+	return result, nil
+}
+
 // GetFuelLabelsByCodes returns display labels for fuel type codes
 func (r *CarRepository) GetFuelLabelsByCodes(codes []string, lang string) ([]string, error) {
 	if len(codes) == 0 {
@@ -1440,16 +1961,12 @@ func NewCarImageRepository(db *Database) *CarImageRepository {
 	return &CarImageRepository{db: db}
 }
 
-// CreateCarImage stores an image in the database
+// CreateCarImage records an image (stored under StorageKey, or in ImageData)
 func (r *CarImageRepository) CreateCarImage(image *CarImage) error {
-	query := `
-		INSERT INTO car_images (car_id, image_data, image_type, image_size, display_order)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id, uploaded_at`
-
-	err := r.db.DB.QueryRow(query,
-		image.CarID, image.ImageData, image.ImageType, image.ImageSize, image.DisplayOrder,
-	).Scan(&image.ID, &image.UploadedAt)
+	err := r.db.DB.QueryRow(insertCarImageQuery,
+		image.CarID, image.ImageData, image.StorageKey, image.ImageType, image.ImageSize,
+		image.Width, image.Height, image.PHash, image.ContentHash, image.DisplayOrder,
+	).Scan(&image.ID, &image.UploadedAt, &image.DataUpdated)
 
 	if err != nil {
 		return fmt.Errorf("failed to create car image: %w", err)
@@ -1458,17 +1975,76 @@ func (r *CarImageRepository) CreateCarImage(image *CarImage) error {
 	return nil
 }
 
-// GetCarImageByID retrieves a single image with data
+const insertCarImageQuery = `
+	INSERT INTO car_images (car_id, image_data, storage_key, image_type, image_size, width, height, phash, content_hash, display_order)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, uploaded_at, data_updated_at`
+
+// CreateCarImages records images of a car in one transaction: either all of them are added,
+// after the car's existing images, or none are. The car row is locked so concurrent uploads
+// are counted against maxImages and ordered one after the other.
+func (r *CarImageRepository) CreateCarImages(carID int, images []*CarImage, maxImages int) error {
+	// Start transaction
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked int
+	err = tx.QueryRow("SELECT id FROM cars WHERE id = $1 FOR UPDATE", carID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("car not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock car: %w", err)
+	}
+
+	var count, nextOrder int
+	err = tx.QueryRow(
+		"SELECT COUNT(*), COALESCE(MAX(display_order) + 1, 0) FROM car_images WHERE car_id = $1",
+		carID,
+	).Scan(&count, &nextOrder)
+	if err != nil {
+		return fmt.Errorf("failed to count car images: %w", err)
+	}
+	if count+len(images) > maxImages {
+		return fmt.Errorf("%w: cannot upload %d images: car already has %d images (max %d)", ErrInvalidImageUpload, len(images), count, maxImages)
+	}
+
+	for i, image := range images {
+		image.CarID = carID
+		image.DisplayOrder = nextOrder + i
+		err := tx.QueryRow(insertCarImageQuery,
+			image.CarID, image.ImageData, image.StorageKey, image.ImageType, image.ImageSize,
+			image.Width, image.Height, image.PHash, image.ContentHash, image.DisplayOrder,
+		).Scan(&image.ID, &image.UploadedAt, &image.DataUpdated)
+		if err != nil {
+			return fmt.Errorf("failed to create car image: %w", err)
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// GetCarImageByID retrieves a single image without its data
 func (r *CarImageRepository) GetCarImageByID(imageID int) (*CarImage, error) {
 	image := &CarImage{}
 	query := `
-		SELECT id, car_id, image_data, image_type, image_size, display_order, uploaded_at
+		SELECT id, car_id, storage_key, image_type, image_size, width, height, content_hash, display_order, uploaded_at,
+			data_updated_at
 		FROM car_images
 		WHERE id = $1`
 
 	err := r.db.DB.QueryRow(query, imageID).Scan(
-		&image.ID, &image.CarID, &image.ImageData, &image.ImageType,
-		&image.ImageSize, &image.DisplayOrder, &image.UploadedAt,
+		&image.ID, &image.CarID, &image.StorageKey, &image.ImageType,
+		&image.ImageSize, &image.Width, &image.Height, &image.ContentHash, &image.DisplayOrder, &image.UploadedAt,
+		&image.DataUpdated,
 	)
 
 	if err != nil {
@@ -1481,10 +2057,270 @@ func (r *CarImageRepository) GetCarImageByID(imageID int) (*CarImage, error) {
 	return image, nil
 }
 
+// GetCarImageData retrieves the data of an image still stored in the database
+func (r *CarImageRepository) GetCarImageData(imageID int) ([]byte, error) {
+	var data []byte
+	err := r.db.DB.QueryRow(`SELECT image_data FROM car_images WHERE id = $1`, imageID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("image not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image data: %w", err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("image data not found")
+	}
+	return data, nil
+}
+
+// GetCarImageStorageKeys returns the image store keys of a car's images
+func (r *CarImageRepository) GetCarImageStorageKeys(carID int) ([]string, error) {
+	rows, err := r.db.DB.Query(
+		`SELECT storage_key FROM car_images WHERE car_id = $1 AND storage_key IS NOT NULL`, carID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get image storage keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan image storage key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetDatabaseStoredImages lists images whose data is still in the database, by ID after afterID
+func (r *CarImageRepository) GetDatabaseStoredImages(afterID, limit int) ([]CarImageMetadata, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT id, car_id, image_type, image_size, width, height, display_order, uploaded_at
+		FROM car_images
+		WHERE storage_key IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list database-stored images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []CarImageMetadata
+	for rows.Next() {
+		var img CarImageMetadata
+		if err := rows.Scan(&img.ID, &img.CarID, &img.ImageType, &img.ImageSize, &img.Width, &img.Height, &img.DisplayOrder, &img.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image metadata: %w", err)
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// MoveCarImageToStorage records that an image's data now lives in the image store and
+// drops it from the database. It returns false when the image was deleted or already moved.
+func (r *CarImageRepository) MoveCarImageToStorage(imageID int, storageKey string) (bool, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE car_images SET storage_key = $2, image_data = NULL
+		WHERE id = $1 AND storage_key IS NULL`, imageID, storageKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to move image %d to storage: %w", imageID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetUncleanedImages lists images uploaded before metadata was stripped on upload, by ID
+// after afterID
+func (r *CarImageRepository) GetUncleanedImages(afterID, limit int) ([]CarImage, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT id, car_id, storage_key, image_type, image_size, display_order, uploaded_at
+		FROM car_images
+		WHERE width IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list uncleaned images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []CarImage
+	for rows.Next() {
+		var img CarImage
+		if err := rows.Scan(&img.ID, &img.CarID, &img.StorageKey, &img.ImageType, &img.ImageSize, &img.DisplayOrder, &img.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// ReplaceCleanedImage points an uncleaned image at its cleaned copy in the image store. It
+// returns false when the image was deleted, cleaned or moved since oldKey was read.
+func (r *CarImageRepository) ReplaceCleanedImage(imageID int, oldKey *string, image *CarImage) (bool, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE car_images
+		SET storage_key = $3, image_data = NULL, image_type = $4, image_size = $5, width = $6, height = $7,
+			phash = $8, content_hash = $9, data_updated_at = NOW()
+		WHERE id = $1 AND width IS NULL AND storage_key IS NOT DISTINCT FROM $2`,
+		imageID, oldKey, image.StorageKey, image.ImageType, image.ImageSize, image.Width, image.Height,
+		image.PHash, image.ContentHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to update image %d: %w", imageID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return n == 1, nil
+}
+
+// GetUnhashedImages lists cleaned images without a perceptual hash, other than WebP images
+// (which cannot be hashed), by ID after afterID
+func (r *CarImageRepository) GetUnhashedImages(afterID, limit int) ([]CarImage, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT id, car_id, storage_key, image_type, image_size, display_order, uploaded_at
+		FROM car_images
+		WHERE phash IS NULL AND width IS NOT NULL AND image_type <> 'image/webp' AND id > $1
+		ORDER BY id
+		LIMIT $2`, afterID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unhashed images: %w", err)
+	}
+	defer rows.Close()
+
+	var images []CarImage
+	for rows.Next() {
+		var img CarImage
+		if err := rows.Scan(&img.ID, &img.CarID, &img.StorageKey, &img.ImageType, &img.ImageSize, &img.DisplayOrder, &img.UploadedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan image: %w", err)
+		}
+		images = append(images, img)
+	}
+	return images, rows.Err()
+}
+
+// SetCarImageContentHash records the SHA-256 of an image's data, unless the image was
+// replaced since it was read (storageKey is the key the data was read from)
+func (r *CarImageRepository) SetCarImageContentHash(imageID int, storageKey *string, hash string) error {
+	_, err := r.db.DB.Exec(`
+		UPDATE car_images SET content_hash = $3
+		WHERE id = $1 AND storage_key IS NOT DISTINCT FROM $2`, imageID, storageKey, hash)
+	if err != nil {
+		return fmt.Errorf("failed to set content hash of image %d: %w", imageID, err)
+	}
+	return nil
+}
+
+// SetCarImageHash records an image's perceptual hash
+func (r *CarImageRepository) SetCarImageHash(imageID int, hash int64) error {
+	if _, err := r.db.DB.Exec(`UPDATE car_images SET phash = $2 WHERE id = $1`, imageID, hash); err != nil {
+		return fmt.Errorf("failed to set hash of image %d: %w", imageID, err)
+	}
+	return nil
+}
+
+// MaxDuplicatePhotoDistance is the largest distance RecordDuplicatePhotos can search for:
+// candidates are found by sharing one of the eight bytes of the hash (car_images.phash_bands),
+// which every hash within 7 bits does
+const MaxDuplicatePhotoDistance = 7
+
+// RecordDuplicatePhotos compares an image's hash with the hashed images that share a byte of
+// it and records those within maxDistance bits that belong to a different seller, in both
+// directions. Blank images (hash 0) are not compared. It returns the number of matched images.
+func (r *CarImageRepository) RecordDuplicatePhotos(imageID, maxDistance int) (int, error) {
+	if maxDistance > MaxDuplicatePhotoDistance {
+		return 0, fmt.Errorf("duplicate photo distance %d is over the maximum of %d", maxDistance, MaxDuplicatePhotoDistance)
+	}
+	result, err := r.db.DB.Exec(`
+		WITH matches AS (
+			SELECT b.id AS matched_image_id, bit_count((a.phash # b.phash)::bit(64)) AS distance
+			FROM car_images a
+			JOIN cars ca ON ca.id = a.car_id
+			JOIN car_images b ON b.phash_bands && a.phash_bands AND b.id <> a.id
+			JOIN cars cb ON cb.id = b.car_id
+			WHERE a.id = $1
+				AND a.phash IS NOT NULL AND a.phash <> 0
+				AND b.phash <> 0
+				AND cb.seller_id <> ca.seller_id
+				AND cb.status <> 'deleted'
+				AND bit_count((a.phash # b.phash)::bit(64)) <= $2
+		)
+		INSERT INTO car_image_duplicates (image_id, matched_image_id, distance)
+		SELECT $1, matched_image_id, distance FROM matches
+		UNION ALL
+		SELECT matched_image_id, $1, distance FROM matches
+		ON CONFLICT (image_id, matched_image_id) DO UPDATE SET distance = EXCLUDED.distance`,
+		imageID, maxDistance)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record duplicate photos of image %d: %w", imageID, err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return int(n) / 2, nil
+}
+
+// GetDuplicatePhotos lists the recorded matches of a car's photos on other sellers' listings,
+// closest first. Matches on deleted listings are left out.
+func (r *CarImageRepository) GetDuplicatePhotos(carID int) ([]DuplicatePhotoMatch, error) {
+	byCar, err := r.GetDuplicatePhotosBatch([]int{carID})
+	if err != nil {
+		return nil, err
+	}
+	if matches, ok := byCar[carID]; ok {
+		return matches, nil
+	}
+	return []DuplicatePhotoMatch{}, nil
+}
+
+// GetDuplicatePhotosBatch lists the recorded duplicate photo matches of several cars in a single
+// query, keyed by car ID. Cars without matches are absent from the map.
+func (r *CarImageRepository) GetDuplicatePhotosBatch(carIDs []int) (map[int][]DuplicatePhotoMatch, error) {
+	byCar := make(map[int][]DuplicatePhotoMatch)
+	if len(carIDs) == 0 {
+		return byCar, nil
+	}
+
+	rows, err := r.db.DB.Query(`
+		SELECT a.car_id, d.image_id, a.content_hash, d.matched_image_id, b.content_hash, b.car_id, cb.status,
+			cb.seller_id, u.name, b.uploaded_at, d.distance, d.detected_at
+		FROM car_image_duplicates d
+		JOIN car_images a ON a.id = d.image_id
+		JOIN car_images b ON b.id = d.matched_image_id
+		JOIN cars cb ON cb.id = b.car_id
+		LEFT JOIN users u ON u.id = cb.seller_id
+		WHERE a.car_id = ANY($1) AND cb.status <> 'deleted'
+		ORDER BY a.car_id, d.distance, a.display_order, d.image_id, b.uploaded_at`, pq.Array(carIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get duplicate photos: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var carID int
+		var m DuplicatePhotoMatch
+		if err := rows.Scan(
+			&carID, &m.ImageID, &m.ImageContentHash, &m.MatchedImageID, &m.MatchedContentHash, &m.MatchedCarID,
+			&m.MatchedCarStatus, &m.MatchedSellerID, &m.MatchedSellerName, &m.MatchedUploadedAt,
+			&m.Distance, &m.DetectedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate photo: %w", err)
+		}
+		byCar[carID] = append(byCar[carID], m)
+	}
+	return byCar, rows.Err()
+}
+
 // GetCarImagesMetadata retrieves all image metadata for a car (without image data)
 func (r *CarImageRepository) GetCarImagesMetadata(carID int) ([]CarImageMetadata, error) {
 	query := `
-		SELECT id, car_id, image_type, image_size, display_order, uploaded_at
+		SELECT id, car_id, image_type, image_size, width, height, display_order, uploaded_at, content_hash
 		FROM car_images
 		WHERE car_id = $1
 		ORDER BY display_order, uploaded_at`
@@ -1498,7 +2334,7 @@ func (r *CarImageRepository) GetCarImagesMetadata(carID int) ([]CarImageMetadata
 	var images []CarImageMetadata
 	for rows.Next() {
 		var img CarImageMetadata
-		err := rows.Scan(&img.ID, &img.CarID, &img.ImageType, &img.ImageSize, &img.DisplayOrder, &img.UploadedAt)
+		err := rows.Scan(&img.ID, &img.CarID, &img.ImageType, &img.ImageSize, &img.Width, &img.Height, &img.DisplayOrder, &img.UploadedAt, &img.ContentHash)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan image metadata: %w", err)
 		}
@@ -1523,7 +2359,7 @@ func (r *CarImageRepository) GetCarImagesMetadataBatch(carIDs []int) (map[int][]
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, car_id, image_type, image_size, display_order, uploaded_at
+		SELECT id, car_id, image_type, image_size, width, height, display_order, uploaded_at, content_hash
 		FROM car_images
 		WHERE car_id IN (%s)
 		ORDER BY car_id, display_order, uploaded_at`, strings.Join(placeholders, ","))
@@ -1537,7 +2373,7 @@ func (r *CarImageRepository) GetCarImagesMetadataBatch(carIDs []int) (map[int][]
 	result := make(map[int][]CarImageMetadata)
 	for rows.Next() {
 		var img CarImageMetadata
-		err := rows.Scan(&img.ID, &img.CarID, &img.ImageType, &img.ImageSize, &img.DisplayOrder, &img.UploadedAt)
+		err := rows.Scan(&img.ID, &img.CarID, &img.ImageType, &img.ImageSize, &img.Width, &img.Height, &img.DisplayOrder, &img.UploadedAt, &img.ContentHash)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan image metadata: %w", err)
 		}
@@ -1597,6 +2433,20 @@ func (r *CarImageRepository) DeleteAllCarImages(carID int) error {
 	return nil
 }
 
+// reorderImages sets the display order of images to their position in imageIDs on a
+// connection or transaction
+func reorderImages(exec sqlExecer, imageIDs []int) error {
+	for i, imageID := range imageIDs {
+		if _, err := exec.Exec(
+			"UPDATE car_images SET display_order = $1 WHERE id = $2",
+			i, imageID,
+		); err != nil {
+			return fmt.Errorf("failed to update image %d: %w", imageID, err)
+		}
+	}
+	return nil
+}
+
 // ReorderImages updates display_order for multiple images in one transaction
 func (r *CarImageRepository) ReorderImages(imageIDs []int) error {
 	// Start transaction
@@ -1606,15 +2456,8 @@ func (r *CarImageRepository) ReorderImages(imageIDs []int) error {
 	}
 	defer tx.Rollback()
 
-	// Update each image with its new position
-	for i, imageID := range imageIDs {
-		_, err = tx.Exec(
-			"UPDATE car_images SET display_order = $1 WHERE id = $2",
-			i, imageID,
-		)
-		if err != nil {
-			return fmt.Errorf("failed to update image %d: %w", imageID, err)
-		}
+	if err := reorderImages(tx, imageIDs); err != nil {
+		return err
 	}
 
 	// Commit transaction
@@ -1635,31 +2478,37 @@ func NewCarFuelRepository(db *Database) *CarFuelRepository {
 	return &CarFuelRepository{db: db}
 }
 
-// SetCarFuels replaces all fuels for a car
-func (r *CarFuelRepository) SetCarFuels(carID int, fuelCodes []string) error {
-	// Start transaction
-	tx, err := r.db.DB.Begin()
-	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
-	}
-	defer tx.Rollback()
-
+// setCarFuels replaces a car's fuel types on a connection or transaction
+func setCarFuels(exec sqlExecer, carID int, fuelCodes []string) error {
 	// Delete existing fuels
-	_, err = tx.Exec("DELETE FROM car_fuel WHERE car_id = $1", carID)
-	if err != nil {
+	if _, err := exec.Exec("DELETE FROM car_fuel WHERE car_id = $1", carID); err != nil {
 		return fmt.Errorf("failed to delete existing fuels: %w", err)
 	}
 
 	// Insert new fuels
 	for _, fuelCode := range fuelCodes {
-		_, err = tx.Exec(
+		if _, err := exec.Exec(
 			"INSERT INTO car_fuel (car_id, fuel_type_code) VALUES ($1, $2)",
 			carID, fuelCode,
-		)
-		if err != nil {
+		); err != nil {
 			return fmt.Errorf("failed to insert fuel %s: %w", fuelCode, err)
 		}
 	}
+	return nil
+}
+
+// SetCarFuels replaces all fuels for a car
+func (r *CarFuelRepository) SetCarFuels(carID int, fuelCodes []string) error {
+	// Start transaction
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := setCarFuels(tx, carID, fuelCodes); err != nil {
+		return err
+	}
 
 	// Commit transaction
 	if err := tx.Commit(); err != nil {
@@ -1764,25 +2613,33 @@ func (r *CarColorRepository) SetCarColors(carID int, colorCodes []string) error
 	}
 	defer tx.Rollback()
 
+	if err := setCarColors(tx, carID, colorCodes); err != nil {
+		return err
+	}
+
+	// Commit
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// setCarColors replaces a car's colors on a connection or transaction
+func setCarColors(exec sqlExecer, carID int, colorCodes []string) error {
 	// Delete existing colors
-	if _, err = tx.Exec("DELETE FROM car_colors WHERE car_id = $1", carID); err != nil {
+	if _, err := exec.Exec("DELETE FROM car_colors WHERE car_id = $1", carID); err != nil {
 		return fmt.Errorf("failed to delete existing colors: %w", err)
 	}
 
 	// Insert new colors with positions
 	for i, colorCode := range colorCodes {
-		if _, err = tx.Exec(
+		if _, err := exec.Exec(
 			"INSERT INTO car_colors (car_id, color_code, position) VALUES ($1, $2, $3)",
 			carID, colorCode, i,
 		); err != nil {
 			return fmt.Errorf("failed to insert color at position %d: %w", i, err)
 		}
 	}
-
-	// Commit
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
 	return nil
 }
 
@@ -0,0 +1,131 @@
+package models
+
+import "errors"
+
+// ErrInvalidComparison is returned when a comparison request has too few, too many
+// or duplicate cars
+var ErrInvalidComparison = errors.New("invalid comparison")
+
+// How many cars can be compared side by side
+const (
+	MinComparedCars = 2
+	MaxComparedCars = 4
+)
+
+// CarComparison is the response for GET /api/cars/compare (API response only).
+// Rows are aligned with Cars: Values[i] and Best indexes refer to Cars[i].
+type CarComparison struct {
+	Cars []CarComparisonEntry `json:"cars"` // In the requested order
+	Rows []CarComparisonRow   `json:"rows"`
+}
+
+// CarComparisonEntry is one compared car
+type CarComparisonEntry struct {
+	Car             interface{}        `json:"car"` // CarDisplay from services
+	ThumbnailURL    *string            `json:"thumbnailUrl"`
+	Inspection      *InspectionSummary `json:"inspection"`      // nil when the car has no inspection
+	EstimatedPrice  *int64             `json:"estimatedPrice"`  // nil when no estimate is available
+	PriceVsEstimate *int64             `json:"priceVsEstimate"` // Asking minus estimated price (negative: below estimate)
+}
+
+// CarComparisonRow is one field of the comparison table
+type CarComparisonRow struct {
+	Field  string        `json:"field"`  // e.g. "price", "mileage", "fuelTypes"
+	Values []interface{} `json:"values"` // One per car; nil when unknown
+	Best   []int         `json:"best"`   // Indexes of the cars with the best value (empty when not ranked or all equal)
+}
+
+// InspectionSummary condenses an inspection result for comparison
+type InspectionSummary struct {
+	Station      *string  `json:"station"`
+	OverallPass  *bool    `json:"overallPass"`
+	PassedCount  int      `json:"passedCount"`
+	CheckedCount int      `json:"checkedCount"` // Items with a recorded result
+	FailedItems  []string `json:"failedItems"`  // InspectionResult JSON keys, e.g. "brakeResult"
+}
+
+// InspectionItem is the result of one inspection item, nil when not recorded
+type InspectionItem struct {
+	Name   string // InspectionResult JSON key, e.g. "brakeResult"
+	Result *bool
+}
+
+// Items lists the results of the individual inspection items (excluding the overall result)
+func (insp *InspectionResult) Items() []InspectionItem {
+	return []InspectionItem{
+		{"brakeResult", insp.BrakeResult},
+		{"handbrakeResult", insp.HandbrakeResult},
+		{"alignmentResult", insp.AlignmentResult},
+		{"noiseResult", insp.NoiseResult},
+		{"emissionResult", insp.EmissionResult},
+		{"hornResult", insp.HornResult},
+		{"speedometerResult", insp.SpeedometerResult},
+		{"highLowBeamResult", insp.HighLowBeamResult},
+		{"signalLightsResult", insp.SignalLightsResult},
+		{"otherLightsResult", insp.OtherLightsResult},
+		{"windshieldResult", insp.WindshieldResult},
+		{"steeringResult", insp.SteeringResult},
+		{"wheelsTiresResult", insp.WheelsTiresResult},
+		{"fuelTankResult", insp.FuelTankResult},
+		{"chassisResult", insp.ChassisResult},
+		{"bodyResult", insp.BodyResult},
+		{"doorsFloorResult", insp.DoorsFloorResult},
+		{"seatbeltResult", insp.SeatbeltResult},
+		{"wiperResult", insp.WiperResult},
+	}
+}
+
+// Summary counts the passed and failed items of an inspection (excluding the overall result)
+func (insp *InspectionResult) Summary() InspectionSummary {
+	summary := InspectionSummary{
+		Station:     insp.Station,
+		OverallPass: insp.OverallPass,
+		FailedItems: []string{},
+	}
+	for _, item := range insp.Items() {
+		if item.Result == nil {
+			continue
+		}
+		summary.CheckedCount++
+		if *item.Result {
+			summary.PassedCount++
+		} else {
+			summary.FailedItems = append(summary.FailedItems, item.Name)
+		}
+	}
+	return summary
+}
+
+// BestValueIndexes returns the indexes of the best known values (ties included).
+// It returns an empty slice when fewer than two values are known or all known values are equal.
+func BestValueIndexes(values []*float64, higherIsBetter bool) []int {
+	var best *float64
+	known, distinct := 0, false
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		known++
+		if best == nil {
+			best = v
+			continue
+		}
+		if *v != *best {
+			distinct = true
+		}
+		if (higherIsBetter && *v > *best) || (!higherIsBetter && *v < *best) {
+			best = v
+		}
+	}
+
+	indexes := []int{}
+	if known < 2 || !distinct {
+		return indexes
+	}
+	for i, v := range values {
+		if v != nil && *v == *best {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
@@ -0,0 +1,146 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded
+// or does not match the requested sort
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// carSortField describes a sortable car column for ORDER BY and keyset pagination
+type carSortField struct {
+	Column  string // Qualified column used in ORDER BY and keyset predicates
+	SQLType string // Type the cursor key is cast to in SQL
+}
+
+// carSortFields lists the sort fields accepted by car search and listings
+// (validated here to prevent SQL injection)
+var carSortFields = map[string]carSortField{
+	"price":            {Column: "cars.price", SQLType: "integer"},
+	"year":             {Column: "cars.year", SQLType: "integer"},
+	"mileage":          {Column: "cars.mileage", SQLType: "integer"},
+	"created_at":       {Column: "cars.created_at", SQLType: "timestamp"},
+	"condition_rating": {Column: "cars.condition_rating", SQLType: "integer"},
+}
+
+// ResolveCarSort normalizes a sort field and order, falling back to created_at desc
+func ResolveCarSort(sortBy, sortOrder string) (string, string) {
+	if _, ok := carSortFields[sortBy]; !ok {
+		sortBy = "created_at"
+	}
+	if sortOrder != "asc" && sortOrder != "desc" {
+		sortOrder = "desc"
+	}
+	return sortBy, sortOrder
+}
+
+// CarCursor is the decoded form of an opaque keyset pagination token.
+// It holds the sort key and ID of the last car on the previous page.
+type CarCursor struct {
+	SortBy    string  `json:"s"`
+	SortOrder string  `json:"o"`
+	Key       *string `json:"k"` // Sort key as text; nil when the car has no value for the sort field
+	ID        int     `json:"i"`
+}
+
+// NewCarCursor builds the cursor that continues after car for the given sort
+func NewCarCursor(car *Car, sortBy, sortOrder string) *CarCursor {
+	sortBy, sortOrder = ResolveCarSort(sortBy, sortOrder)
+
+	var key *string
+	setInt := func(v *int) {
+		if v != nil {
+			s := strconv.Itoa(*v)
+			key = &s
+		}
+	}
+
+	switch sortBy {
+	case "price":
+		setInt(car.Price)
+	case "year":
+		setInt(car.Year)
+	case "mileage":
+		setInt(car.Mileage)
+	case "condition_rating":
+		setInt(car.ConditionRating)
+	default:
+		s := car.CreatedAt.UTC().Format(time.RFC3339Nano)
+		key = &s
+	}
+
+	return &CarCursor{SortBy: sortBy, SortOrder: sortOrder, Key: key, ID: car.ID}
+}
+
+// Encode returns the opaque token for the cursor
+func (c *CarCursor) Encode() string {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCarCursor parses an opaque token produced by CarCursor.Encode
+func DecodeCarCursor(token string) (*CarCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c CarCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if _, ok := carSortFields[c.SortBy]; !ok || (c.SortOrder != "asc" && c.SortOrder != "desc") || c.ID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	// Validate the key against the column type so it is safe to cast in SQL
+	if c.Key != nil {
+		if carSortFields[c.SortBy].SQLType == "timestamp" {
+			if _, err := time.Parse(time.RFC3339Nano, *c.Key); err != nil {
+				return nil, ErrInvalidCursor
+			}
+		} else if _, err := strconv.Atoi(*c.Key); err != nil {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return &c, nil
+}
+
+// carOrderBySQL returns the ORDER BY expression for a resolved sort, with the car ID
+// as a tie-breaker so keyset pagination is stable
+func carOrderBySQL(sortBy, sortOrder string) string {
+	column := carSortFields[sortBy].Column
+	return fmt.Sprintf("%s %s NULLS LAST, cars.id %s", column, sortOrder, sortOrder)
+}
+
+// carKeysetSQL returns the predicate selecting rows after cursor in carOrderBySQL order.
+// Placeholders start at argCounter; the returned args must be appended in order.
+func carKeysetSQL(cursor *CarCursor, argCounter int) (string, []interface{}) {
+	field := carSortFields[cursor.SortBy]
+	cmp := "<"
+	if cursor.SortOrder == "asc" {
+		cmp = ">"
+	}
+
+	// NULL keys sort last, so after a NULL key only NULL rows with a further ID remain
+	if cursor.Key == nil {
+		return fmt.Sprintf("(%s IS NULL AND cars.id %s $%d)", field.Column, cmp, argCounter),
+			[]interface{}{cursor.ID}
+	}
+
+	return fmt.Sprintf(
+		"(%[1]s %[2]s $%[3]d::%[4]s OR (%[1]s = $%[3]d::%[4]s AND cars.id %[2]s $%[5]d) OR %[1]s IS NULL)",
+		field.Column, cmp, argCounter, field.SQLType, argCounter+1,
+	), []interface{}{*cursor.Key, cursor.ID}
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ListingDuration is how long a published listing stays active before it expires
+const ListingDuration = 60 * 24 * time.Hour
+
+// ExpiringCar is an active listing whose seller is due an expiry reminder
+type ExpiringCar struct {
+	CarID       int
+	SellerEmail string
+	BrandName   *string
+	ModelName   *string
+	Year        *int
+	ExpiresAt   time.Time
+}
+
+// SetCarExpiry sets when a listing expires and clears any reminder already sent
+func (r *CarRepository) SetCarExpiry(carID int, expiresAt time.Time) error {
+	result, err := r.db.DB.Exec(
+		`UPDATE cars SET expires_at = $2, expiry_reminder_sent_at = NULL WHERE id = $1`,
+		carID, expiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to set car expiry: %w", err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fmt.Errorf("car not found")
+	}
+	return nil
+}
+
+// GetCarsDueForExpiryReminder returns active listings expiring within the lead time
+// whose seller has not been reminded yet
+func (r *CarRepository) GetCarsDueForExpiryReminder(lead time.Duration) ([]ExpiringCar, error) {
+	query := `
+		SELECT cars.id, users.email, cars.brand_name, cars.model_name, cars.year, cars.expires_at
+		FROM cars
+		JOIN users ON users.id = cars.seller_id
+		WHERE cars.status = 'active'
+			AND cars.expires_at > NOW()
+			AND cars.expires_at <= NOW() + make_interval(secs => $1)
+			AND cars.expiry_reminder_sent_at IS NULL
+		ORDER BY cars.expires_at`
+
+	rows, err := r.db.DB.Query(query, lead.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cars due for expiry reminder: %w", err)
+	}
+	defer rows.Close()
+
+	var cars []ExpiringCar
+	for rows.Next() {
+		var c ExpiringCar
+		if err := rows.Scan(&c.CarID, &c.SellerEmail, &c.BrandName, &c.ModelName, &c.Year, &c.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("failed to scan expiring car: %w", err)
+		}
+		cars = append(cars, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expiring cars: %w", err)
+	}
+	return cars, nil
+}
+
+// MarkExpiryReminderSent records that the seller was reminded about the listing's expiry
+func (r *CarRepository) MarkExpiryReminderSent(carID int) error {
+	if _, err := r.db.DB.Exec(`UPDATE cars SET expiry_reminder_sent_at = NOW() WHERE id = $1`, carID); err != nil {
+		return fmt.Errorf("failed to mark expiry reminder sent: %w", err)
+	}
+	return nil
+}
+
+// ExpireDueCars moves active listings past their expiry to the expired status and
+// returns their IDs
+func (r *CarRepository) ExpireDueCars() ([]int, error) {
+	rows, err := r.db.DB.Query(`
+		UPDATE cars SET status = 'expired'
+		WHERE status = 'active' AND expires_at <= NOW()
+		RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to expire cars: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan expired car: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating expired cars: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CarEngagement holds buyer interest counts for a car
+type CarEngagement struct {
+	Views      int // Recorded views (recent_views keeps 90 days)
+	Favourites int
+}
+
+// GetCarEngagementBatch returns view and favourite counts for multiple cars.
+// Cars without views or favourites are returned with zero counts.
+func (r *CarRepository) GetCarEngagementBatch(carIDs []int) (map[int]CarEngagement, error) {
+	result := make(map[int]CarEngagement)
+	if len(carIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(carIDs))
+	args := make([]interface{}, len(carIDs))
+	for i, id := range carIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT c.id,
+			(SELECT COUNT(*) FROM recent_views rv WHERE rv.car_id = c.id),
+			(SELECT COUNT(*) FROM favourites f WHERE f.car_id = c.id)
+		FROM cars c
+		WHERE c.id IN (%s)`, strings.Join(placeholders, ","))
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch fetch car engagement: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var carID int
+		var e CarEngagement
+		if err := rows.Scan(&carID, &e.Views, &e.Favourites); err != nil {
+			return nil, fmt.Errorf("failed to scan car engagement: %w", err)
+		}
+		result[carID] = e
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating car engagement: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,318 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// FeedWeights sets how much each signal contributes to a car's personalized feed score.
+// Each signal scores between 0 and 1 and is multiplied by its weight.
+type FeedWeights struct {
+	BrandAffinity    float64 // Brand of cars the buyer viewed or favourited
+	BodyTypeAffinity float64 // Body type of cars the buyer viewed or favourited
+	BudgetFit        float64 // Price within the buyer's budget (decays outside it)
+	Proximity        float64 // Distance from the buyer's province
+	Recency          float64 // How recently the car was listed
+}
+
+// DefaultFeedWeights returns the default personalized feed weights
+func DefaultFeedWeights() FeedWeights {
+	return FeedWeights{
+		BrandAffinity:    3,
+		BodyTypeAffinity: 2,
+		BudgetFit:        2,
+		Proximity:        1,
+		Recency:          1.5,
+	}
+}
+
+// FeedCandidateLimit is the most listings a personalized feed ranks. Candidates are the
+// newest listings that share a brand or body type with the buyer's interactions, fit their
+// budget or were listed in the last FeedRecentDays.
+const FeedCandidateLimit = 500
+
+// FeedRecentDays is how recent a listing must be to be a feed candidate without matching
+// the buyer's interactions or budget
+const FeedRecentDays = 30
+
+// FeedProfile is the buyer context used to rank a personalized feed
+type FeedProfile struct {
+	UserID           int
+	BudgetMin        *int
+	BudgetMax        *int
+	OriginProvinceID *int // Buyer's province (nil when unknown)
+	Interactions     int  // Favourites and recent views the affinity signals are built from
+}
+
+// HasSignal reports whether the profile has anything to personalize a feed with
+func (p FeedProfile) HasSignal() bool {
+	return p.Interactions > 0 || p.BudgetMin != nil || p.BudgetMax != nil || p.OriginProvinceID != nil
+}
+
+// FeedCandidate is a listing with the signals its personalized feed score is built from
+type FeedCandidate struct {
+	Car              Car
+	BrandAffinity    float64  // Share of the buyer's top brand interactions, 0 to 1
+	BodyTypeAffinity float64  // Share of the buyer's top body type interactions, 0 to 1
+	DistanceKm       *float64 // From the buyer's province (nil when either is unknown)
+}
+
+// BudgetFit scores a price against a buyer's budget: 1 within it (or without a budget),
+// falling linearly to 0 at 25% above the maximum or 50% below the minimum. Cars without
+// a price score 0.
+func BudgetFit(price, budgetMin, budgetMax *int) float64 {
+	if price == nil {
+		return 0
+	}
+	p := float64(*price)
+	switch {
+	case budgetMax != nil && *price > *budgetMax:
+		if *budgetMax <= 0 {
+			return 0
+		}
+		return math.Max(0, 1-(p-float64(*budgetMax))/(float64(*budgetMax)*0.25))
+	case budgetMin != nil && *price < *budgetMin:
+		return math.Max(0, 1-(float64(*budgetMin)-p)/(float64(*budgetMin)*0.5))
+	}
+	return 1
+}
+
+// FeedScore returns a candidate's personalized feed score. Proximity falls to 0 at 300 km
+// and recency decays with a 14-day time constant.
+func FeedScore(c FeedCandidate, profile FeedProfile, weights FeedWeights, now time.Time) float64 {
+	score := weights.BrandAffinity*c.BrandAffinity +
+		weights.BodyTypeAffinity*c.BodyTypeAffinity +
+		weights.BudgetFit*BudgetFit(c.Car.Price, profile.BudgetMin, profile.BudgetMax)
+	if c.DistanceKm != nil {
+		score += weights.Proximity * math.Max(0, 1-*c.DistanceKm/300)
+	}
+	ageDays := now.Sub(c.Car.CreatedAt).Hours() / 24
+	score += weights.Recency * math.Exp(math.Max(-50, -ageDays/14))
+	return score
+}
+
+// RankFeedCandidates orders candidates by feed score, newest first among equal scores
+func RankFeedCandidates(candidates []FeedCandidate, profile FeedProfile, weights FeedWeights, now time.Time) []Car {
+	scores := make([]float64, len(candidates))
+	order := make([]int, len(candidates))
+	for i, c := range candidates {
+		scores[i] = FeedScore(c, profile, weights, now)
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		ca, cb := candidates[order[a]].Car, candidates[order[b]].Car
+		switch {
+		case scores[order[a]] != scores[order[b]]:
+			return scores[order[a]] > scores[order[b]]
+		case !ca.CreatedAt.Equal(cb.CreatedAt):
+			return ca.CreatedAt.After(cb.CreatedAt)
+		}
+		return ca.ID > cb.ID
+	})
+
+	cars := make([]Car, len(order))
+	for i, idx := range order {
+		cars[i] = candidates[idx].Car
+	}
+	return cars
+}
+
+// MergeFeedSections joins feed sections in order, keeping only the first occurrence of
+// a car that appears in more than one
+func MergeFeedSections(sections ...[]Car) []Car {
+	seen := make(map[int]bool)
+	var cars []Car
+	for _, section := range sections {
+		for _, car := range section {
+			if seen[car.ID] {
+				continue
+			}
+			seen[car.ID] = true
+			cars = append(cars, car)
+		}
+	}
+	return cars
+}
+
+// FeedData is the response for GET /api/feed (API response only)
+type FeedData struct {
+	Cars  []CarListItem `json:"cars"`
+	Mode  string        `json:"mode"` // personalized, trending
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+}
+
+// feedCarColumns are the columns scanned by scanFeedCars
+const feedCarColumns = `cars.id, cars.seller_id, cars.body_type_code, cars.transmission_code, cars.drivetrain_code,
+			cars.brand_name, cars.model_name, cars.submodel_name, cars.chassis_number,
+			cars.year, cars.mileage, cars.engine_cc, cars.seats, cars.doors,
+			cars.prefix, cars.number, cars.province_id, cars.description, cars.price,
+			cars.is_flooded, cars.is_heavily_damaged,
+			cars.status, cars.condition_rating, cars.created_at, cars.updated_at`
+
+// feedInteractionsSQL selects a buyer's ($1) weighted interactions: favourites count
+// double; views from the last 90 days decay with a 30-day time constant
+const feedInteractionsSQL = `
+			SELECT car_id, 2.0::float8 AS weight FROM favourites WHERE user_id = $1
+			UNION ALL
+			SELECT car_id, EXP(-EXTRACT(EPOCH FROM (NOW() - viewed_at))::float8 / (86400 * 30))
+			FROM recent_views
+			WHERE user_id = $1 AND viewed_at > NOW() - INTERVAL '90 days'`
+
+// CountFeedInteractions counts the favourites and recent views a buyer's feed affinity is
+// built from
+func (r *CarRepository) CountFeedInteractions(userID int) (int, error) {
+	var count int
+	err := r.db.DB.QueryRow(fmt.Sprintf(`SELECT COUNT(*) FROM (%s) interactions`, feedInteractionsSQL), userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count feed interactions: %w", err)
+	}
+	return count, nil
+}
+
+// GetFeedCandidates returns up to FeedCandidateLimit active listings for a buyer's
+// personalized feed with their affinity and distance signals, newest first. Brand and
+// body type affinity are the buyer's interaction weight on them relative to their top
+// brand and body type. Cars the buyer already favourited and their own listings are
+// excluded.
+func (r *CarRepository) GetFeedCandidates(profile FeedProfile) ([]FeedCandidate, error) {
+	query := fmt.Sprintf(`
+		WITH interactions AS (%[3]s
+		),
+		brand_affinity AS (
+			SELECT LOWER(c.brand_name) AS brand, SUM(i.weight) AS score
+			FROM interactions i JOIN cars c ON c.id = i.car_id
+			WHERE c.brand_name IS NOT NULL
+			GROUP BY LOWER(c.brand_name)
+		),
+		body_affinity AS (
+			SELECT c.body_type_code AS code, SUM(i.weight) AS score
+			FROM interactions i JOIN cars c ON c.id = i.car_id
+			WHERE c.body_type_code IS NOT NULL
+			GROUP BY c.body_type_code
+		),
+		norms AS (
+			SELECT (SELECT MAX(score) FROM brand_affinity) AS max_brand,
+				(SELECT MAX(score) FROM body_affinity) AS max_body
+		)
+		SELECT %[1]s,
+			COALESCE(ba.score / NULLIF(norms.max_brand, 0), 0),
+			COALESCE(bo.score / NULLIF(norms.max_body, 0), 0),
+			(
+				SELECT %[2]s
+				FROM provinces p JOIN provinces origin ON origin.id = $4::int
+				WHERE p.id = cars.province_id
+			)
+		FROM cars
+		CROSS JOIN norms
+		LEFT JOIN brand_affinity ba ON ba.brand = LOWER(cars.brand_name)
+		LEFT JOIN body_affinity bo ON bo.code = cars.body_type_code
+		WHERE cars.status = 'active'
+			AND cars.seller_id <> $1
+			AND NOT EXISTS (SELECT 1 FROM favourites f WHERE f.user_id = $1 AND f.car_id = cars.id)
+			AND (
+				ba.brand IS NOT NULL
+				OR bo.code IS NOT NULL
+				OR (($2::int IS NOT NULL OR $3::int IS NOT NULL)
+					AND cars.price >= COALESCE($2::int, 0) * 0.5
+					AND cars.price <= COALESCE($3::int * 1.25, cars.price))
+				OR cars.created_at > NOW() - make_interval(days => $5)
+			)
+		ORDER BY cars.created_at DESC, cars.id DESC
+		LIMIT $6`, feedCarColumns, provinceDistanceKmSQL("origin", "p"), feedInteractionsSQL)
+
+	rows, err := r.db.DB.Query(query,
+		profile.UserID, profile.BudgetMin, profile.BudgetMax, profile.OriginProvinceID,
+		FeedRecentDays, FeedCandidateLimit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get feed candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var candidates []FeedCandidate
+	for rows.Next() {
+		var c FeedCandidate
+		var distance sql.NullFloat64
+		dest := append(feedCarScanDest(&c.Car), &c.BrandAffinity, &c.BodyTypeAffinity, &distance)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan feed candidate: %w", err)
+		}
+		if distance.Valid {
+			c.DistanceKm = &distance.Float64
+		}
+		candidates = append(candidates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed candidates: %w", err)
+	}
+	return candidates, nil
+}
+
+// GetTrendingCars ranks active cars by distinct viewers plus favourites (double weight)
+// over the last 7 days, newest first among equally popular cars. A non-zero excludeUserID
+// leaves out that user's own listings and favourites.
+func (r *CarRepository) GetTrendingCars(excludeUserID, limit, offset int) ([]Car, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM cars
+		LEFT JOIN (
+			SELECT car_id, COUNT(DISTINCT user_id) AS viewers
+			FROM recent_views
+			WHERE viewed_at > NOW() - INTERVAL '7 days'
+			GROUP BY car_id
+		) v ON v.car_id = cars.id
+		LEFT JOIN (
+			SELECT car_id, COUNT(*) AS favourites
+			FROM favourites
+			WHERE created_at > NOW() - INTERVAL '7 days'
+			GROUP BY car_id
+		) f ON f.car_id = cars.id
+		WHERE cars.status = 'active'
+			AND ($1 = 0 OR (
+				cars.seller_id <> $1
+				AND NOT EXISTS (SELECT 1 FROM favourites uf WHERE uf.user_id = $1 AND uf.car_id = cars.id)
+			))
+		ORDER BY (COALESCE(v.viewers, 0) + 2 * COALESCE(f.favourites, 0)) DESC, cars.created_at DESC, cars.id DESC
+		LIMIT $2 OFFSET $3`, feedCarColumns)
+
+	rows, err := r.db.DB.Query(query, excludeUserID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trending cars: %w", err)
+	}
+	defer rows.Close()
+
+	return scanFeedCars(rows)
+}
+
+// feedCarScanDest returns the scan destinations of feedCarColumns
+func feedCarScanDest(car *Car) []interface{} {
+	return []interface{}{
+		&car.ID, &car.SellerID, &car.BodyTypeCode, &car.TransmissionCode, &car.DrivetrainCode,
+		&car.BrandName, &car.ModelName, &car.SubmodelName, &car.ChassisNumber,
+		&car.Year, &car.Mileage, &car.EngineCC, &car.Seats, &car.Doors,
+		&car.Prefix, &car.Number, &car.ProvinceID, &car.Description, &car.Price,
+		&car.IsFlooded, &car.IsHeavilyDamaged, &car.Status, &car.ConditionRating,
+		&car.CreatedAt, &car.UpdatedAt,
+	}
+}
+
+// scanFeedCars scans rows selected with feedCarColumns
+func scanFeedCars(rows *sql.Rows) ([]Car, error) {
+	var cars []Car
+	for rows.Next() {
+		var car Car
+		err := rows.Scan(feedCarScanDest(&car)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan feed car: %w", err)
+		}
+		cars = append(cars, car)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating feed cars: %w", err)
+	}
+	return cars, nil
+}
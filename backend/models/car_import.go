@@ -0,0 +1,198 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidCarImport is returned when an import upload cannot be processed at all
+// (bad header, too many rows, unreadable zip)
+var ErrInvalidCarImport = errors.New("invalid import")
+
+// ErrCarImportInProgress is returned when the seller already has an import running
+var ErrCarImportInProgress = errors.New("an import is already in progress")
+
+// Car import job statuses
+const (
+	CarImportPending   = "pending"
+	CarImportRunning   = "running"
+	CarImportCompleted = "completed"
+	CarImportFailed    = "failed"
+)
+
+// Car import row outcomes
+const (
+	CarImportRowCreated   = "created"   // Saved as a draft
+	CarImportRowPublished = "published" // Saved and published
+	CarImportRowFailed    = "failed"    // Nothing saved
+)
+
+// CarImportRowResult is the outcome of one CSV row
+type CarImportRowResult struct {
+	Row    int      `json:"row"` // 1-based data row (the header is not counted)
+	Status string   `json:"status"`
+	CarID  *int     `json:"carId,omitempty"`
+	Errors []string `json:"errors,omitempty"` // Why the row failed
+	Issues []string `json:"issues,omitempty"` // Why a draft was not published (publish requested only)
+}
+
+// CarImportJob is a background bulk import of a seller's listings
+type CarImportJob struct {
+	ID             int                  `json:"id" db:"id"`
+	SellerID       int                  `json:"sellerId" db:"seller_id"`
+	Status         string               `json:"status" db:"status"` // pending, running, completed, failed
+	Publish        bool                 `json:"publish" db:"publish"`
+	TotalRows      int                  `json:"totalRows" db:"total_rows"`
+	ProcessedRows  int                  `json:"processedRows" db:"processed_rows"`
+	CreatedCount   int                  `json:"createdCount" db:"created_count"`
+	PublishedCount int                  `json:"publishedCount" db:"published_count"`
+	FailedCount    int                  `json:"failedCount" db:"failed_count"`
+	Rows           []CarImportRowResult `json:"rows" db:"report"`
+	Error          *string              `json:"error,omitempty" db:"error"`
+	CreatedAt      time.Time            `json:"createdAt" db:"created_at"`
+	StartedAt      *time.Time           `json:"startedAt" db:"started_at"`
+	FinishedAt     *time.Time           `json:"finishedAt" db:"finished_at"`
+}
+
+// AddRow records a processed row and updates the counters
+func (j *CarImportJob) AddRow(result CarImportRowResult) {
+	j.Rows = append(j.Rows, result)
+	j.ProcessedRows++
+	switch result.Status {
+	case CarImportRowCreated:
+		j.CreatedCount++
+	case CarImportRowPublished:
+		j.PublishedCount++
+	default:
+		j.FailedCount++
+	}
+}
+
+// CarImportRepository handles car_import_jobs operations
+type CarImportRepository struct {
+	db *Database
+}
+
+// NewCarImportRepository creates a new car import repository
+func NewCarImportRepository(db *Database) *CarImportRepository {
+	return &CarImportRepository{db: db}
+}
+
+// CreateCarImportJob inserts a pending job and sets its ID and CreatedAt. It returns
+// ErrCarImportInProgress when the seller already has a pending or running job.
+func (r *CarImportRepository) CreateCarImportJob(job *CarImportJob) error {
+	job.Status = CarImportPending
+	if job.Rows == nil {
+		job.Rows = []CarImportRowResult{}
+	}
+	err := r.db.DB.QueryRow(`
+		INSERT INTO car_import_jobs (seller_id, status, publish, total_rows)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at`,
+		job.SellerID, job.Status, job.Publish, job.TotalRows,
+	).Scan(&job.ID, &job.CreatedAt)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		// idx_car_import_jobs_one_unfinished: another upload started an import first
+		return ErrCarImportInProgress
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create import job: %w", err)
+	}
+	return nil
+}
+
+// GetCarImportJob returns an import job
+func (r *CarImportRepository) GetCarImportJob(jobID int) (*CarImportJob, error) {
+	var job CarImportJob
+	var report []byte
+	err := r.db.DB.QueryRow(`
+		SELECT id, seller_id, status, publish, total_rows, processed_rows,
+			created_count, published_count, failed_count, report, error,
+			created_at, started_at, finished_at
+		FROM car_import_jobs
+		WHERE id = $1`, jobID,
+	).Scan(&job.ID, &job.SellerID, &job.Status, &job.Publish, &job.TotalRows, &job.ProcessedRows,
+		&job.CreatedCount, &job.PublishedCount, &job.FailedCount, &report, &job.Error,
+		&job.CreatedAt, &job.StartedAt, &job.FinishedAt)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("import job not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get import job: %w", err)
+	}
+	if err := json.Unmarshal(report, &job.Rows); err != nil {
+		return nil, fmt.Errorf("failed to decode import report: %w", err)
+	}
+	return &job, nil
+}
+
+// HasUnfinishedCarImportJob reports whether the seller has a pending or running import
+func (r *CarImportRepository) HasUnfinishedCarImportJob(sellerID int) (bool, error) {
+	var exists bool
+	err := r.db.DB.QueryRow(`
+		SELECT EXISTS (
+			SELECT 1 FROM car_import_jobs WHERE seller_id = $1 AND status IN ('pending', 'running')
+		)`, sellerID,
+	).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check import jobs: %w", err)
+	}
+	return exists, nil
+}
+
+// StartCarImportJob marks a job as running
+func (r *CarImportRepository) StartCarImportJob(jobID int) error {
+	if _, err := r.db.DB.Exec(
+		`UPDATE car_import_jobs SET status = 'running', started_at = NOW() WHERE id = $1`, jobID,
+	); err != nil {
+		return fmt.Errorf("failed to start import job: %w", err)
+	}
+	return nil
+}
+
+// UpdateCarImportProgress stores a job's counters and report
+func (r *CarImportRepository) UpdateCarImportProgress(job *CarImportJob) error {
+	report, err := json.Marshal(job.Rows)
+	if err != nil {
+		return fmt.Errorf("failed to encode import report: %w", err)
+	}
+	_, err = r.db.DB.Exec(`
+		UPDATE car_import_jobs
+		SET processed_rows = $2, created_count = $3, published_count = $4, failed_count = $5, report = $6
+		WHERE id = $1`,
+		job.ID, job.ProcessedRows, job.CreatedCount, job.PublishedCount, job.FailedCount, report,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update import job: %w", err)
+	}
+	return nil
+}
+
+// FinishCarImportJob marks a job as completed, or failed with errMsg
+func (r *CarImportRepository) FinishCarImportJob(jobID int, status string, errMsg *string) error {
+	if _, err := r.db.DB.Exec(
+		`UPDATE car_import_jobs SET status = $2, error = $3, finished_at = NOW() WHERE id = $1`,
+		jobID, status, errMsg,
+	); err != nil {
+		return fmt.Errorf("failed to finish import job: %w", err)
+	}
+	return nil
+}
+
+// FailUnfinishedCarImportJobs fails jobs left pending or running by a server restart
+func (r *CarImportRepository) FailUnfinishedCarImportJobs() (int64, error) {
+	result, err := r.db.DB.Exec(`
+		UPDATE car_import_jobs
+		SET status = 'failed', error = 'import interrupted by a server restart', finished_at = NOW()
+		WHERE status IN ('pending', 'running')`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fail unfinished import jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// RecentlyReducedDays is how long after a price drop a car counts as "recently reduced"
+const RecentlyReducedDays = 14
+
+// CarPriceChange is one price change of a published listing
+type CarPriceChange struct {
+	ID        int       `json:"id" db:"id"`
+	CarID     int       `json:"carId" db:"car_id"`
+	OldPrice  int       `json:"oldPrice" db:"old_price"`
+	NewPrice  int       `json:"newPrice" db:"new_price"`
+	ChangedAt time.Time `json:"changedAt" db:"changed_at"`
+}
+
+// IsDrop reports whether the change lowered the price
+func (c CarPriceChange) IsDrop() bool {
+	return c.NewPrice < c.OldPrice
+}
+
+// IsRecentDrop reports whether the change was a drop within RecentlyReducedDays of now to
+// the car's current price. Price edits while a car is unpublished aren't recorded, so a
+// price that moved since the change no longer counts as reduced.
+func (c CarPriceChange) IsRecentDrop(currentPrice *int, now time.Time) bool {
+	return c.IsDrop() && currentPrice != nil && *currentPrice == c.NewPrice &&
+		c.ChangedAt.After(now.AddDate(0, 0, -RecentlyReducedDays))
+}
+
+// sqlExecer is implemented by *sql.DB and *sql.Tx
+type sqlExecer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// recentlyReducedSQL matches cars whose latest price change was a drop within
+// RecentlyReducedDays to their current price (see CarPriceChange.IsRecentDrop)
+var recentlyReducedSQL = fmt.Sprintf(`EXISTS (
+	SELECT 1 FROM (
+		SELECT old_price, new_price, changed_at FROM car_price_history
+		WHERE car_price_history.car_id = cars.id
+		ORDER BY changed_at DESC, id DESC
+		LIMIT 1
+	) latest
+	WHERE latest.new_price < latest.old_price AND latest.new_price = cars.price
+		AND latest.changed_at > NOW() - INTERVAL '%d days'
+)`, RecentlyReducedDays)
+
+// UpdateCarWithPriceChange updates a car and records the change from oldPrice to its
+// new price in the price history, in one transaction
+func (r *CarRepository) UpdateCarWithPriceChange(car *Car, oldPrice int) error {
+	if car.Price == nil {
+		return fmt.Errorf("cannot record price change without a new price")
+	}
+
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.updateCar(tx, car); err != nil {
+		return err
+	}
+
+	if err := recordPriceChange(tx, car.ID, oldPrice, *car.Price); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit car update: %w", err)
+	}
+	return nil
+}
+
+// recordPriceChange adds a price change to a car's price history
+func recordPriceChange(exec sqlExecer, carID, oldPrice, newPrice int) error {
+	if _, err := exec.Exec(
+		`INSERT INTO car_price_history (car_id, old_price, new_price) VALUES ($1, $2, $3)`,
+		carID, oldPrice, newPrice,
+	); err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// GetCarPriceHistory returns a car's price changes, newest first
+func (r *CarRepository) GetCarPriceHistory(carID int) ([]CarPriceChange, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT id, car_id, old_price, new_price, changed_at
+		FROM car_price_history
+		WHERE car_id = $1
+		ORDER BY changed_at DESC, id DESC`, carID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	defer rows.Close()
+
+	history := []CarPriceChange{}
+	for rows.Next() {
+		var c CarPriceChange
+		if err := rows.Scan(&c.ID, &c.CarID, &c.OldPrice, &c.NewPrice, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		history = append(history, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price history: %w", err)
+	}
+	return history, nil
+}
+
+// GetLatestPriceChangesBatch returns the latest price change of each car that has one
+func (r *CarRepository) GetLatestPriceChangesBatch(carIDs []int) (map[int]CarPriceChange, error) {
+	result := make(map[int]CarPriceChange)
+	if len(carIDs) == 0 {
+		return result, nil
+	}
+
+	placeholders := make([]string, len(carIDs))
+	args := make([]interface{}, len(carIDs))
+	for i, id := range carIDs {
+		placeholders[i] = fmt.Sprintf("$%d", i+1)
+		args[i] = id
+	}
+
+	query := fmt.Sprintf(`
+		SELECT DISTINCT ON (car_id) id, car_id, old_price, new_price, changed_at
+		FROM car_price_history
+		WHERE car_id IN (%s)
+		ORDER BY car_id, changed_at DESC, id DESC`, strings.Join(placeholders, ","))
+
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch fetch price changes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var c CarPriceChange
+		if err := rows.Scan(&c.ID, &c.CarID, &c.OldPrice, &c.NewPrice, &c.ChangedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan price change: %w", err)
+		}
+		result[c.CarID] = c
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating price changes: %w", err)
+	}
+	return result, nil
+}
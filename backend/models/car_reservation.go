@@ -0,0 +1,38 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// How long a seller can hold a reserved car before it returns to active
+const (
+	DefaultReservationDuration = 48 * time.Hour
+	MaxReservationDuration     = 14 * 24 * time.Hour
+)
+
+// ReleaseExpiredReservations moves reserved listings past their reserved_until back to
+// active and returns their IDs
+func (r *CarRepository) ReleaseExpiredReservations() ([]int, error) {
+	rows, err := r.db.DB.Query(`
+		UPDATE cars SET status = 'active', reserved_until = NULL
+		WHERE status = 'reserved' AND reserved_until <= NOW()
+		RETURNING id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to release reservations: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan released car: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating released cars: %w", err)
+	}
+	return ids, nil
+}
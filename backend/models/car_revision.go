@@ -0,0 +1,340 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Who made a car revision
+const (
+	CarRevisionActorSeller = "seller"
+	CarRevisionActorAdmin  = "admin"
+	CarRevisionActorSystem = "system"
+)
+
+// CarSnapshot is the state of a listing stored with each revision: the editable car
+// fields plus its colors, fuel types and image order
+type CarSnapshot struct {
+	Status           string   `json:"status"`
+	BrandName        *string  `json:"brandName"`
+	ModelName        *string  `json:"modelName"`
+	SubmodelName     *string  `json:"submodelName"`
+	ChassisNumber    *string  `json:"chassisNumber"`
+	Year             *int     `json:"year"`
+	Mileage          *int     `json:"mileage"`
+	EngineCC         *int     `json:"engineCc"`
+	Seats            *int     `json:"seats"`
+	Doors            *int     `json:"doors"`
+	Prefix           *string  `json:"prefix"`
+	Number           *string  `json:"number"`
+	ProvinceID       *int     `json:"provinceId"`
+	Description      *string  `json:"description"`
+	Price            *int     `json:"price"`
+	IsFlooded        bool     `json:"isFlooded"`
+	IsHeavilyDamaged bool     `json:"isHeavilyDamaged"`
+	ConditionRating  *int     `json:"conditionRating"`
+	BodyTypeCode     *string  `json:"bodyTypeCode"`
+	TransmissionCode *string  `json:"transmissionCode"`
+	DrivetrainCode   *string  `json:"drivetrainCode"`
+	Colors           []string `json:"colors"`    // Ordered: primary first
+	FuelTypes        []string `json:"fuelTypes"` // Sorted by code
+	ImageIDs         []int    `json:"imageIds"`  // In display order
+}
+
+// NewCarSnapshot builds a snapshot of a car and its related rows
+func NewCarSnapshot(car *Car, colors, fuelTypes []string, imageIDs []int) CarSnapshot {
+	return CarSnapshot{
+		Status:           car.Status,
+		BrandName:        car.BrandName,
+		ModelName:        car.ModelName,
+		SubmodelName:     car.SubmodelName,
+		ChassisNumber:    car.ChassisNumber,
+		Year:             car.Year,
+		Mileage:          car.Mileage,
+		EngineCC:         car.EngineCC,
+		Seats:            car.Seats,
+		Doors:            car.Doors,
+		Prefix:           car.Prefix,
+		Number:           car.Number,
+		ProvinceID:       car.ProvinceID,
+		Description:      car.Description,
+		Price:            car.Price,
+		IsFlooded:        car.IsFlooded,
+		IsHeavilyDamaged: car.IsHeavilyDamaged,
+		ConditionRating:  car.ConditionRating,
+		BodyTypeCode:     car.BodyTypeCode,
+		TransmissionCode: car.TransmissionCode,
+		DrivetrainCode:   car.DrivetrainCode,
+		Colors:           colors,
+		FuelTypes:        fuelTypes,
+		ImageIDs:         imageIDs,
+	}.normalized()
+}
+
+// normalized replaces nil slices with empty ones so "no colors" always encodes as []
+func (s CarSnapshot) normalized() CarSnapshot {
+	if s.Colors == nil {
+		s.Colors = []string{}
+	}
+	if s.FuelTypes == nil {
+		s.FuelTypes = []string{}
+	}
+	if s.ImageIDs == nil {
+		s.ImageIDs = []int{}
+	}
+	return s
+}
+
+// ApplyTo copies the snapshot's car fields onto car (colors, fuels and images are not touched).
+// Status is left alone: a revert must not move a listing past the status transition and
+// publish checks, e.g. bring a sold or deleted listing back to active. So is the chassis
+// number, which only changes through the ownership and duplicate VIN checks of a new
+// registration book.
+func (s CarSnapshot) ApplyTo(car *Car) {
+	car.BrandName = s.BrandName
+	car.ModelName = s.ModelName
+	car.SubmodelName = s.SubmodelName
+	car.Year = s.Year
+	car.Mileage = s.Mileage
+	car.EngineCC = s.EngineCC
+	car.Seats = s.Seats
+	car.Doors = s.Doors
+	car.Prefix = s.Prefix
+	car.Number = s.Number
+	car.ProvinceID = s.ProvinceID
+	car.Description = s.Description
+	car.Price = s.Price
+	car.IsFlooded = s.IsFlooded
+	car.IsHeavilyDamaged = s.IsHeavilyDamaged
+	car.ConditionRating = s.ConditionRating
+	car.BodyTypeCode = s.BodyTypeCode
+	car.TransmissionCode = s.TransmissionCode
+	car.DrivetrainCode = s.DrivetrainCode
+}
+
+// CarRevision is one recorded change to a listing
+type CarRevision struct {
+	ID             int         `json:"id" db:"id"`
+	CarID          int         `json:"carId" db:"car_id"`
+	ActorType      string      `json:"actorType" db:"actor_type"` // seller, admin, system
+	ActorID        *int        `json:"actorId" db:"actor_id"`
+	ChangedFields  []string    `json:"changedFields" db:"changed_fields"`
+	Snapshot       CarSnapshot `json:"snapshot" db:"snapshot"`
+	RevertedFromID *int        `json:"revertedFromId,omitempty" db:"reverted_from_id"`
+	CreatedAt      time.Time   `json:"createdAt" db:"created_at"`
+}
+
+// CarFieldChange is one field that differs between two revisions
+type CarFieldChange struct {
+	Field string          `json:"field"` // CarSnapshot JSON key, e.g. "price", "colors"
+	From  json.RawMessage `json:"from"`
+	To    json.RawMessage `json:"to"`
+}
+
+// CarRevisionDiff is the response for GET /admin/cars/{id}/revisions/diff (API response only)
+type CarRevisionDiff struct {
+	CarID          int              `json:"carId"`
+	FromRevisionID *int             `json:"fromRevisionId"` // nil when diffing the first revision
+	ToRevisionID   int              `json:"toRevisionId"`
+	Changes        []CarFieldChange `json:"changes"`
+}
+
+// DiffCarSnapshots returns the fields that differ between two snapshots, sorted by field name.
+// Diffing from the zero CarSnapshot lists every field set in to.
+func DiffCarSnapshots(from, to CarSnapshot) []CarFieldChange {
+	fromFields, toFields := snapshotFields(from.normalized()), snapshotFields(to.normalized())
+
+	names := make([]string, 0, len(toFields))
+	for name := range toFields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	changes := []CarFieldChange{}
+	for _, name := range names {
+		if !bytes.Equal(fromFields[name], toFields[name]) {
+			changes = append(changes, CarFieldChange{Field: name, From: fromFields[name], To: toFields[name]})
+		}
+	}
+	return changes
+}
+
+// snapshotFields splits a snapshot into its JSON-encoded fields
+func snapshotFields(s CarSnapshot) map[string]json.RawMessage {
+	// CarSnapshot only holds strings, numbers, bools and slices of them, so encoding cannot fail
+	data, _ := json.Marshal(s)
+	fields := make(map[string]json.RawMessage)
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// CarRevisionRepository handles car_revisions operations
+type CarRevisionRepository struct {
+	db *Database
+}
+
+// NewCarRevisionRepository creates a new car revision repository
+func NewCarRevisionRepository(db *Database) *CarRevisionRepository {
+	return &CarRevisionRepository{db: db}
+}
+
+const carRevisionColumns = `id, car_id, actor_type, actor_id, changed_fields, snapshot, reverted_from_id, created_at`
+
+func scanCarRevision(scanner interface{ Scan(...interface{}) error }) (*CarRevision, error) {
+	var rev CarRevision
+	var changedFields, snapshot []byte
+	if err := scanner.Scan(&rev.ID, &rev.CarID, &rev.ActorType, &rev.ActorID, &changedFields, &snapshot,
+		&rev.RevertedFromID, &rev.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(changedFields, &rev.ChangedFields); err != nil {
+		return nil, fmt.Errorf("failed to decode changed fields: %w", err)
+	}
+	if err := json.Unmarshal(snapshot, &rev.Snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode car snapshot: %w", err)
+	}
+	return &rev, nil
+}
+
+// CreateCarRevision inserts a revision and sets its ID and CreatedAt
+func (r *CarRevisionRepository) CreateCarRevision(rev *CarRevision) error {
+	if rev.ChangedFields == nil {
+		rev.ChangedFields = []string{}
+	}
+	changedFields, err := json.Marshal(rev.ChangedFields)
+	if err != nil {
+		return fmt.Errorf("failed to encode changed fields: %w", err)
+	}
+	snapshot, err := json.Marshal(rev.Snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode car snapshot: %w", err)
+	}
+
+	query := `
+		INSERT INTO car_revisions (car_id, actor_type, actor_id, changed_fields, snapshot, reverted_from_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at`
+	err = r.db.DB.QueryRow(query, rev.CarID, rev.ActorType, rev.ActorID, changedFields, snapshot, rev.RevertedFromID).
+		Scan(&rev.ID, &rev.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create car revision: %w", err)
+	}
+	return nil
+}
+
+// GetCarRevisions returns a car's revisions, newest first
+func (r *CarRevisionRepository) GetCarRevisions(carID int) ([]CarRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM car_revisions WHERE car_id = $1 ORDER BY id DESC`, carRevisionColumns)
+	rows, err := r.db.DB.Query(query, carID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []CarRevision{}
+	for rows.Next() {
+		rev, err := scanCarRevision(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan car revision: %w", err)
+		}
+		revisions = append(revisions, *rev)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating car revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetCarRevision returns one revision of a car
+func (r *CarRevisionRepository) GetCarRevision(carID, revisionID int) (*CarRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM car_revisions WHERE car_id = $1 AND id = $2`, carRevisionColumns)
+	return r.getOne(query, carID, revisionID)
+}
+
+// GetLatestCarRevision returns a car's newest revision, or nil if it has none
+func (r *CarRevisionRepository) GetLatestCarRevision(carID int) (*CarRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM car_revisions WHERE car_id = $1 ORDER BY id DESC LIMIT 1`, carRevisionColumns)
+	return r.getOptional(query, carID)
+}
+
+// GetPreviousCarRevision returns the revision before revisionID, or nil if it is the first
+func (r *CarRevisionRepository) GetPreviousCarRevision(carID, revisionID int) (*CarRevision, error) {
+	query := fmt.Sprintf(`SELECT %s FROM car_revisions WHERE car_id = $1 AND id < $2 ORDER BY id DESC LIMIT 1`, carRevisionColumns)
+	return r.getOptional(query, carID, revisionID)
+}
+
+// GetFirstPublishedCarRevision returns the first revision in which the car was active
+func (r *CarRevisionRepository) GetFirstPublishedCarRevision(carID int) (*CarRevision, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM car_revisions
+		WHERE car_id = $1 AND snapshot->>'status' = 'active'
+		ORDER BY id LIMIT 1`, carRevisionColumns)
+	rev, err := r.getOptional(query, carID)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, fmt.Errorf("car has no published revision")
+	}
+	return rev, nil
+}
+
+func (r *CarRevisionRepository) getOne(query string, args ...interface{}) (*CarRevision, error) {
+	rev, err := r.getOptional(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	if rev == nil {
+		return nil, fmt.Errorf("revision not found")
+	}
+	return rev, nil
+}
+
+func (r *CarRevisionRepository) getOptional(query string, args ...interface{}) (*CarRevision, error) {
+	rev, err := scanCarRevision(r.db.DB.QueryRow(query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car revision: %w", err)
+	}
+	return rev, nil
+}
+
+// RevertCar saves a car's reverted fields, colors, fuel types and image order in one
+// transaction. When priceChangedFrom is set, the change from it to the car's price is
+// recorded in the price history.
+func (r *CarRepository) RevertCar(car *Car, priceChangedFrom *int, colors, fuelTypes []string, imageOrder []int) error {
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.updateCar(tx, car); err != nil {
+		return err
+	}
+	if priceChangedFrom != nil && car.Price != nil {
+		if err := recordPriceChange(tx, car.ID, *priceChangedFrom, *car.Price); err != nil {
+			return err
+		}
+	}
+	if err := setCarColors(tx, car.ID, colors); err != nil {
+		return fmt.Errorf("failed to restore colors: %w", err)
+	}
+	if err := setCarFuels(tx, car.ID, fuelTypes); err != nil {
+		return fmt.Errorf("failed to restore fuels: %w", err)
+	}
+	if err := reorderImages(tx, imageOrder); err != nil {
+		return fmt.Errorf("failed to restore image order: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit car revert: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// These tests live in package models, unlike the rest in backend/tests, because the LIKE
+// escaping is only reachable through buildSearchWhereClause and SearchCars, which needs a
+// database. Exporting either just for tests would widen the models API.
+
+package models
+
+import "testing"
+
+func TestBuildSearchWhereClauseEscapesLikeWildcards(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		pattern string
+	}{
+		{name: "plain text", query: "hilux revo", pattern: "%hilux revo%"},
+		{name: "percent", query: "100%", pattern: `%100\%%`},
+		{name: "underscore", query: "cx_5", pattern: `%cx\_5%`},
+		{name: "backslash is escaped first", query: `a\%`, pattern: `%a\\\%%`},
+		{name: "wildcards only", query: "%_%", pattern: `%\%\_\%%`},
+		{name: "query is normalized before escaping", query: "  cx_5  ", pattern: `%cx\_5%`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			where := buildSearchWhereClause(&SearchCarsRequest{Status: "active", Query: tt.query}, "")
+
+			// The query adds the raw text for similarity, then the ILIKE pattern
+			if len(where.Args) != 3 {
+				t.Fatalf("args = %v, want status, query and pattern", where.Args)
+			}
+			if got := where.Args[1]; got != NormalizeSearchQuery(tt.query) {
+				t.Errorf("similarity arg = %q, want the unescaped query %q", got, NormalizeSearchQuery(tt.query))
+			}
+			if got := where.Args[2]; got != tt.pattern {
+				t.Errorf("ILIKE pattern = %q, want %q", got, tt.pattern)
+			}
+		})
+	}
+}
+
+func TestBuildSearchWhereClauseWithoutQuery(t *testing.T) {
+	where := buildSearchWhereClause(&SearchCarsRequest{Status: "active", Query: "   "}, "")
+
+	if len(where.Args) != 1 {
+		t.Errorf("args = %v, want only the status", where.Args)
+	}
+	if where.RelevanceSQL != "" {
+		t.Errorf("relevance = %q, want none without a query", where.RelevanceSQL)
+	}
+}
@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SimilarCarWeights sets how much each attribute contributes to the similarity score.
+// Each attribute scores between 0 and 1 and is multiplied by its weight; 0 disables it.
+type SimilarCarWeights struct {
+	Brand    float64 `json:"brand"`    // Same brand
+	Model    float64 `json:"model"`    // Same brand and model
+	Submodel float64 `json:"submodel"` // Same brand, model and submodel
+	Year     float64 `json:"year"`     // Closeness of year within YearWindow
+	Price    float64 `json:"price"`    // Closeness of price within PriceBand
+	BodyType float64 `json:"bodyType"` // Same body type
+	Fuel     float64 `json:"fuel"`     // At least one shared fuel type
+	Mileage  float64 `json:"mileage"`  // Closeness of mileage within MileageWindow
+}
+
+// SimilarCarsOptions configures the "similar cars" recommendation
+type SimilarCarsOptions struct {
+	Weights       SimilarCarWeights
+	YearWindow    int     // Years apart at which the year score reaches 0
+	PriceBand     float64 // Price difference, as a fraction of the car's price, at which the price score reaches 0
+	MileageWindow int     // Kilometres apart at which the mileage score reaches 0
+}
+
+// DefaultSimilarCarsOptions returns the default similarity weights and windows
+func DefaultSimilarCarsOptions() SimilarCarsOptions {
+	return SimilarCarsOptions{
+		Weights: SimilarCarWeights{
+			Brand:    2,
+			Model:    3,
+			Submodel: 1,
+			Year:     2,
+			Price:    3,
+			BodyType: 2,
+			Fuel:     1,
+			Mileage:  1,
+		},
+		YearWindow:    4,
+		PriceBand:     0.3,
+		MileageWindow: 60000,
+	}
+}
+
+// ParseSimilarCarWeights overrides weights from a spec such as "brand=2,model=3,price=4".
+// Keys match the SimilarCarWeights JSON names; unspecified weights keep their value in base.
+func ParseSimilarCarWeights(spec string, base SimilarCarWeights) (SimilarCarWeights, error) {
+	weights := base
+	targets := map[string]*float64{
+		"brand":    &weights.Brand,
+		"model":    &weights.Model,
+		"submodel": &weights.Submodel,
+		"year":     &weights.Year,
+		"price":    &weights.Price,
+		"bodytype": &weights.BodyType,
+		"fuel":     &weights.Fuel,
+		"mileage":  &weights.Mileage,
+	}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return base, fmt.Errorf("invalid similar car weight %q: expected key=value", part)
+		}
+		target, ok := targets[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			return base, fmt.Errorf("unknown similar car weight %q", strings.TrimSpace(key))
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			return base, fmt.Errorf("invalid value for similar car weight %q: %s", strings.TrimSpace(key), value)
+		}
+		*target = w
+	}
+
+	return weights, nil
+}
+
+// similarCarScoreSQL is the weighted similarity of cars to target. Weights are
+// placeholders $2-$9 and the year/price/mileage windows $10-$12 (see GetSimilarCars).
+const similarCarScoreSQL = `(
+	$2::float8 * (CASE WHEN LOWER(cars.brand_name) = LOWER(target.brand_name) THEN 1 ELSE 0 END)
+	+ $3::float8 * (CASE WHEN LOWER(cars.brand_name) = LOWER(target.brand_name)
+		AND LOWER(cars.model_name) = LOWER(target.model_name) THEN 1 ELSE 0 END)
+	+ $4::float8 * (CASE WHEN LOWER(cars.brand_name) = LOWER(target.brand_name)
+		AND LOWER(cars.model_name) = LOWER(target.model_name)
+		AND LOWER(cars.submodel_name) = LOWER(target.submodel_name) THEN 1 ELSE 0 END)
+	+ $5::float8 * COALESCE(GREATEST(0, 1 - ABS(cars.year - target.year)::float8 / $10::float8), 0)
+	+ $6::float8 * COALESCE(GREATEST(0, 1 - ABS(cars.price - target.price)::float8 / NULLIF(target.price * $11::float8, 0)), 0)
+	+ $7::float8 * (CASE WHEN cars.body_type_code = target.body_type_code THEN 1 ELSE 0 END)
+	+ $8::float8 * (CASE WHEN EXISTS (
+		SELECT 1 FROM car_fuel cf JOIN car_fuel tf ON tf.fuel_type_code = cf.fuel_type_code
+		WHERE cf.car_id = cars.id AND tf.car_id = target.id) THEN 1 ELSE 0 END)
+	+ $9::float8 * COALESCE(GREATEST(0, 1 - ABS(cars.mileage - target.mileage)::float8 / $12::float8), 0)
+)`
+
+// GetSimilarCars returns active cars most similar to the given car, best match first.
+// Candidates must share the car's brand or body type.
+func (r *CarRepository) GetSimilarCars(carID int, opts SimilarCarsOptions, limit int) ([]Car, error) {
+	if opts.YearWindow <= 0 || opts.PriceBand <= 0 || opts.MileageWindow <= 0 {
+		return nil, fmt.Errorf("similar cars windows must be positive")
+	}
+
+	query := fmt.Sprintf(`
+		SELECT cars.id, cars.seller_id, cars.body_type_code, cars.transmission_code, cars.drivetrain_code,
+			cars.brand_name, cars.model_name, cars.submodel_name, cars.chassis_number,
+			cars.year, cars.mileage, cars.engine_cc, cars.seats, cars.doors,
+			cars.prefix, cars.number, cars.province_id, cars.description, cars.price,
+			cars.is_flooded, cars.is_heavily_damaged,
+			cars.status, cars.condition_rating, cars.created_at, cars.updated_at
+		FROM cars
+		JOIN cars target ON target.id = $1
+		WHERE cars.status = 'active' AND cars.id <> target.id
+			AND (LOWER(cars.brand_name) = LOWER(target.brand_name) OR cars.body_type_code = target.body_type_code)
+		ORDER BY %s DESC, cars.created_at DESC, cars.id DESC
+		LIMIT $13`, similarCarScoreSQL)
+
+	w := opts.Weights
+	rows, err := r.db.DB.Query(query, carID,
+		w.Brand, w.Model, w.Submodel, w.Year, w.Price, w.BodyType, w.Fuel, w.Mileage,
+		opts.YearWindow, opts.PriceBand, opts.MileageWindow, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get similar cars: %w", err)
+	}
+	defer rows.Close()
+
+	var cars []Car
+	for rows.Next() {
+		var car Car
+		err := rows.Scan(
+			&car.ID, &car.SellerID, &car.BodyTypeCode, &car.TransmissionCode, &car.DrivetrainCode,
+			&car.BrandName, &car.ModelName, &car.SubmodelName, &car.ChassisNumber,
+			&car.Year, &car.Mileage, &car.EngineCC, &car.Seats, &car.Doors,
+			&car.Prefix, &car.Number, &car.ProvinceID, &car.Description, &car.Price,
+			&car.IsFlooded, &car.IsHeavilyDamaged, &car.Status, &car.ConditionRating,
+			&car.CreatedAt, &car.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan similar car: %w", err)
+		}
+		cars = append(cars, car)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar cars: %w", err)
+	}
+
+	return cars, nil
+}
@@ -10,6 +10,7 @@ import (
 // MarketPrice represents a row in the market_price table
 type MarketPrice struct {
 	ID          int       `json:"id" db:"id"`
+	VersionID   int       `json:"version_id" db:"version_id"`
 	Brand       string    `json:"brand" db:"brand"`
 	Model       string    `json:"model" db:"model"`
 	SubModel    string    `json:"sub_model" db:"sub_model"`
@@ -31,26 +32,27 @@ func NewMarketPriceRepository(db *Database) *MarketPriceRepository {
 	return &MarketPriceRepository{db: db}
 }
 
-// GetMarketPrice finds the average market price for a given brand, model, and year
+// GetMarketPrice finds the market price for a given brand, model, submodel and year in the active version
 func (r *MarketPriceRepository) GetMarketPrice(brand string, model string, submodel string, year int) (*MarketPrice, error) {
 	mp := &MarketPrice{}
 
 	query := `
 		SELECT 
-			id, brand, model, sub_model, 
+			id, version_id, brand, model, sub_model, 
 			year_start, year_end, 
 			price_min_thb, price_max_thb, 
 			created_at, updated_at
 		FROM market_price
 		WHERE
-			brand ILIKE $1 AND
-			model ILIKE $2 AND
-			sub_model ILIKE $3 AND 
-			$4 BETWEEN year_start AND year_end
+			lower(brand) = lower($1) AND
+			lower(model) = lower($2) AND
+			lower(sub_model) = lower($3) AND
+			$4 BETWEEN year_start AND year_end AND
+			version_id = ` + activeMarketPriceVersion + `
 		LIMIT 1`
 
 	err := r.db.DB.QueryRow(query, brand, model, submodel, year).Scan(
-		&mp.ID, &mp.Brand, &mp.Model, &mp.SubModel,
+		&mp.ID, &mp.VersionID, &mp.Brand, &mp.Model, &mp.SubModel,
 		&mp.YearStart, &mp.YearEnd,
 		&mp.PriceMinTHB, &mp.PriceMaxTHB,
 		&mp.CreatedAt, &mp.UpdatedAt,
@@ -66,8 +68,46 @@ func (r *MarketPriceRepository) GetMarketPrice(brand string, model string, submo
 	return mp, nil
 }
 
+// GetMarketPriceCandidates returns the market prices of a brand (ignoring case) that cover a
+// year in the active version, for matching a car's model and submodel against
+func (r *MarketPriceRepository) GetMarketPriceCandidates(brand string, year int) ([]MarketPrice, error) {
+	query := `
+		SELECT
+			id, version_id, brand, model, sub_model,
+			year_start, year_end,
+			price_min_thb, price_max_thb,
+			created_at, updated_at
+		FROM market_price
+		WHERE
+			lower(brand) = lower($1) AND
+			$2 BETWEEN year_start AND year_end AND
+			version_id = ` + activeMarketPriceVersion + `
+		ORDER BY model, sub_model, year_start`
+
+	rows, err := r.db.DB.Query(query, brand, year)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query market price candidates: %w", err)
+	}
+	defer rows.Close()
+
+	var prices []MarketPrice
+	for rows.Next() {
+		var mp MarketPrice
+		if err := rows.Scan(
+			&mp.ID, &mp.VersionID, &mp.Brand, &mp.Model, &mp.SubModel,
+			&mp.YearStart, &mp.YearEnd,
+			&mp.PriceMinTHB, &mp.PriceMaxTHB,
+			&mp.CreatedAt, &mp.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan market price: %w", err)
+		}
+		prices = append(prices, mp)
+	}
+	return prices, rows.Err()
+}
+
 func (r *MarketPriceRepository) GetDistinctBrands() ([]string, error) {
-	query := `SELECT DISTINCT brand FROM market_price ORDER BY brand;`
+	query := `SELECT DISTINCT brand FROM market_price WHERE version_id = ` + activeMarketPriceVersion + ` ORDER BY brand;`
 
 	rows, err := r.db.DB.Query(query)
 	if err != nil {
@@ -87,7 +127,7 @@ func (r *MarketPriceRepository) GetDistinctBrands() ([]string, error) {
 }
 
 func (r *MarketPriceRepository) GetDistinctModels(brand string) ([]string, error) {
-	query := `SELECT DISTINCT model FROM market_price WHERE brand = $1 ORDER BY model;`
+	query := `SELECT DISTINCT model FROM market_price WHERE brand = $1 AND version_id = ` + activeMarketPriceVersion + ` ORDER BY model;`
 
 	rows, err := r.db.DB.Query(query, brand)
 	if err != nil {
@@ -107,7 +147,7 @@ func (r *MarketPriceRepository) GetDistinctModels(brand string) ([]string, error
 }
 
 func (r *MarketPriceRepository) GetDistinctSubModels(brand string, model string) ([]string, error) {
-	query := `SELECT DISTINCT sub_model FROM market_price WHERE brand = $1 AND model = $2 ORDER BY sub_model;`
+	query := `SELECT DISTINCT sub_model FROM market_price WHERE brand = $1 AND model = $2 AND version_id = ` + activeMarketPriceVersion + ` ORDER BY sub_model;`
 
 	rows, err := r.db.DB.Query(query, brand, model)
 	if err != nil {
@@ -0,0 +1,477 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrInvalidMarketPriceFile is returned when an uploaded market price file cannot be imported
+var ErrInvalidMarketPriceFile = errors.New("invalid market price file")
+
+// ErrMarketPriceFileImported is returned when the same file was already imported as a version
+var ErrMarketPriceFileImported = errors.New("file already imported")
+
+// MarketPriceVersion is one imported market price dataset. Each version holds a complete
+// copy of the market prices; the active one answers price lookups.
+type MarketPriceVersion struct {
+	ID             int        `json:"id"`
+	SourceFilename *string    `json:"sourceFilename"`
+	SourceHash     *string    `json:"sourceHash"` // SHA-256 of the imported file
+	RowCount       int        `json:"rowCount"`
+	ImportedCount  int        `json:"importedCount"` // Rows in the imported file
+	InsertedCount  int        `json:"insertedCount"` // Imported rows that were new
+	UpdatedCount   int        `json:"updatedCount"`  // Imported rows that changed a price
+	UploadedBy     *int       `json:"uploadedBy"`
+	UploadedByName *string    `json:"uploadedByName"`
+	EffectiveFrom  time.Time  `json:"effectiveFrom"` // Informational: lookups read the active version whatever its date
+	IsActive       bool       `json:"isActive"`
+	ActivatedAt    *time.Time `json:"activatedAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+// MarketPriceVersionSource describes the file a market price version is imported from
+type MarketPriceVersionSource struct {
+	Filename      string
+	FileHash      string
+	UploadedBy    *int       // Admin ID
+	EffectiveFrom *time.Time // Date the prices apply from, for display and history (defaults to today)
+	Activate      bool       // Make the new version the active one
+}
+
+// MarketPriceChange is a row that differs between two market price versions (API response only)
+type MarketPriceChange struct {
+	Change      string `json:"change"` // "added", "removed" or "changed"
+	Brand       string `json:"brand"`
+	Model       string `json:"model"`
+	SubModel    string `json:"subModel"`
+	YearStart   int    `json:"yearStart"`
+	YearEnd     int    `json:"yearEnd"`
+	OldPriceMin *int64 `json:"oldPriceMin"`
+	OldPriceMax *int64 `json:"oldPriceMax"`
+	NewPriceMin *int64 `json:"newPriceMin"`
+	NewPriceMax *int64 `json:"newPriceMax"`
+}
+
+// MarketPriceActivation is one time a version became the active one
+type MarketPriceActivation struct {
+	ID         int
+	VersionID  int
+	RolledBack bool // Undone by a rollback
+}
+
+// MarketPriceRollbackTarget returns the current activation and the one a rollback returns
+// to, given the activation history oldest first: the latest activation of another version
+// that was not itself rolled back. Versions imported without being activated never appear
+// in the history, so they are never rolled back to. previous is nil when there is nothing
+// to roll back to.
+func MarketPriceRollbackTarget(history []MarketPriceActivation) (current, previous *MarketPriceActivation) {
+	for i := len(history) - 1; i >= 0; i-- {
+		a := &history[i]
+		switch {
+		case a.RolledBack:
+			continue
+		case current == nil:
+			current = a
+		case a.VersionID != current.VersionID:
+			return current, a
+		}
+	}
+	return current, nil
+}
+
+// MarketPriceHistoryPoint is a model year's market price in one version (API response only)
+type MarketPriceHistoryPoint struct {
+	VersionID     int       `json:"versionId"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+	IsActive      bool      `json:"isActive"`
+	PriceMinTHB   int64     `json:"priceMinThb"`
+	PriceMaxTHB   int64     `json:"priceMaxThb"`
+}
+
+// activeMarketPriceVersion selects the ID of the active version
+const activeMarketPriceVersion = `(SELECT id FROM market_price_versions WHERE is_active)`
+
+const marketPriceVersionColumns = `
+	v.id, v.source_filename, v.source_hash, v.row_count, v.imported_count, v.inserted_count,
+	v.updated_count, v.uploaded_by, a.name, v.effective_from, v.is_active, v.activated_at, v.created_at`
+
+func scanMarketPriceVersion(row interface{ Scan(...interface{}) error }) (*MarketPriceVersion, error) {
+	v := &MarketPriceVersion{}
+	err := row.Scan(
+		&v.ID, &v.SourceFilename, &v.SourceHash, &v.RowCount, &v.ImportedCount, &v.InsertedCount,
+		&v.UpdatedCount, &v.UploadedBy, &v.UploadedByName, &v.EffectiveFrom, &v.IsActive, &v.ActivatedAt, &v.CreatedAt,
+	)
+	return v, err
+}
+
+// CreateVersion creates a market price version from the active version's rows updated with
+// prices, in one transaction. Rows are matched on brand, model, submodel and year range.
+// A file that was already imported is refused.
+func (r *MarketPriceRepository) CreateVersion(source MarketPriceVersionSource, prices []MarketPrice) (*MarketPriceVersion, error) {
+	if source.FileHash != "" {
+		if err := r.checkNotImported(source.FileHash); err != nil {
+			return nil, err
+		}
+	}
+
+	// Start transaction
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var versionID int
+	err = tx.QueryRow(`
+		INSERT INTO market_price_versions (source_filename, source_hash, imported_count, uploaded_by, effective_from)
+		VALUES (NULLIF($1, ''), NULLIF($2, ''), $3, $4, COALESCE($5, CURRENT_DATE))
+		RETURNING id`,
+		source.Filename, source.FileHash, len(prices), source.UploadedBy, source.EffectiveFrom,
+	).Scan(&versionID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		// idx_market_price_versions_source_hash: the same file was imported concurrently
+		if err := r.checkNotImported(source.FileHash); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create market price version: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO market_price (version_id, brand, model, sub_model, year_start, year_end, price_min_thb, price_max_thb, created_at, updated_at)
+		SELECT $1, brand, model, sub_model, year_start, year_end, price_min_thb, price_max_thb, created_at, updated_at
+		FROM market_price
+		WHERE version_id = `+activeMarketPriceVersion,
+		versionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy active market prices: %w", err)
+	}
+
+	// Unchanged rows return nothing
+	stmt, err := tx.Prepare(`
+		INSERT INTO market_price (version_id, brand, model, sub_model, year_start, year_end, price_min_thb, price_max_thb)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (version_id, brand, model, sub_model, year_start, year_end)
+		DO UPDATE SET
+			price_min_thb = EXCLUDED.price_min_thb,
+			price_max_thb = EXCLUDED.price_max_thb,
+			updated_at = NOW()
+		WHERE (market_price.price_min_thb, market_price.price_max_thb)
+			IS DISTINCT FROM (EXCLUDED.price_min_thb, EXCLUDED.price_max_thb)
+		RETURNING (xmax = 0) AS inserted`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare upsert statement: %w", err)
+	}
+	defer stmt.Close()
+
+	var inserted, updated int
+	for i, p := range prices {
+		var isInsert bool
+		err := stmt.QueryRow(versionID, p.Brand, p.Model, p.SubModel, p.YearStart, p.YearEnd, p.PriceMinTHB, p.PriceMaxTHB).Scan(&isInsert)
+		switch {
+		case err == sql.ErrNoRows:
+			continue
+		case err != nil:
+			return nil, fmt.Errorf("failed to upsert record #%d (%s %s %s %d-%d): %w",
+				i+1, p.Brand, p.Model, p.SubModel, p.YearStart, p.YearEnd, err)
+		case isInsert:
+			inserted++
+		default:
+			updated++
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE market_price_versions
+		SET row_count = (SELECT COUNT(*) FROM market_price WHERE version_id = $1),
+			inserted_count = $2,
+			updated_count = $3
+		WHERE id = $1`,
+		versionID, inserted, updated,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update market price version: %w", err)
+	}
+
+	if source.Activate {
+		if err := activateMarketPriceVersion(tx, versionID); err != nil {
+			return nil, err
+		}
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.GetVersion(versionID)
+}
+
+// checkNotImported returns an error if a file was already imported
+func (r *MarketPriceRepository) checkNotImported(fileHash string) error {
+	var existingID int
+	err := r.db.DB.QueryRow("SELECT id FROM market_price_versions WHERE source_hash = $1", fileHash).Scan(&existingID)
+	if err == nil {
+		return fmt.Errorf("%w as version %d", ErrMarketPriceFileImported, existingID)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check imported files: %w", err)
+	}
+	return nil
+}
+
+// GetVersions lists market price versions, newest first
+func (r *MarketPriceRepository) GetVersions() ([]MarketPriceVersion, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT ` + marketPriceVersionColumns + `
+		FROM market_price_versions v
+		LEFT JOIN admins a ON a.id = v.uploaded_by
+		ORDER BY v.id DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market price versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []MarketPriceVersion{}
+	for rows.Next() {
+		v, err := scanMarketPriceVersion(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan market price version: %w", err)
+		}
+		versions = append(versions, *v)
+	}
+	return versions, rows.Err()
+}
+
+// GetVersion retrieves a market price version
+func (r *MarketPriceRepository) GetVersion(versionID int) (*MarketPriceVersion, error) {
+	v, err := scanMarketPriceVersion(r.db.DB.QueryRow(`
+		SELECT `+marketPriceVersionColumns+`
+		FROM market_price_versions v
+		LEFT JOIN admins a ON a.id = v.uploaded_by
+		WHERE v.id = $1`,
+		versionID,
+	))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("market price version not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market price version: %w", err)
+	}
+	return v, nil
+}
+
+// ActivateVersion makes a version the one price lookups read from
+func (r *MarketPriceRepository) ActivateVersion(versionID int) error {
+	// Start transaction
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := activateMarketPriceVersion(tx, versionID); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// RollbackVersion reactivates the version that was active before the current one and
+// returns it. The current activation is marked as rolled back, so rolling back again goes
+// one step further back (see MarketPriceRollbackTarget).
+func (r *MarketPriceRepository) RollbackVersion() (*MarketPriceVersion, error) {
+	// Start transaction
+	tx, err := r.db.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := lockActiveMarketPriceVersion(tx); err != nil {
+		return nil, err
+	}
+	history, err := getMarketPriceActivations(tx)
+	if err != nil {
+		return nil, err
+	}
+	current, previous := MarketPriceRollbackTarget(history)
+	if previous == nil {
+		return nil, fmt.Errorf("no earlier market price version to roll back to")
+	}
+
+	if _, err := tx.Exec("UPDATE market_price_version_activations SET rolled_back_at = NOW() WHERE id = $1", current.ID); err != nil {
+		return nil, fmt.Errorf("failed to record market price rollback: %w", err)
+	}
+	if err := switchActiveMarketPriceVersion(tx, previous.VersionID); err != nil {
+		return nil, err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return r.GetVersion(previous.VersionID)
+}
+
+// getMarketPriceActivations returns the activation history, oldest first
+func getMarketPriceActivations(tx *sql.Tx) ([]MarketPriceActivation, error) {
+	rows, err := tx.Query(`
+		SELECT id, version_id, rolled_back_at IS NOT NULL
+		FROM market_price_version_activations
+		ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market price activations: %w", err)
+	}
+	defer rows.Close()
+
+	var history []MarketPriceActivation
+	for rows.Next() {
+		var a MarketPriceActivation
+		if err := rows.Scan(&a.ID, &a.VersionID, &a.RolledBack); err != nil {
+			return nil, fmt.Errorf("failed to scan market price activation: %w", err)
+		}
+		history = append(history, a)
+	}
+	return history, rows.Err()
+}
+
+// lockActiveMarketPriceVersion locks the active version so concurrent activations don't
+// both succeed, and returns its ID (0 if there is none)
+func lockActiveMarketPriceVersion(tx *sql.Tx) (int, error) {
+	var activeID int
+	err := tx.QueryRow("SELECT id FROM market_price_versions WHERE is_active FOR UPDATE").Scan(&activeID)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to lock active market price version: %w", err)
+	}
+	return activeID, nil
+}
+
+// activateMarketPriceVersion switches the active version within a transaction and records
+// the activation in the history rollbacks walk back through
+func activateMarketPriceVersion(tx *sql.Tx, versionID int) error {
+	activeID, err := lockActiveMarketPriceVersion(tx)
+	if err != nil {
+		return err
+	}
+	if activeID == versionID {
+		return nil
+	}
+	if err := switchActiveMarketPriceVersion(tx, versionID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("INSERT INTO market_price_version_activations (version_id) VALUES ($1)", versionID); err != nil {
+		return fmt.Errorf("failed to record market price activation: %w", err)
+	}
+	return nil
+}
+
+// switchActiveMarketPriceVersion makes a version the active one; the caller holds the lock
+// from lockActiveMarketPriceVersion
+func switchActiveMarketPriceVersion(tx *sql.Tx, versionID int) error {
+	if _, err := tx.Exec("UPDATE market_price_versions SET is_active = FALSE WHERE is_active AND id <> $1", versionID); err != nil {
+		return fmt.Errorf("failed to deactivate market price version: %w", err)
+	}
+	result, err := tx.Exec("UPDATE market_price_versions SET is_active = TRUE, activated_at = NOW() WHERE id = $1", versionID)
+	if err != nil {
+		return fmt.Errorf("failed to activate market price version: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("market price version not found")
+	}
+	return nil
+}
+
+// DiffVersions lists the rows added, removed or repriced from one version to another
+func (r *MarketPriceRepository) DiffVersions(fromID, toID int) ([]MarketPriceChange, error) {
+	for _, id := range []int{fromID, toID} {
+		if _, err := r.GetVersion(id); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := r.db.DB.Query(`
+		SELECT
+			CASE
+				WHEN f.id IS NULL THEN 'added'
+				WHEN t.id IS NULL THEN 'removed'
+				ELSE 'changed'
+			END,
+			COALESCE(t.brand, f.brand), COALESCE(t.model, f.model), COALESCE(t.sub_model, f.sub_model),
+			COALESCE(t.year_start, f.year_start), COALESCE(t.year_end, f.year_end),
+			f.price_min_thb, f.price_max_thb, t.price_min_thb, t.price_max_thb
+		FROM (SELECT * FROM market_price WHERE version_id = $1) f
+		FULL OUTER JOIN (SELECT * FROM market_price WHERE version_id = $2) t
+			ON t.brand = f.brand AND t.model = f.model AND t.sub_model = f.sub_model
+			AND t.year_start = f.year_start AND t.year_end = f.year_end
+		WHERE f.id IS NULL OR t.id IS NULL
+			OR (f.price_min_thb, f.price_max_thb) IS DISTINCT FROM (t.price_min_thb, t.price_max_thb)
+		ORDER BY 2, 3, 4, 5, 6`,
+		fromID, toID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff market price versions: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []MarketPriceChange{}
+	for rows.Next() {
+		var c MarketPriceChange
+		if err := rows.Scan(
+			&c.Change, &c.Brand, &c.Model, &c.SubModel, &c.YearStart, &c.YearEnd,
+			&c.OldPriceMin, &c.OldPriceMax, &c.NewPriceMin, &c.NewPriceMax,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan market price change: %w", err)
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// GetPriceHistory returns a model year's market price in every version that lists it,
+// oldest effective date first, for charting depreciation
+func (r *MarketPriceRepository) GetPriceHistory(brand, model, submodel string, year int) ([]MarketPriceHistoryPoint, error) {
+	rows, err := r.db.DB.Query(`
+		SELECT DISTINCT ON (v.id) v.id, v.effective_from, v.is_active, mp.price_min_thb, mp.price_max_thb
+		FROM market_price mp
+		JOIN market_price_versions v ON v.id = mp.version_id
+		WHERE lower(mp.brand) = lower($1) AND lower(mp.model) = lower($2) AND lower(mp.sub_model) = lower($3)
+			AND $4 BETWEEN mp.year_start AND mp.year_end
+		ORDER BY v.id, mp.id`,
+		brand, model, submodel, year,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get market price history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []MarketPriceHistoryPoint{}
+	for rows.Next() {
+		var p MarketPriceHistoryPoint
+		if err := rows.Scan(&p.VersionID, &p.EffectiveFrom, &p.IsActive, &p.PriceMinTHB, &p.PriceMaxTHB); err != nil {
+			return nil, fmt.Errorf("failed to scan market price history: %w", err)
+		}
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Versions are numbered in import order, but may take effect in a different order
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].EffectiveFrom.Before(points[j].EffectiveFrom)
+	})
+	return points, nil
+}
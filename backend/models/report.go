@@ -52,6 +52,8 @@ type AdminReportResponse struct {
 	CreatedAt       string  `json:"createdAt"`
 	ResolvedAt      *string `json:"resolvedAt,omitempty"`
 	ResolvedBy      *string `json:"resolvedBy,omitempty"` // Admin name
+	// For car reports: the car's photos that match photos on other sellers' listings
+	DuplicatePhotos []DuplicatePhotoMatch `json:"duplicatePhotos,omitempty"`
 }
 
 // AdminReportsListResponse represents the response for admin reports list (API response only)
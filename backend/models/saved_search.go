@@ -0,0 +1,412 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SavedSearch is a buyer's named car search with new-match alerts
+type SavedSearch struct {
+	ID               int                 `json:"id" db:"id"`
+	UserID           int                 `json:"userId" db:"user_id"`
+	Name             string              `json:"name" db:"name"`
+	Criteria         SavedSearchCriteria `json:"criteria" db:"criteria"`
+	EmailEnabled     bool                `json:"emailEnabled" db:"email_enabled"`
+	Frequency        string              `json:"frequency" db:"frequency"` // instant, daily
+	LastDigestSentAt *time.Time          `json:"lastDigestSentAt,omitempty" db:"last_digest_sent_at"`
+	CreatedAt        time.Time           `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time           `json:"updatedAt" db:"updated_at"`
+}
+
+// SavedSearchCriteria holds the search filters of a saved search.
+// Field names match the /api/cars/search query parameters.
+type SavedSearchCriteria struct {
+	Query            string   `json:"q,omitempty"`
+	MinPrice         *int     `json:"minPrice,omitempty"`
+	MaxPrice         *int     `json:"maxPrice,omitempty"`
+	ProvinceID       *int     `json:"provinceId,omitempty"`
+	Region           string   `json:"region,omitempty"`
+	NearProvinceID   *int     `json:"near_province,omitempty"`
+	RadiusKm         *int     `json:"radius_km,omitempty"`
+	MinYear          *int     `json:"minYear,omitempty"`
+	MaxYear          *int     `json:"maxYear,omitempty"`
+	MinMileage       *int     `json:"minMileage,omitempty"`
+	MaxMileage       *int     `json:"maxMileage,omitempty"`
+	BodyTypeCodes    []string `json:"bodyType,omitempty"`
+	TransmissionCode *string  `json:"transmission,omitempty"`
+	DrivetrainCode   *string  `json:"drivetrain,omitempty"`
+	FuelTypeCodes    []string `json:"fuelTypes,omitempty"`
+	ColorCodes       []string `json:"colors,omitempty"`
+	ConditionRating  *int     `json:"conditionRating,omitempty"`
+}
+
+// ToSearchRequest converts the criteria into a SearchCarsRequest over active cars
+func (c SavedSearchCriteria) ToSearchRequest() *SearchCarsRequest {
+	return &SearchCarsRequest{
+		Query:            c.Query,
+		MinPrice:         c.MinPrice,
+		MaxPrice:         c.MaxPrice,
+		ProvinceID:       c.ProvinceID,
+		Region:           c.Region,
+		NearProvinceID:   c.NearProvinceID,
+		RadiusKm:         c.RadiusKm,
+		MinYear:          c.MinYear,
+		MaxYear:          c.MaxYear,
+		MinMileage:       c.MinMileage,
+		MaxMileage:       c.MaxMileage,
+		BodyTypeCodes:    c.BodyTypeCodes,
+		TransmissionCode: c.TransmissionCode,
+		DrivetrainCode:   c.DrivetrainCode,
+		FuelTypeCodes:    c.FuelTypeCodes,
+		ColorCodes:       c.ColorCodes,
+		ConditionRating:  c.ConditionRating,
+		Status:           "active",
+	}
+}
+
+// SavedSearchRequest is the body for creating or updating a saved search
+type SavedSearchRequest struct {
+	Name         string              `json:"name"`
+	Criteria     SavedSearchCriteria `json:"criteria"`
+	EmailEnabled bool                `json:"emailEnabled"`
+	Frequency    string              `json:"frequency"` // instant (default), daily
+}
+
+// SavedSearchMatch is an inbox entry for a car that matched a saved search when it was published
+type SavedSearchMatch struct {
+	ID              int        `json:"id" db:"id"`
+	SavedSearchID   int        `json:"savedSearchId" db:"saved_search_id"`
+	SavedSearchName string     `json:"savedSearchName" db:"-"`
+	UserID          int        `json:"userId" db:"user_id"`
+	CarID           int        `json:"carId" db:"car_id"`
+	IsRead          bool       `json:"isRead" db:"is_read"`
+	EmailedAt       *time.Time `json:"emailedAt,omitempty" db:"emailed_at"`
+	CreatedAt       time.Time  `json:"createdAt" db:"created_at"`
+}
+
+// SavedSearchInboxItem is an inbox entry with its car (API response only)
+type SavedSearchInboxItem struct {
+	SavedSearchMatch
+	Car *CarListItem `json:"car,omitempty"` // nil when the car is no longer listed
+}
+
+// SavedSearchInboxResponse is the response for GET /api/saved-searches/inbox (API response only)
+type SavedSearchInboxResponse struct {
+	Items       []SavedSearchInboxItem `json:"items"`
+	UnreadCount int                    `json:"unreadCount"`
+}
+
+// MarkInboxReadRequest is the body for marking inbox entries as read
+type MarkInboxReadRequest struct {
+	IDs []int `json:"ids"` // Empty marks every entry as read
+}
+
+// SavedSearchRepository handles saved search and inbox database operations
+type SavedSearchRepository struct {
+	db *Database
+}
+
+// NewSavedSearchRepository creates a new saved search repository
+func NewSavedSearchRepository(db *Database) *SavedSearchRepository {
+	return &SavedSearchRepository{db: db}
+}
+
+const savedSearchColumns = `id, user_id, name, criteria, email_enabled, frequency, last_digest_sent_at, created_at, updated_at`
+
+// scanSavedSearch scans a row selected with savedSearchColumns
+func scanSavedSearch(scanner interface{ Scan(...interface{}) error }) (*SavedSearch, error) {
+	var ss SavedSearch
+	var criteria []byte
+	if err := scanner.Scan(&ss.ID, &ss.UserID, &ss.Name, &criteria, &ss.EmailEnabled, &ss.Frequency,
+		&ss.LastDigestSentAt, &ss.CreatedAt, &ss.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if len(criteria) > 0 {
+		if err := json.Unmarshal(criteria, &ss.Criteria); err != nil {
+			return nil, fmt.Errorf("failed to decode saved search criteria: %w", err)
+		}
+	}
+	return &ss, nil
+}
+
+// CreateSavedSearch inserts a saved search and fills in its ID and timestamps
+func (r *SavedSearchRepository) CreateSavedSearch(ss *SavedSearch) error {
+	criteria, err := json.Marshal(ss.Criteria)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved search criteria: %w", err)
+	}
+
+	query := `
+		INSERT INTO saved_searches (user_id, name, criteria, email_enabled, frequency)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at`
+	err = r.db.DB.QueryRow(query, ss.UserID, ss.Name, criteria, ss.EmailEnabled, ss.Frequency).
+		Scan(&ss.ID, &ss.CreatedAt, &ss.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create saved search: %w", err)
+	}
+	return nil
+}
+
+// GetSavedSearchByID retrieves a saved search by ID
+func (r *SavedSearchRepository) GetSavedSearchByID(id int) (*SavedSearch, error) {
+	query := fmt.Sprintf("SELECT %s FROM saved_searches WHERE id = $1", savedSearchColumns)
+	ss, err := scanSavedSearch(r.db.DB.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("saved search not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+	return ss, nil
+}
+
+// GetSavedSearchesByUserID retrieves a user's saved searches, newest first
+func (r *SavedSearchRepository) GetSavedSearchesByUserID(userID int) ([]SavedSearch, error) {
+	query := fmt.Sprintf("SELECT %s FROM saved_searches WHERE user_id = $1 ORDER BY created_at DESC, id DESC", savedSearchColumns)
+	return r.querySavedSearches(query, userID)
+}
+
+// GetSavedSearchesForMatching retrieves the saved searches a car may match, skipping those
+// owned by its seller (sellers are not alerted about their own listings). Only the criteria
+// that compare a single car column are checked here, so the result is a superset of the
+// matches and each search still needs the full CarMatchesSearch check.
+func (r *SavedSearchRepository) GetSavedSearchesForMatching(car *Car) ([]SavedSearch, error) {
+	// A criterion set on a search fails when the car's value is NULL, as in the search itself
+	query := fmt.Sprintf(`
+		SELECT %s FROM saved_searches
+		WHERE user_id <> $1
+		  AND (criteria->>'minPrice' IS NULL OR (criteria->>'minPrice')::numeric <= $2::int)
+		  AND (criteria->>'maxPrice' IS NULL OR (criteria->>'maxPrice')::numeric >= $2::int)
+		  AND (criteria->>'minYear' IS NULL OR (criteria->>'minYear')::numeric <= $3::int)
+		  AND (criteria->>'maxYear' IS NULL OR (criteria->>'maxYear')::numeric >= $3::int)
+		  AND (criteria->>'minMileage' IS NULL OR (criteria->>'minMileage')::numeric <= $4::int)
+		  AND (criteria->>'maxMileage' IS NULL OR (criteria->>'maxMileage')::numeric >= $4::int)
+		  AND (criteria->>'provinceId' IS NULL OR (criteria->>'provinceId')::numeric = $5::int)
+		  AND (COALESCE(jsonb_array_length(criteria->'bodyType'), 0) = 0 OR criteria->'bodyType' ? $6::text)
+		  AND (criteria->>'transmission' IS NULL OR criteria->>'transmission' = $7::text)
+		  AND (criteria->>'drivetrain' IS NULL OR criteria->>'drivetrain' = $8::text)
+		  AND (criteria->>'conditionRating' IS NULL OR (criteria->>'conditionRating')::numeric <= $9::int)
+		ORDER BY id`, savedSearchColumns)
+	return r.querySavedSearches(query,
+		car.SellerID, car.Price, car.Year, car.Mileage, car.ProvinceID,
+		car.BodyTypeCode, car.TransmissionCode, car.DrivetrainCode, car.ConditionRating,
+	)
+}
+
+// GetDailyDigestSearches retrieves daily saved searches with email enabled whose last digest
+// was sent before the given time (or never) and that have unemailed matches
+func (r *SavedSearchRepository) GetDailyDigestSearches(sentBefore time.Time) ([]SavedSearch, error) {
+	query := fmt.Sprintf(`
+		SELECT %s FROM saved_searches ss
+		WHERE ss.frequency = 'daily' AND ss.email_enabled = TRUE
+			AND (ss.last_digest_sent_at IS NULL OR ss.last_digest_sent_at < $1)
+			AND EXISTS (
+				SELECT 1 FROM saved_search_matches m
+				WHERE m.saved_search_id = ss.id AND m.emailed_at IS NULL
+			)
+		ORDER BY ss.id`, savedSearchColumns)
+	return r.querySavedSearches(query, sentBefore)
+}
+
+func (r *SavedSearchRepository) querySavedSearches(query string, args ...interface{}) ([]SavedSearch, error) {
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved searches: %w", err)
+	}
+	defer rows.Close()
+
+	searches := []SavedSearch{}
+	for rows.Next() {
+		ss, err := scanSavedSearch(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan saved search: %w", err)
+		}
+		searches = append(searches, *ss)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved searches: %w", err)
+	}
+
+	return searches, nil
+}
+
+// CountSavedSearchesByUserID returns how many saved searches a user has
+func (r *SavedSearchRepository) CountSavedSearchesByUserID(userID int) (int, error) {
+	var count int
+	err := r.db.DB.QueryRow("SELECT COUNT(*) FROM saved_searches WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count saved searches: %w", err)
+	}
+	return count, nil
+}
+
+// UpdateSavedSearch updates a saved search's name, criteria and alert settings
+func (r *SavedSearchRepository) UpdateSavedSearch(ss *SavedSearch) error {
+	criteria, err := json.Marshal(ss.Criteria)
+	if err != nil {
+		return fmt.Errorf("failed to encode saved search criteria: %w", err)
+	}
+
+	query := `
+		UPDATE saved_searches
+		SET name = $2, criteria = $3, email_enabled = $4, frequency = $5
+		WHERE id = $1
+		RETURNING updated_at`
+	err = r.db.DB.QueryRow(query, ss.ID, ss.Name, criteria, ss.EmailEnabled, ss.Frequency).Scan(&ss.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("saved search not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update saved search: %w", err)
+	}
+	return nil
+}
+
+// DeleteSavedSearch deletes a saved search (its inbox entries are removed by cascade)
+func (r *SavedSearchRepository) DeleteSavedSearch(id int) error {
+	result, err := r.db.DB.Exec("DELETE FROM saved_searches WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("saved search not found")
+	}
+	return nil
+}
+
+// SetLastDigestSentAt records when a saved search's digest email was sent
+func (r *SavedSearchRepository) SetLastDigestSentAt(id int, sentAt time.Time) error {
+	_, err := r.db.DB.Exec("UPDATE saved_searches SET last_digest_sent_at = $2 WHERE id = $1", id, sentAt)
+	if err != nil {
+		return fmt.Errorf("failed to update digest time: %w", err)
+	}
+	return nil
+}
+
+// AddMatch records a match in the user's inbox.
+// Returns false when the car was already recorded for this saved search.
+func (r *SavedSearchRepository) AddMatch(savedSearchID, userID, carID int) (bool, error) {
+	query := `
+		INSERT INTO saved_search_matches (saved_search_id, user_id, car_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (saved_search_id, car_id) DO NOTHING`
+	result, err := r.db.DB.Exec(query, savedSearchID, userID, carID)
+	if err != nil {
+		return false, fmt.Errorf("failed to add saved search match: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+const savedSearchMatchColumns = `m.id, m.saved_search_id, ss.name, m.user_id, m.car_id, m.is_read, m.emailed_at, m.created_at`
+
+// GetInbox retrieves a user's most recent saved search matches
+func (r *SavedSearchRepository) GetInbox(userID int, unreadOnly bool, limit int) ([]SavedSearchMatch, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM saved_search_matches m
+		INNER JOIN saved_searches ss ON ss.id = m.saved_search_id
+		WHERE m.user_id = $1`, savedSearchMatchColumns)
+	if unreadOnly {
+		query += " AND m.is_read = FALSE"
+	}
+	query += " ORDER BY m.created_at DESC, m.id DESC LIMIT $2"
+	return r.queryMatches(query, userID, limit)
+}
+
+// GetUnemailedMatches retrieves matches of a saved search that have not been emailed yet
+func (r *SavedSearchRepository) GetUnemailedMatches(savedSearchID int) ([]SavedSearchMatch, error) {
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM saved_search_matches m
+		INNER JOIN saved_searches ss ON ss.id = m.saved_search_id
+		WHERE m.saved_search_id = $1 AND m.emailed_at IS NULL
+		ORDER BY m.created_at, m.id`, savedSearchMatchColumns)
+	return r.queryMatches(query, savedSearchID)
+}
+
+func (r *SavedSearchRepository) queryMatches(query string, args ...interface{}) ([]SavedSearchMatch, error) {
+	rows, err := r.db.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search matches: %w", err)
+	}
+	defer rows.Close()
+
+	matches := []SavedSearchMatch{}
+	for rows.Next() {
+		var m SavedSearchMatch
+		if err := rows.Scan(&m.ID, &m.SavedSearchID, &m.SavedSearchName, &m.UserID, &m.CarID,
+			&m.IsRead, &m.EmailedAt, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved search match: %w", err)
+		}
+		matches = append(matches, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved search matches: %w", err)
+	}
+
+	return matches, nil
+}
+
+// CountUnreadMatches returns the number of unread inbox entries for a user
+func (r *SavedSearchRepository) CountUnreadMatches(userID int) (int, error) {
+	var count int
+	err := r.db.DB.QueryRow("SELECT COUNT(*) FROM saved_search_matches WHERE user_id = $1 AND is_read = FALSE", userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count unread matches: %w", err)
+	}
+	return count, nil
+}
+
+// MarkMatchesRead marks a user's inbox entries as read (all entries when ids is empty)
+func (r *SavedSearchRepository) MarkMatchesRead(userID int, ids []int) error {
+	query := "UPDATE saved_search_matches SET is_read = TRUE WHERE user_id = $1 AND is_read = FALSE"
+	args := []interface{}{userID}
+
+	if len(ids) > 0 {
+		placeholders := make([]string, len(ids))
+		for i, id := range ids {
+			placeholders[i] = fmt.Sprintf("$%d", i+2)
+			args = append(args, id)
+		}
+		query += fmt.Sprintf(" AND id IN (%s)", strings.Join(placeholders, ","))
+	}
+
+	if _, err := r.db.DB.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to mark matches as read: %w", err)
+	}
+	return nil
+}
+
+// MarkMatchesEmailed records that the given matches were included in an alert email
+func (r *SavedSearchRepository) MarkMatchesEmailed(ids []int, emailedAt time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	placeholders := make([]string, len(ids))
+	args := []interface{}{emailedAt}
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, id)
+	}
+
+	query := fmt.Sprintf("UPDATE saved_search_matches SET emailed_at = $1 WHERE id IN (%s)", strings.Join(placeholders, ","))
+	if _, err := r.db.DB.Exec(query, args...); err != nil {
+		return fmt.Errorf("failed to mark matches as emailed: %w", err)
+	}
+	return nil
+}
@@ -218,6 +218,7 @@ type SellerData struct {
 	Seller        Seller          `json:"seller"`
 	Contacts      []SellerContact `json:"contacts"`
 	Cars          []CarListItem   `json:"cars,omitempty"`          // Lightweight car list items for efficient listing
+	NextCursor    string          `json:"nextCursor,omitempty"`    // Keyset cursor for the next page of cars (when paginated)
 	SoldCarsCount int             `json:"soldCarsCount,omitempty"` // Count of sold cars
 }
 
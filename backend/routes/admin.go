@@ -253,6 +253,44 @@ func AdminRoutes(
 			adminExtractionHandler.ImportMarketPrices(w, r)
 		}))
 
+	// GET /admin/market-price/versions (List import versions)
+	router.HandleFunc(basePath+"/market-price/versions",
+		applyAdminAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			adminExtractionHandler.GetMarketPriceVersions(w, r)
+		}))
+
+	// GET /admin/market-price/versions/diff?from=&to=
+	// POST /admin/market-price/versions/rollback
+	// POST /admin/market-price/versions/{id}/activate
+	router.HandleFunc(basePath+"/market-price/versions/",
+		applyAdminAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			path := r.URL.Path
+			switch {
+			case strings.HasSuffix(path, "/versions/diff") && r.Method == http.MethodGet:
+				adminExtractionHandler.DiffMarketPriceVersions(w, r)
+			case strings.HasSuffix(path, "/versions/rollback") && r.Method == http.MethodPost:
+				adminExtractionHandler.RollbackMarketPriceVersion(w, r)
+			case strings.HasSuffix(path, "/activate") && r.Method == http.MethodPost:
+				adminExtractionHandler.ActivateMarketPriceVersion(w, r)
+			default:
+				utils.WriteError(w, http.StatusNotFound, "Not found")
+			}
+		}))
+
+	// GET /admin/market-price/history?brand=&model=&submodel=&year=
+	router.HandleFunc(basePath+"/market-price/history",
+		applyAdminAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet {
+				utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			adminExtractionHandler.GetMarketPriceHistory(w, r)
+		}))
+
 	// --- Admin Reports Routes ---
 	// Handler for action routes with IDs: /admin/reports/{id}/resolve, /admin/reports/{id}/dismiss
 	router.HandleFunc(basePath+"/reports/",
@@ -338,6 +376,27 @@ func AdminRoutes(
 				adminReportsHandler.RemoveCar(w, r)
 				return
 			}
+			if strings.HasSuffix(path, "/duplicate-photos") {
+				if r.Method != http.MethodGet {
+					utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+					return
+				}
+				adminCarHandler.GetDuplicatePhotos(w, r)
+				return
+			}
+			if strings.Contains(path, "/revisions") {
+				switch {
+				case strings.HasSuffix(path, "/revisions") && r.Method == http.MethodGet:
+					adminCarHandler.GetRevisions(w, r)
+				case strings.HasSuffix(path, "/revisions/diff") && r.Method == http.MethodGet:
+					adminCarHandler.DiffRevisions(w, r)
+				case strings.HasSuffix(path, "/revert") && r.Method == http.MethodPost:
+					adminCarHandler.RevertRevision(w, r)
+				default:
+					utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+				}
+				return
+			}
 			switch r.Method {
 			case http.MethodPatch:
 				adminCarHandler.UpdateCar(w, r)
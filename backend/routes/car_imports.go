@@ -0,0 +1,63 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/uzimpp/CarJai/backend/handlers"
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// CarImportRoutes sets up routes for sellers' bulk CSV listing imports
+func CarImportRoutes(importService *services.CarImportService, userService *services.UserService, corsOrigins []string) *http.ServeMux {
+	router := http.NewServeMux()
+	handler := handlers.NewCarImportHandler(importService, userService)
+
+	// Create auth middleware
+	authMiddleware := middleware.NewUserAuthMiddleware(userService)
+
+	// POST /api/cars/import - Start an import (multipart: file, images, publish)
+	router.HandleFunc("/api/cars/import",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.RequireAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								if r.Method != http.MethodPost {
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+									return
+								}
+								handler.StartImport(w, r)
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	// GET /api/cars/import/{id} - Import progress and per-row report
+	router.HandleFunc("/api/cars/import/",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.RequireAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								if r.Method != http.MethodGet {
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+									return
+								}
+								handler.GetImport(w, r)
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return router
+}
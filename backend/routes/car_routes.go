@@ -29,8 +29,48 @@ func CarRoutes(
 	// Create router
 	router := http.NewServeMux()
 
-	// Public search endpoint (GET)
+	// Public search endpoint (GET) - optional auth so "distance" sort can use the buyer's province
 	router.HandleFunc("/api/cars/search",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.OptionalAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								if r.Method != http.MethodGet {
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+									return
+								}
+								carHandler.SearchCars(w, r)
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	// Public search facets endpoint (GET) - per-filter counts for the search sidebar
+	router.HandleFunc("/api/cars/facets",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						func(w http.ResponseWriter, r *http.Request) {
+							if r.Method != http.MethodGet {
+								utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+								return
+							}
+							carHandler.GetSearchFacets(w, r)
+						},
+					),
+				),
+			),
+		),
+	)
+
+	// Public comparison endpoint (GET) - up to four active cars side by side
+	router.HandleFunc("/api/cars/compare",
 		middleware.CORSMiddleware(corsOrigins)(
 			middleware.SecurityHeadersMiddleware(
 				middleware.GeneralRateLimit()(
@@ -40,7 +80,7 @@ func CarRoutes(
 								utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
 								return
 							}
-							carHandler.SearchCars(w, r)
+							carHandler.CompareCars(w, r)
 						},
 					),
 				),
@@ -69,6 +109,27 @@ func CarRoutes(
 		),
 	)
 
+	// Export current user's cars (GET) - authenticated, streamed as csv, json or xlsx
+	router.HandleFunc("/api/cars/my/export",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.RequireAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								if r.Method != http.MethodGet {
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+									return
+								}
+								carHandler.ExportMyCars(w, r)
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
 	// Image management by image ID (GET public, DELETE authenticated)
 	router.HandleFunc("/api/cars/images/",
 		middleware.CORSMiddleware(corsOrigins)(
@@ -144,6 +205,16 @@ func handleCarRoutes(
 		return
 	}
 
+	// /api/cars/{id}/similar - Similar active listings (public)
+	if strings.HasSuffix(path, "/similar") {
+		if r.Method != http.MethodGet {
+			utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+			return
+		}
+		handler.GetSimilarCars(w, r)
+		return
+	}
+
 	// /api/cars/{id}/book - Upload registration book to existing car (authenticated)
 	if strings.HasSuffix(path, "/book") {
 		authMiddleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -192,6 +263,18 @@ func handleCarRoutes(
 		return
 	}
 
+	// /api/cars/{id}/renew - Renew an active or expired listing (authenticated)
+	if strings.HasSuffix(path, "/renew") {
+		authMiddleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodPost {
+				utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+				return
+			}
+			handler.RenewCar(w, r)
+		})(w, r)
+		return
+	}
+
 	// /api/cars/{id}/inspection - Upload inspection (authenticated)
 	if strings.HasSuffix(path, "/inspection") {
 		authMiddleware.RequireAuth(func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,42 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/uzimpp/CarJai/backend/handlers"
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// FeedRoutes sets up the home feed route
+func FeedRoutes(feedService *services.FeedService, userService *services.UserService, corsOrigins []string) *http.ServeMux {
+	router := http.NewServeMux()
+	handler := handlers.NewFeedHandler(feedService)
+
+	// Create auth middleware
+	authMiddleware := middleware.NewUserAuthMiddleware(userService)
+
+	// GET /api/feed - Personalized for signed-in users, trending for anonymous users
+	router.HandleFunc("/api/feed",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.OptionalAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								if r.Method != http.MethodGet {
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+									return
+								}
+								handler.GetFeed(w, r)
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return router
+}
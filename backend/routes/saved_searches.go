@@ -0,0 +1,99 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/handlers"
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// SavedSearchRoutes sets up routes for buyers' saved searches and their match inbox
+func SavedSearchRoutes(savedSearchService *services.SavedSearchService, userService *services.UserService, corsOrigins []string) *http.ServeMux {
+	router := http.NewServeMux()
+	handler := handlers.NewSavedSearchHandler(savedSearchService, userService)
+
+	// Create auth middleware
+	authMiddleware := middleware.NewUserAuthMiddleware(userService)
+
+	// GET /api/saved-searches - List saved searches
+	// POST /api/saved-searches - Create saved search
+	router.HandleFunc("/api/saved-searches",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.RequireAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								switch r.Method {
+								case http.MethodGet:
+									handler.ListSavedSearches(w, r)
+								case http.MethodPost:
+									handler.CreateSavedSearch(w, r)
+								default:
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+								}
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	// GET /api/saved-searches/inbox - Matches of newly published cars
+	// POST /api/saved-searches/inbox/read - Mark inbox entries as read
+	// GET/PUT/DELETE /api/saved-searches/{id} - Manage a saved search
+	router.HandleFunc("/api/saved-searches/",
+		middleware.CORSMiddleware(corsOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.GeneralRateLimit()(
+					middleware.LoggingMiddleware(
+						authMiddleware.RequireAuth(
+							func(w http.ResponseWriter, r *http.Request) {
+								path := strings.TrimPrefix(r.URL.Path, "/api/saved-searches/")
+
+								switch path {
+								case "inbox":
+									if r.Method != http.MethodGet {
+										utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+										return
+									}
+									handler.GetInbox(w, r)
+									return
+								case "inbox/read":
+									if r.Method != http.MethodPost {
+										utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+										return
+									}
+									handler.MarkInboxRead(w, r)
+									return
+								}
+
+								// Only /api/saved-searches/{id} remains (no nested paths)
+								if strings.Contains(path, "/") || path == "" {
+									utils.WriteError(w, http.StatusNotFound, "Not found")
+									return
+								}
+								switch r.Method {
+								case http.MethodGet:
+									handler.GetSavedSearch(w, r)
+								case http.MethodPut:
+									handler.UpdateSavedSearch(w, r)
+								case http.MethodDelete:
+									handler.DeleteSavedSearch(w, r)
+								default:
+									utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+								}
+							},
+						),
+					),
+				),
+			),
+		),
+	)
+
+	return router
+}
@@ -0,0 +1,38 @@
+package routes
+
+import (
+	"net/http"
+
+	"github.com/uzimpp/CarJai/backend/handlers"
+	"github.com/uzimpp/CarJai/backend/middleware"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// ValuationRoutes sets up the public valuation route. allowedOrigins includes the sites the
+// valuation widget is embedded on.
+func ValuationRoutes(carService *services.CarService, allowedOrigins []string) *http.ServeMux {
+	router := http.NewServeMux()
+	handler := handlers.NewValuationHandler(carService)
+
+	// POST /api/valuation - Estimate the price of a car from its specs (public)
+	router.HandleFunc("/api/valuation",
+		middleware.CORSMiddleware(allowedOrigins)(
+			middleware.SecurityHeadersMiddleware(
+				middleware.ValuationRateLimit()(
+					middleware.LoggingMiddleware(
+						func(w http.ResponseWriter, r *http.Request) {
+							if r.Method != http.MethodPost {
+								utils.WriteError(w, http.StatusMethodNotAllowed, "Method not allowed")
+								return
+							}
+							handler.Valuate(w, r)
+						},
+					),
+				),
+			),
+		),
+	)
+
+	return router
+}
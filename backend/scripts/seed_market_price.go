@@ -6,14 +6,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/uzimpp/CarJai/backend/models"
 	"github.com/uzimpp/CarJai/backend/services"
 )
 
 // seedMarketPriceData seeds market prices from PDF using the extraction service
 func seedMarketPriceData(db *sql.DB) error {
 	// Create extraction service
-	extractionService := services.NewExtractionService(db)
+	extractionService := services.NewExtractionService(db, models.NewMarketPriceRepository(models.NewDatabase(db)))
 
 	// Single source of truth: tests directory
 	// Docker: mounted at /app/tests/price2568.pdf
@@ -41,14 +44,21 @@ func seedMarketPriceData(db *sql.DB) error {
 
 	// Use the existing extraction service to import market prices
 	ctx := context.Background()
-	insertedCount, updatedCount, err := extractionService.ImportMarketPricesFromPDF(ctx, pdfPath)
+	version, _, err := extractionService.ImportMarketPriceFile(ctx, pdfPath, models.MarketPriceVersionSource{
+		Filename: filepath.Base(pdfPath),
+		Activate: true,
+	})
+	if err != nil && strings.Contains(err.Error(), "already imported") {
+		log.Printf("✓ Market prices already seeded (%v)", err)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to import market prices from PDF: %w", err)
 	}
 
-	log.Printf("✓ Market prices imported successfully")
-	log.Printf("  Inserted: %d records", insertedCount)
-	log.Printf("  Updated: %d records", updatedCount)
+	log.Printf("✓ Market prices imported successfully as version %d", version.ID)
+	log.Printf("  Inserted: %d records", version.InsertedCount)
+	log.Printf("  Updated: %d records", version.UpdatedCount)
 
 	return nil
 }
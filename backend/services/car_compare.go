@@ -0,0 +1,200 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+// comparisonField is one row of the comparison table. Ranked fields mark the cars with the
+// best value; rank returns nil when the car's value is unknown.
+type comparisonField struct {
+	name           string
+	value          func(d *CarDisplay, e *models.CarComparisonEntry) interface{}
+	rank           func(d *CarDisplay, e *models.CarComparisonEntry) *float64 // nil for unranked fields
+	higherIsBetter bool
+}
+
+// comparisonFields are the comparison table rows, in display order
+var comparisonFields = []comparisonField{
+	{
+		name:  "price",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Price },
+		rank:  func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankInt(d.Price) },
+	},
+	{
+		name:  "estimatedPrice",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return e.EstimatedPrice },
+	},
+	{
+		name:  "priceVsEstimate",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return e.PriceVsEstimate },
+		rank: func(d *CarDisplay, e *models.CarComparisonEntry) *float64 {
+			if e.PriceVsEstimate == nil {
+				return nil
+			}
+			v := float64(*e.PriceVsEstimate)
+			return &v
+		},
+	},
+	{
+		name:           "year",
+		value:          func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Year },
+		rank:           func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankInt(d.Year) },
+		higherIsBetter: true,
+	},
+	{
+		name:  "mileage",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Mileage },
+		rank:  func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankInt(d.Mileage) },
+	},
+	{
+		name:           "conditionRating",
+		value:          func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.ConditionRating },
+		rank:           func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankInt(d.ConditionRating) },
+		higherIsBetter: true,
+	},
+	{
+		name: "inspectionOverallPass",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} {
+			if e.Inspection == nil {
+				return nil
+			}
+			return e.Inspection.OverallPass
+		},
+		rank: func(d *CarDisplay, e *models.CarComparisonEntry) *float64 {
+			if e.Inspection == nil || e.Inspection.OverallPass == nil {
+				return nil
+			}
+			return rankBool(*e.Inspection.OverallPass)
+		},
+		higherIsBetter: true,
+	},
+	{
+		name: "inspectionFailures",
+		value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} {
+			if e.Inspection == nil {
+				return nil
+			}
+			return len(e.Inspection.FailedItems)
+		},
+		rank: func(d *CarDisplay, e *models.CarComparisonEntry) *float64 {
+			if e.Inspection == nil {
+				return nil
+			}
+			v := float64(len(e.Inspection.FailedItems))
+			return &v
+		},
+	},
+	{
+		name:           "isFlooded",
+		value:          func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.IsFlooded },
+		rank:           func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankBool(!d.IsFlooded) },
+		higherIsBetter: true,
+	},
+	{
+		name:           "isHeavilyDamaged",
+		value:          func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.IsHeavilyDamaged },
+		rank:           func(d *CarDisplay, e *models.CarComparisonEntry) *float64 { return rankBool(!d.IsHeavilyDamaged) },
+		higherIsBetter: true,
+	},
+	{name: "bodyType", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.BodyType }},
+	{name: "transmission", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Transmission }},
+	{name: "drivetrain", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Drivetrain }},
+	{name: "fuelTypes", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.FuelTypes }},
+	{name: "colors", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Colors }},
+	{name: "engineCc", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.EngineCC }},
+	{name: "seats", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Seats }},
+	{name: "doors", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Doors }},
+	{name: "province", value: func(d *CarDisplay, e *models.CarComparisonEntry) interface{} { return d.Province }},
+}
+
+func rankInt(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+func rankBool(v bool) *float64 {
+	f := 0.0
+	if v {
+		f = 1
+	}
+	return &f
+}
+
+// CompareCars returns active cars side by side: their translated specs, inspection
+// summaries and price estimates, plus a table of fields with the best values marked
+func (s *CarService) CompareCars(carIDs []int, lang string) (*models.CarComparison, error) {
+	if len(carIDs) < models.MinComparedCars || len(carIDs) > models.MaxComparedCars {
+		return nil, fmt.Errorf("%w: compare between %d and %d cars", models.ErrInvalidComparison, models.MinComparedCars, models.MaxComparedCars)
+	}
+	seen := make(map[int]bool, len(carIDs))
+	for _, id := range carIDs {
+		if seen[id] {
+			return nil, fmt.Errorf("%w: car %d is listed more than once", models.ErrInvalidComparison, id)
+		}
+		seen[id] = true
+	}
+
+	entries := make([]models.CarComparisonEntry, len(carIDs))
+	displays := make([]*CarDisplay, len(carIDs))
+	for i, id := range carIDs {
+		carWithImages, err := s.GetCarWithImages(id)
+		if err != nil {
+			return nil, fmt.Errorf("car %d: %w", id, err)
+		}
+		car := &carWithImages.Car
+		if !IsPublishedStatus(car.Status) {
+			return nil, fmt.Errorf("car %d not found", id)
+		}
+
+		display, err := s.TranslateCarForDisplay(car, lang)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate car %d: %w", id, err)
+		}
+		displays[i] = display.CarDisplay
+
+		entry := models.CarComparisonEntry{
+			Car:          display.CarDisplay,
+			ThumbnailURL: s.translator.GetThumbnailURL(carWithImages.Images),
+		}
+		if carWithImages.Inspection != nil {
+			summary := carWithImages.Inspection.Summary()
+			entry.Inspection = &summary
+		}
+		// No estimate is not an error: market data only covers some models
+		if estimate, err := s.EstimateCarPrice(id); err == nil {
+			entry.EstimatedPrice = &estimate
+			if car.Price != nil {
+				diff := int64(*car.Price) - estimate
+				entry.PriceVsEstimate = &diff
+			}
+		}
+		entries[i] = entry
+	}
+
+	rows := make([]models.CarComparisonRow, len(comparisonFields))
+	for r, field := range comparisonFields {
+		row := models.CarComparisonRow{
+			Field:  field.name,
+			Values: make([]interface{}, len(entries)),
+			Best:   []int{},
+		}
+		ranks := make([]*float64, len(entries))
+		for i := range entries {
+			row.Values[i] = field.value(displays[i], &entries[i])
+			if field.rank != nil {
+				ranks[i] = field.rank(displays[i], &entries[i])
+			}
+		}
+		if field.rank != nil {
+			row.Best = models.BestValueIndexes(ranks, field.higherIsBetter)
+		}
+		rows[r] = row
+	}
+
+	return &models.CarComparison{Cars: entries, Rows: rows}, nil
+}
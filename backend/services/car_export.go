@@ -0,0 +1,376 @@
+package services
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// carExportPageSize is how many cars are loaded and written at a time
+const carExportPageSize = 100
+
+// Inventory export formats
+const (
+	CarExportCSV  = "csv"
+	CarExportJSON = "json"
+	CarExportXLSX = "xlsx"
+)
+
+var carExportContentTypes = map[string]string{
+	CarExportCSV:  "text/csv; charset=utf-8",
+	CarExportJSON: "application/json",
+	CarExportXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// CarExportContentType returns the content type of an export format, or false if the
+// format is not supported
+func CarExportContentType(format string) (string, bool) {
+	contentType, ok := carExportContentTypes[format]
+	return contentType, ok
+}
+
+// CarExportRow is one listing of a seller's inventory export
+type CarExportRow struct {
+	ID               int        `json:"id"`
+	Status           string     `json:"status"`
+	BrandName        *string    `json:"brandName"`
+	ModelName        *string    `json:"modelName"`
+	SubmodelName     *string    `json:"submodelName"`
+	Year             *int       `json:"year"`
+	Mileage          *int       `json:"mileage"`
+	Price            *int       `json:"price"`
+	BodyType         *string    `json:"bodyType"`
+	Transmission     *string    `json:"transmission"`
+	Drivetrain       *string    `json:"drivetrain"`
+	FuelTypes        []string   `json:"fuelTypes"`
+	Colors           []string   `json:"colors"`
+	Province         *string    `json:"province"`
+	LicensePlate     string     `json:"licensePlate"`
+	ChassisNumber    *string    `json:"chassisNumber"`
+	EngineCC         *int       `json:"engineCc"`
+	Seats            *int       `json:"seats"`
+	Doors            *int       `json:"doors"`
+	ConditionRating  *int       `json:"conditionRating"`
+	IsFlooded        bool       `json:"isFlooded"`
+	IsHeavilyDamaged bool       `json:"isHeavilyDamaged"`
+	Views            int        `json:"views"` // Last 90 days
+	Favourites       int        `json:"favourites"`
+	ImageURLs        []string   `json:"imageUrls"` // In display order
+	CreatedAt        time.Time  `json:"createdAt"`
+	ExpiresAt        *time.Time `json:"expiresAt"`
+	ReservedUntil    *time.Time `json:"reservedUntil"`
+}
+
+// carExportColumns are the CSV/XLSX columns. Names and list separators match the import
+// CSV columns where they overlap.
+var carExportColumns = []struct {
+	name  string
+	value func(r *CarExportRow) interface{}
+}{
+	{"id", func(r *CarExportRow) interface{} { return r.ID }},
+	{"status", func(r *CarExportRow) interface{} { return r.Status }},
+	{"brandName", func(r *CarExportRow) interface{} { return exportString(r.BrandName) }},
+	{"modelName", func(r *CarExportRow) interface{} { return exportString(r.ModelName) }},
+	{"submodelName", func(r *CarExportRow) interface{} { return exportString(r.SubmodelName) }},
+	{"year", func(r *CarExportRow) interface{} { return exportInt(r.Year) }},
+	{"mileage", func(r *CarExportRow) interface{} { return exportInt(r.Mileage) }},
+	{"price", func(r *CarExportRow) interface{} { return exportInt(r.Price) }},
+	{"bodyType", func(r *CarExportRow) interface{} { return exportString(r.BodyType) }},
+	{"transmission", func(r *CarExportRow) interface{} { return exportString(r.Transmission) }},
+	{"drivetrain", func(r *CarExportRow) interface{} { return exportString(r.Drivetrain) }},
+	{"fuelTypes", func(r *CarExportRow) interface{} { return strings.Join(r.FuelTypes, "|") }},
+	{"colors", func(r *CarExportRow) interface{} { return strings.Join(r.Colors, "|") }},
+	{"province", func(r *CarExportRow) interface{} { return exportString(r.Province) }},
+	{"licensePlate", func(r *CarExportRow) interface{} { return r.LicensePlate }},
+	{"chassisNumber", func(r *CarExportRow) interface{} { return exportString(r.ChassisNumber) }},
+	{"engineCc", func(r *CarExportRow) interface{} { return exportInt(r.EngineCC) }},
+	{"seats", func(r *CarExportRow) interface{} { return exportInt(r.Seats) }},
+	{"doors", func(r *CarExportRow) interface{} { return exportInt(r.Doors) }},
+	{"conditionRating", func(r *CarExportRow) interface{} { return exportInt(r.ConditionRating) }},
+	{"isFlooded", func(r *CarExportRow) interface{} { return r.IsFlooded }},
+	{"isHeavilyDamaged", func(r *CarExportRow) interface{} { return r.IsHeavilyDamaged }},
+	{"views", func(r *CarExportRow) interface{} { return r.Views }},
+	{"favourites", func(r *CarExportRow) interface{} { return r.Favourites }},
+	{"imageUrls", func(r *CarExportRow) interface{} { return strings.Join(r.ImageURLs, "|") }},
+	{"createdAt", func(r *CarExportRow) interface{} { return r.CreatedAt }},
+	{"expiresAt", func(r *CarExportRow) interface{} { return exportTime(r.ExpiresAt) }},
+	{"reservedUntil", func(r *CarExportRow) interface{} { return exportTime(r.ReservedUntil) }},
+}
+
+// exportString, exportInt and exportTime return nil for missing values so they
+// are left empty rather than written as zero values
+func exportString(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func exportInt(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func exportTime(v *time.Time) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+// CarExportEncoder writes export rows in one format
+type CarExportEncoder interface {
+	WriteRow(row *CarExportRow) error
+	Close() error // Writes any trailer and flushes; must be called even with no rows
+}
+
+// NewCarExportEncoder creates an encoder for a format returned by CarExportContentType
+func NewCarExportEncoder(format string, w io.Writer) (CarExportEncoder, error) {
+	switch format {
+	case CarExportCSV:
+		// A BOM lets spreadsheet apps detect UTF-8 (Thai labels)
+		if _, err := io.WriteString(w, "\ufeff"); err != nil {
+			return nil, err
+		}
+		enc := &csvCarExportEncoder{w: csv.NewWriter(w)}
+		if err := enc.w.Write(carExportHeader()); err != nil {
+			return nil, err
+		}
+		return enc, nil
+	case CarExportJSON:
+		return &jsonCarExportEncoder{w: w}, nil
+	case CarExportXLSX:
+		xw, err := utils.NewXLSXWriter(w, "Inventory")
+		if err != nil {
+			return nil, err
+		}
+		header := make([]interface{}, len(carExportColumns))
+		for i, name := range carExportHeader() {
+			header[i] = name
+		}
+		if err := xw.WriteRow(header); err != nil {
+			return nil, err
+		}
+		return &xlsxCarExportEncoder{w: xw}, nil
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+func carExportHeader() []string {
+	header := make([]string, len(carExportColumns))
+	for i, col := range carExportColumns {
+		header[i] = col.name
+	}
+	return header
+}
+
+type csvCarExportEncoder struct {
+	w *csv.Writer
+}
+
+func (e *csvCarExportEncoder) WriteRow(row *CarExportRow) error {
+	record := make([]string, len(carExportColumns))
+	for i, col := range carExportColumns {
+		switch v := col.value(row).(type) {
+		case nil:
+		case string:
+			record[i] = v
+		case int:
+			record[i] = strconv.Itoa(v)
+		case bool:
+			record[i] = "no"
+			if v {
+				record[i] = "yes"
+			}
+		case time.Time:
+			record[i] = v.Format(time.RFC3339)
+		}
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvCarExportEncoder) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonCarExportEncoder writes a JSON array one element at a time
+type jsonCarExportEncoder struct {
+	w    io.Writer
+	rows int
+}
+
+func (e *jsonCarExportEncoder) WriteRow(row *CarExportRow) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if e.rows == 0 {
+		sep = "[\n"
+	}
+	e.rows++
+	if _, err := io.WriteString(e.w, sep); err != nil {
+		return err
+	}
+	_, err = e.w.Write(data)
+	return err
+}
+
+func (e *jsonCarExportEncoder) Close() error {
+	end := "\n]\n"
+	if e.rows == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type xlsxCarExportEncoder struct {
+	w *utils.XLSXWriter
+}
+
+func (e *xlsxCarExportEncoder) WriteRow(row *CarExportRow) error {
+	cells := make([]interface{}, len(carExportColumns))
+	for i, col := range carExportColumns {
+		cells[i] = col.value(row)
+	}
+	return e.w.WriteRow(cells)
+}
+
+func (e *xlsxCarExportEncoder) Close() error {
+	return e.w.Close()
+}
+
+// ExportSellerCars writes all of a seller's listings (except deleted ones) with translated
+// labels, newest first. Cars are loaded a page at a time and written as they are translated.
+// Nothing is written to w when loading the first page fails.
+func (s *CarService) ExportSellerCars(sellerID int, lang, format string, w io.Writer) error {
+	if lang == "" {
+		lang = "en"
+	}
+
+	var enc CarExportEncoder
+	var after *models.CarCursor
+	for {
+		cars, err := s.carRepo.GetCarsBySellerIDPage(sellerID, nil, after, carExportPageSize)
+		if err != nil {
+			return err
+		}
+
+		rows, err := s.buildCarExportRows(cars, lang)
+		if err != nil {
+			return err
+		}
+
+		if enc == nil {
+			if enc, err = NewCarExportEncoder(format, w); err != nil {
+				return err
+			}
+		}
+		for i := range rows {
+			if err := enc.WriteRow(&rows[i]); err != nil {
+				return fmt.Errorf("failed to write export: %w", err)
+			}
+		}
+
+		if len(cars) < carExportPageSize {
+			break
+		}
+		after = models.NewCarCursor(&cars[len(cars)-1], "created_at", "desc")
+	}
+
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	return nil
+}
+
+// buildCarExportRows translates one page of cars, reusing the list item translation for
+// labels and TranslateCarForDisplay for the detail fields
+func (s *CarService) buildCarExportRows(cars []models.Car, lang string) ([]CarExportRow, error) {
+	var listed []models.Car
+	for _, car := range cars {
+		if car.Status != "deleted" {
+			listed = append(listed, car)
+		}
+	}
+	if len(listed) == 0 {
+		return nil, nil
+	}
+
+	items, err := s.batchTranslateCarsToListItems(listed, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	carIDs := make([]int, len(listed))
+	for i, car := range listed {
+		carIDs[i] = car.ID
+	}
+	imagesMap, err := s.imageRepo.GetCarImagesMetadataBatch(carIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to batch fetch images: %w", err)
+	}
+	engagement, err := s.carRepo.GetCarEngagementBatch(carIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]CarExportRow, 0, len(listed))
+	for i := range listed {
+		car, item := &listed[i], items[i]
+		display, err := s.TranslateCarForDisplay(car, lang)
+		if err != nil {
+			return nil, fmt.Errorf("failed to translate car %d: %w", car.ID, err)
+		}
+		d := display.CarDisplay
+
+		imageURLs := []string{}
+		for _, img := range imagesMap[car.ID] {
+			imageURLs = append(imageURLs, CarImageURL(img.ID, img.ContentHash, ""))
+		}
+
+		rows = append(rows, CarExportRow{
+			ID:               car.ID,
+			Status:           car.Status,
+			BrandName:        item.BrandName,
+			ModelName:        item.ModelName,
+			SubmodelName:     item.SubmodelName,
+			Year:             item.Year,
+			Mileage:          item.Mileage,
+			Price:            item.Price,
+			BodyType:         item.BodyType,
+			Transmission:     item.Transmission,
+			Drivetrain:       item.Drivetrain,
+			FuelTypes:        item.FuelTypes,
+			Colors:           item.Colors,
+			Province:         d.Province,
+			LicensePlate:     d.LicensePlate,
+			ChassisNumber:    d.ChassisNumber,
+			EngineCC:         d.EngineCC,
+			Seats:            d.Seats,
+			Doors:            d.Doors,
+			ConditionRating:  item.ConditionRating,
+			IsFlooded:        d.IsFlooded,
+			IsHeavilyDamaged: d.IsHeavilyDamaged,
+			Views:            engagement[car.ID].Views,
+			Favourites:       engagement[car.ID].Favourites,
+			ImageURLs:        imageURLs,
+			CreatedAt:        car.CreatedAt,
+			ExpiresAt:        car.ExpiresAt,
+			ReservedUntil:    car.ReservedUntil,
+		})
+	}
+	return rows, nil
+}
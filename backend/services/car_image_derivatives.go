@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for uploaded image types
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"sort"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/storage"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// CarImageSize is a fixed-size derivative of car images. Derivatives are JPEG only, not
+// WebP: the standard library has no WebP encoder, and JPEG at these qualities is a fraction
+// of the original's size.
+type CarImageSize struct {
+	MaxWidth  int
+	MaxHeight int
+	Quality   int // JPEG quality (1-100)
+}
+
+// CarImageSizes are the derivatives served by GET /api/cars/images/{id}?size=
+var CarImageSizes = map[string]CarImageSize{
+	"thumb": {MaxWidth: 480, MaxHeight: 360, Quality: 75},   // Listing cards
+	"card":  {MaxWidth: 960, MaxHeight: 720, Quality: 80},   // Galleries and large cards
+	"full":  {MaxWidth: 1920, MaxHeight: 1440, Quality: 85}, // Full-screen viewing
+}
+
+// CarImageSizeOriginal serves the image as uploaded
+const CarImageSizeOriginal = "original"
+
+// errNoDerivatives means an image cannot be resized (unsupported format, corrupt or larger
+// than utils.MaxDecodedImagePixels), so every size serves the original
+var errNoDerivatives = errors.New("image has no derivatives")
+
+// derivativeMemoryBudget bounds the memory held by images being decoded and resized at
+// once; a single image over the budget is still resized, but alone
+const derivativeMemoryBudget = 1 << 30 // 1GB
+
+// derivativeBytesPerPixel estimates the memory an image needs while its derivatives are
+// built: the decoded source (up to 8 bytes per pixel for 16-bit PNGs) plus the flattened
+// RGBA copy every size is scaled from
+const derivativeBytesPerPixel = 8 + 4
+
+// derivativeMemory limits how many images are decoded and resized at once by their size.
+// Cleaning uploads takes from it too while it rotates JPEGs (see cleanImage), and so does
+// perceptual hashing (see hashImageData).
+var derivativeMemory = utils.NewWeightedSemaphore(derivativeMemoryBudget)
+
+// derivativeCall is an in-progress generation that concurrent requests for the same image wait on
+type derivativeCall struct {
+	done   chan struct{}
+	result map[string][]byte
+	err    error
+}
+
+// cleanImage strips an image's metadata and applies its orientation (see utils.CleanImage),
+// decoding within derivativeMemory so concurrent uploads cannot exhaust memory
+func cleanImage(data []byte, contentType string) (*utils.CleanedImage, error) {
+	return utils.CleanImageWithin(data, contentType, derivativeMemory)
+}
+
+// carImageDerivativeKey returns the image store key of a derivative. Keys depend only on
+// the image ID, so derivatives stay valid when migrate-images moves the original.
+func carImageDerivativeKey(carID, imageID int, size string) string {
+	return fmt.Sprintf("cars/%d/derivatives/%d_%s.jpg", carID, imageID, size)
+}
+
+// carImageDerivativeKeys returns the keys of all derivatives of an image
+func carImageDerivativeKeys(carID, imageID int) []string {
+	keys := make([]string, 0, len(CarImageSizes))
+	for size := range CarImageSizes {
+		keys = append(keys, carImageDerivativeKey(carID, imageID, size))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// canDeriveImage reports whether images of a content type can be decoded for resizing
+func canDeriveImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif":
+		return true
+	}
+	return false // WebP has no standard library decoder
+}
+
+// OpenCarImageSize returns a derivative of an image returned by GetCarImageForServing ("" or
+// "original" for the image as uploaded) and a reader for it; the caller must close it.
+// Derivatives missing from the image store, such as those of images uploaded before
+// derivatives existed, are generated and stored on first request.
+func (s *CarService) OpenCarImageSize(ctx context.Context, image *models.CarImage, size string) (*models.CarImage, io.ReadSeekCloser, error) {
+	if size == "" || size == CarImageSizeOriginal || !canDeriveImage(image.ImageType) {
+		return s.openCarImageData(ctx, image)
+	}
+
+	derivative := *image
+	derivative.StorageKey = nil
+	derivative.ImageType = "image/jpeg"
+
+	rc, n, err := storage.Open(ctx, s.imageStore, carImageDerivativeKey(image.CarID, image.ID, size))
+	if err == nil {
+		derivative.ImageSize = int(n)
+		return &derivative, rc, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, err
+	}
+
+	derivatives, err := s.generateCarImageDerivatives(ctx, image)
+	if errors.Is(err, errNoDerivatives) {
+		return s.openCarImageData(ctx, image)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	data := derivatives[size]
+	derivative.ImageSize = len(data)
+	return &derivative, bytesReadSeekCloser{bytes.NewReader(data)}, nil
+}
+
+// warmCarImageDerivatives generates a new image's derivatives in the background so the
+// first visitors don't wait for them
+func (s *CarService) warmCarImageDerivatives(image *models.CarImage) {
+	go func() {
+		_, err := s.generateCarImageDerivatives(context.Background(), image)
+		if err != nil && !errors.Is(err, errNoDerivatives) {
+			utils.AppLogger.WithField("image_id", image.ID).Error("Failed to generate image derivatives: " + err.Error())
+		}
+	}()
+}
+
+// generateCarImageDerivatives creates and stores every derivative of an image, returning
+// their data by size. Concurrent calls for the same image share one generation.
+func (s *CarService) generateCarImageDerivatives(ctx context.Context, image *models.CarImage) (map[string][]byte, error) {
+	s.derivativeMu.Lock()
+	if call, ok := s.derivativeCalls[image.ID]; ok {
+		s.derivativeMu.Unlock()
+		select {
+		case <-call.done:
+			return call.result, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if s.derivativeCalls == nil {
+		s.derivativeCalls = make(map[int]*derivativeCall)
+	}
+	call := &derivativeCall{done: make(chan struct{})}
+	s.derivativeCalls[image.ID] = call
+	s.derivativeMu.Unlock()
+
+	// Not tied to ctx: other requests may be waiting for the result
+	call.result, call.err = s.buildCarImageDerivatives(image)
+
+	s.derivativeMu.Lock()
+	delete(s.derivativeCalls, image.ID)
+	s.derivativeMu.Unlock()
+	close(call.done)
+	return call.result, call.err
+}
+
+// buildCarImageDerivatives decodes and flattens the original once and encodes every size from it
+func (s *CarService) buildCarImageDerivatives(img *models.CarImage) (map[string][]byte, error) {
+	ctx := context.Background()
+	_, rc, err := s.openCarImageData(ctx, img)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > utils.MaxDecodedImagePixels {
+		return nil, errNoDerivatives
+	}
+
+	held := derivativeMemory.Acquire(int64(cfg.Width) * int64(cfg.Height) * derivativeBytesPerPixel)
+	defer derivativeMemory.Release(held)
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errNoDerivatives
+	}
+	flat := utils.FlattenOnWhite(src)
+
+	result := make(map[string][]byte, len(CarImageSizes))
+	for name, size := range CarImageSizes {
+		var buf bytes.Buffer
+		scaled := utils.ScaleFlatToFit(flat, size.MaxWidth, size.MaxHeight)
+		if err := jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: size.Quality}); err != nil {
+			return nil, fmt.Errorf("failed to encode %s image: %w", name, err)
+		}
+		result[name] = buf.Bytes()
+
+		// A failed write only means the derivative is generated again next time
+		key := carImageDerivativeKey(img.CarID, img.ID, name)
+		if err := s.imageStore.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), "image/jpeg"); err != nil {
+			utils.AppLogger.WithField("storage_key", key).Error("Failed to store image derivative: " + err.Error())
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// DuplicatePhotoMaxDistance is how many of the 64 perceptual hash bits two photos may differ
+// in to count as the same photo. Resized, recompressed and lightly edited copies stay well
+// within it, while different photos of the same model differ in about half the bits. It
+// must stay within models.MaxDuplicatePhotoDistance.
+const DuplicatePhotoMaxDistance = 6
+
+// SetBlockDuplicatePhotos sets whether publishing is refused for listings with a photo that
+// matches one on another seller's active listing
+func (s *CarService) SetBlockDuplicatePhotos(block bool) {
+	s.blockDuplicatePhotos = block
+}
+
+// hashImageData returns the perceptual hash of a cleaned image, or nil when it cannot be
+// decoded (WebP, corrupt or larger than utils.MaxDecodedImagePixels). The image is decoded
+// within derivativeMemory, as hashing needs the same decoded and flattened copies as resizing.
+func hashImageData(data []byte, contentType string) *int64 {
+	if !canDeriveImage(contentType) {
+		return nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width*cfg.Height > utils.MaxDecodedImagePixels {
+		return nil
+	}
+
+	held := derivativeMemory.Acquire(int64(cfg.Width) * int64(cfg.Height) * derivativeBytesPerPixel)
+	defer derivativeMemory.Release(held)
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	hash := int64(utils.DifferenceHash(img))
+	return &hash
+}
+
+// recordDuplicatePhotos flags the other sellers' photos a newly hashed image matches. A
+// failure only means the image goes unflagged, so it is logged rather than returned.
+func (s *CarService) recordDuplicatePhotos(image *models.CarImage) {
+	if image.PHash == nil {
+		return
+	}
+	if _, err := s.imageRepo.RecordDuplicatePhotos(image.ID, DuplicatePhotoMaxDistance); err != nil {
+		utils.AppLogger.WithField("image_id", image.ID).Error("Failed to check for duplicate photos: " + err.Error())
+	}
+}
+
+// GetDuplicatePhotos lists the photos of a car that match photos on other sellers' listings
+func (s *CarService) GetDuplicatePhotos(carID int) ([]models.DuplicatePhotoMatch, error) {
+	if _, err := s.carRepo.GetCarByID(carID); err != nil {
+		return nil, fmt.Errorf("car not found")
+	}
+	matches, err := s.imageRepo.GetDuplicatePhotos(carID)
+	if err != nil {
+		return nil, err
+	}
+	setDuplicatePhotoURLs(matches)
+	return matches, nil
+}
+
+// GetDuplicatePhotosBatch lists the duplicate photo matches of several cars, keyed by car ID.
+// Unlike GetDuplicatePhotos it does not check that the cars exist.
+func (s *CarService) GetDuplicatePhotosBatch(carIDs []int) (map[int][]models.DuplicatePhotoMatch, error) {
+	byCar, err := s.imageRepo.GetDuplicatePhotosBatch(carIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, matches := range byCar {
+		setDuplicatePhotoURLs(matches)
+	}
+	return byCar, nil
+}
+
+// setDuplicatePhotoURLs fills in the thumbnail URLs of both photos of each match
+func setDuplicatePhotoURLs(matches []models.DuplicatePhotoMatch) {
+	for i := range matches {
+		matches[i].ImageURL = CarImageURL(matches[i].ImageID, matches[i].ImageContentHash, "thumb")
+		matches[i].MatchedImageURL = CarImageURL(matches[i].MatchedImageID, matches[i].MatchedContentHash, "thumb")
+	}
+}
+
+// countDuplicatePhotosOfActiveListings returns how many of a car's photos match a photo on
+// another seller's published (active or reserved) listing
+func (s *CarService) countDuplicatePhotosOfActiveListings(carID int) (int, error) {
+	matches, err := s.imageRepo.GetDuplicatePhotos(carID)
+	if err != nil {
+		return 0, err
+	}
+	images := make(map[int]bool)
+	for _, m := range matches {
+		if IsPublishedStatus(m.MatchedCarStatus) {
+			images[m.ImageID] = true
+		}
+	}
+	return len(images), nil
+}
+
+// HashExistingImages computes the perceptual hash of images cleaned before hashes existed,
+// batchSize at a time, and flags their duplicates. Images that cannot be read are logged and
+// counted as failed.
+func (s *CarService) HashExistingImages(ctx context.Context, batchSize int) (hashed, failed int, err error) {
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	afterID := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return hashed, failed, err
+		}
+		images, err := s.imageRepo.GetUnhashedImages(afterID, batchSize)
+		if err != nil {
+			return hashed, failed, err
+		}
+		if len(images) == 0 {
+			return hashed, failed, nil
+		}
+
+		for i := range images {
+			img := &images[i]
+			afterID = img.ID
+			ok, err := s.hashExistingImage(ctx, img)
+			if err != nil {
+				failed++
+				utils.AppLogger.WithField("image_id", img.ID).Error("Failed to hash image: " + err.Error())
+			} else if ok {
+				hashed++
+			}
+		}
+	}
+}
+
+// hashExistingImage hashes one image. It returns false when the image cannot be decoded.
+func (s *CarService) hashExistingImage(ctx context.Context, img *models.CarImage) (bool, error) {
+	_, rc, err := s.openCarImageData(ctx, img)
+	if err != nil {
+		return false, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	img.PHash = hashImageData(data, img.ImageType)
+	if img.PHash == nil {
+		return false, nil
+	}
+	if err := s.imageRepo.SetCarImageHash(img.ID, *img.PHash); err != nil {
+		return false, err
+	}
+	s.recordDuplicatePhotos(img)
+	return true, nil
+}
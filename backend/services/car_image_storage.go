@@ -0,0 +1,317 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/storage"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// storeCarImage writes a cleaned image to the image store and records it. The object is
+// removed again if it cannot be recorded.
+func (s *CarService) storeCarImage(carID int, cleaned *utils.CleanedImage, contentType string, displayOrder int) (*models.CarImage, error) {
+	image, err := s.putCleanedImage(context.Background(), carID, cleaned, contentType)
+	if err != nil {
+		return nil, err
+	}
+	key := *image.StorageKey
+	image.DisplayOrder = displayOrder
+
+	if err := s.imageRepo.CreateCarImage(image); err != nil {
+		s.deleteStoredImages([]string{key})
+		return nil, err
+	}
+	s.processNewImage(image)
+	return image, nil
+}
+
+// processNewImage flags a newly recorded image's duplicates and starts generating its derivatives
+func (s *CarService) processNewImage(image *models.CarImage) {
+	s.recordDuplicatePhotos(image)
+	if canDeriveImage(image.ImageType) {
+		s.warmCarImageDerivatives(image)
+	}
+}
+
+// putCleanedImage writes a cleaned image to the image store under a new key and returns its
+// unsaved record, including its content and perceptual hashes
+func (s *CarService) putCleanedImage(ctx context.Context, carID int, cleaned *utils.CleanedImage, contentType string) (*models.CarImage, error) {
+	key, err := storage.NewImageKey(carID, contentType)
+	if err != nil {
+		return nil, err
+	}
+	size := len(cleaned.Data)
+	if err := s.imageStore.Put(ctx, key, bytes.NewReader(cleaned.Data), int64(size), contentType); err != nil {
+		return nil, err
+	}
+	contentHash := sha256Hex(cleaned.Data)
+	return &models.CarImage{
+		CarID:       carID,
+		StorageKey:  &key,
+		ImageType:   contentType,
+		ImageSize:   size,
+		Width:       &cleaned.Width,
+		Height:      &cleaned.Height,
+		PHash:       hashImageData(cleaned.Data, contentType),
+		ContentHash: &contentHash,
+	}, nil
+}
+
+// OpenCarImage returns an image's metadata and a reader for its data; the caller must close it
+func (s *CarService) OpenCarImage(ctx context.Context, imageID int) (*models.CarImage, io.ReadSeekCloser, error) {
+	image, err := s.imageRepo.GetCarImageByID(imageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s.openCarImageData(ctx, image)
+}
+
+// openCarImageData opens the data of an image looked up by GetCarImageByID
+func (s *CarService) openCarImageData(ctx context.Context, image *models.CarImage) (*models.CarImage, io.ReadSeekCloser, error) {
+	if image.StorageKey == nil {
+		// Uploaded before the image store and not migrated yet
+		data, err := s.imageRepo.GetCarImageData(image.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return image, bytesReadSeekCloser{bytes.NewReader(data)}, nil
+	}
+
+	rc, _, err := storage.Open(ctx, s.imageStore, *image.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, fmt.Errorf("image data not found")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return image, rc, nil
+}
+
+// bytesReadSeekCloser serves in-memory image data where a file would otherwise be opened
+type bytesReadSeekCloser struct {
+	*bytes.Reader
+}
+
+func (bytesReadSeekCloser) Close() error { return nil }
+
+// deleteCar hard-deletes a car and then removes its images and their derivatives from
+// the image store
+func (s *CarService) deleteCar(carID int) error {
+	keys, err := s.carImageKeys(carID)
+	if err != nil {
+		return err
+	}
+	if err := s.carRepo.DeleteCar(carID); err != nil {
+		return err
+	}
+	s.deleteStoredImages(keys)
+	return nil
+}
+
+// carImageKeys returns the storage keys of a car's images and their derivatives
+func (s *CarService) carImageKeys(carID int) ([]string, error) {
+	keys, err := s.imageRepo.GetCarImageStorageKeys(carID)
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.imageRepo.GetCarImagesMetadata(carID)
+	if err != nil {
+		return nil, err
+	}
+	for _, img := range images {
+		keys = append(keys, carImageDerivativeKeys(carID, img.ID)...)
+	}
+	return keys, nil
+}
+
+// sellerImageKeys returns the storage keys of the images and derivatives of all of a
+// seller's cars, whatever their status. Collect them before deleting the seller: the
+// rows cascade away with the user.
+func (s *CarService) sellerImageKeys(sellerID int) ([]string, error) {
+	cars, err := s.carRepo.GetCarsBySellerID(sellerID, nil)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for _, car := range cars {
+		carKeys, err := s.carImageKeys(car.ID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, carKeys...)
+	}
+	return keys, nil
+}
+
+// deleteStoredImages removes objects whose rows are gone. Failures only leave unused
+// objects behind, so they are logged rather than returned.
+func (s *CarService) deleteStoredImages(keys []string) {
+	for _, key := range keys {
+		if err := s.imageStore.Delete(context.Background(), key); err != nil {
+			utils.AppLogger.WithField("storage_key", key).Error("Failed to delete stored image: " + err.Error())
+		}
+	}
+}
+
+// ImageMigrationResult summarises a run of MigrateDatabaseImages
+type ImageMigrationResult struct {
+	Moved   int
+	Skipped int // Deleted or moved by someone else during the run
+	Failed  int
+	Bytes   int64
+}
+
+// MigrateDatabaseImages moves up to limit images (0 for all) whose data is still in the
+// database into the image store, batchSize at a time. Each image is copied before its
+// database data is dropped, so the run can be stopped and resumed at any point.
+// With dryRun set, images are only counted.
+func (s *CarService) MigrateDatabaseImages(ctx context.Context, batchSize, limit int, dryRun bool, logf func(format string, args ...interface{})) (ImageMigrationResult, error) {
+	var result ImageMigrationResult
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+
+	afterID := 0
+	for limit <= 0 || result.Moved+result.Skipped+result.Failed < limit {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		n := batchSize
+		if remaining := limit - (result.Moved + result.Skipped + result.Failed); limit > 0 && remaining < n {
+			n = remaining
+		}
+		images, err := s.imageRepo.GetDatabaseStoredImages(afterID, n)
+		if err != nil {
+			return result, err
+		}
+		if len(images) == 0 {
+			break
+		}
+
+		for _, img := range images {
+			afterID = img.ID
+			if dryRun {
+				result.Moved++
+				result.Bytes += int64(img.ImageSize)
+				continue
+			}
+
+			moved, err := s.migrateDatabaseImage(ctx, img)
+			switch {
+			case err != nil:
+				result.Failed++
+				logf("image %d (car %d): %v", img.ID, img.CarID, err)
+			case !moved:
+				result.Skipped++
+			default:
+				result.Moved++
+				result.Bytes += int64(img.ImageSize)
+			}
+		}
+		logf("processed up to image %d: %d moved, %d skipped, %d failed", afterID, result.Moved, result.Skipped, result.Failed)
+	}
+	return result, nil
+}
+
+// migrateDatabaseImage copies one image to the image store and drops its database data
+func (s *CarService) migrateDatabaseImage(ctx context.Context, img models.CarImageMetadata) (bool, error) {
+	data, err := s.imageRepo.GetCarImageData(img.ID)
+	if err != nil {
+		return false, err
+	}
+	key, err := storage.NewImageKey(img.CarID, img.ImageType)
+	if err != nil {
+		return false, err
+	}
+	if err := s.imageStore.Put(ctx, key, bytes.NewReader(data), int64(len(data)), img.ImageType); err != nil {
+		return false, err
+	}
+
+	moved, err := s.imageRepo.MoveCarImageToStorage(img.ID, key)
+	if err != nil || !moved {
+		s.deleteStoredImages([]string{key})
+	}
+	return moved, err
+}
+
+// CleanExistingImages strips metadata from and auto-orients images uploaded before that was
+// done on upload, batchSize at a time. Cleaned copies are saved to the image store (moving
+// images still in the database) and replace the originals and their derivatives. Images that
+// cannot be cleaned are logged and counted as failed.
+func (s *CarService) CleanExistingImages(ctx context.Context, batchSize int) (cleaned, failed int, err error) {
+	if batchSize <= 0 {
+		batchSize = 50
+	}
+	afterID := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return cleaned, failed, err
+		}
+		images, err := s.imageRepo.GetUncleanedImages(afterID, batchSize)
+		if err != nil {
+			return cleaned, failed, err
+		}
+		if len(images) == 0 {
+			return cleaned, failed, nil
+		}
+
+		for i := range images {
+			img := &images[i]
+			afterID = img.ID
+			ok, err := s.cleanExistingImage(ctx, img)
+			if err != nil {
+				failed++
+				utils.AppLogger.WithField("image_id", img.ID).Error("Failed to clean image: " + err.Error())
+			} else if ok {
+				cleaned++
+			}
+		}
+	}
+}
+
+// cleanExistingImage replaces one image with a cleaned copy. It returns false when the image
+// changed in the meantime.
+func (s *CarService) cleanExistingImage(ctx context.Context, img *models.CarImage) (bool, error) {
+	_, rc, err := s.openCarImageData(ctx, img)
+	if err != nil {
+		return false, err
+	}
+	data, err := io.ReadAll(rc)
+	rc.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to read image: %w", err)
+	}
+
+	contentType := http.DetectContentType(data)
+	cleanedImage, err := cleanImage(data, contentType)
+	if err != nil {
+		return false, err
+	}
+	replacement, err := s.putCleanedImage(ctx, img.CarID, cleanedImage, contentType)
+	if err != nil {
+		return false, err
+	}
+
+	replaced, err := s.imageRepo.ReplaceCleanedImage(img.ID, img.StorageKey, replacement)
+	if err != nil || !replaced {
+		s.deleteStoredImages([]string{*replacement.StorageKey})
+		return false, err
+	}
+
+	// The old file and derivatives made from it still have the metadata or orientation
+	stale := carImageDerivativeKeys(img.CarID, img.ID)
+	if img.StorageKey != nil {
+		stale = append(stale, *img.StorageKey)
+	}
+	s.deleteStoredImages(stale)
+
+	replacement.ID = img.ID
+	s.recordDuplicatePhotos(replacement)
+	return true, nil
+}
@@ -0,0 +1,560 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// MaxCarImportRows is the most listings one CSV import may contain
+const MaxCarImportRows = 500
+
+// CarImportRecord is one parsed CSV row
+type CarImportRecord struct {
+	Row        int // 1-based data row (the header is not counted)
+	Request    models.UpdateCarRequest
+	ColorCodes []string
+	Errors     []string // Cell errors; the row is not imported when set
+}
+
+// carImportColumns maps CSV headers (case-insensitive) to the request field they set.
+// Name columns (province, bodyType, ...) are mapped to codes like the draft wizard does.
+var carImportColumns = map[string]func(rec *CarImportRecord, value string) error{
+	"chassisNumber":    importString(func(r *models.UpdateCarRequest, v *string) { r.ChassisNumber = v }),
+	"brandName":        importString(func(r *models.UpdateCarRequest, v *string) { r.BrandName = v }),
+	"modelName":        importString(func(r *models.UpdateCarRequest, v *string) { r.ModelName = v }),
+	"submodelName":     importString(func(r *models.UpdateCarRequest, v *string) { r.SubmodelName = v }),
+	"prefix":           importString(func(r *models.UpdateCarRequest, v *string) { r.Prefix = v }),
+	"number":           importString(func(r *models.UpdateCarRequest, v *string) { r.Number = v }),
+	"description":      importString(func(r *models.UpdateCarRequest, v *string) { r.Description = v }),
+	"province":         importString(func(r *models.UpdateCarRequest, v *string) { r.ProvinceNameTh = v }),
+	"bodyType":         importString(func(r *models.UpdateCarRequest, v *string) { r.BodyTypeName = v }),
+	"transmission":     importString(func(r *models.UpdateCarRequest, v *string) { r.TransmissionName = v }),
+	"drivetrain":       importString(func(r *models.UpdateCarRequest, v *string) { r.DrivetrainName = v }),
+	"bodyTypeCode":     importCode(func(r *models.UpdateCarRequest, v *string) { r.BodyTypeCode = v }),
+	"transmissionCode": importCode(func(r *models.UpdateCarRequest, v *string) { r.TransmissionCode = v }),
+	"drivetrainCode":   importCode(func(r *models.UpdateCarRequest, v *string) { r.DrivetrainCode = v }),
+	"year":             importInt(1900, 3000, func(r *models.UpdateCarRequest, v *int) { r.Year = v }),
+	"mileage":          importInt(0, 0, func(r *models.UpdateCarRequest, v *int) { r.Mileage = v }),
+	"engineCc":         importInt(0, 0, func(r *models.UpdateCarRequest, v *int) { r.EngineCC = v }),
+	"seats":            importInt(1, 0, func(r *models.UpdateCarRequest, v *int) { r.Seats = v }),
+	"doors":            importInt(0, 0, func(r *models.UpdateCarRequest, v *int) { r.Doors = v }),
+	"price":            importInt(1, 0, func(r *models.UpdateCarRequest, v *int) { r.Price = v }),
+	"conditionRating":  importInt(1, 5, func(r *models.UpdateCarRequest, v *int) { r.ConditionRating = v }),
+	"provinceId":       importInt(1, 0, func(r *models.UpdateCarRequest, v *int) { r.ProvinceID = v }),
+	"isFlooded":        importBool(func(r *models.UpdateCarRequest, v *bool) { r.IsFlooded = v }),
+	"isHeavilyDamaged": importBool(func(r *models.UpdateCarRequest, v *bool) { r.IsHeavilyDamaged = v }),
+	"fuelTypes": func(rec *CarImportRecord, value string) error {
+		rec.Request.FuelLabels = splitImportList(value, false)
+		return nil
+	},
+	"fuelCodes": func(rec *CarImportRecord, value string) error {
+		rec.Request.FuelCodes = splitImportList(value, true)
+		return nil
+	},
+	"colors": func(rec *CarImportRecord, value string) error {
+		rec.ColorCodes = splitImportList(value, true)
+		return nil
+	},
+}
+
+func importString(set func(r *models.UpdateCarRequest, v *string)) func(*CarImportRecord, string) error {
+	return func(rec *CarImportRecord, value string) error {
+		set(&rec.Request, &value)
+		return nil
+	}
+}
+
+func importCode(set func(r *models.UpdateCarRequest, v *string)) func(*CarImportRecord, string) error {
+	return func(rec *CarImportRecord, value string) error {
+		code := strings.ToUpper(value)
+		set(&rec.Request, &code)
+		return nil
+	}
+}
+
+// importInt parses a whole number of at least min and, when max > 0, at most max.
+// Thousands separators are allowed ("520,000").
+func importInt(min, max int, set func(r *models.UpdateCarRequest, v *int)) func(*CarImportRecord, string) error {
+	return func(rec *CarImportRecord, value string) error {
+		n, err := strconv.Atoi(strings.ReplaceAll(value, ",", ""))
+		if err != nil {
+			return fmt.Errorf("%q is not a whole number", value)
+		}
+		if n < min || (max > 0 && n > max) {
+			if max > 0 {
+				return fmt.Errorf("%d must be between %d and %d", n, min, max)
+			}
+			return fmt.Errorf("%d must be at least %d", n, min)
+		}
+		set(&rec.Request, &n)
+		return nil
+	}
+}
+
+func importBool(set func(r *models.UpdateCarRequest, v *bool)) func(*CarImportRecord, string) error {
+	return func(rec *CarImportRecord, value string) error {
+		var b bool
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "1":
+			b = true
+		case "false", "no", "n", "0":
+			b = false
+		default:
+			return fmt.Errorf("%q is not yes or no", value)
+		}
+		set(&rec.Request, &b)
+		return nil
+	}
+}
+
+// splitImportList splits a "|"-separated cell, optionally upper-casing codes
+func splitImportList(value string, codes bool) []string {
+	var items []string
+	for _, item := range strings.Split(value, "|") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if codes {
+			item = strings.ToUpper(item)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// ParseCarImportCSV parses an import CSV: a header row of carImportColumns names followed
+// by one listing per row. Header problems fail the whole file; cell problems are recorded
+// on the row.
+func ParseCarImportCSV(r io.Reader) ([]CarImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: CSV file is empty", models.ErrInvalidCarImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidCarImport, err)
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")) // Spreadsheet apps may add a BOM
+		column := ""
+		for known := range carImportColumns {
+			if strings.EqualFold(known, name) {
+				column = known
+				break
+			}
+		}
+		if column == "" {
+			return nil, fmt.Errorf("%w: unknown column %q", models.ErrInvalidCarImport, name)
+		}
+		if seen[column] {
+			return nil, fmt.Errorf("%w: duplicate column %q", models.ErrInvalidCarImport, name)
+		}
+		seen[column] = true
+		columns[i] = column
+	}
+
+	var records []CarImportRecord
+	for {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", models.ErrInvalidCarImport, err)
+		}
+		if len(records) == MaxCarImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows can be imported at once", models.ErrInvalidCarImport, MaxCarImportRows)
+		}
+
+		rec := CarImportRecord{Row: len(records) + 1}
+		if len(cells) > len(columns) {
+			rec.Errors = append(rec.Errors, fmt.Sprintf("row has %d cells but the header has %d columns", len(cells), len(columns)))
+		}
+		for i, value := range cells {
+			value = strings.TrimSpace(value)
+			if i >= len(columns) || value == "" {
+				continue
+			}
+			if err := carImportColumns[columns[i]](&rec, value); err != nil {
+				rec.Errors = append(rec.Errors, fmt.Sprintf("%s: %v", columns[i], err))
+			}
+		}
+		records = append(records, rec)
+	}
+
+	if len(records) == 0 {
+		return nil, fmt.Errorf("%w: CSV file has no rows", models.ErrInvalidCarImport)
+	}
+	return records, nil
+}
+
+// GroupCarImportImages groups the images of an import zip by CSV row. Images go in a folder
+// named after the 1-based data row (e.g. "3/front.jpg") and are ordered by file name.
+func GroupCarImportImages(files []*zip.File) (map[int][]*zip.File, error) {
+	images := make(map[int][]*zip.File)
+	for _, f := range files {
+		name := f.Name
+		base := path.Base(name)
+		if f.FileInfo().IsDir() || strings.HasPrefix(base, ".") || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		row, err := strconv.Atoi(path.Base(path.Dir(name)))
+		if err != nil || row < 1 {
+			return nil, fmt.Errorf("%w: image %q is not in a row folder (e.g. \"1/%s\")", models.ErrInvalidCarImport, name, base)
+		}
+		images[row] = append(images[row], f)
+	}
+	for _, rowImages := range images {
+		sort.Slice(rowImages, func(i, j int) bool { return rowImages[i].Name < rowImages[j].Name })
+	}
+	return images, nil
+}
+
+// CarImportService imports sellers' listings from CSV files in the background
+type CarImportService struct {
+	importRepo *models.CarImportRepository
+	carService *CarService
+}
+
+// NewCarImportService creates a new car import service
+func NewCarImportService(importRepo *models.CarImportRepository, carService *CarService) *CarImportService {
+	return &CarImportService{
+		importRepo: importRepo,
+		carService: carService,
+	}
+}
+
+// FailInterruptedImports fails imports that were pending or running when the server stopped
+func (s *CarImportService) FailInterruptedImports() (int64, error) {
+	return s.importRepo.FailUnfinishedCarImportJobs()
+}
+
+// StartImport validates an import upload and starts importing it in the background. imagesZip
+// is optional. With publish set, drafts that pass publish validation are published.
+func (s *CarImportService) StartImport(sellerID int, csvData io.Reader, imagesZip io.Reader, publish bool) (*models.CarImportJob, error) {
+	unfinished, err := s.importRepo.HasUnfinishedCarImportJob(sellerID)
+	if err != nil {
+		return nil, err
+	}
+	if unfinished {
+		return nil, models.ErrCarImportInProgress
+	}
+
+	records, err := ParseCarImportCSV(csvData)
+	if err != nil {
+		return nil, err
+	}
+
+	// The zip is kept in a temporary file until the job finishes
+	images := map[int][]*zip.File{}
+	cleanup := func() {}
+	if imagesZip != nil {
+		zr, remove, err := openImportZip(imagesZip)
+		if err != nil {
+			return nil, err
+		}
+		cleanup = remove
+		images, err = GroupCarImportImages(zr.File)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		for row := range images {
+			if row > len(records) {
+				cleanup()
+				return nil, fmt.Errorf("%w: images for row %d but the CSV has %d rows", models.ErrInvalidCarImport, row, len(records))
+			}
+		}
+	}
+
+	job := &models.CarImportJob{SellerID: sellerID, Publish: publish, TotalRows: len(records)}
+	if err := s.importRepo.CreateCarImportJob(job); err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	go s.runImport(job, records, images, cleanup)
+	return job, nil
+}
+
+// GetImport returns one of the seller's import jobs
+func (s *CarImportService) GetImport(jobID, sellerID int) (*models.CarImportJob, error) {
+	job, err := s.importRepo.GetCarImportJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.SellerID != sellerID {
+		return nil, fmt.Errorf("import job not found")
+	}
+	return job, nil
+}
+
+// openImportZip copies an uploaded zip to a temporary file and opens it. The returned
+// function closes and removes the file.
+func openImportZip(data io.Reader) (*zip.ReadCloser, func(), error) {
+	tmp, err := os.CreateTemp("", "car-import-*.zip")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to store images: %w", err)
+	}
+	name := tmp.Name()
+	_, err = io.Copy(tmp, data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(name)
+		return nil, nil, fmt.Errorf("failed to store images: %w", err)
+	}
+
+	zr, err := zip.OpenReader(name)
+	if err != nil {
+		os.Remove(name)
+		return nil, nil, fmt.Errorf("%w: images must be a zip file", models.ErrInvalidCarImport)
+	}
+	return zr, func() {
+		zr.Close()
+		os.Remove(name)
+	}, nil
+}
+
+// runImport imports every record, saving progress after each row
+func (s *CarImportService) runImport(job *models.CarImportJob, records []CarImportRecord, images map[int][]*zip.File, cleanup func()) {
+	defer cleanup()
+	logger := utils.AppLogger.WithField("import_job_id", job.ID)
+	defer func() {
+		if r := recover(); r != nil {
+			msg := fmt.Sprintf("import stopped unexpectedly: %v", r)
+			logger.Error(msg)
+			if err := s.importRepo.FinishCarImportJob(job.ID, models.CarImportFailed, &msg); err != nil {
+				logger.Error(err.Error())
+			}
+		}
+	}()
+
+	if err := s.importRepo.StartCarImportJob(job.ID); err != nil {
+		logger.Error(err.Error())
+	}
+
+	for _, rec := range records {
+		job.AddRow(s.importRecord(job.SellerID, rec, images[rec.Row], job.Publish))
+		if err := s.importRepo.UpdateCarImportProgress(job); err != nil {
+			logger.Error(err.Error())
+		}
+	}
+
+	if err := s.importRepo.FinishCarImportJob(job.ID, models.CarImportCompleted, nil); err != nil {
+		logger.Error(err.Error())
+	}
+}
+
+// importRecord creates a draft from one row and publishes it when requested and ready
+func (s *CarImportService) importRecord(sellerID int, rec CarImportRecord, images []*zip.File, publish bool) models.CarImportRowResult {
+	result := models.CarImportRowResult{Row: rec.Row, Status: models.CarImportRowFailed}
+	if len(rec.Errors) > 0 {
+		result.Errors = rec.Errors
+		return result
+	}
+
+	car, errs := s.createDraft(sellerID, rec, images)
+	if len(errs) > 0 {
+		result.Errors = errs
+		return result
+	}
+	result.CarID = &car.ID
+	result.Status = models.CarImportRowCreated
+
+	if !publish {
+		return result
+	}
+	if ready, issues := s.carService.ValidatePublish(car.ID); !ready {
+		result.Issues = issues
+		return result
+	}
+	car.Status = "active"
+	if err := s.carService.saveCar(car, "draft", car.Price); err != nil {
+		result.Issues = []string{fmt.Sprintf("failed to publish: %v", err)}
+		return result
+	}
+	s.carService.trackRevision(car.ID, models.CarRevisionActorSeller, &sellerID)
+	result.Status = models.CarImportRowPublished
+	return result
+}
+
+// createDraft validates a row against reference data, existing listings and its images, then
+// saves it as a draft through the same update path as the draft wizard. Nothing is kept when
+// the row fails.
+func (s *CarImportService) createDraft(sellerID int, rec CarImportRecord, images []*zip.File) (*models.Car, []string) {
+	cs := s.carService
+	req := rec.Request
+	var errs []string
+
+	_ = cs.mapTextFieldsToIDs(&req)
+	if req.ProvinceNameTh != nil && req.ProvinceID == nil {
+		errs = append(errs, fmt.Sprintf("province: unknown province %q", *req.ProvinceNameTh))
+	}
+	if req.BodyTypeName != nil && req.BodyTypeCode == nil {
+		errs = append(errs, fmt.Sprintf("bodyType: unknown body type %q", *req.BodyTypeName))
+	}
+	if req.TransmissionName != nil && req.TransmissionCode == nil {
+		errs = append(errs, fmt.Sprintf("transmission: unknown transmission %q", *req.TransmissionName))
+	}
+	if req.DrivetrainName != nil && req.DrivetrainCode == nil {
+		errs = append(errs, fmt.Sprintf("drivetrain: unknown drivetrain %q", *req.DrivetrainName))
+	}
+
+	fuelCodes := req.FuelCodes
+	if len(req.FuelLabels) > 0 {
+		codes, err := cs.carRepo.LookupFuelCodesByLabels(req.FuelLabels)
+		if err != nil {
+			errs = append(errs, err.Error())
+		} else if len(codes) < len(req.FuelLabels) {
+			errs = append(errs, fmt.Sprintf("fuelTypes: unknown fuel type in %q", strings.Join(req.FuelLabels, "|")))
+		} else {
+			fuelCodes = append(fuelCodes, codes...)
+		}
+	}
+
+	if len(rec.ColorCodes) > 3 {
+		errs = append(errs, "colors: at most 3 colors are allowed")
+	}
+
+	if req.ChassisNumber != nil {
+		chassis := utils.NormalizeChassis(*req.ChassisNumber)
+		req.ChassisNumber = &chassis
+		existing, err := cs.carRepo.FindCarsByChassisNumber(chassis)
+		if err != nil {
+			errs = append(errs, err.Error())
+		}
+		for _, other := range existing {
+			if other.Status != "deleted" {
+				errs = append(errs, fmt.Sprintf("chassisNumber: %s is already used by car %d", chassis, other.ID))
+				break
+			}
+		}
+	}
+
+	if len(images) > MaxImagesPerCar {
+		errs = append(errs, fmt.Sprintf("images: %d images found (max %d)", len(images), MaxImagesPerCar))
+	}
+	for _, f := range images {
+		if err := checkImportImage(f); err != nil {
+			errs = append(errs, "images: "+err.Error())
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	car, err := cs.CreateCar(sellerID)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+	discard := func(err error) (*models.Car, []string) {
+		if delErr := cs.deleteCar(car.ID); delErr != nil {
+			utils.AppLogger.WithField("car_id", car.ID).Error("Failed to discard imported draft: " + delErr.Error())
+		}
+		return nil, []string{err.Error()}
+	}
+
+	// Document fields (normally from the registration book) are not set by applyCarUpdates
+	cs.applyCarUpdates(car, &req)
+	car.ChassisNumber = req.ChassisNumber
+	if req.BrandName != nil {
+		car.BrandName = req.BrandName
+	}
+	if req.EngineCC != nil {
+		car.EngineCC = req.EngineCC
+	}
+	if err := cs.saveCar(car, car.Status, nil); err != nil {
+		return discard(err)
+	}
+
+	if len(fuelCodes) > 0 {
+		if err := cs.fuelRepo.SetCarFuels(car.ID, fuelCodes); err != nil {
+			return discard(err)
+		}
+	}
+	if len(rec.ColorCodes) > 0 {
+		if err := cs.colorRepo.SetCarColors(car.ID, rec.ColorCodes); err != nil {
+			return discard(err)
+		}
+	}
+	for i, f := range images {
+		if err := cs.storeImportImage(car.ID, f, i); err != nil {
+			return discard(err)
+		}
+	}
+
+	cs.trackRevision(car.ID, models.CarRevisionActorSeller, &sellerID)
+	return car, nil
+}
+
+// checkImportImage checks an image's size and type without reading all of it
+func checkImportImage(f *zip.File) error {
+	name := path.Base(f.Name)
+	if f.UncompressedSize64 > MaxImageSize {
+		return fmt.Errorf("image %s exceeds maximum size of 50MB", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open image %s: %w", name, err)
+	}
+	defer rc.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(rc, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to read image %s: %w", name, err)
+	}
+	if contentType := http.DetectContentType(head[:n]); !AllowedImageTypes[contentType] {
+		return fmt.Errorf("invalid image type %s for file %s (allowed: JPEG, PNG, WebP, GIF)", contentType, name)
+	}
+	return nil
+}
+
+// storeImportImage cleans an image checked by checkImportImage and saves it to the image store
+func (s *CarService) storeImportImage(carID int, f *zip.File, displayOrder int) error {
+	name := path.Base(f.Name)
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open image %s: %w", name, err)
+	}
+	data, err := io.ReadAll(io.LimitReader(rc, MaxImageSize+1))
+	rc.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read image %s: %w", name, err)
+	}
+	if len(data) > MaxImageSize {
+		return fmt.Errorf("image %s exceeds maximum size of 50MB", name)
+	}
+
+	contentType := http.DetectContentType(data)
+	cleaned, err := cleanImage(data, contentType)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, name)
+	}
+	if _, err := s.storeCarImage(carID, cleaned, contentType, displayOrder); err != nil {
+		return fmt.Errorf("failed to save image %s: %w", name, err)
+	}
+	return nil
+}
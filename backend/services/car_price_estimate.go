@@ -0,0 +1,218 @@
+package services
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+// priceEstimateUncertainty widens the confidence range of an estimate, as a fraction of the
+// price, for each of the condition rating, mileage and inspection that is unknown
+const priceEstimateUncertainty = 0.05
+
+// EstimateCarPrice calculates an estimated price based on market data and car condition
+func (s *CarService) EstimateCarPrice(carID int) (int64, error) {
+	estimate, err := s.ExplainCarPrice(carID)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.EstimatedPrice, nil
+}
+
+// ExplainCarPrice estimates a car's price and returns the market data and adjustments the
+// estimate is based on
+func (s *CarService) ExplainCarPrice(carID int) (*models.EstimatedPriceResponse, error) {
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return nil, fmt.Errorf("car not found: %w", err)
+	}
+
+	insp, err := s.inspectionRepo.GetInspectionByCarID(carID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("failed to check inspection data: %w", err)
+	}
+
+	if car.BrandName == nil || car.ModelName == nil || car.SubmodelName == nil || car.Year == nil {
+		return nil, fmt.Errorf("estimation unavailable: missing brand, model, submodel, or year")
+	}
+
+	marketPrice, match, err := s.findMarketPrice(*car.BrandName, *car.ModelName, *car.SubmodelName, *car.Year)
+	if err != nil {
+		return nil, fmt.Errorf("estimation unavailable: no matching market data found")
+	}
+
+	estimate, err := CalculatePriceEstimate(marketPrice, priceEstimateInputForCar(car, insp), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	estimate.Match = match
+	return estimate, nil
+}
+
+// EstimateSpecPrice estimates the price of a car described by its specs, such as one a buyer
+// wants to trade in, with the same adjustments as a listed car's estimate
+func (s *CarService) EstimateSpecPrice(req models.ValuationRequest) (*models.EstimatedPriceResponse, error) {
+	brand := strings.TrimSpace(req.BrandName)
+	model := strings.TrimSpace(req.ModelName)
+	submodel := strings.TrimSpace(req.SubmodelName)
+	if brand == "" || model == "" || submodel == "" || req.Year == 0 {
+		return nil, fmt.Errorf("invalid request: brandName, modelName, submodelName and year are required")
+	}
+	if maxYear := time.Now().Year() + 1; req.Year < 1900 || req.Year > maxYear {
+		return nil, fmt.Errorf("invalid request: year must be between 1900 and %d", maxYear)
+	}
+	if req.Mileage != nil && *req.Mileage < 0 {
+		return nil, fmt.Errorf("invalid request: mileage cannot be negative")
+	}
+	if req.ConditionRating != nil && (*req.ConditionRating < 1 || *req.ConditionRating > 5) {
+		return nil, fmt.Errorf("invalid request: conditionRating must be between 1 and 5")
+	}
+
+	marketPrice, match, err := s.findMarketPrice(brand, model, submodel, req.Year)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return nil, fmt.Errorf("estimation unavailable: no matching market data found")
+		}
+		return nil, err
+	}
+
+	estimate, err := CalculatePriceEstimate(marketPrice, PriceEstimateInput{
+		Year:             req.Year,
+		Mileage:          req.Mileage,
+		ConditionRating:  req.ConditionRating,
+		IsFlooded:        req.IsFlooded,
+		IsHeavilyDamaged: req.IsHeavilyDamaged,
+	}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	estimate.Match = match
+	return estimate, nil
+}
+
+// PriceEstimateInput is what a price estimate adjusts the market price for
+type PriceEstimateInput struct {
+	Year             int
+	Mileage          *int // km
+	ConditionRating  *int // 1-5
+	IsFlooded        bool
+	IsHeavilyDamaged bool
+	// Inspection results including "overallPass" (nil when the car has no inspection).
+	// Items without a recorded result count as failed.
+	Inspection []models.InspectionItem
+}
+
+// priceEstimateInputForCar returns the input of a stored car's estimate
+func priceEstimateInputForCar(car *models.Car, insp *models.InspectionResult) PriceEstimateInput {
+	input := PriceEstimateInput{
+		Year:             *car.Year,
+		Mileage:          car.Mileage,
+		ConditionRating:  car.ConditionRating,
+		IsFlooded:        car.IsFlooded,
+		IsHeavilyDamaged: car.IsHeavilyDamaged,
+	}
+	if insp != nil {
+		input.Inspection = append([]models.InspectionItem{{Name: "overallPass", Result: insp.OverallPass}}, insp.Items()...)
+	}
+	return input
+}
+
+// CalculatePriceEstimate prices a car from its matching market price. The midpoint of the
+// market range is adjusted for the car's condition rating, mileage per year, flood and heavy
+// damage, and inspection; each adjustment is listed with its contribution. The confidence
+// range applies the same adjustments to the ends of the market range, widened for every
+// input that is unknown. Prices are rounded to the nearest 1,000 THB.
+func CalculatePriceEstimate(marketPrice *models.MarketPrice, input PriceEstimateInput, now time.Time) (*models.EstimatedPriceResponse, error) {
+	basePrice := (marketPrice.PriceMinTHB + marketPrice.PriceMaxTHB) / 2
+	if basePrice <= 0 {
+		return nil, fmt.Errorf("invalid base price from market data")
+	}
+
+	estimate := &models.EstimatedPriceResponse{
+		BasePrice:   basePrice,
+		MarketPrice: marketPrice,
+		Adjustments: []models.PriceAdjustment{},
+	}
+	totalPercent := 0.0
+	add := func(adj models.PriceAdjustment) {
+		adj.Amount = int64(math.Round(float64(basePrice) * adj.Percent / 100))
+		estimate.Adjustments = append(estimate.Adjustments, adj)
+		totalPercent += adj.Percent
+	}
+	unknown := 0
+
+	// Rating 3 is average; each step is worth 5%
+	if input.ConditionRating != nil {
+		rating := *input.ConditionRating
+		add(models.PriceAdjustment{
+			Factor:  models.PriceFactorCondition,
+			Value:   &rating,
+			Percent: float64(rating-3) * 5,
+		})
+	} else {
+		unknown++
+	}
+
+	if input.Mileage != nil {
+		carAge := now.Year() - input.Year + 1
+		if carAge <= 0 {
+			carAge = 1
+		}
+		mileagePerYear := float64(*input.Mileage) / float64(carAge)
+		percent := 0.0
+		if mileagePerYear < 15000 {
+			percent = 5
+		} else if mileagePerYear > 30000 {
+			percent = -5
+		}
+		perYear := int(math.Round(mileagePerYear))
+		add(models.PriceAdjustment{
+			Factor:  models.PriceFactorMileage,
+			Value:   &perYear,
+			Percent: percent,
+		})
+	} else {
+		unknown++
+	}
+
+	if input.IsFlooded {
+		add(models.PriceAdjustment{Factor: models.PriceFactorFlooded, Percent: -30})
+	}
+	if input.IsHeavilyDamaged {
+		add(models.PriceAdjustment{Factor: models.PriceFactorHeavilyDamaged, Percent: -40})
+	}
+
+	if input.Inspection != nil {
+		var failed []string
+		for _, item := range input.Inspection {
+			if item.Result == nil || !*item.Result {
+				failed = append(failed, item.Name)
+			}
+		}
+		if len(failed) == 0 {
+			add(models.PriceAdjustment{Factor: models.PriceFactorInspectionPassed, Percent: 20})
+		}
+		for _, name := range failed {
+			add(models.PriceAdjustment{Factor: models.PriceFactorInspectionFailed, Item: name, Percent: -1})
+		}
+	} else {
+		unknown++
+	}
+
+	factor := 1 + totalPercent/100
+	spread := priceEstimateUncertainty * float64(unknown)
+	estimate.AdjustmentFactor = factor
+	estimate.EstimatedPrice = roundEstimatedPrice(float64(basePrice) * factor)
+	estimate.LowPrice = roundEstimatedPrice(float64(marketPrice.PriceMinTHB) * factor * (1 - spread))
+	estimate.HighPrice = roundEstimatedPrice(float64(marketPrice.PriceMaxTHB) * factor * (1 + spread))
+	return estimate, nil
+}
+
+// roundEstimatedPrice rounds a price to the nearest 1,000 THB, never below zero
+func roundEstimatedPrice(price float64) int64 {
+	return int64(math.Max(0, math.Round(price/1000)*1000))
+}
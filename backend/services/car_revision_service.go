@@ -0,0 +1,213 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// snapshotCar captures the current state of a car, its colors, fuels and image order
+func (s *CarService) snapshotCar(carID int) (models.CarSnapshot, error) {
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return models.CarSnapshot{}, err
+	}
+	colors, err := s.colorRepo.GetCarColors(carID)
+	if err != nil {
+		return models.CarSnapshot{}, fmt.Errorf("failed to get car colors: %w", err)
+	}
+	fuels, err := s.fuelRepo.GetCarFuels(carID)
+	if err != nil {
+		return models.CarSnapshot{}, fmt.Errorf("failed to get car fuels: %w", err)
+	}
+	images, err := s.imageRepo.GetCarImagesMetadata(carID)
+	if err != nil {
+		return models.CarSnapshot{}, fmt.Errorf("failed to get car images: %w", err)
+	}
+	imageIDs := make([]int, len(images))
+	for i, img := range images {
+		imageIDs[i] = img.ID
+	}
+	return models.NewCarSnapshot(car, colors, fuels, imageIDs), nil
+}
+
+// recordRevision stores a revision of the car's current state if it differs from the latest one
+func (s *CarService) recordRevision(carID int, actorType string, actorID *int, revertedFromID *int) (*models.CarRevision, error) {
+	snapshot, err := s.snapshotCar(carID)
+	if err != nil {
+		return nil, err
+	}
+
+	latest, err := s.revisionRepo.GetLatestCarRevision(carID)
+	if err != nil {
+		return nil, err
+	}
+	var previous models.CarSnapshot
+	if latest != nil {
+		previous = latest.Snapshot
+	}
+
+	changes := models.DiffCarSnapshots(previous, snapshot)
+	if latest != nil && len(changes) == 0 && revertedFromID == nil {
+		return latest, nil
+	}
+
+	changedFields := make([]string, len(changes))
+	for i, c := range changes {
+		changedFields[i] = c.Field
+	}
+
+	rev := &models.CarRevision{
+		CarID:          carID,
+		ActorType:      actorType,
+		ActorID:        actorID,
+		ChangedFields:  changedFields,
+		Snapshot:       snapshot,
+		RevertedFromID: revertedFromID,
+	}
+	if err := s.revisionRepo.CreateCarRevision(rev); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// trackRevision records a revision after a successful edit. Failures are logged rather than
+// returned since the edit itself has already been saved.
+func (s *CarService) trackRevision(carID int, actorType string, actorID *int) {
+	if _, err := s.recordRevision(carID, actorType, actorID, nil); err != nil {
+		utils.AppLogger.WithField("car_id", carID).Error("Failed to record car revision: " + err.Error())
+	}
+}
+
+// trackEdit records a revision after an edit through the seller-facing endpoints
+func (s *CarService) trackEdit(carID, userID int, isAdmin bool) {
+	if isAdmin {
+		// Admins editing through the seller endpoints are not identified by admin ID
+		s.trackRevision(carID, models.CarRevisionActorAdmin, nil)
+		return
+	}
+	s.trackRevision(carID, models.CarRevisionActorSeller, &userID)
+}
+
+// GetCarRevisions returns a car's revision history, newest first
+func (s *CarService) GetCarRevisions(carID int) ([]models.CarRevision, error) {
+	if _, err := s.carRepo.GetCarByID(carID); err != nil {
+		return nil, err
+	}
+	return s.revisionRepo.GetCarRevisions(carID)
+}
+
+// GetFirstPublishedRevisionID returns the ID of the revision in which the car was first active
+func (s *CarService) GetFirstPublishedRevisionID(carID int) (int, error) {
+	rev, err := s.revisionRepo.GetFirstPublishedCarRevision(carID)
+	if err != nil {
+		return 0, err
+	}
+	return rev.ID, nil
+}
+
+// DiffCarRevisions returns the field-level changes between two revisions of a car.
+// toID 0 means the latest revision; fromID 0 means the revision just before toID.
+func (s *CarService) DiffCarRevisions(carID, fromID, toID int) (*models.CarRevisionDiff, error) {
+	var to *models.CarRevision
+	var err error
+	if toID > 0 {
+		to, err = s.revisionRepo.GetCarRevision(carID, toID)
+	} else {
+		to, err = s.revisionRepo.GetLatestCarRevision(carID)
+		if err == nil && to == nil {
+			err = fmt.Errorf("revision not found")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var from *models.CarRevision
+	if fromID > 0 {
+		from, err = s.revisionRepo.GetCarRevision(carID, fromID)
+	} else {
+		from, err = s.revisionRepo.GetPreviousCarRevision(carID, to.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.CarRevisionDiff{CarID: carID, ToRevisionID: to.ID}
+	var fromSnapshot models.CarSnapshot
+	if from != nil {
+		diff.FromRevisionID = &from.ID
+		fromSnapshot = from.Snapshot
+	}
+	diff.Changes = models.DiffCarSnapshots(fromSnapshot, to.Snapshot)
+	return diff, nil
+}
+
+// RevertCarToRevision restores a car's fields, colors, fuels and image order to a previous
+// revision in one transaction and records the result as a new revision. The status is kept
+// as it is; use the status endpoints to change it. Image data is not versioned, so images
+// deleted since cannot be restored and images added since are kept at the end.
+func (s *CarService) RevertCarToRevision(carID, revisionID, adminID int) (*models.CarRevision, error) {
+	target, err := s.revisionRepo.GetCarRevision(carID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return nil, err
+	}
+	images, err := s.imageRepo.GetCarImagesMetadata(carID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get car images: %w", err)
+	}
+
+	oldPrice := car.Price
+	target.Snapshot.ApplyTo(car)
+
+	// Price changes of published listings go to the price history, as in saveCar
+	var priceChangedFrom *int
+	if IsPublishedStatus(car.Status) && oldPrice != nil && car.Price != nil && *oldPrice != *car.Price {
+		priceChangedFrom = oldPrice
+	}
+
+	order := revertedImageOrder(target.Snapshot.ImageIDs, images)
+	if err := s.carRepo.RevertCar(car, priceChangedFrom, target.Snapshot.Colors, target.Snapshot.FuelTypes, order); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.recordRevision(carID, models.CarRevisionActorAdmin, &adminID, &revisionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record revert: %w", err)
+	}
+
+	utils.AppLogger.LogAdminAction(adminID, "revert_car", fmt.Sprintf("car:%d", carID), map[string]interface{}{
+		"revision_id": revisionID,
+	})
+	return rev, nil
+}
+
+// revertedImageOrder orders the car's current images as in the revision, followed by
+// images that were added after it
+func revertedImageOrder(revisionImageIDs []int, current []models.CarImageMetadata) []int {
+	exists := make(map[int]bool, len(current))
+	for _, img := range current {
+		exists[img.ID] = true
+	}
+
+	order := make([]int, 0, len(current))
+	placed := make(map[int]bool, len(current))
+	for _, id := range revisionImageIDs {
+		if exists[id] && !placed[id] {
+			order = append(order, id)
+			placed[id] = true
+		}
+	}
+	for _, img := range current {
+		if !placed[img.ID] {
+			order = append(order, img.ID)
+		}
+	}
+	return order
+}
@@ -1,15 +1,17 @@
 package services
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"io"
-	"math"
 	"mime/multipart"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/storage"
 	"github.com/uzimpp/CarJai/backend/utils"
 )
 
@@ -30,6 +32,7 @@ const (
 	ErrCodeCarDuplicateOwnDraft         = "CAR_DUPLICATE_OWN_DRAFT"
 	ErrCodeCarDuplicateOwnActive        = "CAR_DUPLICATE_OWN_ACTIVE"
 	ErrCodeCarDuplicateOwnSold          = "CAR_DUPLICATE_OWN_SOLD"
+	ErrCodeCarDuplicateOwnExpired       = "CAR_DUPLICATE_OWN_EXPIRED"
 	ErrCodeCarDuplicateOwnDeleted       = "CAR_DUPLICATE_OWN_DELETED"
 	ErrCodeCarDuplicateOtherOwned       = "CAR_DUPLICATE_OTHER_OWNED"
 	ErrCodeCarMultipleDrafts            = "CAR_MULTIPLE_DRAFTS"
@@ -66,7 +69,17 @@ type CarService struct {
 	colorRepo       *models.CarColorRepository
 	fuelRepo        *models.CarFuelRepository
 	marketPriceRepo *models.MarketPriceRepository
+	revisionRepo    *models.CarRevisionRepository
+	imageStore      storage.ImageStore
 	translator      *CarTranslator
+	similarCarsOpts models.SimilarCarsOptions
+	// Refuse to publish listings with a photo matching another seller's active listing
+	blockDuplicatePhotos bool
+	// Alerts buyers whose saved searches match a newly published listing (optional)
+	savedSearchService *SavedSearchService
+
+	derivativeMu    sync.Mutex
+	derivativeCalls map[int]*derivativeCall // By image ID
 }
 
 // NewCarService creates a new car service
@@ -77,6 +90,8 @@ func NewCarService(
 	colorRepo *models.CarColorRepository,
 	fuelRepo *models.CarFuelRepository,
 	marketPriceRepo *models.MarketPriceRepository,
+	revisionRepo *models.CarRevisionRepository,
+	imageStore storage.ImageStore,
 ) *CarService {
 	return &CarService{
 		carRepo:         carRepo,
@@ -85,119 +100,13 @@ func NewCarService(
 		colorRepo:       colorRepo,
 		fuelRepo:        fuelRepo,
 		marketPriceRepo: marketPriceRepo,
+		revisionRepo:    revisionRepo,
+		imageStore:      imageStore,
 		translator:      NewCarTranslator(carRepo, imageRepo, fuelRepo, colorRepo),
+		similarCarsOpts: models.DefaultSimilarCarsOptions(),
 	}
 }
 
-// --- EstimateCarPrice ---
-// EstimateCarPrice calculates an estimated price based on market data and car condition
-func (s *CarService) EstimateCarPrice(carID int) (int64, error) {
-	// 1. Get car data
-	car, err := s.carRepo.GetCarByID(carID)
-	if err != nil {
-		return 0, fmt.Errorf("car not found: %w", err)
-	}
-
-	insp, err := s.inspectionRepo.GetInspectionByCarID(carID)
-	if err != nil && err != sql.ErrNoRows {
-		return 0, fmt.Errorf("failed to check inspection data: %w", err)
-	}
-
-	// 3. Check for required fields for estimation
-
-	if car.BrandName == nil || car.ModelName == nil || car.SubmodelName == nil || car.Year == nil {
-		return 0, fmt.Errorf("estimation unavailable: missing brand, model, submodel, or year")
-	}
-
-	// 4. Get base market price
-	marketPrice, err := s.marketPriceRepo.GetMarketPrice(*car.BrandName, *car.ModelName, *car.SubmodelName, *car.Year)
-	if err != nil {
-		return 0, fmt.Errorf("estimation unavailable: no matching market data found")
-	}
-
-	// 5. Calculate base price (average of min/max)
-	basePrice := (marketPrice.PriceMinTHB + marketPrice.PriceMaxTHB) / 2
-	if basePrice <= 0 {
-		return 0, fmt.Errorf("invalid base price from market data")
-	}
-
-	// 6. Calculate Condition Score (Adjustment Factor)
-	adjustmentFactor := 1.0
-
-	if car.ConditionRating != nil {
-		adjustmentFactor += (float64(*car.ConditionRating) - 3.0) * 0.05
-	}
-
-	if car.Mileage != nil {
-		carAge := time.Now().Year() - *car.Year + 1
-		if carAge <= 0 {
-			carAge = 1
-		}
-		mileagePerYear := float64(*car.Mileage) / float64(carAge)
-
-		if mileagePerYear < 15000 {
-			adjustmentFactor += 0.05
-		} else if mileagePerYear > 30000 {
-			adjustmentFactor -= 0.05
-		}
-	}
-
-	if car.IsFlooded {
-		adjustmentFactor -= 0.30
-	}
-	if car.IsHeavilyDamaged {
-		adjustmentFactor -= 0.40
-	}
-
-	if insp != nil {
-
-		inspectionFields := []*bool{
-			insp.OverallPass,
-			insp.BrakeResult,
-			insp.HandbrakeResult,
-			insp.AlignmentResult,
-			insp.NoiseResult,
-			insp.EmissionResult,
-			insp.HornResult,
-			insp.SpeedometerResult,
-			insp.HighLowBeamResult,
-			insp.SignalLightsResult,
-			insp.OtherLightsResult,
-			insp.WindshieldResult,
-			insp.SteeringResult,
-			insp.WheelsTiresResult,
-			insp.FuelTankResult,
-			insp.ChassisResult,
-			insp.BodyResult,
-			insp.DoorsFloorResult,
-			insp.SeatbeltResult,
-			insp.WiperResult,
-		}
-
-		failedCount := 0
-		for _, field := range inspectionFields {
-			if field == nil || !*field {
-				failedCount++
-			}
-		}
-
-		if failedCount == 0 {
-			adjustmentFactor += 0.20
-		} else {
-			penalty := float64(failedCount) * 0.01
-			adjustmentFactor -= penalty
-		}
-	}
-
-	// 8. Calculate final price
-	finalPrice := float64(basePrice) * adjustmentFactor
-
-	// Round to nearest 1,000 THB
-	estimatedPrice := int64(math.Round(finalPrice/1000) * 1000)
-
-	return estimatedPrice, nil
-}
-
 // CreateCar creates a new empty draft car (ephemeral)
 // All fields are initialized to defaults; actual data comes from book upload
 func (s *CarService) CreateCar(sellerID int) (*models.Car, error) {
@@ -212,6 +121,7 @@ func (s *CarService) CreateCar(sellerID int) (*models.Car, error) {
 		return nil, err
 	}
 
+	s.trackRevision(car.ID, models.CarRevisionActorSeller, &sellerID)
 	return car, nil
 }
 
@@ -229,27 +139,81 @@ func (s *CarService) GetManagedCars() (*[]models.AdminManagedCar, error) {
 	return cars, nil
 }
 
-func (s *CarService) UpdateCarByAdmin(carID int, req models.AdminUpdateCarRequest) (*models.Car, error) {
-	updatedCar, err := s.carRepo.UpdateCarByAdmin(carID, req)
+// UpdateCarByAdmin handles admin edits of a car from the admin panel
+func (s *CarService) UpdateCarByAdmin(carID int, req models.AdminUpdateCarRequest, adminID int) (*models.Car, error) {
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Admins follow the listing state machine too
+	if req.Status != nil {
+		if err := ValidateCarStatusTransition(car.Status, *req.Status); err != nil {
+			return nil, err
+		}
+	}
+
+	// Publishing needs a complete listing, as for sellers
+	if req.Status != nil && IsPublishedStatus(*req.Status) && !IsPublishedStatus(car.Status) {
+		if ready, issues := s.ValidatePublish(carID); !ready {
+			return nil, fmt.Errorf("cannot publish car: %v", issues)
+		}
+	}
+
+	// Save through saveCar so a repriced published car is recorded in its price history
+	previousStatus, oldPrice := car.Status, car.Price
+	applyAdminCarUpdates(car, req)
+	if err := s.saveCar(car, previousStatus, oldPrice); err != nil {
+		return nil, err
+	}
+
+	updatedCar, err := s.carRepo.GetCarByID(carID)
 	if err != nil {
 		return nil, err
 	}
 
+	s.trackRevision(carID, models.CarRevisionActorAdmin, &adminID)
 	return updatedCar, nil
 }
 
+// applyAdminCarUpdates applies the fields set in an admin update request to a car
+func applyAdminCarUpdates(car *models.Car, req models.AdminUpdateCarRequest) {
+	if req.BrandName != nil {
+		car.BrandName = req.BrandName
+	}
+	if req.ModelName != nil {
+		car.ModelName = req.ModelName
+	}
+	if req.SubmodelName != nil {
+		car.SubmodelName = req.SubmodelName
+	}
+	if req.Year != nil {
+		car.Year = req.Year
+	}
+	if req.Price != nil {
+		car.Price = req.Price
+	}
+	if req.Mileage != nil {
+		car.Mileage = req.Mileage
+	}
+	if req.Status != nil {
+		car.Status = *req.Status
+	}
+}
+
 // CreateCarByAdmin handles admin creation of a new car
-func (s *CarService) CreateCarByAdmin(req models.AdminCreateCarRequest) (*models.Car, error) {
+func (s *CarService) CreateCarByAdmin(req models.AdminCreateCarRequest, adminID int) (*models.Car, error) {
 	newCar, err := s.carRepo.CreateCarByAdmin(req)
 	if err != nil {
 		return nil, err
 	}
+	s.trackRevision(newCar.ID, models.CarRevisionActorAdmin, &adminID)
 	return newCar, nil
 }
 
 // DeleteCarByAdmin handles admin deletion of a car
 func (s *CarService) DeleteCarByAdmin(carID int) error {
-	err := s.carRepo.DeleteCar(carID)
+	err := s.deleteCar(carID)
 	if err != nil {
 		return err
 	}
@@ -307,6 +271,11 @@ func (s *CarService) batchTranslateCarsToListItems(cars []models.Car, lang strin
 		return nil, fmt.Errorf("failed to batch fetch colors: %w", err)
 	}
 
+	priceChangesMap, err := s.carRepo.GetLatestPriceChangesBatch(carIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Batch fetch labels
 	bodyTypeLabels := make(map[string]string)
 	if len(bodyTypeCodes) > 0 {
@@ -408,6 +377,15 @@ func (s *CarService) batchTranslateCarsToListItems(cars []models.Car, lang strin
 			return nil, fmt.Errorf("failed to translate car %d: %w", car.ID, err)
 		}
 
+		// Price drop badge, unless the price moved again since (e.g. while unlisted)
+		if change, ok := priceChangesMap[car.ID]; ok && change.IsRecentDrop(car.Price, time.Now()) {
+			previousPrice := change.OldPrice
+			item.PriceDropped = true
+			item.PreviousPrice = &previousPrice
+		}
+		item.ExpiresAt = car.ExpiresAt
+		item.ReservedUntil = car.ReservedUntil
+
 		items = append(items, item)
 	}
 
@@ -433,11 +411,10 @@ func (s *CarService) GetCarListItemsByIDs(carIDs []int, lang string) ([]models.C
 // GetCarListItemsBySellerID retrieves lightweight car list items for a seller
 // Returns only essential fields needed for listing/display, with translated labels
 // Used for: seller profile, seller dashboard listings
-// If status is empty string, returns all cars with all statuses
-// If status is specified (e.g., "active"), returns only cars with that status
-func (s *CarService) GetCarListItemsBySellerID(sellerID int, lang string, status string) ([]models.CarListItem, error) {
-	// Pass status as-is: empty string gets all statuses, specified status filters by that status
-	cars, err := s.carRepo.GetCarsBySellerID(sellerID, status)
+// If statuses is empty, returns all cars with all statuses
+// If statuses are specified (e.g., PublishedStatuses), returns only cars with one of them
+func (s *CarService) GetCarListItemsBySellerID(sellerID int, lang string, statuses []string) ([]models.CarListItem, error) {
+	cars, err := s.carRepo.GetCarsBySellerID(sellerID, statuses)
 	if err != nil {
 		return nil, err
 	}
@@ -445,9 +422,38 @@ func (s *CarService) GetCarListItemsBySellerID(sellerID int, lang string, status
 	return s.batchTranslateCarsToListItems(cars, lang)
 }
 
+// GetCarListItemsBySellerIDPage retrieves one keyset-paginated page of a seller's car list items
+// Returns the items and the cursor for the next page (empty on the last page)
+func (s *CarService) GetCarListItemsBySellerIDPage(sellerID int, lang string, statuses []string, after *models.CarCursor, limit int) ([]models.CarListItem, string, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+
+	// Fetch one extra row to know whether another page exists
+	cars, err := s.carRepo.GetCarsBySellerIDPage(sellerID, statuses, after, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(cars) > limit {
+		cars = cars[:limit]
+		nextCursor = models.NewCarCursor(&cars[limit-1], "created_at", "desc").Encode()
+	}
+
+	items, err := s.batchTranslateCarsToListItems(cars, lang)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return items, nextCursor, nil
+}
+
 // SearchActiveCarsAsListItems retrieves active car listings as lightweight list items
-// Returns CarListItem with translated labels for optimal performance in browse/search
-func (s *CarService) SearchActiveCarsAsListItems(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error) {
+// Returns CarListItem with translated labels for optimal performance in browse/search,
+// the total count (-1 when req.SkipCount is set) and the cursor for the next page
+// (empty on the last page, and for relevance and distance sorts which only page by offset)
+func (s *CarService) SearchActiveCarsAsListItems(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
 	// Set defaults
 	if req.Status == "" {
 		req.Status = "active"
@@ -462,19 +468,137 @@ func (s *CarService) SearchActiveCarsAsListItems(req *models.SearchCarsRequest,
 		lang = "en"
 	}
 
-	// Get cars from repository
+	// Fetch one extra row to know whether another page exists
+	limit := req.Limit
+	req.Limit = limit + 1
 	cars, total, err := s.carRepo.GetActiveCars(req)
+	req.Limit = limit
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
+	}
+
+	nextCursor := ""
+	if len(cars) > limit {
+		cars = cars[:limit]
+		if !req.UsesRelevanceSort() && !req.UsesDistanceSort() {
+			nextCursor = models.NewCarCursor(&cars[limit-1], req.SortBy, req.SortOrder).Encode()
+		}
 	}
 
 	// Convert to CarListItem using batch translation
 	items, err := s.batchTranslateCarsToListItems(cars, lang)
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
+	}
+
+	return items, total, nextCursor, nil
+}
+
+// GetSearchFacets returns per-filter counts of active cars for the search sidebar,
+// with code labels translated into the requested language
+func (s *CarService) GetSearchFacets(req *models.SearchCarsRequest, lang string) (*models.SearchFacets, error) {
+	if req.Status == "" {
+		req.Status = "active"
+	}
+	if lang == "" {
+		lang = "en"
+	}
+
+	facets, err := s.carRepo.GetSearchFacets(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Labels are best effort; a failed lookup leaves the code without a label
+	if labels, err := s.carRepo.GetBodyTypeLabelsByCodes(facetCodes(facets.BodyTypes), lang); err == nil {
+		applyFacetLabels(facets.BodyTypes, labels)
+	}
+	if labels, err := s.carRepo.GetTransmissionLabelsByCodes(facetCodes(facets.Transmissions), lang); err == nil {
+		applyFacetLabels(facets.Transmissions, labels)
+	}
+	if labels, err := s.carRepo.GetDrivetrainLabelsByCodes(facetCodes(facets.Drivetrains), lang); err == nil {
+		applyFacetLabels(facets.Drivetrains, labels)
+	}
+	if labels, err := s.carRepo.GetFuelLabelsByCodesMap(facetCodes(facets.FuelTypes), lang); err == nil {
+		applyFacetLabels(facets.FuelTypes, labels)
+	}
+	if labels, err := s.carRepo.GetColorLabelsByCodesMap(facetCodes(facets.Colors), lang); err == nil {
+		applyFacetLabels(facets.Colors, labels)
+	}
+
+	provinceIDs := make([]int, len(facets.Provinces))
+	for i, p := range facets.Provinces {
+		provinceIDs[i] = p.ID
+	}
+	if labels, err := s.carRepo.GetProvinceLabelsByIDs(provinceIDs, lang); err == nil {
+		for i := range facets.Provinces {
+			facets.Provinces[i].Label = labels[facets.Provinces[i].ID]
+		}
 	}
 
-	return items, total, nil
+	return facets, nil
+}
+
+// SetSimilarCarWeights replaces the weights used to rank similar cars
+func (s *CarService) SetSimilarCarWeights(weights models.SimilarCarWeights) {
+	s.similarCarsOpts.Weights = weights
+}
+
+// SimilarCarWeights returns the weights used to rank similar cars
+func (s *CarService) SimilarCarWeights() models.SimilarCarWeights {
+	return s.similarCarsOpts.Weights
+}
+
+// GetSimilarCarListItems returns active cars similar to an active car as list items
+func (s *CarService) GetSimilarCarListItems(carID int, lang string, limit int) ([]models.CarListItem, error) {
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return nil, err
+	}
+	// Only listings visible to the public have recommendations
+	if !IsPublishedStatus(car.Status) {
+		return nil, fmt.Errorf("car not found")
+	}
+
+	cars, err := s.carRepo.GetSimilarCars(carID, s.similarCarsOpts, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.batchTranslateCarsToListItems(cars, lang)
+}
+
+// LookupProvinceIDByName finds a province ID by its Thai or English name (nil when unknown)
+func (s *CarService) LookupProvinceIDByName(name string) (*int, error) {
+	return s.carRepo.LookupProvinceByName(name)
+}
+
+// CarMatchesSearch reports whether a car satisfies the filters of a search request
+func (s *CarService) CarMatchesSearch(carID int, req *models.SearchCarsRequest) (bool, error) {
+	if req.Status == "" {
+		req.Status = "active"
+	}
+	return s.carRepo.CarMatchesSearch(carID, req)
+}
+
+// facetCodes returns the codes of the given facet counts
+func facetCodes(counts []models.SearchFacetCount) []string {
+	codes := make([]string, len(counts))
+	for i, c := range counts {
+		codes[i] = c.Code
+	}
+	return codes
+}
+
+// applyFacetLabels sets each facet's label from labels, falling back to the code
+func applyFacetLabels(counts []models.SearchFacetCount, labels map[string]string) {
+	for i := range counts {
+		if label, ok := labels[counts[i].Code]; ok {
+			counts[i].Label = label
+		} else {
+			counts[i].Label = counts[i].Code
+		}
+	}
 }
 
 // UpdateCar updates a car listing with step-2 guards and validations
@@ -490,6 +614,13 @@ func (s *CarService) UpdateCar(carID, userID int, req *models.UpdateCarRequest,
 		return err
 	}
 
+	// Enforce the listing state machine (for admins too)
+	if req.Status != nil {
+		if err := ValidateCarStatusTransition(car.Status, *req.Status); err != nil {
+			return err
+		}
+	}
+
 	// Prevent editing sold cars (unless admin or changing status away from sold)
 	if car.Status == "sold" && !isAdmin {
 		// Only allow status changes away from sold
@@ -498,8 +629,8 @@ func (s *CarService) UpdateCar(carID, userID int, req *models.UpdateCarRequest,
 		}
 	}
 
-	// If trying to change status to "active", run full publish validation
-	if req.Status != nil && *req.Status == "active" && car.Status != "active" {
+	// If trying to publish the car, run full publish validation
+	if req.Status != nil && IsPublishedStatus(*req.Status) && !IsPublishedStatus(car.Status) {
 		ready, issues := s.ValidatePublish(carID)
 		if !ready {
 			return fmt.Errorf("cannot publish car: %v", issues)
@@ -516,10 +647,88 @@ func (s *CarService) UpdateCar(carID, userID int, req *models.UpdateCarRequest,
 		return err
 	}
 
+	// Reserving the car starts its reservation; reserving it again extends it
+	if req.ReservationHours != nil && (req.Status == nil || *req.Status != "reserved") {
+		return fmt.Errorf("reservationHours can only be set when reserving a car")
+	}
+	if req.Status != nil && *req.Status == "reserved" && (car.Status != "reserved" || req.ReservationHours != nil) {
+		until, err := ReservationEnd(req.ReservationHours, time.Now())
+		if err != nil {
+			return err
+		}
+		car.ReservedUntil = &until
+	}
+
 	// Apply updates to car
+	previousStatus, oldPrice := car.Status, car.Price
 	s.applyCarUpdates(car, req)
 
-	return s.carRepo.UpdateCar(car)
+	if err := s.saveCar(car, previousStatus, oldPrice); err != nil {
+		return err
+	}
+
+	s.trackEdit(carID, userID, isAdmin)
+	return nil
+}
+
+// saveCar persists an updated car, recording the change in the price history when the
+// price of a listing that was and stays published changed, and starting a new listing
+// period and alerting matching saved searches when the car was (re)published
+func (s *CarService) saveCar(car *models.Car, previousStatus string, oldPrice *int) error {
+	normalizeReservation(car)
+
+	wasPublished := IsPublishedStatus(previousStatus)
+	var err error
+	if wasPublished && IsPublishedStatus(car.Status) && oldPrice != nil && car.Price != nil && *oldPrice != *car.Price {
+		err = s.carRepo.UpdateCarWithPriceChange(car, *oldPrice)
+	} else {
+		err = s.carRepo.UpdateCar(car)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !wasPublished && IsPublishedStatus(car.Status) {
+		if err := s.startListingPeriod(car); err != nil {
+			return err
+		}
+		s.alertSavedSearches(car.ID)
+	}
+	return nil
+}
+
+// SetSavedSearchService enables new-listing alerts for saved searches
+func (s *CarService) SetSavedSearchService(savedSearchService *SavedSearchService) {
+	s.savedSearchService = savedSearchService
+}
+
+// alertSavedSearches records a newly published car in the inbox of matching saved searches.
+// Matching and instant emails run in the background so publishing never waits on them;
+// alerts are best effort, so failures are only logged.
+func (s *CarService) alertSavedSearches(carID int) {
+	if s.savedSearchService == nil {
+		return
+	}
+	go func() {
+		if _, err := s.savedSearchService.MatchNewListing(carID); err != nil {
+			utils.AppLogger.WithField("car_id", carID).Error("Failed to match saved searches: " + err.Error())
+		}
+	}()
+}
+
+// startListingPeriod sets a published car to expire after ListingDuration
+func (s *CarService) startListingPeriod(car *models.Car) error {
+	expiresAt := time.Now().Add(models.ListingDuration)
+	if err := s.carRepo.SetCarExpiry(car.ID, expiresAt); err != nil {
+		return err
+	}
+	car.ExpiresAt = &expiresAt
+	return nil
+}
+
+// GetCarPriceHistory returns a car's published price changes, newest first
+func (s *CarService) GetCarPriceHistory(carID int) ([]models.CarPriceChange, error) {
+	return s.carRepo.GetCarPriceHistory(carID)
 }
 
 // AutoSaveDraft saves a car draft without strict validation (for auto-save functionality)
@@ -560,6 +769,7 @@ func (s *CarService) AutoSaveDraft(carID, userID int, req *models.UpdateCarReque
 	}
 
 	// Apply updates to car basic fields
+	previousStatus, oldPrice := car.Status, car.Price
 	s.applyCarUpdates(car, req)
 
 	// Replace fuels if provided (from either FuelCodes or FuelLabels)
@@ -577,7 +787,59 @@ func (s *CarService) AutoSaveDraft(carID, userID int, req *models.UpdateCarReque
 		}
 	}
 
-	return s.carRepo.UpdateCar(car)
+	if err := s.saveCar(car, previousStatus, oldPrice); err != nil {
+		return err
+	}
+
+	s.trackRevision(carID, models.CarRevisionActorSeller, &userID)
+	return nil
+}
+
+// RenewCar starts a new listing period for a published or expired car, re-running the
+// publish validation first. Expired cars become active again.
+func (s *CarService) RenewCar(carID, userID int) (*models.Car, error) {
+	car, err := s.carRepo.GetCarByID(carID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkCarOwnership(car, userID, false); err != nil {
+		return nil, err
+	}
+
+	if !IsPublishedStatus(car.Status) && car.Status != "expired" {
+		return nil, fmt.Errorf("only active, reserved or expired listings can be renewed")
+	}
+
+	if ready, issues := s.ValidatePublish(carID); !ready {
+		return nil, fmt.Errorf("cannot renew car: %v", issues)
+	}
+
+	if car.Status == "expired" {
+		car.Status = "active"
+		if err := s.saveCar(car, "expired", car.Price); err != nil {
+			return nil, err
+		}
+		s.trackRevision(carID, models.CarRevisionActorSeller, &userID)
+		return car, nil
+	}
+
+	if err := s.startListingPeriod(car); err != nil {
+		return nil, err
+	}
+	return car, nil
+}
+
+// ExpireDueListings moves active listings past their expiry to the expired status
+func (s *CarService) ExpireDueListings() (int, error) {
+	ids, err := s.carRepo.ExpireDueCars()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.trackRevision(id, models.CarRevisionActorSystem, nil)
+	}
+	return len(ids), nil
 }
 
 // mapTextFieldsToIDs maps text field inputs to their corresponding code fields
@@ -773,94 +1035,142 @@ func (s *CarService) DeleteCar(carID, userID int, isAdmin bool) error {
 		return fmt.Errorf("unauthorized: you can only delete your own cars")
 	}
 
-	return s.carRepo.DeleteCar(carID)
+	return s.deleteCar(carID)
 }
 
-// UploadCarImages handles multiple image uploads for a car
-func (s *CarService) UploadCarImages(carID, userID int, files []*multipart.FileHeader, isAdmin bool) ([]models.CarImageMetadata, error) {
+// UploadCarImages uploads images to a car, all of them or none. Every file is validated and
+// cleaned, then they are recorded in one transaction after the car's existing images. The
+// results report each file in upload order, also when the upload is rejected.
+func (s *CarService) UploadCarImages(carID, userID int, files []*multipart.FileHeader, isAdmin bool) ([]models.CarImageMetadata, []models.ImageUploadResult, error) {
 	// Get the car to check ownership
 	car, err := s.carRepo.GetCarByID(carID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Check authorization - only owner or admin can upload images
 	if !isAdmin && car.SellerID != userID {
-		return nil, fmt.Errorf("unauthorized: you can only upload images to your own cars")
+		return nil, nil, fmt.Errorf("unauthorized: you can only upload images to your own cars")
 	}
 
-	// Check current image count
+	// Reject uploads that cannot fit before reading them; CreateCarImages checks again
 	currentCount, err := s.imageRepo.CountCarImages(carID)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-
-	// Check if adding new images would exceed the limit
 	if currentCount+len(files) > MaxImagesPerCar {
-		return nil, fmt.Errorf("cannot upload %d images: car already has %d images (max %d)", len(files), currentCount, MaxImagesPerCar)
+		return nil, nil, fmt.Errorf("%w: cannot upload %d images: car already has %d images (max %d)", models.ErrInvalidImageUpload, len(files), currentCount, MaxImagesPerCar)
 	}
 
-	var uploadedImages []models.CarImageMetadata
-
+	// Validate and store each file. Once one fails, the rest are only validated so every
+	// problem is reported at once.
+	results := make([]models.ImageUploadResult, len(files))
+	images := make([]*models.CarImage, 0, len(files))
+	var keys []string
+	var invalid int
+	var saveErr error
 	for i, fileHeader := range files {
-		// Validate file size
-		if fileHeader.Size > MaxImageSize {
-			return nil, fmt.Errorf("image %s exceeds maximum size of 50MB", fileHeader.Filename)
-		}
+		results[i].Filename = fileHeader.Filename
 
-		// Open the file
-		file, err := fileHeader.Open()
+		cleaned, contentType, err := readUploadedImage(fileHeader)
 		if err != nil {
-			return nil, fmt.Errorf("failed to open file %s: %w", fileHeader.Filename, err)
+			results[i].Status = models.ImageUploadStatusInvalid
+			results[i].Error = err.Error()
+			invalid++
+			continue
 		}
-		defer file.Close()
-
-		// Read file data
-		fileData, err := io.ReadAll(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read file %s: %w", fileHeader.Filename, err)
+		if invalid > 0 || saveErr != nil {
+			results[i].Status = models.ImageUploadStatusSkipped
+			continue
 		}
 
-		// Detect content type
-		contentType := http.DetectContentType(fileData)
-
-		// Validate content type
-		if !AllowedImageTypes[contentType] {
-			return nil, fmt.Errorf("invalid image type %s for file %s (allowed: JPEG, PNG, WebP, GIF)", contentType, fileHeader.Filename)
+		image, err := s.putCleanedImage(context.Background(), carID, cleaned, contentType)
+		if err != nil {
+			results[i].Status = models.ImageUploadStatusFailed
+			results[i].Error = "failed to save image"
+			saveErr = fmt.Errorf("failed to save image %s: %w", fileHeader.Filename, err)
+			continue
 		}
+		images = append(images, image)
+		keys = append(keys, *image.StorageKey)
+	}
 
-		// Create image record
-		image := &models.CarImage{
-			CarID:        carID,
-			ImageData:    fileData,
-			ImageType:    contentType,
-			ImageSize:    int(fileHeader.Size),
-			DisplayOrder: currentCount + i,
+	if invalid > 0 || saveErr != nil {
+		s.deleteStoredImages(keys)
+		skipUploadResults(results)
+		if saveErr != nil {
+			return nil, results, saveErr
 		}
+		return nil, results, fmt.Errorf("%w: %d of %d images are invalid; no images were uploaded", models.ErrInvalidImageUpload, invalid, len(files))
+	}
 
-		// Save to database
-		err = s.imageRepo.CreateCarImage(image)
-		if err != nil {
-			return nil, fmt.Errorf("failed to save image %s: %w", fileHeader.Filename, err)
-		}
+	// Record them all or none
+	if err := s.imageRepo.CreateCarImages(carID, images, MaxImagesPerCar); err != nil {
+		s.deleteStoredImages(keys)
+		skipUploadResults(results)
+		return nil, results, err
+	}
 
-		// Add to uploaded images list
-		uploadedImages = append(uploadedImages, models.CarImageMetadata{
+	uploadedImages := make([]models.CarImageMetadata, len(images))
+	for i, image := range images {
+		s.processNewImage(image)
+		results[i].Status = models.ImageUploadStatusUploaded
+		results[i].ImageID = &image.ID
+		uploadedImages[i] = models.CarImageMetadata{
 			ID:           image.ID,
 			CarID:        image.CarID,
 			ImageType:    image.ImageType,
 			ImageSize:    image.ImageSize,
+			Width:        image.Width,
+			Height:       image.Height,
 			DisplayOrder: image.DisplayOrder,
 			UploadedAt:   image.UploadedAt,
-		})
+			ContentHash:  image.ContentHash,
+			URL:          CarImageURL(image.ID, image.ContentHash, ""),
+		}
 	}
 
-	return uploadedImages, nil
+	s.trackEdit(carID, userID, isAdmin)
+	return uploadedImages, results, nil
 }
 
-// GetCarImage retrieves a single image with data
-func (s *CarService) GetCarImage(imageID int) (*models.CarImage, error) {
-	return s.imageRepo.GetCarImageByID(imageID)
+// readUploadedImage reads, validates and cleans one uploaded file, closing it before returning
+func readUploadedImage(fileHeader *multipart.FileHeader) (*utils.CleanedImage, string, error) {
+	if fileHeader.Size > MaxImageSize {
+		return nil, "", fmt.Errorf("exceeds maximum size of 50MB")
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open file: %w", err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+
+	// Validate content type
+	contentType := http.DetectContentType(data)
+	if !AllowedImageTypes[contentType] {
+		return nil, "", fmt.Errorf("invalid image type %s (allowed: JPEG, PNG, WebP, GIF)", contentType)
+	}
+
+	// Strip metadata (EXIF including GPS) and apply the EXIF orientation
+	cleaned, err := cleanImage(data, contentType)
+	if err != nil {
+		return nil, "", err
+	}
+	return cleaned, contentType, nil
+}
+
+// skipUploadResults marks the files of a rejected upload that were not at fault as skipped
+func skipUploadResults(results []models.ImageUploadResult) {
+	for i := range results {
+		if results[i].Status == "" {
+			results[i].Status = models.ImageUploadStatusSkipped
+		}
+	}
 }
 
 // DeleteCarImage deletes a single image
@@ -882,7 +1192,17 @@ func (s *CarService) DeleteCarImage(imageID, userID int, isAdmin bool) error {
 		return fmt.Errorf("unauthorized: you can only delete images from your own cars")
 	}
 
-	return s.imageRepo.DeleteCarImage(imageID)
+	if err := s.imageRepo.DeleteCarImage(imageID); err != nil {
+		return err
+	}
+	keys := carImageDerivativeKeys(image.CarID, image.ID)
+	if image.StorageKey != nil {
+		keys = append(keys, *image.StorageKey)
+	}
+	s.deleteStoredImages(keys)
+
+	s.trackEdit(image.CarID, userID, isAdmin)
+	return nil
 }
 
 // / GetCarWithImages retrieves a car with its image metadata and inspection data
@@ -994,7 +1314,12 @@ func (s *CarService) ReorderImagesBulk(carID int, imageIDs []int, userID int, is
 	}
 
 	// Perform bulk reorder
-	return s.imageRepo.ReorderImages(imageIDs)
+	if err := s.imageRepo.ReorderImages(imageIDs); err != nil {
+		return err
+	}
+
+	s.trackEdit(carID, userID, isAdmin)
+	return nil
 }
 
 // SetCarFuels sets fuel types for a car
@@ -1016,7 +1341,12 @@ func (s *CarService) SetCarFuels(carID int, fuelCodes []string, userID int, isAd
 	}
 
 	// Perform the update
-	return s.fuelRepo.SetCarFuels(carID, fuelCodes)
+	if err := s.fuelRepo.SetCarFuels(carID, fuelCodes); err != nil {
+		return err
+	}
+
+	s.trackEdit(carID, userID, isAdmin)
+	return nil
 }
 
 // ValidatePublish checks if a car is ready to be published (Step 4 validation)
@@ -1106,6 +1436,16 @@ func (s *CarService) ValidatePublish(carID int) (bool, []string) {
 		issues = append(issues, "Maximum 3 colors allowed")
 	}
 
+	// Check photos taken from other sellers' listings (when enabled)
+	if s.blockDuplicatePhotos {
+		count, err := s.countDuplicatePhotosOfActiveListings(carID)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("Failed to check duplicate photos: %v", err))
+		} else if count > 0 {
+			issues = append(issues, fmt.Sprintf("%d images match photos on another seller's active listing", count))
+		}
+	}
+
 	return len(issues) == 0, issues
 }
 
@@ -1190,6 +1530,9 @@ type CarDisplay struct {
 	ConditionRating  *int `json:"conditionRating"`
 	IsFlooded        bool `json:"isFlooded"`
 	IsHeavilyDamaged bool `json:"isHeavilyDamaged"`
+
+	// Reservation (set only while the listing is reserved)
+	ReservedUntil *time.Time `json:"reservedUntil,omitempty"`
 }
 
 // InspectionDisplay contains stringified inspection results for UI consumption
@@ -1234,6 +1577,7 @@ func (s *CarService) TranslateCarForDisplay(car *models.Car, lang string) (*Tran
 			Mileage:          car.Mileage,
 			Price:            car.Price,
 			Status:           car.Status,
+			ReservedUntil:    car.ReservedUntil,
 			CreatedAt:        car.CreatedAt,
 			BrandName:        car.BrandName,
 			ModelName:        car.ModelName,
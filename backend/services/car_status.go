@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+// carStatusTransitions is the listing state machine: the statuses a seller or admin can move
+// a listing to from each status. Listings only become expired through the expiry job, and
+// expired listings come back by renewal. active -> draft is the seller's "Unpublish" action,
+// which takes a listing down for editing.
+var carStatusTransitions = map[string][]string{
+	"draft":    {"active", "deleted"},
+	"active":   {"draft", "reserved", "sold", "deleted"},
+	"reserved": {"active", "sold", "deleted"},
+	"expired":  {"active", "deleted"},
+	"sold":     {"deleted"},
+	"deleted":  {},
+}
+
+// ValidateCarStatus checks that status is a known listing status
+func ValidateCarStatus(status string) error {
+	if _, ok := carStatusTransitions[status]; !ok {
+		return fmt.Errorf("invalid status %q", status)
+	}
+	return nil
+}
+
+// ValidateCarStatusTransition checks that a seller or admin may move a listing from one status
+// to another. Keeping the same status is always allowed.
+func ValidateCarStatusTransition(from, to string) error {
+	if err := ValidateCarStatus(to); err != nil {
+		return err
+	}
+	if from == to {
+		return nil
+	}
+	if to == "expired" {
+		return fmt.Errorf("cannot set status to expired; listings expire automatically")
+	}
+
+	allowed := carStatusTransitions[from]
+	for _, status := range allowed {
+		if status == to {
+			return nil
+		}
+	}
+	if len(allowed) == 0 {
+		return fmt.Errorf("cannot change status from %s to %s; a %s listing cannot change status", from, to, from)
+	}
+	return fmt.Errorf("cannot change status from %s to %s (allowed: %s)", from, to, strings.Join(allowed, ", "))
+}
+
+// PublishedStatuses are the statuses of listings visible to the public
+var PublishedStatuses = []string{"active", "reserved"}
+
+// IsPublishedStatus reports whether listings with this status are visible to the public
+func IsPublishedStatus(status string) bool {
+	return status == "active" || status == "reserved"
+}
+
+// ReservationEnd returns when a reservation made at now for the given number of hours
+// lapses (DefaultReservationDuration when hours is nil)
+func ReservationEnd(hours *int, now time.Time) (time.Time, error) {
+	if hours == nil {
+		return now.Add(models.DefaultReservationDuration), nil
+	}
+	maxHours := int(models.MaxReservationDuration / time.Hour)
+	if *hours < 1 || *hours > maxHours {
+		return time.Time{}, fmt.Errorf("reservationHours must be between 1 and %d", maxHours)
+	}
+	return now.Add(time.Duration(*hours) * time.Hour), nil
+}
+
+// normalizeReservation gives a reserved car without an end the default reservation and
+// clears the end of a car that is no longer reserved, reporting whether it changed
+func normalizeReservation(car *models.Car) bool {
+	if car.Status != "reserved" {
+		if car.ReservedUntil == nil {
+			return false
+		}
+		car.ReservedUntil = nil
+		return true
+	}
+	if car.ReservedUntil != nil {
+		return false
+	}
+	until, _ := ReservationEnd(nil, time.Now())
+	car.ReservedUntil = &until
+	return true
+}
+
+// ReleaseExpiredReservations moves reserved listings whose reservation lapsed back to active
+func (s *CarService) ReleaseExpiredReservations() (int, error) {
+	ids, err := s.carRepo.ReleaseExpiredReservations()
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range ids {
+		s.trackRevision(id, models.CarRevisionActorSystem, nil)
+	}
+	return len(ids), nil
+}
@@ -1,8 +1,6 @@
 package services
 
 import (
-	"fmt"
-
 	"github.com/uzimpp/CarJai/backend/models"
 )
 
@@ -56,7 +54,7 @@ func (t *CarTranslator) GetThumbnailURL(images []models.CarImageMetadata) *strin
 		}
 	}
 
-	thumbnailURL := fmt.Sprintf("/api/cars/images/%d", thumbnailImage.ID)
+	thumbnailURL := CarImageURL(thumbnailImage.ID, thumbnailImage.ContentHash, "thumb")
 	return &thumbnailURL
 }
 
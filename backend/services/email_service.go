@@ -3,7 +3,11 @@ package services
 import (
 	"crypto/tls"
 	"fmt"
+	"html"
+	"mime"
 	"net/smtp"
+	"strings"
+	"time"
 )
 
 // EmailService handles email sending operations
@@ -80,12 +84,41 @@ func (s *EmailService) TestConnection() error {
 func (s *EmailService) SendPasswordResetEmail(toEmail, resetLink string, frontendURL string) error {
 	subject := "Reset Your Password - CarJai"
 	body := s.buildPasswordResetEmailHTML(resetLink)
+	return s.sendHTMLEmail(toEmail, subject, body)
+}
+
+// SavedSearchAlertCar is a car listed in a saved search alert email
+type SavedSearchAlertCar struct {
+	Title string // e.g. "2019 Toyota Vios"
+	Price string // Formatted asking price
+	Link  string // Listing URL on the frontend
+}
+
+// SendSavedSearchAlertEmail notifies a buyer about new listings matching a saved search
+func (s *EmailService) SendSavedSearchAlertEmail(toEmail, searchName string, cars []SavedSearchAlertCar, manageLink string) error {
+	if len(cars) == 0 {
+		return nil
+	}
+
+	subject := fmt.Sprintf("%d new car(s) for \"%s\" - CarJai", len(cars), searchName)
+	body := s.buildSavedSearchAlertEmailHTML(searchName, cars, manageLink)
+	return s.sendHTMLEmail(toEmail, subject, body)
+}
+
+// SendListingExpiryReminderEmail warns a seller that their listing is about to expire
+func (s *EmailService) SendListingExpiryReminderEmail(toEmail, carTitle string, expiresAt time.Time, renewLink string) error {
+	subject := fmt.Sprintf("Your listing \"%s\" expires soon - CarJai", carTitle)
+	body := s.buildListingExpiryReminderEmailHTML(carTitle, expiresAt, renewLink)
+	return s.sendHTMLEmail(toEmail, subject, body)
+}
 
+// sendHTMLEmail sends an HTML email over SMTP with STARTTLS
+func (s *EmailService) sendHTMLEmail(toEmail, subject, body string) error {
 	// Compose message
 	message := []byte(
 		"From: " + s.smtpFrom + "\r\n" +
 			"To: " + toEmail + "\r\n" +
-			"Subject: " + subject + "\r\n" +
+			"Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n" +
 			"MIME-Version: 1.0\r\n" +
 			"Content-Type: text/html; charset=UTF-8\r\n" +
 			"\r\n" +
@@ -281,3 +314,75 @@ func (s *EmailService) buildPasswordResetEmailHTML(resetLink string) string {
 </body>
 </html>`, resetLink)
 }
+
+// buildSavedSearchAlertEmailHTML creates the HTML body for a saved search alert
+func (s *EmailService) buildSavedSearchAlertEmailHTML(searchName string, cars []SavedSearchAlertCar, manageLink string) string {
+	var rows strings.Builder
+	for _, car := range cars {
+		fmt.Fprintf(&rows, `
+            <tr>
+                <td style="padding: 12px 0; border-bottom: 1px solid #e5e7eb;">
+                    <a href="%s" style="color: #7c2d12; font-weight: 600; text-decoration: none;">%s</a>
+                </td>
+                <td style="padding: 12px 0; border-bottom: 1px solid #e5e7eb; text-align: right; color: #1f2937;">%s</td>
+            </tr>`, html.EscapeString(car.Link), html.EscapeString(car.Title), html.EscapeString(car.Price))
+	}
+
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>New cars for your saved search - CarJai</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; line-height: 1.6; color: #1f2937; background-color: #f3f4f6; padding: 40px 20px;">
+    <div style="max-width: 500px; margin: 0 auto; background-color: #ffffff; border-radius: 8px; overflow: hidden;">
+        <div style="padding: 40px 30px 30px; text-align: center;">
+            <h1 style="margin: 0; font-size: 28px; font-weight: 700; color: #7c2d12;">CarJai</h1>
+        </div>
+        <div style="padding: 0 40px 40px;">
+            <h2 style="margin: 0 0 16px 0; font-size: 22px; font-weight: 600;">New matches for "%s"</h2>
+            <p style="color: #4b5563; font-size: 15px;">These cars were just listed and match your saved search.</p>
+            <table style="width: 100%%; border-collapse: collapse; margin: 24px 0; font-size: 15px;">%s
+            </table>
+            <p style="color: #6b7280; font-size: 13px; border-top: 1px solid #e5e7eb; padding-top: 24px;">
+                You are receiving this email because you turned on alerts for this saved search.
+                <a href="%s" style="color: #7c2d12;">Manage your saved searches</a>.
+            </p>
+        </div>
+    </div>
+</body>
+</html>`, html.EscapeString(searchName), rows.String(), html.EscapeString(manageLink))
+}
+
+// buildListingExpiryReminderEmailHTML creates the HTML body for a listing expiry reminder
+func (s *EmailService) buildListingExpiryReminderEmailHTML(carTitle string, expiresAt time.Time, renewLink string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html lang="en">
+<head>
+    <meta charset="UTF-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1.0">
+    <title>Your listing expires soon - CarJai</title>
+</head>
+<body style="font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, 'Helvetica Neue', Arial, sans-serif; line-height: 1.6; color: #1f2937; background-color: #f3f4f6; padding: 40px 20px;">
+    <div style="max-width: 500px; margin: 0 auto; background-color: #ffffff; border-radius: 8px; overflow: hidden;">
+        <div style="padding: 40px 30px 30px; text-align: center;">
+            <h1 style="margin: 0; font-size: 28px; font-weight: 700; color: #7c2d12;">CarJai</h1>
+        </div>
+        <div style="padding: 0 40px 40px;">
+            <h2 style="margin: 0 0 16px 0; font-size: 22px; font-weight: 600;">Your listing expires soon</h2>
+            <p style="color: #4b5563; font-size: 15px;">
+                Your listing <strong>%s</strong> will be hidden from buyers on <strong>%s</strong>.
+                If the car is still for sale, renew the listing to keep it visible. If it has been sold, please mark it as sold.
+            </p>
+            <div style="text-align: center; margin: 32px 0;">
+                <a href="%s" style="display: inline-block; padding: 12px 32px; background-color: #7c2d12; color: #ffffff; text-decoration: none; border-radius: 6px; font-weight: 600;">Renew listing</a>
+            </div>
+            <p style="color: #6b7280; font-size: 13px; border-top: 1px solid #e5e7eb; padding-top: 24px;">
+                You are receiving this email because you have a car listed on CarJai.
+            </p>
+        </div>
+    </div>
+</body>
+</html>`, html.EscapeString(carTitle), expiresAt.Format("2 January 2006"), html.EscapeString(renewLink))
+}
@@ -3,15 +3,20 @@ package services
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"database/sql"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
 )
 
 // MarketPrice represents the structure for market price data.
@@ -35,11 +40,12 @@ type ExtractionPOCResponse struct {
 
 // --- Service Struct and Constructor ---
 type ExtractionService struct {
-	db *sql.DB
+	db              *sql.DB
+	marketPriceRepo *models.MarketPriceRepository
 }
 
-func NewExtractionService(db *sql.DB) *ExtractionService {
-	return &ExtractionService{db: db}
+func NewExtractionService(db *sql.DB, marketPriceRepo *models.MarketPriceRepository) *ExtractionService {
+	return &ExtractionService{db: db, marketPriceRepo: marketPriceRepo}
 }
 
 // --- Constants and Variables ---
@@ -456,77 +462,35 @@ func (s *ExtractionService) ExtractMarketPricesFromPDF(ctx context.Context, file
 	return pocResponse, nil
 }
 
-func (s *ExtractionService) CommitMarketPrices(ctx context.Context, pricesToCommit []MarketPrice) (insertedCount int, updatedCount int, err error) {
-	if len(pricesToCommit) == 0 {
-		log.Println("No records received to commit.")
-		return 0, 0, nil
-	}
-	log.Printf("Starting database commit for %d market price records...", len(pricesToCommit))
-	stmt, prepErr := s.db.PrepareContext(ctx, `
-		INSERT INTO market_price (brand, model, sub_model, year_start, year_end, price_min_thb, price_max_thb, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
-		ON CONFLICT (brand, model, sub_model, year_start, year_end)
-		DO UPDATE SET
-			price_min_thb = EXCLUDED.price_min_thb,
-			price_max_thb = EXCLUDED.price_max_thb,
-			updated_at = EXCLUDED.updated_at
-		RETURNING (xmax = 0) AS inserted;
-	`)
-	if prepErr != nil {
-		err = fmt.Errorf("failed to prepare upsert statement: %w", prepErr)
-		return
+// CommitMarketPrices saves prices as a new market price version: a copy of the active
+// version with the prices added or updated. The active version is left untouched, so an
+// import can be compared with it and rolled back.
+func (s *ExtractionService) CommitMarketPrices(ctx context.Context, pricesToCommit []MarketPrice, source models.MarketPriceVersionSource) (*models.MarketPriceVersion, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
-	defer stmt.Close()
-	tx, txErr := s.db.BeginTx(ctx, nil)
-	if txErr != nil {
-		err = fmt.Errorf("failed to begin transaction: %w", txErr)
-		return
-	}
-	defer func() {
-		if p := recover(); p != nil {
-			_ = tx.Rollback()
-			panic(p)
-		} else if err != nil {
-			log.Printf("Rolling back transaction due to error: %v", err)
-			if rollbackErr := tx.Rollback(); rollbackErr != nil {
-				log.Printf("Error during transaction rollback: %v", rollbackErr)
-				err = fmt.Errorf("original error: %w; rollback error: %v", err, rollbackErr)
-			}
-		} else {
-			commitErr := tx.Commit()
-			if commitErr != nil {
-				log.Printf("Error committing transaction: %v", commitErr)
-				insertedCount = 0
-				updatedCount = 0
-				err = fmt.Errorf("failed to commit transaction: %w", commitErr)
-			} else {
-				log.Println("Transaction committed successfully.")
-			}
-		}
-	}()
-	txStmt := tx.StmtContext(ctx, stmt)
-	now := time.Now()
+	log.Printf("Creating market price version from %d records...", len(pricesToCommit))
+
+	rows := make([]models.MarketPrice, len(pricesToCommit))
 	for i, price := range pricesToCommit {
-		var inserted bool
-		scanErr := txStmt.QueryRowContext(ctx,
-			price.Brand, price.Model, price.SubModel, price.YearStart, price.YearEnd,
-			price.PriceMin, price.PriceMax, now, now,
-		).Scan(&inserted)
-		if scanErr != nil {
-			recordJson, _ := json.Marshal(price)
-			log.Printf("Failed to upsert record #%d [%s]: %v", i+1, string(recordJson), scanErr)
-			err = fmt.Errorf("failed to upsert record #%d (%s %s %s %d-%d): %w",
-				i+1, price.Brand, price.Model, price.SubModel, price.YearStart, price.YearEnd, scanErr)
-			return
-		}
-		if inserted {
-			insertedCount++
-		} else {
-			updatedCount++
+		rows[i] = models.MarketPrice{
+			Brand:       price.Brand,
+			Model:       price.Model,
+			SubModel:    price.SubModel,
+			YearStart:   price.YearStart,
+			YearEnd:     price.YearEnd,
+			PriceMinTHB: price.PriceMin,
+			PriceMaxTHB: price.PriceMax,
 		}
 	}
-	log.Printf("Database commit loop completed. Inserted: %d, Updated: %d", insertedCount, updatedCount)
-	return
+
+	version, err := s.marketPriceRepo.CreateVersion(source, rows)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("Market price version %d created. Inserted: %d, Updated: %d, Active: %t",
+		version.ID, version.InsertedCount, version.UpdatedCount, version.IsActive)
+	return version, nil
 }
 
 // GetAllMarketPrices retrieves every market price record from the database.
@@ -543,6 +507,7 @@ func (s *ExtractionService) GetAllMarketPrices(ctx context.Context) ([]MarketPri
 			created_at,
 			updated_at
 		FROM market_price
+		WHERE version_id = (SELECT id FROM market_price_versions WHERE is_active)
 		ORDER BY brand, model, sub_model, year_start, year_end;
 	`
 
@@ -579,19 +544,73 @@ func (s *ExtractionService) GetAllMarketPrices(ctx context.Context) ([]MarketPri
 	return prices, nil
 }
 
-// --- Import Function ---
-func (s *ExtractionService) ImportMarketPricesFromPDF(ctx context.Context, filePath string) (insertedCount int, updatedCount int, err error) {
-	log.Printf("Starting market price import (Extraction + DB) from PDF: %s", filePath)
+// hashFile returns the hex SHA-256 of a file
+func hashFile(filePath string) (string, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// GetMarketPriceVersions lists the imported market price versions, newest first
+func (s *ExtractionService) GetMarketPriceVersions() ([]models.MarketPriceVersion, error) {
+	return s.marketPriceRepo.GetVersions()
+}
+
+// ActivateMarketPriceVersion makes a version the one price estimates use
+func (s *ExtractionService) ActivateMarketPriceVersion(versionID int) (*models.MarketPriceVersion, error) {
+	if err := s.marketPriceRepo.ActivateVersion(versionID); err != nil {
+		return nil, err
+	}
+	return s.marketPriceRepo.GetVersion(versionID)
+}
+
+// RollbackMarketPriceVersion reactivates the version that was active before the current one
+func (s *ExtractionService) RollbackMarketPriceVersion() (*models.MarketPriceVersion, error) {
+	return s.marketPriceRepo.RollbackVersion()
+}
+
+// MarketPriceVersionDiff lists the differences between two market price versions
+type MarketPriceVersionDiff struct {
+	From    int                        `json:"from"`
+	To      int                        `json:"to"`
+	Added   int                        `json:"added"`
+	Removed int                        `json:"removed"`
+	Changed int                        `json:"changed"`
+	Changes []models.MarketPriceChange `json:"changes"`
+}
 
-	pocResponse, extractErr := s.ExtractMarketPricesFromPDF(ctx, filePath)
-	if extractErr != nil {
-		err = fmt.Errorf("extraction failed during import: %w", extractErr)
-		return
+// DiffMarketPriceVersions compares two market price versions
+func (s *ExtractionService) DiffMarketPriceVersions(fromID, toID int) (*MarketPriceVersionDiff, error) {
+	changes, err := s.marketPriceRepo.DiffVersions(fromID, toID)
+	if err != nil {
+		return nil, err
 	}
+	diff := &MarketPriceVersionDiff{From: fromID, To: toID, Changes: changes}
+	for _, c := range changes {
+		switch c.Change {
+		case "added":
+			diff.Added++
+		case "removed":
+			diff.Removed++
+		default:
+			diff.Changed++
+		}
+	}
+	return diff, nil
+}
 
-	if len(pocResponse.FinalPrices) == 0 {
-		log.Println("No records found in PDF to import.")
-		return 0, 0, nil
+// GetMarketPriceHistory returns a model year's market price in each version, for charting
+// depreciation over time
+func (s *ExtractionService) GetMarketPriceHistory(brand, model, submodel string, year int) ([]models.MarketPriceHistoryPoint, error) {
+	if brand == "" || model == "" || submodel == "" || year == 0 {
+		return nil, fmt.Errorf("invalid request: brand, model, submodel and year are required")
 	}
-	return s.CommitMarketPrices(ctx, pocResponse.FinalPrices)
+	return s.marketPriceRepo.GetPriceHistory(brand, model, submodel, year)
 }
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+const (
+	FeedModePersonalized = "personalized"
+	FeedModeTrending     = "trending"
+)
+
+// FeedService builds the home feed: personalized for signed-in buyers, trending otherwise
+type FeedService struct {
+	carRepo        *models.CarRepository
+	carService     *CarService
+	profileService *ProfileService
+	weights        models.FeedWeights
+}
+
+// NewFeedService creates a new feed service
+func NewFeedService(carRepo *models.CarRepository, carService *CarService, profileService *ProfileService) *FeedService {
+	return &FeedService{
+		carRepo:        carRepo,
+		carService:     carService,
+		profileService: profileService,
+		weights:        models.DefaultFeedWeights(),
+	}
+}
+
+// FeedMode returns the feed a user gets: trending for anonymous users (nil profile) and
+// for users without anything to personalize with, personalized otherwise
+func FeedMode(profile *models.FeedProfile) string {
+	if profile == nil || !profile.HasSignal() {
+		return FeedModeTrending
+	}
+	return FeedModePersonalized
+}
+
+// GetFeed returns a page of the home feed. userID is 0 for anonymous users, who get the trending feed.
+func (s *FeedService) GetFeed(userID int, lang string, page, limit int) (*models.FeedData, error) {
+	if lang == "" {
+		lang = "en"
+	}
+	offset := (page - 1) * limit
+
+	var profile *models.FeedProfile
+	if userID > 0 {
+		p, err := s.feedProfile(userID)
+		if err != nil {
+			return nil, err
+		}
+		profile = &p
+	}
+
+	mode := FeedMode(profile)
+	var cars []models.Car
+	var err error
+	if mode == FeedModePersonalized {
+		cars, err = s.personalizedFeedCars(*profile, limit, offset)
+	} else {
+		cars, err = s.carRepo.GetTrendingCars(userID, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.carService.batchTranslateCarsToListItems(cars, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.FeedData{Cars: items, Mode: mode, Page: page, Limit: limit}, nil
+}
+
+// personalizedFeedCars returns a page of the ranked feed candidates followed by the
+// trending cars that aren't candidates, so the feed continues past the candidate pool
+func (s *FeedService) personalizedFeedCars(profile models.FeedProfile, limit, offset int) ([]models.Car, error) {
+	candidates, err := s.carRepo.GetFeedCandidates(profile)
+	if err != nil {
+		return nil, err
+	}
+	ranked := models.RankFeedCandidates(candidates, profile, s.weights, time.Now())
+
+	var trending []models.Car
+	if end := offset + limit; end > len(ranked) {
+		// Trending cars may also be candidates, so fetch enough to fill the page after de-duplication
+		trending, err = s.carRepo.GetTrendingCars(profile.UserID, end, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cars := models.MergeFeedSections(ranked, trending)
+	if offset >= len(cars) {
+		return []models.Car{}, nil
+	}
+	return cars[offset:min(offset+limit, len(cars))], nil
+}
+
+// feedProfile loads the budget and province from the user's buyer profile (best effort;
+// users without a buyer profile are ranked on their views and favourites only) and counts
+// their interactions
+func (s *FeedService) feedProfile(userID int) (models.FeedProfile, error) {
+	profile := models.FeedProfile{UserID: userID}
+
+	interactions, err := s.carRepo.CountFeedInteractions(userID)
+	if err != nil {
+		return profile, err
+	}
+	profile.Interactions = interactions
+
+	buyer, err := s.profileService.GetBuyerByUserID(userID)
+	if err != nil {
+		return profile, nil
+	}
+	profile.BudgetMin = buyer.BudgetMin
+	profile.BudgetMax = buyer.BudgetMax
+	if buyer.Province != nil && *buyer.Province != "" {
+		if provinceID, err := s.carService.LookupProvinceIDByName(*buyer.Province); err == nil {
+			profile.OriginProvinceID = provinceID
+		}
+	}
+
+	return profile, nil
+}
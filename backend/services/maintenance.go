@@ -17,6 +17,13 @@ type MaintenanceService struct {
 	ipWhitelistRepo *models.IPWhitelistRepository
 	carRepo         *models.CarRepository
 	logger          *utils.Logger
+
+	savedSearchService *SavedSearchService
+
+	// Listing expiry and reservations (see SetListingLifecycle)
+	carService   *CarService
+	emailService *EmailService
+	frontendURL  string
 }
 
 // NewMaintenanceService creates a new maintenance service
@@ -36,6 +43,21 @@ func NewMaintenanceService(
 	}
 }
 
+// SetSavedSearchService enables the saved search digest job (set after construction
+// because the saved search service depends on services created later)
+func (s *MaintenanceService) SetSavedSearchService(savedSearchService *SavedSearchService) {
+	s.savedSearchService = savedSearchService
+}
+
+// SetListingLifecycle enables the listing expiry and reservation jobs: active listings past
+// their expiry move to the expired status (sellers are emailed ExpiryReminderLeadTime
+// beforehand), and reserved listings past their reservation return to active
+func (s *MaintenanceService) SetListingLifecycle(carService *CarService, emailService *EmailService, frontendURL string) {
+	s.carService = carService
+	s.emailService = emailService
+	s.frontendURL = frontendURL
+}
+
 // MaintenanceConfig holds maintenance configuration
 type MaintenanceConfig struct {
 	SessionCleanupInterval        time.Duration
@@ -44,6 +66,10 @@ type MaintenanceConfig struct {
 	MaxSessionAge                 time.Duration
 	MaxLogAge                     time.Duration
 	MaxEphemeralDraftAge          time.Duration
+	SavedSearchDigestInterval     time.Duration
+	ListingExpiryInterval         time.Duration
+	ExpiryReminderLeadTime        time.Duration
+	ReservationReleaseInterval    time.Duration
 }
 
 // DefaultMaintenanceConfig returns default maintenance configuration
@@ -55,6 +81,10 @@ func DefaultMaintenanceConfig() *MaintenanceConfig {
 		MaxSessionAge:                 24 * time.Hour,      // Sessions expire after 24 hours
 		MaxLogAge:                     30 * 24 * time.Hour, // Keep logs for 30 days
 		MaxEphemeralDraftAge:          24 * time.Hour,      // Delete ephemeral drafts older than 24 hours
+		SavedSearchDigestInterval:     1 * time.Hour,       // Check for due daily digests every hour
+		ListingExpiryInterval:         1 * time.Hour,       // Expire listings and send reminders every hour
+		ExpiryReminderLeadTime:        3 * 24 * time.Hour,  // Remind sellers 3 days before expiry
+		ReservationReleaseInterval:    15 * time.Minute,    // Return lapsed reservations to active every 15 minutes
 	}
 }
 
@@ -77,6 +107,17 @@ func (s *MaintenanceService) StartMaintenance(ctx context.Context, config *Maint
 		go s.runEphemeralDraftCleanup(ctx, config.EphemeralDraftCleanupInterval, config.MaxEphemeralDraftAge)
 	}
 
+	// Start saved search daily digests
+	if s.savedSearchService != nil {
+		go s.runSavedSearchDigest(ctx, config.SavedSearchDigestInterval)
+	}
+
+	// Start listing expiry and reservation release
+	if s.carService != nil {
+		go s.runListingExpiry(ctx, config.ListingExpiryInterval, config.ExpiryReminderLeadTime)
+		go s.runReservationRelease(ctx, config.ReservationReleaseInterval)
+	}
+
 	// Start health monitoring
 	go s.runHealthMonitoring(ctx, 5*time.Minute)
 }
@@ -254,6 +295,110 @@ func (s *MaintenanceService) runEphemeralDraftCleanup(ctx context.Context, inter
 	}
 }
 
+// runSavedSearchDigest periodically sends daily saved search digests that are due
+func (s *MaintenanceService) runSavedSearchDigest(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Saved search digest started with interval " + interval.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Saved search digest stopped")
+			return
+		case <-ticker.C:
+			sent, err := s.savedSearchService.SendDailyDigests()
+			if err != nil {
+				s.logger.Error("Failed to send saved search digests: " + err.Error())
+			} else if sent > 0 {
+				s.logger.Info("Sent " + strconv.Itoa(sent) + " saved search digests")
+			}
+		}
+	}
+}
+
+// runListingExpiry periodically reminds sellers of listings about to expire and expires
+// listings that are past their expiry
+func (s *MaintenanceService) runListingExpiry(ctx context.Context, interval, reminderLead time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Listing expiry started with interval " + interval.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Listing expiry stopped")
+			return
+		case <-ticker.C:
+			reminded, err := s.sendExpiryReminders(reminderLead)
+			if err != nil {
+				s.logger.Error("Failed to send listing expiry reminders: " + err.Error())
+			} else if reminded > 0 {
+				s.logger.Info("Sent " + strconv.Itoa(reminded) + " listing expiry reminders")
+			}
+
+			expired, err := s.carService.ExpireDueListings()
+			if err != nil {
+				s.logger.Error("Failed to expire listings: " + err.Error())
+			} else if expired > 0 {
+				s.logger.Info("Expired " + strconv.Itoa(expired) + " listings")
+			}
+		}
+	}
+}
+
+// runReservationRelease periodically returns reserved listings whose reservation lapsed to active
+func (s *MaintenanceService) runReservationRelease(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Reservation release started with interval " + interval.String())
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Reservation release stopped")
+			return
+		case <-ticker.C:
+			released, err := s.carService.ReleaseExpiredReservations()
+			if err != nil {
+				s.logger.Error("Failed to release reservations: " + err.Error())
+			} else if released > 0 {
+				s.logger.Info("Released " + strconv.Itoa(released) + " reservations")
+			}
+		}
+	}
+}
+
+// sendExpiryReminders emails sellers whose listings expire within the lead time
+func (s *MaintenanceService) sendExpiryReminders(lead time.Duration) (int, error) {
+	if s.emailService == nil {
+		return 0, nil
+	}
+
+	cars, err := s.carRepo.GetCarsDueForExpiryReminder(lead)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	renewLink := s.frontendURL + "/listings"
+	for _, car := range cars {
+		title := carAlertTitle(models.CarListItem{ID: car.CarID, BrandName: car.BrandName, ModelName: car.ModelName, Year: car.Year})
+		if err := s.emailService.SendListingExpiryReminderEmail(car.SellerEmail, title, car.ExpiresAt, renewLink); err != nil {
+			s.logger.WithField("car_id", car.CarID).Error("Failed to send listing expiry reminder: " + err.Error())
+			continue
+		}
+		if err := s.carRepo.MarkExpiryReminderSent(car.CarID); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+	return sent, nil
+}
+
 // cleanupOldEphemeralDrafts deletes ephemeral drafts older than maxAge
 func (s *MaintenanceService) cleanupOldEphemeralDrafts(maxAge time.Duration) (int64, error) {
 	// Note: This requires implementing GetCarsByStatus in CarRepository
@@ -0,0 +1,416 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// MaxMarketPriceRows is the most prices one spreadsheet may contain
+const MaxMarketPriceRows = 20000
+
+// Limits of market price spreadsheets beyond the row count, so an upload can't exhaust memory
+const (
+	maxMarketPriceColumns          = 64       // Columns with data
+	maxMarketPriceXLSXUncompressed = 64 << 20 // 64MB of XML, several times what 20,000 rows take
+)
+
+// Market price file formats
+const (
+	MarketPriceFormatPDF  = "pdf"
+	MarketPriceFormatCSV  = "csv"
+	MarketPriceFormatXLSX = "xlsx"
+)
+
+// MarketPriceFile is the prices read from an uploaded file
+type MarketPriceFile struct {
+	Format   string
+	Prices   []MarketPrice
+	Errors   []models.MarketPriceRowError
+	DebugLog []string // PDF extraction log
+}
+
+// MarketPriceImportPreview is the result of a dry-run import: what the file holds and how
+// it would change the active market prices
+type MarketPriceImportPreview struct {
+	Format         string                       `json:"format"`
+	RowCount       int                          `json:"rowCount"`       // Valid prices in the file
+	InsertCount    int                          `json:"insertCount"`    // Prices the active version lacks
+	UpdateCount    int                          `json:"updateCount"`    // Prices that differ from the active version
+	UnchangedCount int                          `json:"unchangedCount"` // Prices the active version already has
+	Errors         []models.MarketPriceRowError `json:"errors"`
+	Prices         []MarketPrice                `json:"prices"`
+	DebugLog       []string                     `json:"debugLog,omitempty"`
+}
+
+// marketPriceColumns maps spreadsheet headers, compared case-insensitively and ignoring
+// spaces, underscores and hyphens, to their column. The year and price ranges are either
+// one cell ("2018-2020", "350,000-450,000") or separate start/end and min/max cells.
+var marketPriceColumns = map[string]string{
+	"brand":       "brand",
+	"model":       "model",
+	"submodel":    "sub_model",
+	"year":        "year",
+	"years":       "year",
+	"yearstart":   "year_start",
+	"yearend":     "year_end",
+	"price":       "price",
+	"pricerange":  "price",
+	"pricemin":    "price_min_thb",
+	"priceminthb": "price_min_thb",
+	"pricemax":    "price_max_thb",
+	"pricemaxthb": "price_max_thb",
+}
+
+var errTooManyMarketPriceRows = fmt.Errorf("%w: at most %d rows can be imported at once", models.ErrInvalidMarketPriceFile, MaxMarketPriceRows)
+
+var (
+	marketPriceHeaderRegex = regexp.MustCompile(`[\s_-]+`)
+	singleYearRegex        = regexp.MustCompile(`^\d{4}$`)
+)
+
+// ParseMarketPriceCSV parses a market price CSV; see parseMarketPriceRows. Reading stops
+// as soon as the file has more than MaxMarketPriceRows prices.
+func ParseMarketPriceCSV(r io.Reader) ([]MarketPrice, []models.MarketPriceRowError, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var rows [][]string
+	nonBlank := 0
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", models.ErrInvalidMarketPriceFile, err)
+		}
+		if len(row) > maxMarketPriceColumns {
+			return nil, nil, fmt.Errorf("%w: at most %d columns are allowed", models.ErrInvalidMarketPriceFile, maxMarketPriceColumns)
+		}
+		if strings.TrimSpace(strings.Join(row, "")) != "" {
+			// The header and the prices
+			if nonBlank++; nonBlank > MaxMarketPriceRows+1 {
+				return nil, nil, errTooManyMarketPriceRows
+			}
+		}
+		rows = append(rows, row)
+	}
+	return parseMarketPriceRows(rows)
+}
+
+// ParseMarketPriceXLSX parses the first sheet of a market price workbook; see
+// parseMarketPriceRows
+func ParseMarketPriceXLSX(r io.ReaderAt, size int64) ([]MarketPrice, []models.MarketPriceRowError, error) {
+	rows, err := utils.ReadXLSXRows(r, size, utils.XLSXLimits{
+		MaxRows:         MaxMarketPriceRows + 1, // The header and the prices
+		MaxColumns:      maxMarketPriceColumns,
+		MaxUncompressed: maxMarketPriceXLSXUncompressed,
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", models.ErrInvalidMarketPriceFile, err)
+	}
+	return parseMarketPriceRows(rows)
+}
+
+// parseMarketPriceRows parses a header row of marketPriceColumns names followed by one price
+// per row. Header problems fail the whole file; cell problems are recorded per row. Years
+// and prices are read like the PDF extraction reads them (parseYearRange, parsePriceRange),
+// and names are upper-cased to match the PDF data.
+func parseMarketPriceRows(rows [][]string) ([]MarketPrice, []models.MarketPriceRowError, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("%w: file is empty", models.ErrInvalidMarketPriceFile)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")) // Spreadsheet apps may add a BOM
+		if name == "" {
+			continue
+		}
+		column, ok := marketPriceColumns[marketPriceHeaderRegex.ReplaceAllString(strings.ToLower(name), "")]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: unknown column %q", models.ErrInvalidMarketPriceFile, name)
+		}
+		if _, dup := columns[column]; dup {
+			return nil, nil, fmt.Errorf("%w: duplicate column %q", models.ErrInvalidMarketPriceFile, name)
+		}
+		columns[column] = i
+	}
+	if err := checkMarketPriceColumns(columns); err != nil {
+		return nil, nil, err
+	}
+
+	var prices []MarketPrice
+	var rowErrors []models.MarketPriceRowError
+	seen := make(map[string]int)
+	rowCount := 0
+	for i, cells := range rows[1:] {
+		rowNum := i + 2
+		cell := func(column string) string {
+			if c, ok := columns[column]; ok && c < len(cells) {
+				return strings.TrimSpace(cells[c])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(cells, "")) == "" {
+			continue // Blank rows are common at the end of spreadsheets
+		}
+		rowCount++
+		if rowCount > MaxMarketPriceRows {
+			return nil, nil, errTooManyMarketPriceRows
+		}
+
+		price, errs := parseMarketPriceRow(columns, cell)
+		if len(errs) == 0 {
+			key := marketPriceKey(price)
+			if first, dup := seen[key]; dup {
+				errs = append(errs, fmt.Sprintf("duplicates row %d", first))
+			} else {
+				seen[key] = rowNum
+			}
+		}
+		if len(errs) > 0 {
+			rowErrors = append(rowErrors, models.MarketPriceRowError{Row: rowNum, Errors: errs})
+			continue
+		}
+		prices = append(prices, price)
+	}
+
+	if rowCount == 0 {
+		return nil, nil, fmt.Errorf("%w: file has no rows", models.ErrInvalidMarketPriceFile)
+	}
+	return prices, rowErrors, nil
+}
+
+// checkMarketPriceColumns checks that a header has a brand, a model, a year range and a
+// price range, each in only one form
+func checkMarketPriceColumns(columns map[string]int) error {
+	has := func(column string) bool {
+		_, ok := columns[column]
+		return ok
+	}
+	for _, column := range []string{"brand", "model"} {
+		if !has(column) {
+			return fmt.Errorf("%w: missing column %q", models.ErrInvalidMarketPriceFile, column)
+		}
+	}
+	ranges := []struct{ single, start, end string }{
+		{"year", "year_start", "year_end"},
+		{"price", "price_min_thb", "price_max_thb"},
+	}
+	for _, r := range ranges {
+		switch {
+		case has(r.single) && (has(r.start) || has(r.end)):
+			return fmt.Errorf("%w: use either a %q column or %q and %q columns", models.ErrInvalidMarketPriceFile, r.single, r.start, r.end)
+		case !has(r.single) && !(has(r.start) && has(r.end)):
+			return fmt.Errorf("%w: missing column %q (or %q and %q)", models.ErrInvalidMarketPriceFile, r.single, r.start, r.end)
+		}
+	}
+	return nil
+}
+
+// parseMarketPriceRow reads one price from a row's cells
+func parseMarketPriceRow(columns map[string]int, cell func(column string) string) (MarketPrice, []string) {
+	var price MarketPrice
+	var errs []string
+
+	price.Brand = normalizeMarketPriceName(cell("brand"))
+	if price.Brand == "" {
+		errs = append(errs, "brand is required")
+	}
+	model := normalizeMarketPriceName(cell("model"))
+	if model == "" {
+		errs = append(errs, "model is required")
+	}
+	if _, ok := columns["sub_model"]; ok {
+		price.Model = model
+		price.SubModel = normalizeMarketPriceName(cell("sub_model"))
+	} else {
+		price.Model, price.SubModel = splitModelSubModel(model, price.Brand)
+	}
+
+	years := cell("year")
+	if _, ok := columns["year"]; !ok {
+		years = joinMarketPriceRange(cell("year_start"), cell("year_end"))
+	}
+	if singleYearRegex.MatchString(years) {
+		years = years + "-" + years
+	}
+	if years == "" {
+		errs = append(errs, "year is required")
+	} else if start, end, err := parseYearRange(years); err != nil {
+		errs = append(errs, fmt.Sprintf("year: %v", err))
+	} else if start < 1900 || end > 3000 || start > end {
+		errs = append(errs, fmt.Sprintf("year: %d-%d is not a valid year range", start, end))
+	} else {
+		price.YearStart, price.YearEnd = start, end
+	}
+
+	prices := cell("price")
+	if _, ok := columns["price"]; !ok {
+		prices = joinMarketPriceRange(cell("price_min_thb"), cell("price_max_thb"))
+	}
+	if prices == "" {
+		errs = append(errs, "price is required")
+	} else if min, max, err := parsePriceRange(prices); err != nil {
+		errs = append(errs, fmt.Sprintf("price: %v", err))
+	} else if min <= 0 {
+		errs = append(errs, fmt.Sprintf("price: %d must be at least 1", min))
+	} else {
+		price.PriceMin, price.PriceMax = min, max
+	}
+
+	return price, errs
+}
+
+// joinMarketPriceRange joins separate start and end cells into a range. When one is missing
+// the other is returned alone, which reads as a single year or price.
+func joinMarketPriceRange(start, end string) string {
+	switch {
+	case start == "" && end == "":
+		return ""
+	case start == "" || end == "":
+		return start + end
+	}
+	return start + "-" + end
+}
+
+// normalizeMarketPriceName upper-cases a name and collapses its whitespace
+func normalizeMarketPriceName(name string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(name), " "))
+}
+
+// marketPriceKey identifies a price the way the market_price unique key does
+func marketPriceKey(p MarketPrice) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d\x00%d", p.Brand, p.Model, p.SubModel, p.YearStart, p.YearEnd)
+}
+
+// marketPriceFileFormat returns the format of an uploaded file from its extension
+func marketPriceFileFormat(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".pdf":
+		return MarketPriceFormatPDF, nil
+	case ".csv":
+		return MarketPriceFormatCSV, nil
+	case ".xlsx":
+		return MarketPriceFormatXLSX, nil
+	}
+	return "", fmt.Errorf("%w: unsupported file type (allowed: pdf, csv, xlsx)", models.ErrInvalidMarketPriceFile)
+}
+
+// ReadMarketPriceFile reads the prices of a PDF, CSV or XLSX file, by its extension
+func (s *ExtractionService) ReadMarketPriceFile(ctx context.Context, filePath string) (*MarketPriceFile, error) {
+	format, err := marketPriceFileFormat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	file := &MarketPriceFile{Format: format}
+
+	if format == MarketPriceFormatPDF {
+		pocResponse, err := s.ExtractMarketPricesFromPDF(ctx, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("extraction failed during import: %w", err)
+		}
+		file.Prices = pocResponse.FinalPrices
+		file.DebugLog = pocResponse.DebugLog
+		return file, nil
+	}
+
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer f.Close()
+	if format == MarketPriceFormatCSV {
+		file.Prices, file.Errors, err = ParseMarketPriceCSV(f)
+	} else {
+		info, statErr := f.Stat()
+		if statErr != nil {
+			return nil, fmt.Errorf("failed to read file: %w", statErr)
+		}
+		file.Prices, file.Errors, err = ParseMarketPriceXLSX(f, info.Size())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// PreviewMarketPriceImport reads a file without importing it and compares its prices with
+// the active version
+func (s *ExtractionService) PreviewMarketPriceImport(ctx context.Context, filePath string) (*MarketPriceImportPreview, error) {
+	file, err := s.ReadMarketPriceFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	active, err := s.GetAllMarketPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]MarketPrice, len(active))
+	for _, p := range active {
+		current[marketPriceKey(p)] = p
+	}
+
+	preview := &MarketPriceImportPreview{
+		Format:   file.Format,
+		RowCount: len(file.Prices),
+		Errors:   file.Errors,
+		Prices:   file.Prices,
+		DebugLog: file.DebugLog,
+	}
+	if preview.Errors == nil {
+		preview.Errors = []models.MarketPriceRowError{}
+	}
+	if preview.Prices == nil {
+		preview.Prices = []MarketPrice{}
+	}
+	for _, p := range file.Prices {
+		existing, ok := current[marketPriceKey(p)]
+		switch {
+		case !ok:
+			preview.InsertCount++
+		case existing.PriceMin != p.PriceMin || existing.PriceMax != p.PriceMax:
+			preview.UpdateCount++
+		default:
+			preview.UnchangedCount++
+		}
+	}
+	return preview, nil
+}
+
+// ImportMarketPriceFile imports the prices of a PDF, CSV or XLSX file as a new market price
+// version. Spreadsheets with row errors are refused and the errors returned. The file's
+// hash is recorded so the same file isn't imported twice.
+func (s *ExtractionService) ImportMarketPriceFile(ctx context.Context, filePath string, source models.MarketPriceVersionSource) (*models.MarketPriceVersion, []models.MarketPriceRowError, error) {
+	log.Printf("Starting market price import from %s", filePath)
+
+	file, err := s.ReadMarketPriceFile(ctx, filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(file.Errors) > 0 {
+		return nil, file.Errors, fmt.Errorf("%w: %d rows have errors", models.ErrInvalidMarketPriceFile, len(file.Errors))
+	}
+	if len(file.Prices) == 0 {
+		return nil, nil, fmt.Errorf("%w: no market prices found in the file", models.ErrInvalidMarketPriceFile)
+	}
+
+	source.FileHash, err = hashFile(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	version, err := s.CommitMarketPrices(ctx, file.Prices, source)
+	return version, nil, err
+}
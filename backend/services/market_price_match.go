@@ -0,0 +1,122 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// MarketPriceBrandThreshold is the lowest similarity at which a brand, such as a misread one
+// from OCR, is taken for a market price brand. It is pg_trgm's default similarity threshold;
+// brands are few and far apart, so the most similar one above it is rarely wrong.
+const MarketPriceBrandThreshold = 0.3
+
+// MarketPriceMatchThreshold is the lowest similarity at which a model and submodel are taken
+// for a market price's. Below it the names usually belong to another model.
+const MarketPriceMatchThreshold = 0.5
+
+// MatchMarketPriceBrand returns the market price brand a car's brand refers to and how
+// similar they are. Brands that are equal once normalised and aliased score 1; otherwise
+// the most similar brand is returned, or "" when none reaches MarketPriceBrandThreshold.
+func MatchMarketPriceBrand(brands []string, brand string) (string, float64) {
+	canonical := utils.CanonicalBrand(brand)
+	if canonical == "" {
+		return "", 0
+	}
+	best, bestScore := "", 0.0
+	for _, b := range brands {
+		candidate := utils.CanonicalBrand(b)
+		if candidate == canonical {
+			return b, 1
+		}
+		if score := utils.TrigramSimilarity(candidate, canonical); score > bestScore {
+			best, bestScore = b, score
+		}
+	}
+	if bestScore < MarketPriceBrandThreshold {
+		return "", 0
+	}
+	return best, bestScore
+}
+
+// MatchMarketPriceExact returns the candidate whose model and submodel equal a car's,
+// ignoring case, or nil. Names are compared as text, so "%" or "_" only match themselves.
+func MatchMarketPriceExact(candidates []models.MarketPrice, model, submodel string) *models.MarketPrice {
+	for i := range candidates {
+		if strings.EqualFold(candidates[i].Model, model) && strings.EqualFold(candidates[i].SubModel, submodel) {
+			return &candidates[i]
+		}
+	}
+	return nil
+}
+
+// MatchMarketPrice returns the candidate whose model and submodel best match a car's and
+// how similar they are. Names are compared as one string, so a word typed in the model
+// instead of the submodel still matches. Names that are equal once normalised score 1;
+// otherwise the most similar candidate is returned, or nil when none reaches
+// MarketPriceMatchThreshold.
+func MatchMarketPrice(candidates []models.MarketPrice, model, submodel string) (*models.MarketPrice, float64) {
+	name := utils.NormalizeVehicleName(model + submodel)
+	if name == "" {
+		return nil, 0
+	}
+	var best *models.MarketPrice
+	bestScore := 0.0
+	for i := range candidates {
+		c := &candidates[i]
+		candidate := utils.NormalizeVehicleName(c.Model + c.SubModel)
+		if candidate == name {
+			return c, 1
+		}
+		if score := utils.TrigramSimilarity(candidate, name); score > bestScore {
+			best, bestScore = c, score
+		}
+	}
+	if bestScore < MarketPriceMatchThreshold {
+		return nil, 0
+	}
+	return best, bestScore
+}
+
+// findMarketPrice finds the market price of a car. Names are matched exactly (ignoring
+// case) first, then once normalised with brand aliases resolved, then by similarity.
+func (s *CarService) findMarketPrice(brand, model, submodel string, year int) (*models.MarketPrice, *models.MarketPriceMatch, error) {
+	candidates, err := s.marketPriceRepo.GetMarketPriceCandidates(brand, year)
+	if err != nil {
+		return nil, nil, err
+	}
+	if marketPrice := MatchMarketPriceExact(candidates, model, submodel); marketPrice != nil {
+		return marketPrice, &models.MarketPriceMatch{Type: models.MarketPriceMatchExact, Score: 1}, nil
+	}
+	notFound := fmt.Errorf("market price not found for %s %s %s (%d)", brand, model, submodel, year)
+
+	brands, err := s.marketPriceRepo.GetDistinctBrands()
+	if err != nil {
+		return nil, nil, err
+	}
+	matchedBrand, brandScore := MatchMarketPriceBrand(brands, brand)
+	if matchedBrand == "" {
+		return nil, nil, notFound
+	}
+	if !strings.EqualFold(matchedBrand, brand) {
+		candidates, err = s.marketPriceRepo.GetMarketPriceCandidates(matchedBrand, year)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	marketPrice, nameScore := MatchMarketPrice(candidates, model, submodel)
+	if marketPrice == nil {
+		return nil, nil, notFound
+	}
+
+	match := &models.MarketPriceMatch{Type: models.MarketPriceMatchNormalized, Score: 1}
+	if score := brandScore * nameScore; score < 1 {
+		match.Type = models.MarketPriceMatchFuzzy
+		match.Score = math.Round(score*100) / 100
+		match.NeedsConfirmation = true
+	}
+	return marketPrice, match, nil
+}
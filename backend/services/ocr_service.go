@@ -215,6 +215,7 @@ func (s *CarService) UploadBookToDraft(carID int, sellerID int, bookFields *Book
 	if err := s.carRepo.UpdateCar(currentCar); err != nil {
 		return nil, "", nil, "", fmt.Errorf("failed to save OCR fields: %w", err)
 	}
+	s.trackRevision(carID, models.CarRevisionActorSeller, &sellerID)
 
 	return currentCar, "stay", nil, "", nil
 }
@@ -23,9 +23,9 @@ func NewRecentViewsService(db *sql.DB, carService *CarService) *RecentViewsServi
 
 // RecordView records a car view for a user
 func (s *RecentViewsService) RecordView(userID, carID int) error {
-	// Check if car exists and is active
+	// Check if car exists and is published (reserved cars stay visible)
 	var carExists bool
-	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1 AND status = 'active')", carID).Scan(&carExists)
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM cars WHERE id = $1 AND status IN ('active', 'reserved'))", carID).Scan(&carExists)
 	if err != nil {
 		return fmt.Errorf("failed to check car existence: %w", err)
 	}
@@ -0,0 +1,372 @@
+package services
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+const (
+	// MaxSavedSearchesPerUser limits how many saved searches a buyer can keep
+	MaxSavedSearchesPerUser = 20
+	// MaxSavedSearchNameLength is the maximum length of a saved search name
+	MaxSavedSearchNameLength = 100
+	// SavedSearchInboxLimit is the number of inbox entries returned at once
+	SavedSearchInboxLimit = 100
+
+	SavedSearchFrequencyInstant = "instant"
+	SavedSearchFrequencyDaily   = "daily"
+)
+
+// SavedSearchService handles saved searches, new-listing matching and alert emails
+type SavedSearchService struct {
+	savedSearchRepo *models.SavedSearchRepository
+	userRepo        *models.UserRepository
+	carService      *CarService
+	emailService    *EmailService
+	frontendURL     string
+	logger          *utils.Logger
+}
+
+// NewSavedSearchService creates a new saved search service
+func NewSavedSearchService(
+	savedSearchRepo *models.SavedSearchRepository,
+	userRepo *models.UserRepository,
+	carService *CarService,
+	emailService *EmailService,
+	frontendURL string,
+	logger *utils.Logger,
+) *SavedSearchService {
+	return &SavedSearchService{
+		savedSearchRepo: savedSearchRepo,
+		userRepo:        userRepo,
+		carService:      carService,
+		emailService:    emailService,
+		frontendURL:     frontendURL,
+		logger:          logger,
+	}
+}
+
+// validateSavedSearchRequest normalizes and validates a create/update request
+func validateSavedSearchRequest(req *models.SavedSearchRequest) error {
+	req.Name = strings.Join(strings.Fields(req.Name), " ")
+	if req.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len([]rune(req.Name)) > MaxSavedSearchNameLength {
+		return fmt.Errorf("name must be at most %d characters", MaxSavedSearchNameLength)
+	}
+
+	if req.Frequency == "" {
+		req.Frequency = SavedSearchFrequencyInstant
+	}
+	if req.Frequency != SavedSearchFrequencyInstant && req.Frequency != SavedSearchFrequencyDaily {
+		return fmt.Errorf("frequency must be 'instant' or 'daily'")
+	}
+
+	c := req.Criteria
+	if c.MinPrice != nil && c.MaxPrice != nil && *c.MinPrice > *c.MaxPrice {
+		return fmt.Errorf("minPrice must not exceed maxPrice")
+	}
+	if c.MinYear != nil && c.MaxYear != nil && *c.MinYear > *c.MaxYear {
+		return fmt.Errorf("minYear must not exceed maxYear")
+	}
+	if c.MinMileage != nil && c.MaxMileage != nil && *c.MinMileage > *c.MaxMileage {
+		return fmt.Errorf("minMileage must not exceed maxMileage")
+	}
+	if c.ConditionRating != nil && (*c.ConditionRating < 1 || *c.ConditionRating > 5) {
+		return fmt.Errorf("conditionRating must be between 1 and 5")
+	}
+	req.Criteria.Query = models.NormalizeSearchQuery(c.Query)
+
+	return nil
+}
+
+// CreateSavedSearch saves a new search for a user
+func (s *SavedSearchService) CreateSavedSearch(userID int, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	if err := validateSavedSearchRequest(&req); err != nil {
+		return nil, err
+	}
+
+	count, err := s.savedSearchRepo.CountSavedSearchesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= MaxSavedSearchesPerUser {
+		return nil, fmt.Errorf("you can keep at most %d saved searches", MaxSavedSearchesPerUser)
+	}
+
+	ss := &models.SavedSearch{
+		UserID:       userID,
+		Name:         req.Name,
+		Criteria:     req.Criteria,
+		EmailEnabled: req.EmailEnabled,
+		Frequency:    req.Frequency,
+	}
+	if err := s.savedSearchRepo.CreateSavedSearch(ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// GetSavedSearches returns a user's saved searches
+func (s *SavedSearchService) GetSavedSearches(userID int) ([]models.SavedSearch, error) {
+	return s.savedSearchRepo.GetSavedSearchesByUserID(userID)
+}
+
+// GetSavedSearch returns one of the user's saved searches
+func (s *SavedSearchService) GetSavedSearch(userID, id int) (*models.SavedSearch, error) {
+	ss, err := s.savedSearchRepo.GetSavedSearchByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if ss.UserID != userID {
+		// Do not reveal other users' saved searches
+		return nil, fmt.Errorf("saved search not found")
+	}
+	return ss, nil
+}
+
+// UpdateSavedSearch replaces a saved search's name, criteria and alert settings
+func (s *SavedSearchService) UpdateSavedSearch(userID, id int, req models.SavedSearchRequest) (*models.SavedSearch, error) {
+	if err := validateSavedSearchRequest(&req); err != nil {
+		return nil, err
+	}
+
+	ss, err := s.GetSavedSearch(userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.Name = req.Name
+	ss.Criteria = req.Criteria
+	ss.EmailEnabled = req.EmailEnabled
+	ss.Frequency = req.Frequency
+	if err := s.savedSearchRepo.UpdateSavedSearch(ss); err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+// DeleteSavedSearch deletes one of the user's saved searches
+func (s *SavedSearchService) DeleteSavedSearch(userID, id int) error {
+	if _, err := s.GetSavedSearch(userID, id); err != nil {
+		return err
+	}
+	return s.savedSearchRepo.DeleteSavedSearch(id)
+}
+
+// GetInbox returns the user's saved search matches with their cars
+func (s *SavedSearchService) GetInbox(userID int, unreadOnly bool, lang string) (*models.SavedSearchInboxResponse, error) {
+	matches, err := s.savedSearchRepo.GetInbox(userID, unreadOnly, SavedSearchInboxLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	unread, err := s.savedSearchRepo.CountUnreadMatches(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Batch fetch the cars; only listings that are still active are shown
+	carIDs := make([]int, 0, len(matches))
+	seen := make(map[int]bool)
+	for _, m := range matches {
+		if !seen[m.CarID] {
+			seen[m.CarID] = true
+			carIDs = append(carIDs, m.CarID)
+		}
+	}
+	cars, err := s.carService.GetCarListItemsByIDs(carIDs, lang)
+	if err != nil {
+		return nil, err
+	}
+	carsByID := make(map[int]*models.CarListItem, len(cars))
+	for i := range cars {
+		if IsPublishedStatus(cars[i].Status) {
+			carsByID[cars[i].ID] = &cars[i]
+		}
+	}
+
+	items := make([]models.SavedSearchInboxItem, len(matches))
+	for i, m := range matches {
+		items[i] = models.SavedSearchInboxItem{SavedSearchMatch: m, Car: carsByID[m.CarID]}
+	}
+
+	return &models.SavedSearchInboxResponse{Items: items, UnreadCount: unread}, nil
+}
+
+// MarkInboxRead marks inbox entries as read (all entries when ids is empty)
+func (s *SavedSearchService) MarkInboxRead(userID int, ids []int) error {
+	return s.savedSearchRepo.MarkMatchesRead(userID, ids)
+}
+
+// MatchNewListing records a newly published car in the inbox of every saved search it matches
+// and sends instant alert emails. Returns the number of saved searches matched.
+func (s *SavedSearchService) MatchNewListing(carID int) (int, error) {
+	car, err := s.carService.GetCarByID(carID)
+	if err != nil {
+		return 0, err
+	}
+	if car.Status != "active" {
+		return 0, nil
+	}
+
+	// Sellers share their user ID, so this skips the seller's own saved searches. The
+	// candidates are pre-filtered on the car's own columns; the rest is checked per search.
+	searches, err := s.savedSearchRepo.GetSavedSearchesForMatching(car)
+	if err != nil {
+		return 0, err
+	}
+
+	matched := 0
+	for _, ss := range searches {
+		ok, err := s.carService.CarMatchesSearch(carID, ss.Criteria.ToSearchRequest())
+		if err != nil {
+			s.logger.WithFields(map[string]interface{}{
+				"saved_search_id": ss.ID,
+				"car_id":          carID,
+				"error":           err.Error(),
+			}).Error("Failed to match car against saved search")
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		inserted, err := s.savedSearchRepo.AddMatch(ss.ID, ss.UserID, carID)
+		if err != nil {
+			s.logger.WithField("error", err.Error()).Error("Failed to record saved search match")
+			continue
+		}
+		if !inserted {
+			// Already alerted about this car (e.g. republished after being unlisted)
+			continue
+		}
+		matched++
+
+		if ss.EmailEnabled && ss.Frequency == SavedSearchFrequencyInstant {
+			if err := s.sendAlert(&ss); err != nil {
+				s.logger.WithFields(map[string]interface{}{
+					"saved_search_id": ss.ID,
+					"error":           err.Error(),
+				}).Error("Failed to send saved search alert")
+			}
+		}
+	}
+
+	return matched, nil
+}
+
+// SendDailyDigests emails every daily saved search that has new matches and whose last
+// digest is at least a day old. Returns the number of digests sent.
+func (s *SavedSearchService) SendDailyDigests() (int, error) {
+	searches, err := s.savedSearchRepo.GetDailyDigestSearches(time.Now().Add(-24 * time.Hour))
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range searches {
+		if err := s.sendAlert(&searches[i]); err != nil {
+			s.logger.WithFields(map[string]interface{}{
+				"saved_search_id": searches[i].ID,
+				"error":           err.Error(),
+			}).Error("Failed to send saved search digest")
+			continue
+		}
+		if err := s.savedSearchRepo.SetLastDigestSentAt(searches[i].ID, time.Now()); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// sendAlert emails the unemailed matches of a saved search and marks them as emailed
+func (s *SavedSearchService) sendAlert(ss *models.SavedSearch) error {
+	matches, err := s.savedSearchRepo.GetUnemailedMatches(ss.ID)
+	if err != nil || len(matches) == 0 {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ss.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	matchIDs := make([]int, len(matches))
+	carIDs := make([]int, len(matches))
+	for i, m := range matches {
+		matchIDs[i] = m.ID
+		carIDs[i] = m.CarID
+	}
+
+	cars, err := s.carService.GetCarListItemsByIDs(carIDs, "en")
+	if err != nil {
+		return err
+	}
+
+	alertCars := make([]SavedSearchAlertCar, 0, len(cars))
+	for _, car := range cars {
+		// Skip listings that were unpublished before the alert went out
+		if !IsPublishedStatus(car.Status) {
+			continue
+		}
+		alertCars = append(alertCars, SavedSearchAlertCar{
+			Title: carAlertTitle(car),
+			Price: formatTHB(car.Price),
+			Link:  fmt.Sprintf("%s/car/%d", s.frontendURL, car.ID),
+		})
+	}
+
+	// Every match was unpublished since it was recorded: nothing to send, but don't retry them
+	if len(alertCars) == 0 {
+		return s.savedSearchRepo.MarkMatchesEmailed(matchIDs, time.Now())
+	}
+
+	manageLink := fmt.Sprintf("%s/settings", s.frontendURL)
+	if err := s.emailService.SendSavedSearchAlertEmail(user.Email, ss.Name, alertCars, manageLink); err != nil {
+		return err
+	}
+
+	return s.savedSearchRepo.MarkMatchesEmailed(matchIDs, time.Now())
+}
+
+// carAlertTitle returns a short title such as "2019 Toyota Vios"
+func carAlertTitle(car models.CarListItem) string {
+	parts := []string{}
+	if car.Year != nil {
+		parts = append(parts, strconv.Itoa(*car.Year))
+	}
+	for _, p := range []*string{car.BrandName, car.ModelName, car.SubmodelName} {
+		if p != nil && *p != "" {
+			parts = append(parts, *p)
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("Car #%d", car.ID)
+	}
+	return strings.Join(parts, " ")
+}
+
+// formatTHB formats a price in baht with thousands separators (e.g. "฿450,000")
+func formatTHB(price *int) string {
+	if price == nil {
+		return "-"
+	}
+	digits := strconv.Itoa(*price)
+	var b strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(d)
+	}
+	return "฿" + b.String()
+}
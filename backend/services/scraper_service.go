@@ -333,10 +333,12 @@ func (s *CarService) UploadInspectionToDraft(carID int, sellerID int, inspection
 				switch car.Status {
 				case "draft":
 					return nil, &car.ID, ErrCodeCarDuplicateOwnDraft, fmt.Errorf("you already have a draft for this vehicle. Do you want to continue with the existing draft or create a new listing?")
-				case "active":
+				case "active", "reserved":
 					return nil, nil, ErrCodeCarDuplicateOwnActive, fmt.Errorf("you already have an active listing for this vehicle")
 				case "sold":
 					return nil, nil, ErrCodeCarDuplicateOwnSold, fmt.Errorf("you have already sold this vehicle")
+				case "expired":
+					return nil, nil, ErrCodeCarDuplicateOwnExpired, fmt.Errorf("your listing for this vehicle has expired; renew it instead of creating a new one")
 				case "deleted":
 					return nil, nil, ErrCodeCarDuplicateOwnDeleted, fmt.Errorf("this vehicle was previously deleted from your listings")
 				}
@@ -403,6 +405,7 @@ func (s *CarService) UploadInspectionToDraft(carID int, sellerID int, inspection
 	if err := s.carRepo.UpdateCar(currentCar); err != nil {
 		return nil, nil, "", fmt.Errorf("failed to save inspection fields: %w", err)
 	}
+	s.trackRevision(carID, models.CarRevisionActorSeller, &sellerID)
 
 	// Return the result with match status
 	return currentCar, nil, "", nil
@@ -21,6 +21,7 @@ type UserService struct {
 	userSessionRepo              *models.UserSessionRepository
 	jwtManager                   *utils.JWTManager
 	profileService               *ProfileService
+	carService                   *CarService // Removes deleted sellers' stored images
 	emailService                 *EmailService
 	passwordResetJWTSecret       string
 	passwordResetTokenExpiration int
@@ -59,6 +60,11 @@ func (s *UserService) SetProfileService(profileService *ProfileService) {
 	s.profileService = profileService
 }
 
+// SetCarService sets the car service used to remove a deleted user's stored car images
+func (s *UserService) SetCarService(carService *CarService) {
+	s.carService = carService
+}
+
 // GetManagedUsers retrieves all users with their roles for the admin panel
 func (s *UserService) GetManagedUsers() (*[]models.AdminManagedUser, error) {
 	users, err := s.userRepo.GetManagedUsers()
@@ -101,12 +107,26 @@ func (s *UserService) DeleteUserByAdmin(userID int) error {
 	// (We don't care about the error too much, but it's good to try)
 	_, _ = s.userSessionRepo.DeleteAllSessionsForUser(userID)
 
+	// Their cars and image rows cascade away with the user, so collect the stored
+	// image keys first
+	var imageKeys []string
+	if s.carService != nil {
+		keys, err := s.carService.sellerImageKeys(userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user's car images: %w", err)
+		}
+		imageKeys = keys
+	}
+
 	// Delete the user
 	err := s.userRepo.DeleteUser(userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
 
+	if s.carService != nil {
+		s.carService.deleteStoredImages(imageKeys)
+	}
 	return nil
 }
 
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// LocalStore keeps objects as files under a root directory
+type LocalStore struct {
+	root string
+}
+
+// NewLocalStore creates a local store, creating the root directory if needed
+func NewLocalStore(root string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local image storage directory is not set")
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create image storage directory: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if err := ValidateKey(key); err != nil {
+		return "", err
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+// Put writes the object to a temporary file and renames it into place, so readers
+// never see a partial file
+func (s *LocalStore) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create image directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create image file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	written, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write image file: %w", err)
+	}
+	if size >= 0 && written != size {
+		return fmt.Errorf("failed to write image file: wrote %d of %d bytes", written, size)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to store image file: %w", err)
+	}
+	return nil
+}
+
+// Get opens the object's file
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	f, size, err := s.open(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, size, nil
+}
+
+// GetRange opens the object's file at offset
+func (s *LocalStore) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	f, _, err := s.open(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to seek image file: %w", err)
+	}
+	return f, nil
+}
+
+// open opens the object's file and returns its size
+func (s *LocalStore) open(key string) (*os.File, int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, ErrNotFound
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open image file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, fmt.Errorf("failed to open image file: %w", err)
+	}
+	return f, info.Size(), nil
+}
+
+// Delete removes the object's file
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete image file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Open opens an object for random access, e.g. with http.ServeContent, and returns its
+// size. Objects the backend can't seek in are reopened at the new offset with GetRange
+// when a read follows a seek, so range requests don't download the whole object.
+func Open(ctx context.Context, store ImageStore, key string) (io.ReadSeekCloser, int64, error) {
+	body, size, err := store.Get(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
+	if rs, ok := body.(io.ReadSeekCloser); ok {
+		return rs, size, nil
+	}
+	if size < 0 {
+		body.Close()
+		return nil, 0, errors.New("image store did not return the object size")
+	}
+	return &objectReader{ctx: ctx, store: store, key: key, size: size, body: body}, size, nil
+}
+
+// objectReader reads an object by offset, keeping the open body while reads are sequential
+type objectReader struct {
+	ctx     context.Context
+	store   ImageStore
+	key     string
+	size    int64
+	pos     int64         // Offset of the next Read
+	body    io.ReadCloser // Nil after a seek until the next Read
+	bodyPos int64         // Offset of body's next byte
+}
+
+func (r *objectReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.body != nil && r.bodyPos != r.pos {
+		r.body.Close()
+		r.body = nil
+	}
+	if r.body == nil {
+		body, err := r.store.GetRange(r.ctx, r.key, r.pos)
+		if err != nil {
+			return 0, err
+		}
+		r.body, r.bodyPos = body, r.pos
+	}
+	n, err := r.body.Read(p)
+	r.pos += int64(n)
+	r.bodyPos = r.pos
+	return n, err
+}
+
+func (r *objectReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *objectReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
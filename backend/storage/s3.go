@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3-compatible object store (AWS S3, MinIO, ...)
+type S3Config struct {
+	Endpoint        string // e.g. "https://s3.ap-southeast-1.amazonaws.com" or "http://minio:9000"
+	Region          string // Defaults to "us-east-1"
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	PathStyle       bool // Address the bucket as endpoint/bucket/key (MinIO) instead of bucket.endpoint/key
+}
+
+// S3Store keeps objects in an S3 bucket, signing requests with Signature Version 4
+type S3Store struct {
+	cfg      S3Config
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3Store creates an S3 store. A nil client uses http.DefaultClient.
+func NewS3Store(cfg S3Config, client *http.Client) (*S3Store, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3 image storage requires an endpoint, bucket and access key")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" || (endpoint.Scheme != "http" && endpoint.Scheme != "https") {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", cfg.Endpoint)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3Store{cfg: cfg, endpoint: endpoint, client: client, now: time.Now}, nil
+}
+
+// objectURL returns the URL of an object with its key escaped as S3 expects
+func (s *S3Store) objectURL(key string) *url.URL {
+	u := *s.endpoint
+	prefix := u.Path
+	if s.cfg.PathStyle {
+		prefix += "/" + s.cfg.Bucket
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+	}
+	u.Path = prefix + "/" + key
+	u.RawPath = uriEncode(prefix, false) + "/" + uriEncode(key, false)
+	return &u
+}
+
+// do signs and sends a request for an object
+func (s *S3Store) do(ctx context.Context, method, key string, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.objectURL(key).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := EmptyPayloadHash
+	if body != nil {
+		req.ContentLength = size
+		payloadHash = UnsignedPayload
+	}
+	SignV4(req, payloadHash, Credentials{s.cfg.AccessKeyID, s.cfg.SecretAccessKey}, s.cfg.Region, "s3", s.now())
+	return s.client.Do(req)
+}
+
+// Put uploads the object. S3 needs the size up front; the body is streamed unsigned.
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if size < 0 {
+		return fmt.Errorf("S3 uploads require the object size")
+	}
+	header := http.Header{}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	resp, err := s.do(ctx, http.MethodPut, key, r, size, header)
+	if err != nil {
+		return fmt.Errorf("failed to upload image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to upload image: %w", s3Error(resp))
+	}
+	return nil
+}
+
+// Get downloads the object; the body is streamed from S3
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to download image: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, resp.ContentLength, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, 0, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, 0, fmt.Errorf("failed to download image: %w", s3Error(resp))
+	}
+}
+
+// GetRange downloads the object from offset with a ranged GET
+func (s *S3Store) GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error) {
+	header := http.Header{}
+	header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	resp, err := s.do(ctx, http.MethodGet, key, nil, 0, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download image: %w", err)
+	}
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		return resp.Body, nil
+	case http.StatusOK: // The whole object (servers may ignore the range)
+		if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+			resp.Body.Close()
+			return nil, fmt.Errorf("failed to download image: %w", err)
+		}
+		return resp.Body, nil
+	case http.StatusRequestedRangeNotSatisfiable: // offset is at the end
+		resp.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	default:
+		defer resp.Body.Close()
+		return nil, fmt.Errorf("failed to download image: %w", s3Error(resp))
+	}
+}
+
+// Delete removes the object (S3 also succeeds for missing objects)
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, 0, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete image: %w", err)
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("failed to delete image: %w", s3Error(resp))
+	}
+}
+
+// s3Error describes an error response, using the S3 error code when there is one
+func s3Error(resp *http.Response) error {
+	var body struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if xml.Unmarshal(data, &body) == nil && body.Code != "" {
+		return fmt.Errorf("S3 returned %d %s: %s", resp.StatusCode, body.Code, body.Message)
+	}
+	return fmt.Errorf("S3 returned %d", resp.StatusCode)
+}
@@ -0,0 +1,86 @@
+// Package storage keeps car image files outside the database, on the local filesystem
+// or in an S3-compatible object store. The database stores each image's key.
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ErrNotFound is returned by Get when no object exists for the key
+var ErrNotFound = errors.New("object not found")
+
+// ImageStore stores image objects by key
+type ImageStore interface {
+	// Put stores an object of size bytes, replacing any object with the same key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens an object and returns its size; the caller must close it
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+	// GetRange opens an object from offset to its end; the caller must close it
+	GetRange(ctx context.Context, key string, offset int64) (io.ReadCloser, error)
+	// Delete removes an object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+}
+
+// Storage backends
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+)
+
+// Config selects and configures the image store
+type Config struct {
+	Backend  string // "local" (default) or "s3"
+	LocalDir string // Root directory for the local backend
+	S3       S3Config
+}
+
+// New creates the image store selected by cfg
+func New(cfg Config) (ImageStore, error) {
+	switch cfg.Backend {
+	case "", BackendLocal:
+		return NewLocalStore(cfg.LocalDir)
+	case BackendS3:
+		return NewS3Store(cfg.S3, nil)
+	default:
+		return nil, fmt.Errorf("unknown image storage backend %q (allowed: local, s3)", cfg.Backend)
+	}
+}
+
+// imageExtensions maps image content types to file extensions
+var imageExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/jpg":  ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+// NewImageKey returns a new random key for a car image, e.g. "cars/12/3f2a...9c.jpg".
+// Keys are never reused, so a stored object never changes.
+func NewImageKey(carID int, contentType string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate image key: %w", err)
+	}
+	return fmt.Sprintf("cars/%d/%s%s", carID, hex.EncodeToString(b), imageExtensions[contentType]), nil
+}
+
+// ValidateKey rejects keys that could escape the store's root: empty, absolute,
+// containing backslashes or "." / ".." segments
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("invalid object key %q", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package tests
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestBestValueIndexes(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name           string
+		values         []*float64
+		higherIsBetter bool
+		want           []int
+	}{
+		{"lowest wins", []*float64{f(500000), f(420000), f(610000)}, false, []int{1}},
+		{"highest wins", []*float64{f(2018), f(2021), f(2019)}, true, []int{1}},
+		{"ties are all marked", []*float64{f(3), f(5), f(5)}, true, []int{1, 2}},
+		{"unknown values are skipped", []*float64{nil, f(90000), f(60000)}, false, []int{2}},
+		{"all equal marks none", []*float64{f(4), f(4)}, true, []int{}},
+		{"single known value marks none", []*float64{f(4), nil}, true, []int{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.BestValueIndexes(tt.values, tt.higherIsBetter)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInspectionSummary(t *testing.T) {
+	pass, fail := true, false
+	insp := &models.InspectionResult{
+		Station:        strPtr("Bangkok Station"),
+		OverallPass:    &fail,
+		BrakeResult:    &pass,
+		HornResult:     &fail,
+		WiperResult:    &pass,
+		EmissionResult: &fail,
+	}
+
+	got := insp.Summary()
+	if got.PassedCount != 2 || got.CheckedCount != 4 {
+		t.Errorf("got %d passed of %d checked, want 2 of 4", got.PassedCount, got.CheckedCount)
+	}
+	if want := []string{"emissionResult", "hornResult"}; !reflect.DeepEqual(got.FailedItems, want) {
+		t.Errorf("failed items = %v, want %v", got.FailedItems, want)
+	}
+	if got.OverallPass == nil || *got.OverallPass {
+		t.Errorf("overall pass should be false")
+	}
+}
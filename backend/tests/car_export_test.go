@@ -0,0 +1,169 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+func TestXLSXColumnName(t *testing.T) {
+	tests := map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"}
+	for index, want := range tests {
+		if got := utils.XLSXColumnName(index); got != want {
+			t.Errorf("XLSXColumnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	xw, err := utils.NewXLSXWriter(&buf, "Inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.WriteRow([]interface{}{"name", "price", "flooded"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.WriteRow([]interface{}{"Toyota <Yaris> & co", 520000, true, nil, "after gap"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("not a zip: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(data)
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		content, ok := parts[name]
+		if !ok {
+			t.Fatalf("missing part %s", name)
+		}
+		var v interface{}
+		if err := xml.Unmarshal([]byte(content), &v); err != nil {
+			t.Errorf("%s is not well-formed XML: %v", name, err)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	for _, want := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">Toyota &lt;Yaris&gt; &amp; co</t></is></c>`,
+		`<c r="B2"><v>520000</v></c>`,
+		`<c r="C2" t="b"><v>1</v></c>`,
+		`<c r="E2" t="inlineStr">`,
+	} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet missing %s", want)
+		}
+	}
+	if strings.Contains(sheet, `r="D2"`) {
+		t.Errorf("nil cells should be left empty")
+	}
+}
+
+func TestCarExportEncoders(t *testing.T) {
+	created := time.Date(2025, 1, 10, 8, 0, 0, 0, time.UTC)
+	row := services.CarExportRow{
+		ID:         12,
+		Status:     "active",
+		BrandName:  strPtr("Toyota"),
+		Price:      intPtr(520000),
+		FuelTypes:  []string{"Gasoline", "LPG"},
+		Colors:     []string{},
+		IsFlooded:  true,
+		Views:      40,
+		Favourites: 3,
+		ImageURLs:  []string{"/api/cars/images/1", "/api/cars/images/2"},
+		CreatedAt:  created,
+	}
+
+	var csvBuf bytes.Buffer
+	enc, err := services.NewCarExportEncoder(services.CarExportCSV, &csvBuf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.WriteRow(&row); err != nil {
+		t.Fatal(err)
+	}
+	if err := enc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(csvBuf.String(), "\ufeff"))).ReadAll()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("got %d records, %v", len(records), err)
+	}
+	got := map[string]string{}
+	for i, name := range records[0] {
+		got[name] = records[1][i]
+	}
+	want := map[string]string{
+		"id":            "12",
+		"brandName":     "Toyota",
+		"modelName":     "",
+		"price":         "520000",
+		"fuelTypes":     "Gasoline|LPG",
+		"isFlooded":     "yes",
+		"views":         "40",
+		"favourites":    "3",
+		"imageUrls":     "/api/cars/images/1|/api/cars/images/2",
+		"createdAt":     "2025-01-10T08:00:00Z",
+		"expiresAt":     "",
+		"colors":        "",
+		"status":        "active",
+		"mileage":       "",
+		"seats":         "",
+		"chassisNumber": "",
+	}
+	for name, value := range want {
+		if got[name] != value {
+			t.Errorf("csv %s = %q, want %q", name, got[name], value)
+		}
+	}
+
+	for _, rows := range []int{0, 2} {
+		var jsonBuf bytes.Buffer
+		enc, err := services.NewCarExportEncoder(services.CarExportJSON, &jsonBuf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < rows; i++ {
+			if err := enc.WriteRow(&row); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := enc.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var decoded []services.CarExportRow
+		if err := json.Unmarshal(jsonBuf.Bytes(), &decoded); err != nil {
+			t.Fatalf("%d rows: invalid JSON %q: %v", rows, jsonBuf.String(), err)
+		}
+		if len(decoded) != rows {
+			t.Errorf("got %d JSON rows, want %d", len(decoded), rows)
+		}
+	}
+
+	if _, err := services.NewCarExportEncoder("pdf", &bytes.Buffer{}); err == nil {
+		t.Errorf("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,118 @@
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+)
+
+func TestBudgetFit(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     *int
+		budgetMin *int
+		budgetMax *int
+		want      float64
+	}{
+		{name: "no price", price: nil, budgetMin: intPtr(300000), budgetMax: intPtr(500000), want: 0},
+		{name: "no budget", price: intPtr(900000), want: 1},
+		{name: "within budget", price: intPtr(400000), budgetMin: intPtr(300000), budgetMax: intPtr(500000), want: 1},
+		{name: "at the maximum", price: intPtr(500000), budgetMin: intPtr(300000), budgetMax: intPtr(500000), want: 1},
+		{name: "10% over the maximum", price: intPtr(550000), budgetMax: intPtr(500000), want: 0.6},
+		{name: "25% over the maximum", price: intPtr(625000), budgetMax: intPtr(500000), want: 0},
+		{name: "far over the maximum", price: intPtr(2000000), budgetMax: intPtr(500000), want: 0},
+		{name: "25% under the minimum", price: intPtr(300000), budgetMin: intPtr(400000), want: 0.5},
+		{name: "far under the minimum", price: intPtr(100000), budgetMin: intPtr(400000), want: 0},
+		{name: "only a minimum", price: intPtr(900000), budgetMin: intPtr(400000), want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.BudgetFit(tt.price, tt.budgetMin, tt.budgetMax); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("models.BudgetFit() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankFeedCandidates(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	listed := now.Add(-60 * 24 * time.Hour) // Old enough that recency barely counts
+	profile := models.FeedProfile{UserID: 1, BudgetMax: intPtr(500000)}
+	weights := models.DefaultFeedWeights()
+
+	candidates := []models.FeedCandidate{
+		{Car: models.Car{ID: 1, Price: intPtr(900000), CreatedAt: listed}},                            // Over budget
+		{Car: models.Car{ID: 2, Price: intPtr(450000), CreatedAt: listed}},                            // Fits the budget
+		{Car: models.Car{ID: 3, Price: intPtr(900000), CreatedAt: listed}, BrandAffinity: 1},          // Preferred brand
+		{Car: models.Car{ID: 4, Price: intPtr(450000), CreatedAt: listed.Add(time.Hour)}},             // Fits, newer than 2
+		{Car: models.Car{ID: 5, Price: intPtr(900000), CreatedAt: now}},                               // Just listed
+		{Car: models.Car{ID: 6, Price: intPtr(450000), CreatedAt: listed}, DistanceKm: floatPtr(150)}, // Fits, 150 km away
+	}
+
+	got := models.RankFeedCandidates(candidates, profile, weights, now)
+	want := []int{3, 6, 4, 2, 5, 1}
+	if len(got) != len(want) {
+		t.Fatalf("got %d cars, want %d", len(got), len(want))
+	}
+	for i, car := range got {
+		if car.ID != want[i] {
+			t.Errorf("position %d: got car %d, want %d (order %v)", i, car.ID, want[i], carIDs(got))
+		}
+	}
+}
+
+func TestFeedMode(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile *models.FeedProfile
+		want    string
+	}{
+		{name: "anonymous", profile: nil, want: services.FeedModeTrending},
+		{name: "no signal", profile: &models.FeedProfile{UserID: 1}, want: services.FeedModeTrending},
+		{name: "views or favourites", profile: &models.FeedProfile{UserID: 1, Interactions: 3}, want: services.FeedModePersonalized},
+		{name: "budget only", profile: &models.FeedProfile{UserID: 1, BudgetMax: intPtr(500000)}, want: services.FeedModePersonalized},
+		{name: "province only", profile: &models.FeedProfile{UserID: 1, OriginProvinceID: intPtr(10)}, want: services.FeedModePersonalized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := services.FeedMode(tt.profile); got != tt.want {
+				t.Errorf("services.FeedMode() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeFeedSections(t *testing.T) {
+	personalized := []models.Car{{ID: 3}, {ID: 1}, {ID: 2}}
+	trending := []models.Car{{ID: 2}, {ID: 5}, {ID: 3}, {ID: 4}}
+
+	got := carIDs(models.MergeFeedSections(personalized, trending))
+	want := []int{3, 1, 2, 5, 4}
+	if len(got) != len(want) {
+		t.Fatalf("models.MergeFeedSections() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("models.MergeFeedSections() = %v, want %v", got, want)
+		}
+	}
+
+	if got := models.MergeFeedSections(nil, nil); len(got) != 0 {
+		t.Errorf("merging empty sections = %v, want none", carIDs(got))
+	}
+}
+
+func floatPtr(f float64) *float64 { return &f }
+
+func carIDs(cars []models.Car) []int {
+	ids := make([]int, len(cars))
+	for i, car := range cars {
+		ids[i] = car.ID
+	}
+	return ids
+}
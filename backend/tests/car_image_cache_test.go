@@ -0,0 +1,54 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+)
+
+func TestCarImageURL(t *testing.T) {
+	hash := strings.Repeat("ab", 32)
+	tests := []struct {
+		contentHash *string
+		size        string
+		want        string
+	}{
+		{&hash, "", "/api/cars/images/7?v=abababababababab"},
+		{&hash, "thumb", "/api/cars/images/7?size=thumb&v=abababababababab"},
+		{nil, "thumb", "/api/cars/images/7?size=thumb"}, // Hash not known yet
+		{nil, "", "/api/cars/images/7"},
+	}
+	for _, tt := range tests {
+		if got := services.CarImageURL(7, tt.contentHash, tt.size); got != tt.want {
+			t.Errorf("CarImageURL(%v, %q) = %q, want %q", tt.contentHash != nil, tt.size, got, tt.want)
+		}
+	}
+}
+
+func TestCarImageETag(t *testing.T) {
+	hash := strings.Repeat("0f", 32)
+	jpeg := &models.CarImage{ImageType: "image/jpeg", ContentHash: &hash}
+	webp := &models.CarImage{ImageType: "image/webp", ContentHash: &hash}
+
+	if got := services.CarImageETag(jpeg, ""); got != `"`+hash+`"` {
+		t.Errorf("original ETag = %s", got)
+	}
+	if got := services.CarImageETag(jpeg, "original"); got != `"`+hash+`"` {
+		t.Errorf("original ETag = %s", got)
+	}
+	if got := services.CarImageETag(jpeg, "thumb"); got != `"`+hash+`-thumb"` {
+		t.Errorf("thumb ETag = %s", got)
+	}
+	// WebP images have no derivatives, so every size is the original
+	if got := services.CarImageETag(webp, "thumb"); got != `"`+hash+`"` {
+		t.Errorf("WebP thumb ETag = %s", got)
+	}
+	if got := services.CarImageETag(&models.CarImage{ImageType: "image/jpeg"}, "thumb"); got != "" {
+		t.Errorf("expected no ETag without a content hash, got %s", got)
+	}
+	if got := services.CarImageVersion(jpeg); got != hash[:16] {
+		t.Errorf("version = %q, want %q", got, hash[:16])
+	}
+}
@@ -0,0 +1,130 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+)
+
+func TestParseCarImportCSV(t *testing.T) {
+	data := "\ufeffBrandName,modelName,year,price,colors,fuelTypes,isFlooded,bodyTypeCode\n" +
+		"Toyota,Yaris,2019,\"520,000\",white|Black,Gasoline|LPG,no,sedan\n" +
+		"Honda,,20x9,0,,,maybe,\n"
+
+	records, err := services.ParseCarImportCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+
+	first := records[0]
+	if first.Row != 1 || len(first.Errors) != 0 {
+		t.Fatalf("row 1: got row %d with errors %v", first.Row, first.Errors)
+	}
+	req := first.Request
+	if req.BrandName == nil || *req.BrandName != "Toyota" || req.ModelName == nil || *req.ModelName != "Yaris" {
+		t.Errorf("brand/model not parsed: %+v", req)
+	}
+	if req.Year == nil || *req.Year != 2019 || req.Price == nil || *req.Price != 520000 {
+		t.Errorf("year/price not parsed: %+v", req)
+	}
+	if req.IsFlooded == nil || *req.IsFlooded {
+		t.Errorf("isFlooded should be false")
+	}
+	if req.BodyTypeCode == nil || *req.BodyTypeCode != "SEDAN" {
+		t.Errorf("body type code should be upper-cased, got %v", req.BodyTypeCode)
+	}
+	if want := []string{"WHITE", "BLACK"}; !reflect.DeepEqual(first.ColorCodes, want) {
+		t.Errorf("colors = %v, want %v", first.ColorCodes, want)
+	}
+	if want := []string{"Gasoline", "LPG"}; !reflect.DeepEqual(req.FuelLabels, want) {
+		t.Errorf("fuel labels = %v, want %v", req.FuelLabels, want)
+	}
+
+	second := records[1]
+	if second.Request.ModelName != nil {
+		t.Errorf("empty cells should be skipped")
+	}
+	if len(second.Errors) != 3 {
+		t.Errorf("row 2: got errors %v, want year, price and isFlooded errors", second.Errors)
+	}
+}
+
+func TestParseCarImportCSVRejectsFile(t *testing.T) {
+	tests := map[string]string{
+		"empty":            "",
+		"header only":      "brandName,year\n",
+		"unknown column":   "brandName,colour\nToyota,white\n",
+		"duplicate column": "year,Year\n2019,2020\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := services.ParseCarImportCSV(strings.NewReader(data))
+			if !errors.Is(err, models.ErrInvalidCarImport) {
+				t.Errorf("got %v, want ErrInvalidCarImport", err)
+			}
+		})
+	}
+
+	var b strings.Builder
+	b.WriteString("brandName\n")
+	for i := 0; i <= services.MaxCarImportRows; i++ {
+		b.WriteString("Toyota\n")
+	}
+	if _, err := services.ParseCarImportCSV(strings.NewReader(b.String())); !errors.Is(err, models.ErrInvalidCarImport) {
+		t.Errorf("too many rows: got %v, want ErrInvalidCarImport", err)
+	}
+}
+
+func TestGroupCarImportImages(t *testing.T) {
+	open := func(t *testing.T, names ...string) *zip.Reader {
+		t.Helper()
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, name := range names {
+			if _, err := zw.Create(name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return zr
+	}
+
+	zr := open(t, "cars/2/b.jpg", "1/", "1/rear.jpg", "1/front.jpg", "__MACOSX/1/._front.jpg", "2/.DS_Store")
+	images, err := services.GroupCarImportImages(zr.File)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := map[int][]string{}
+	for row, files := range images {
+		for _, f := range files {
+			got[row] = append(got[row], f.Name)
+		}
+	}
+	want := map[int][]string{
+		1: {"1/front.jpg", "1/rear.jpg"},
+		2: {"cars/2/b.jpg"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	zr = open(t, "front.jpg")
+	if _, err := services.GroupCarImportImages(zr.File); !errors.Is(err, models.ErrInvalidCarImport) {
+		t.Errorf("image outside a row folder: got %v, want ErrInvalidCarImport", err)
+	}
+}
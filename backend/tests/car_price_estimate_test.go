@@ -0,0 +1,97 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+)
+
+func TestCalculatePriceEstimate(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	market := &models.MarketPrice{ID: 7, PriceMinTHB: 400000, PriceMaxTHB: 500000}
+	year, mileage, rating := 2020, 70000, 4
+
+	// Good condition and 10,000 km a year, no inspection
+	input := services.PriceEstimateInput{Year: year, Mileage: &mileage, ConditionRating: &rating}
+	got, err := services.CalculatePriceEstimate(market, input, now)
+	if err != nil {
+		t.Fatalf("CalculatePriceEstimate: %v", err)
+	}
+	if got.BasePrice != 450000 || got.MarketPrice.ID != 7 {
+		t.Errorf("base = %d from market price %d, want 450000 from 7", got.BasePrice, got.MarketPrice.ID)
+	}
+	if got.EstimatedPrice != 495000 {
+		t.Errorf("estimate = %d, want 495000", got.EstimatedPrice)
+	}
+	// The market range scaled by 1.10, widened by 5% for the unknown inspection
+	if got.LowPrice != 418000 || got.HighPrice != 578000 {
+		t.Errorf("range = %d-%d, want 418000-578000", got.LowPrice, got.HighPrice)
+	}
+	if len(got.Adjustments) != 2 {
+		t.Fatalf("expected condition and mileage adjustments, got %+v", got.Adjustments)
+	}
+	condition, mileageAdj := got.Adjustments[0], got.Adjustments[1]
+	if condition.Factor != models.PriceFactorCondition || *condition.Value != 4 || condition.Amount != 22500 {
+		t.Errorf("unexpected condition adjustment %+v", condition)
+	}
+	if mileageAdj.Factor != models.PriceFactorMileage || *mileageAdj.Value != 10000 || mileageAdj.Percent != 5 {
+		t.Errorf("unexpected mileage adjustment %+v", mileageAdj)
+	}
+
+	// Flooded, with one recorded inspection item passing: the rest count as failed
+	pass, fail := true, false
+	insp := &models.InspectionResult{BrakeResult: &pass}
+	flooded := services.PriceEstimateInput{
+		Year:       year,
+		IsFlooded:  true,
+		Inspection: append([]models.InspectionItem{{Name: "overallPass", Result: &fail}}, insp.Items()...),
+	}
+	got, err = services.CalculatePriceEstimate(market, flooded, now)
+	if err != nil {
+		t.Fatalf("CalculatePriceEstimate: %v", err)
+	}
+	failed := 0
+	for _, adj := range got.Adjustments {
+		if adj.Factor == models.PriceFactorInspectionFailed {
+			failed++
+			if adj.Item == "brakeResult" {
+				t.Error("expected the passed brake item not to be penalised")
+			}
+		}
+	}
+	if failed != 19 {
+		t.Errorf("expected 19 failed inspection items, got %d", failed)
+	}
+	// 1 - 30% - 19%, widened by 10% for the unknown condition and mileage
+	if got.EstimatedPrice != 230000 || got.LowPrice != 184000 || got.HighPrice != 281000 {
+		t.Errorf("got %d (%d-%d), want 230000 (184000-281000)", got.EstimatedPrice, got.LowPrice, got.HighPrice)
+	}
+
+	if _, err := services.CalculatePriceEstimate(&models.MarketPrice{}, input, now); err == nil {
+		t.Error("expected an error for a market price without a range")
+	}
+}
+
+func TestEstimateSpecPriceRejectsInvalidSpecs(t *testing.T) {
+	svc := &services.CarService{} // Specs are checked before market data is looked up
+	negative, rating := -1, 6
+	valid := models.ValuationRequest{BrandName: "TOYOTA", ModelName: "HILUX VIGO", SubmodelName: "2.5 E", Year: 2012}
+
+	tests := map[string]func(r *models.ValuationRequest){
+		"missing submodel":   func(r *models.ValuationRequest) { r.SubmodelName = " " },
+		"missing year":       func(r *models.ValuationRequest) { r.Year = 0 },
+		"future year":        func(r *models.ValuationRequest) { r.Year = time.Now().Year() + 2 },
+		"negative mileage":   func(r *models.ValuationRequest) { r.Mileage = &negative },
+		"condition too high": func(r *models.ValuationRequest) { r.ConditionRating = &rating },
+	}
+	for name, modify := range tests {
+		req := valid
+		modify(&req)
+		if _, err := svc.EstimateSpecPrice(req); err == nil || !strings.HasPrefix(err.Error(), "invalid request") {
+			t.Errorf("%s: expected an invalid request error, got %v", name, err)
+		}
+	}
+}
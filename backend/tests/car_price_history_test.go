@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestCarPriceChangeIsRecentDrop(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	recentDrop := models.CarPriceChange{OldPrice: 900000, NewPrice: 850000, ChangedAt: now.AddDate(0, 0, -3)}
+	price := func(p int) *int { return &p }
+
+	tests := []struct {
+		name         string
+		change       models.CarPriceChange
+		currentPrice *int
+		want         bool
+	}{
+		{"recent drop", recentDrop, price(850000), true},
+		{"old drop", models.CarPriceChange{OldPrice: 900000, NewPrice: 850000, ChangedAt: now.AddDate(0, 0, -models.RecentlyReducedDays-1)}, price(850000), false},
+		{"recent increase", models.CarPriceChange{OldPrice: 850000, NewPrice: 900000, ChangedAt: now.AddDate(0, 0, -1)}, price(900000), false},
+		{"raised again while unpublished", recentDrop, price(950000), false},
+		{"price removed", recentDrop, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.change.IsRecentDrop(tt.currentPrice, now); got != tt.want {
+				t.Errorf("IsRecentDrop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestDiffCarSnapshots(t *testing.T) {
+	car := &models.Car{Status: "active", Price: intPtr(850000), ConditionRating: intPtr(5), Description: strPtr("Well kept")}
+	from := models.NewCarSnapshot(car, []string{"WHITE"}, []string{"GASOLINE"}, []int{10, 11, 12})
+
+	edited := *car
+	edited.Price = intPtr(820000)
+	edited.ConditionRating = intPtr(3)
+	to := models.NewCarSnapshot(&edited, []string{"WHITE"}, []string{"GASOLINE"}, []int{11, 10, 12})
+
+	changes := models.DiffCarSnapshots(from, to)
+
+	want := map[string][2]string{
+		"conditionRating": {"5", "3"},
+		"imageIds":        {"[10,11,12]", "[11,10,12]"},
+		"price":           {"850000", "820000"},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes %+v, want %d", len(changes), changes, len(want))
+	}
+	for i, c := range changes {
+		w, ok := want[c.Field]
+		if !ok {
+			t.Errorf("unexpected change %q", c.Field)
+			continue
+		}
+		if string(c.From) != w[0] || string(c.To) != w[1] {
+			t.Errorf("%s: got %s -> %s, want %s -> %s", c.Field, c.From, c.To, w[0], w[1])
+		}
+		if i > 0 && changes[i-1].Field > c.Field {
+			t.Errorf("changes not sorted by field: %q before %q", changes[i-1].Field, c.Field)
+		}
+	}
+}
+
+func TestDiffCarSnapshotsFirstRevision(t *testing.T) {
+	// A new draft has no earlier revision; nil relations must not show up as changes
+	to := models.NewCarSnapshot(&models.Car{Status: "draft"}, nil, nil, nil)
+	changes := models.DiffCarSnapshots(models.CarSnapshot{}, to)
+
+	if len(changes) != 1 || changes[0].Field != "status" {
+		t.Fatalf("got %+v, want only status", changes)
+	}
+}
+
+func TestCarSnapshotRoundTrip(t *testing.T) {
+	// Snapshots are stored as JSONB; decoding must give back an identical diff base
+	snap := models.NewCarSnapshot(&models.Car{Status: "active", Year: intPtr(2020)}, []string{"RED", "BLACK"}, nil, []int{1})
+	data, err := json.Marshal(snap)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var decoded models.CarSnapshot
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if changes := models.DiffCarSnapshots(snap, decoded); len(changes) != 0 {
+		t.Errorf("round trip changed fields: %+v", changes)
+	}
+}
+
+func TestCarSnapshotApplyToKeepsStatus(t *testing.T) {
+	// Reverting a sold listing to a revision from when it was active must not relist it
+	snap := models.NewCarSnapshot(&models.Car{Status: "active", Price: intPtr(850000), Year: intPtr(2020)}, nil, nil, nil)
+	car := &models.Car{Status: "sold", Price: intPtr(800000), Year: intPtr(2019)}
+
+	snap.ApplyTo(car)
+
+	if car.Status != "sold" {
+		t.Errorf("status = %q, want it left at sold", car.Status)
+	}
+	if car.Price == nil || *car.Price != 850000 || car.Year == nil || *car.Year != 2020 {
+		t.Errorf("fields not restored: price %v, year %v", car.Price, car.Year)
+	}
+}
+
+func TestCarSnapshotApplyToKeepsChassisNumber(t *testing.T) {
+	// The chassis number is a verified identity field, so a revert must not rewrite it
+	oldChassis, chassis := "MR0FB8CD1K0000001", "MR0FB8CD1K0000002"
+	snap := models.NewCarSnapshot(&models.Car{ChassisNumber: &oldChassis, Price: intPtr(850000)}, nil, nil, nil)
+	car := &models.Car{ChassisNumber: &chassis, Price: intPtr(800000)}
+
+	snap.ApplyTo(car)
+
+	if car.ChassisNumber == nil || *car.ChassisNumber != chassis {
+		t.Errorf("chassis number = %v, want it left at %s", car.ChassisNumber, chassis)
+	}
+	if car.Price == nil || *car.Price != 850000 {
+		t.Errorf("price not restored: %v", car.Price)
+	}
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestNormalizeSearchQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "already normalized",
+			query: "toyota vios",
+			want:  "toyota vios",
+		},
+		{
+			name:  "surrounding and inner whitespace",
+			query: "  toyata \t  vios  ",
+			want:  "toyata vios",
+		},
+		{
+			name:  "thai query",
+			query: " วีออส ",
+			want:  "วีออส",
+		},
+		{
+			name:  "whitespace only",
+			query: "   ",
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.NormalizeSearchQuery(tt.query); got != tt.want {
+				t.Errorf("models.NormalizeSearchQuery() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesRelevanceSort(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.SearchCarsRequest
+		want bool
+	}{
+		{name: "relevance with query", req: models.SearchCarsRequest{SortBy: "relevance", Query: "toyata"}, want: true},
+		{name: "relevance with blank query", req: models.SearchCarsRequest{SortBy: "relevance", Query: "   "}, want: false},
+		{name: "relevance without query", req: models.SearchCarsRequest{SortBy: "relevance"}, want: false},
+		{name: "other sort with query", req: models.SearchCarsRequest{SortBy: "price", Query: "toyota"}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.UsesRelevanceSort(); got != tt.want {
+				t.Errorf("UsesRelevanceSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSearchFacetBuckets(t *testing.T) {
+	buckets := models.NewSearchFacetBuckets([]int{2000, 2010, 2020})
+
+	if len(buckets) != 4 {
+		t.Fatalf("expected 4 buckets, got %d", len(buckets))
+	}
+
+	if buckets[0].Min != nil || buckets[0].Max == nil || *buckets[0].Max != 2000 {
+		t.Errorf("first bucket should be open below 2000, got %+v", buckets[0])
+	}
+	if buckets[1].Min == nil || *buckets[1].Min != 2000 || buckets[1].Max == nil || *buckets[1].Max != 2010 {
+		t.Errorf("second bucket should be [2000, 2010), got %+v", buckets[1])
+	}
+	if buckets[3].Min == nil || *buckets[3].Min != 2020 || buckets[3].Max != nil {
+		t.Errorf("last bucket should be open from 2020, got %+v", buckets[3])
+	}
+	for i, b := range buckets {
+		if b.Count != 0 {
+			t.Errorf("bucket %d should start empty, got count %d", i, b.Count)
+		}
+	}
+}
+
+func TestCarCursorRoundTrip(t *testing.T) {
+	price := 450000
+	createdAt := time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.UTC)
+	car := &models.Car{ID: 42, Price: &price, CreatedAt: createdAt}
+
+	tests := []struct {
+		name      string
+		sortBy    string
+		sortOrder string
+		wantSort  string
+		wantOrder string
+		wantKey   *string
+	}{
+		{
+			name:      "price ascending",
+			sortBy:    "price",
+			sortOrder: "asc",
+			wantSort:  "price",
+			wantOrder: "asc",
+			wantKey:   strPtr("450000"),
+		},
+		{
+			name:      "created_at keeps sub-second precision",
+			sortBy:    "created_at",
+			sortOrder: "desc",
+			wantSort:  "created_at",
+			wantOrder: "desc",
+			wantKey:   strPtr("2025-03-14T09:26:53.589793Z"),
+		},
+		{
+			name:      "missing value encodes a nil key",
+			sortBy:    "mileage",
+			sortOrder: "desc",
+			wantSort:  "mileage",
+			wantOrder: "desc",
+			wantKey:   nil,
+		},
+		{
+			name:      "invalid sort falls back to created_at desc",
+			sortBy:    "seller_id",
+			sortOrder: "sideways",
+			wantSort:  "created_at",
+			wantOrder: "desc",
+			wantKey:   strPtr("2025-03-14T09:26:53.589793Z"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := models.NewCarCursor(car, tt.sortBy, tt.sortOrder).Encode()
+			got, err := models.DecodeCarCursor(token)
+			if err != nil {
+				t.Fatalf("models.DecodeCarCursor() error = %v", err)
+			}
+			if got.SortBy != tt.wantSort || got.SortOrder != tt.wantOrder || got.ID != 42 {
+				t.Errorf("decoded cursor = %+v, want sort %s %s and id 42", got, tt.wantSort, tt.wantOrder)
+			}
+			if (got.Key == nil) != (tt.wantKey == nil) || (got.Key != nil && *got.Key != *tt.wantKey) {
+				t.Errorf("decoded key = %v, want %v", got.Key, tt.wantKey)
+			}
+		})
+	}
+}
+
+func TestDecodeCarCursorRejectsInvalidTokens(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "not base64", token: "***"},
+		{name: "not json", token: encode("price:100")},
+		{name: "unknown sort field", token: encode(`{"s":"seller_id","o":"asc","k":"1","i":1}`)},
+		{name: "invalid order", token: encode(`{"s":"price","o":"up","k":"1","i":1}`)},
+		{name: "missing id", token: encode(`{"s":"price","o":"asc","k":"1"}`)},
+		{name: "non-numeric key", token: encode(`{"s":"price","o":"asc","k":"1; DROP TABLE cars","i":1}`)},
+		{name: "invalid timestamp key", token: encode(`{"s":"created_at","o":"desc","k":"yesterday","i":1}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := models.DecodeCarCursor(tt.token); !errors.Is(err, models.ErrInvalidCursor) {
+				t.Errorf("models.DecodeCarCursor() error = %v, want ErrInvalidCursor", err)
+			}
+		})
+	}
+}
+
+func strPtr(s string) *string { return &s }
+func intPtr(i int) *int       { return &i }
+
+func TestSearchCarsRequestUsesDistanceSort(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.SearchCarsRequest
+		want bool
+	}{
+		{name: "distance with origin", req: models.SearchCarsRequest{SortBy: "distance", OriginProvinceID: intPtr(1)}, want: true},
+		{name: "distance without origin", req: models.SearchCarsRequest{SortBy: "distance"}, want: false},
+		{name: "other sort with origin", req: models.SearchCarsRequest{SortBy: "price", OriginProvinceID: intPtr(1)}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.req.UsesDistanceSort(); got != tt.want {
+				t.Errorf("UsesDistanceSort() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
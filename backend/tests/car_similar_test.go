@@ -0,0 +1,45 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestParseSimilarCarWeights(t *testing.T) {
+	base := models.DefaultSimilarCarsOptions().Weights
+
+	t.Run("overrides only the given weights", func(t *testing.T) {
+		got, err := models.ParseSimilarCarWeights(" price=5, bodyType=0 ,mileage=1.5", base)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := base
+		want.Price = 5
+		want.BodyType = 0
+		want.Mileage = 1.5
+		if got != want {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("empty spec keeps the base weights", func(t *testing.T) {
+		got, err := models.ParseSimilarCarWeights("", base)
+		if err != nil || got != base {
+			t.Errorf("got %+v, %v; want %+v", got, err, base)
+		}
+	})
+
+	invalid := []string{"price", "colour=1", "year=abc", "fuel=-1"}
+	for _, spec := range invalid {
+		t.Run("rejects "+spec, func(t *testing.T) {
+			got, err := models.ParseSimilarCarWeights(spec, base)
+			if err == nil {
+				t.Fatalf("expected error for %q", spec)
+			}
+			if got != base {
+				t.Errorf("weights should be unchanged on error, got %+v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+)
+
+func TestValidateCarStatusTransition(t *testing.T) {
+	allowed := [][2]string{
+		{"draft", "active"},
+		{"active", "draft"}, // the seller's "Unpublish" action
+		{"active", "reserved"},
+		{"reserved", "active"},
+		{"reserved", "sold"},
+		{"active", "sold"},
+		{"expired", "active"},
+		{"sold", "deleted"},
+		{"reserved", "reserved"},
+	}
+	for _, tt := range allowed {
+		if err := services.ValidateCarStatusTransition(tt[0], tt[1]); err != nil {
+			t.Errorf("%s -> %s: unexpected error: %v", tt[0], tt[1], err)
+		}
+	}
+
+	rejected := [][2]string{
+		{"draft", "reserved"},
+		{"draft", "sold"},
+		{"sold", "active"},
+		{"sold", "reserved"},
+		{"deleted", "active"},
+		{"active", "expired"},
+		{"active", "pending"},
+		// Expired listings come back only by renewal
+		{"expired", "draft"},
+		{"expired", "sold"},
+	}
+	for _, tt := range rejected {
+		if err := services.ValidateCarStatusTransition(tt[0], tt[1]); err == nil {
+			t.Errorf("%s -> %s: expected error", tt[0], tt[1])
+		}
+	}
+}
+
+func TestReservationEnd(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	got, err := services.ReservationEnd(nil, now)
+	if err != nil || !got.Equal(now.Add(models.DefaultReservationDuration)) {
+		t.Errorf("default: got %v, %v", got, err)
+	}
+
+	got, err = services.ReservationEnd(intPtr(72), now)
+	if err != nil || !got.Equal(now.Add(72*time.Hour)) {
+		t.Errorf("72 hours: got %v, %v", got, err)
+	}
+
+	for _, hours := range []int{0, -1, int(models.MaxReservationDuration/time.Hour) + 1} {
+		if _, err := services.ReservationEnd(intPtr(hours), now); err == nil {
+			t.Errorf("%d hours: expected error", hours)
+		}
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
@@ -240,15 +242,15 @@ func TestCarHandler_SearchCars(t *testing.T) {
 		name                            string
 		method                          string
 		queryParams                     string
-		searchActiveCarsAsListItemsFunc func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error)
+		searchActiveCarsAsListItemsFunc func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error)
 		expectedStatus                  int
 	}{
 		{
 			name:        "Successful search",
 			method:      "GET",
 			queryParams: "?q=toyota&page=1&limit=20",
-			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error) {
-				return []models.CarListItem{}, 0, nil
+			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
+				return []models.CarListItem{}, 0, "", nil
 			},
 			expectedStatus: http.StatusOK,
 		},
@@ -256,11 +258,51 @@ func TestCarHandler_SearchCars(t *testing.T) {
 			name:        "Search with filters",
 			method:      "GET",
 			queryParams: "?minPrice=100000&maxPrice=500000&minYear=2020",
-			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error) {
-				return []models.CarListItem{}, 0, nil
+			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
+				return []models.CarListItem{}, 0, "", nil
 			},
 			expectedStatus: http.StatusOK,
 		},
+		{
+			name:        "Search with cursor",
+			method:      "GET",
+			queryParams: "?sortBy=price&sortOrder=asc&count=false&cursor=" + models.NewCarCursor(&models.Car{ID: 7, Price: intPtr(450000)}, "price", "asc").Encode(),
+			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
+				if req.After == nil || req.After.ID != 7 || !req.SkipCount {
+					return nil, 0, "", errors.New("cursor not parsed")
+				}
+				return []models.CarListItem{}, -1, "", nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:        "Distance sort from near_province",
+			method:      "GET",
+			queryParams: "?region=Central&near_province=1&radius_km=150&sortBy=distance",
+			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
+				if req.Region != "Central" || req.RadiusKm == nil || *req.RadiusKm != 150 ||
+					req.OriginProvinceID == nil || *req.OriginProvinceID != 1 || !req.UsesDistanceSort() {
+					return nil, 0, "", errors.New("region/distance params not parsed")
+				}
+				return []models.CarListItem{}, 0, "", nil
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Invalid cursor",
+			method:         "GET",
+			queryParams:    "?cursor=not-a-cursor",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:        "Cursor for a different sort",
+			method:      "GET",
+			queryParams: "?sortBy=year&cursor=" + models.NewCarCursor(&models.Car{ID: 7, Price: intPtr(450000)}, "price", "asc").Encode(),
+			searchActiveCarsAsListItemsFunc: func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
+				return nil, 0, "", fmt.Errorf("%w: cursor does not match the requested sort", models.ErrInvalidCursor)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
 		{
 			name:           "Method not allowed",
 			method:         "POST",
@@ -305,22 +347,52 @@ func (h *testCarHandler) SearchCars(w http.ResponseWriter, r *http.Request) {
 		Offset: 0,
 	}
 
+	req.SortBy = query.Get("sortBy")
+	req.SortOrder = query.Get("sortOrder")
+	req.Region = query.Get("region")
+	if nearID, err := strconv.Atoi(query.Get("near_province")); err == nil && nearID > 0 {
+		req.NearProvinceID = &nearID
+	}
+	if radiusKm, err := strconv.Atoi(query.Get("radius_km")); err == nil && radiusKm > 0 {
+		req.RadiusKm = &radiusKm
+	}
+	// Anonymous requests measure distance from near_province
+	if req.SortBy == "distance" {
+		req.OriginProvinceID = req.NearProvinceID
+	}
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := models.DecodeCarCursor(cursor)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid cursor")
+			return
+		}
+		req.After = after
+	}
+	if query.Get("count") == "false" {
+		req.SkipCount = true
+	}
+
 	lang := r.URL.Query().Get("lang")
 	if lang == "" {
 		lang = "en"
 	}
 
-	listItems, total, err := h.carService.SearchActiveCarsAsListItems(req, lang)
+	listItems, total, nextCursor, err := h.carService.SearchActiveCarsAsListItems(req, lang)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			utils.WriteError(w, http.StatusBadRequest, "Cursor does not match the requested sort")
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, "Failed to search cars")
 		return
 	}
 
 	response := models.PaginatedCarListingData{
-		Cars:  listItems,
-		Total: total,
-		Page:  1,
-		Limit: 20,
+		Cars:       listItems,
+		Total:      total,
+		Page:       1,
+		Limit:      20,
+		NextCursor: nextCursor,
 	}
 	utils.WriteJSON(w, http.StatusOK, response, "")
 }
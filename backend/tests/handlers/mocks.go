@@ -221,7 +221,7 @@ func (m *mockRecentViewsService) GetUserRecentViews(userID, limit int, lang stri
 type mockCarService struct {
 	createCarFunc                   func(userID int) (*models.Car, error)
 	getCarWithImagesFunc            func(carID int) (*models.CarWithImages, error)
-	searchActiveCarsAsListItemsFunc func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error)
+	searchActiveCarsAsListItemsFunc func(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error)
 	updateCarFunc                   func(carID, userID int, req *models.UpdateCarRequest, isAdmin bool) error
 	autoSaveDraftFunc               func(carID, userID int, req *models.UpdateCarRequest) error
 	deleteCarFunc                   func(carID, userID int, isAdmin bool) error
@@ -253,11 +253,11 @@ func (m *mockCarService) GetCarWithImages(carID int) (*models.CarWithImages, err
 	return nil, nil
 }
 
-func (m *mockCarService) SearchActiveCarsAsListItems(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, error) {
+func (m *mockCarService) SearchActiveCarsAsListItems(req *models.SearchCarsRequest, lang string) ([]models.CarListItem, int, string, error) {
 	if m.searchActiveCarsAsListItemsFunc != nil {
 		return m.searchActiveCarsAsListItemsFunc(req, lang)
 	}
-	return nil, 0, nil
+	return nil, 0, "", nil
 }
 
 func (m *mockCarService) UpdateCar(carID, userID int, req *models.UpdateCarRequest, isAdmin bool) error {
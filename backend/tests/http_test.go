@@ -42,6 +42,36 @@ func TestWriteError(t *testing.T) {
 	}
 }
 
+func TestWriteErrorWithFields(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	utils.WriteErrorWithFields(rec, http.StatusBadRequest, "invalid rows", map[string]interface{}{
+		"rowErrors": []string{"row 2"},
+		"success":   true, // Standard fields can't be overridden
+	})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Fatalf("expected Content-Type application/json, got %s", ct)
+	}
+
+	var resp struct {
+		utils.Response
+		RowErrors []string `json:"rowErrors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.Success || resp.Code != http.StatusBadRequest || resp.Message != "invalid rows" {
+		t.Errorf("unexpected standard fields: %+v", resp.Response)
+	}
+	if len(resp.RowErrors) != 1 || resp.RowErrors[0] != "row 2" {
+		t.Errorf("expected rowErrors [row 2], got %v", resp.RowErrors)
+	}
+}
+
 // TestWriteJSON_SuccessWithData tests success responses with data (statusCode < 400, data != nil)
 func TestWriteJSON_SuccessWithData(t *testing.T) {
 	rec := httptest.NewRecorder()
@@ -151,3 +181,23 @@ func TestWriteJSON_ErrorViaWriteJSON(t *testing.T) {
 		t.Errorf("expected data to be nil for error responses, got %v", resp.Data)
 	}
 }
+
+func TestETagMatches(t *testing.T) {
+	etag := `"abc123-thumb"`
+	tests := []struct {
+		ifNoneMatch string
+		want        bool
+	}{
+		{`"abc123-thumb"`, true},
+		{`W/"abc123-thumb"`, true}, // Weak comparison
+		{`"other", "abc123-thumb"`, true},
+		{`*`, true},
+		{`"abc123"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		if got := utils.ETagMatches(tt.ifNoneMatch, etag); got != tt.want {
+			t.Errorf("ETagMatches(%q) = %v, want %v", tt.ifNoneMatch, got, tt.want)
+		}
+	}
+}
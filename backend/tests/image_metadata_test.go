@@ -0,0 +1,312 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+// halfAndHalf returns a width x height image, red on the left and blue on the right
+func halfAndHalf(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if x < width/2 {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+	return img
+}
+
+func jpegSegment(marker byte, payload []byte) []byte {
+	n := len(payload) + 2
+	return append([]byte{0xFF, marker, byte(n >> 8), byte(n)}, payload...)
+}
+
+// exifPayload is an APP1 EXIF payload with an orientation and a GPS tag
+func exifPayload(orientation uint16) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 2) // Entries
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01)
+	tiff = binary.BigEndian.AppendUint16(tiff, orientation)
+	tiff = append(tiff, 0x00, 0x00)
+	tiff = append(tiff, 0x88, 0x25, 0x00, 0x04, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x20)
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00) // No next IFD
+	tiff = append(tiff, "GPS 13.7563N 100.5018E"...)
+	return append([]byte("Exif\x00\x00"), tiff...)
+}
+
+// withJPEGMetadata inserts EXIF, XMP, IPTC and comment segments after the SOI marker and
+// appends data after the end of the image
+func withJPEGMetadata(plain []byte, orientation uint16) []byte {
+	var b bytes.Buffer
+	b.Write(plain[:2])
+	b.Write(jpegSegment(0xE1, exifPayload(orientation)))
+	b.Write(jpegSegment(0xE1, []byte("http://ns.adobe.com/xap/1.0/\x00<x:xmpmeta>GPS 13.7563N</x:xmpmeta>")))
+	b.Write(jpegSegment(0xED, []byte("Photoshop 3.0\x00GPS 13.7563N")))
+	b.Write(jpegSegment(0xFE, []byte("GPS 13.7563N")))
+	b.Write(plain[2:])
+	b.WriteString("GPS 13.7563N trailing thumbnail")
+	return b.Bytes()
+}
+
+func TestCleanImageStripsJPEGMetadata(t *testing.T) {
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, halfAndHalf(16, 8), &jpeg.Options{Quality: 90})
+
+	cleaned, err := utils.CleanImage(withJPEGMetadata(plain.Bytes(), 1), "image/jpeg")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	// Upright images are not re-encoded
+	if !bytes.Equal(cleaned.Data, plain.Bytes()) {
+		t.Error("expected the metadata segments and trailing data to be removed losslessly")
+	}
+	if cleaned.Width != 16 || cleaned.Height != 8 {
+		t.Errorf("dimensions = %dx%d, want 16x8", cleaned.Width, cleaned.Height)
+	}
+}
+
+func TestCleanImageOrientsJPEG(t *testing.T) {
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, halfAndHalf(32, 16), &jpeg.Options{Quality: 95})
+
+	// Orientation 6: stored sideways, displayed rotated 90 degrees clockwise
+	cleaned, err := utils.CleanImage(withJPEGMetadata(plain.Bytes(), 6), "image/jpeg")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	if bytes.Contains(cleaned.Data, []byte("GPS")) || bytes.Contains(cleaned.Data, []byte("Exif")) {
+		t.Error("expected no metadata in the re-encoded image")
+	}
+	if cleaned.Width != 16 || cleaned.Height != 32 {
+		t.Fatalf("dimensions = %dx%d, want 16x32", cleaned.Width, cleaned.Height)
+	}
+
+	img, err := jpeg.Decode(bytes.NewReader(cleaned.Data))
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	// The left (red) half is now the top
+	if r, _, b, _ := img.At(8, 4).RGBA(); r < b {
+		t.Errorf("expected red at the top, got %v", img.At(8, 4))
+	}
+	if r, _, b, _ := img.At(8, 28).RGBA(); b < r {
+		t.Errorf("expected blue at the bottom, got %v", img.At(8, 28))
+	}
+}
+
+func TestCleanImageWithinReleasesMemory(t *testing.T) {
+	var plain bytes.Buffer
+	jpeg.Encode(&plain, halfAndHalf(32, 16), &jpeg.Options{Quality: 95})
+	rotated := withJPEGMetadata(plain.Bytes(), 6)
+
+	// A budget smaller than one image still lets it run alone, and a second rotation only
+	// gets the memory back if the first released it
+	mem := utils.NewWeightedSemaphore(1)
+	for i := 0; i < 2; i++ {
+		cleaned, err := utils.CleanImageWithin(rotated, "image/jpeg", mem)
+		if err != nil {
+			t.Fatalf("CleanImageWithin: %v", err)
+		}
+		if cleaned.Width != 16 || cleaned.Height != 32 {
+			t.Fatalf("dimensions = %dx%d, want 16x32", cleaned.Width, cleaned.Height)
+		}
+	}
+}
+
+func TestCleanImageKeepsOrientationOfLargeJPEG(t *testing.T) {
+	// Only the headers of a JPEG too large to decode: 10000x6000 pixels, stored sideways
+	sof := []byte{0x08, 0x17, 0x70, 0x27, 0x10, 0x01, 0x01, 0x11, 0x00} // 8-bit, 6000 rows, 10000 columns, gray
+	var b bytes.Buffer
+	b.Write([]byte{0xFF, 0xD8})
+	b.Write(jpegSegment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00")))
+	b.Write(jpegSegment(0xE1, exifPayload(6)))
+	b.Write(jpegSegment(0xC0, sof))
+	b.Write([]byte{0xFF, 0xD9})
+
+	cleaned, err := utils.CleanImage(b.Bytes(), "image/jpeg")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	if bytes.Contains(cleaned.Data, []byte("GPS")) {
+		t.Error("expected the GPS tag to be removed")
+	}
+	if cleaned.Width != 6000 || cleaned.Height != 10000 {
+		t.Errorf("dimensions = %dx%d, want the displayed 6000x10000", cleaned.Width, cleaned.Height)
+	}
+
+	// The orientation is kept in an EXIF segment right after the JFIF one
+	app0 := jpegSegment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	rest := cleaned.Data[2+len(app0):]
+	if !bytes.HasPrefix(rest, []byte{0xFF, 0xE1}) || !bytes.HasPrefix(rest[4:], []byte("Exif\x00\x00")) {
+		t.Fatal("expected an EXIF segment after the JFIF segment")
+	}
+	if again, err := utils.CleanImage(cleaned.Data, "image/jpeg"); err != nil || !bytes.Equal(again.Data, cleaned.Data) {
+		t.Error("expected cleaning the result again to keep the same orientation tag")
+	}
+}
+
+func TestOrientImage(t *testing.T) {
+	// A B
+	// C D
+	src := image.NewRGBA(image.Rect(0, 0, 2, 2))
+	a, b, c, d := color.RGBA{1, 0, 0, 255}, color.RGBA{2, 0, 0, 255}, color.RGBA{3, 0, 0, 255}, color.RGBA{4, 0, 0, 255}
+	src.SetRGBA(0, 0, a)
+	src.SetRGBA(1, 0, b)
+	src.SetRGBA(0, 1, c)
+	src.SetRGBA(1, 1, d)
+
+	want := map[int][4]color.RGBA{ // Top-left, top-right, bottom-left, bottom-right
+		1: {a, b, c, d},
+		2: {b, a, d, c},
+		3: {d, c, b, a},
+		4: {c, d, a, b},
+		5: {a, c, b, d},
+		6: {c, a, d, b},
+		7: {d, b, c, a},
+		8: {b, d, a, c},
+	}
+	for orientation, w := range want {
+		dst := utils.OrientImage(src, orientation)
+		got := [4]color.RGBA{dst.RGBAAt(0, 0), dst.RGBAAt(1, 0), dst.RGBAAt(0, 1), dst.RGBAAt(1, 1)}
+		if got != w {
+			t.Errorf("orientation %d: got %v, want %v", orientation, got, w)
+		}
+	}
+
+	// Rotations swap width and height
+	if b := utils.OrientImage(image.NewRGBA(image.Rect(0, 0, 3, 1)), 6).Bounds(); b.Dx() != 1 || b.Dy() != 3 {
+		t.Errorf("expected 1x3 after rotating, got %v", b)
+	}
+}
+
+func pngChunk(chunkType string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, chunkType...)
+	chunk = append(chunk, data...)
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(append([]byte(chunkType), data...)))
+}
+
+func TestCleanImageStripsPNGMetadata(t *testing.T) {
+	var plain bytes.Buffer
+	png.Encode(&plain, halfAndHalf(6, 3))
+	data := plain.Bytes()
+
+	// Signature and IHDR, then text and EXIF chunks
+	ihdrEnd := 8 + 12 + 13
+	var withMetadata []byte
+	withMetadata = append(withMetadata, data[:ihdrEnd]...)
+	withMetadata = append(withMetadata, pngChunk("tEXt", []byte("Comment\x00GPS 13.7563N"))...)
+	withMetadata = append(withMetadata, pngChunk("eXIf", exifPayload(1)[6:])...)
+	withMetadata = append(withMetadata, data[ihdrEnd:]...)
+
+	cleaned, err := utils.CleanImage(withMetadata, "image/png")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	if !bytes.Equal(cleaned.Data, data) {
+		t.Error("expected only the metadata chunks to be removed")
+	}
+	if cleaned.Width != 6 || cleaned.Height != 3 {
+		t.Errorf("dimensions = %dx%d, want 6x3", cleaned.Width, cleaned.Height)
+	}
+}
+
+func TestCleanImageStripsGIFComments(t *testing.T) {
+	var plain bytes.Buffer
+	gif.Encode(&plain, halfAndHalf(4, 2), nil)
+	data := plain.Bytes()
+
+	// Header, screen descriptor and global color table, then a comment
+	headerEnd := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		headerEnd += 3 << (flags&0x07 + 1)
+	}
+	comment := append([]byte{0x21, 0xFE, 12}, "GPS 13.7563N"...)
+	comment = append(comment, 0x00)
+	var withMetadata []byte
+	withMetadata = append(withMetadata, data[:headerEnd]...)
+	withMetadata = append(withMetadata, comment...)
+	withMetadata = append(withMetadata, data[headerEnd:]...)
+
+	cleaned, err := utils.CleanImage(withMetadata, "image/gif")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	if !bytes.Equal(cleaned.Data, data) {
+		t.Error("expected only the comment to be removed")
+	}
+	if cleaned.Width != 4 || cleaned.Height != 2 {
+		t.Errorf("dimensions = %dx%d, want 4x2", cleaned.Width, cleaned.Height)
+	}
+}
+
+func riffChunk(fourCC string, data []byte) []byte {
+	chunk := append([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(data)))...)
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func TestCleanImageStripsWebPMetadata(t *testing.T) {
+	// Extended WebP (VP8X with EXIF and XMP flags) around a lossless 300x200 image header
+	vp8x := []byte{0x08 | 0x04, 0, 0, 0}
+	vp8x = append(vp8x, 299&0xFF, 299>>8, 0, 199, 0, 0)
+	bits := uint32(299) | uint32(199)<<14
+	vp8l := append([]byte{0x2F}, binary.LittleEndian.AppendUint32(nil, bits)...)
+
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, riffChunk("VP8X", vp8x)...)
+	body = append(body, riffChunk("VP8L", vp8l)...)
+	body = append(body, riffChunk("EXIF", exifPayload(1)[6:])...)
+	body = append(body, riffChunk("XMP ", []byte("<x:xmpmeta>GPS 13.7563N</x:xmpmeta>"))...)
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+	data = append(data, body...)
+
+	cleaned, err := utils.CleanImage(data, "image/webp")
+	if err != nil {
+		t.Fatalf("CleanImage: %v", err)
+	}
+	if bytes.Contains(cleaned.Data, []byte("GPS")) || bytes.Contains(cleaned.Data, []byte("EXIF")) {
+		t.Error("expected the EXIF and XMP chunks to be removed")
+	}
+	if got := int(binary.LittleEndian.Uint32(cleaned.Data[4:8])); got != len(cleaned.Data)-8 {
+		t.Errorf("RIFF size = %d, want %d", got, len(cleaned.Data)-8)
+	}
+	if flags := cleaned.Data[20]; flags&(0x08|0x04) != 0 {
+		t.Errorf("expected the EXIF and XMP flags to be cleared, got %#x", flags)
+	}
+	if cleaned.Width != 300 || cleaned.Height != 200 {
+		t.Errorf("dimensions = %dx%d, want 300x200", cleaned.Width, cleaned.Height)
+	}
+}
+
+func TestCleanImageRejectsInvalidImages(t *testing.T) {
+	tests := map[string][]byte{
+		"image/jpeg": {0xFF, 0xD8, 0xFF, 0xE1, 0x00},
+		"image/png":  []byte("\x89PNG\r\n\x1a\n\x00\x00"),
+		"image/gif":  []byte("GIF89a"),
+		"image/webp": []byte("RIFF\x04\x00\x00\x00WEBP"),
+	}
+	for contentType, data := range tests {
+		if _, err := utils.CleanImage(data, contentType); !errors.Is(err, utils.ErrInvalidImage) {
+			t.Errorf("%s: expected ErrInvalidImage, got %v", contentType, err)
+		}
+	}
+}
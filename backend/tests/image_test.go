@@ -0,0 +1,178 @@
+package tests
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+func TestFitSize(t *testing.T) {
+	tests := []struct {
+		width, height, maxWidth, maxHeight int
+		wantWidth, wantHeight              int
+	}{
+		{4000, 3000, 480, 360, 480, 360},
+		{3000, 4000, 480, 360, 270, 360}, // Portrait is limited by height
+		{4000, 1000, 480, 360, 480, 120}, // Panorama is limited by width
+		{320, 240, 480, 360, 320, 240},   // Never upscaled
+		{10000, 1, 480, 360, 480, 1},     // At least one pixel
+		{0, 100, 480, 360, 0, 0},
+	}
+	for _, tt := range tests {
+		w, h := utils.FitSize(tt.width, tt.height, tt.maxWidth, tt.maxHeight)
+		if w != tt.wantWidth || h != tt.wantHeight {
+			t.Errorf("FitSize(%d, %d, %d, %d) = %dx%d, want %dx%d",
+				tt.width, tt.height, tt.maxWidth, tt.maxHeight, w, h, tt.wantWidth, tt.wantHeight)
+		}
+	}
+}
+
+func TestScaleToFitAveragesPixels(t *testing.T) {
+	// Left half red, right half blue
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if x < 2 {
+				src.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				src.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	dst := utils.ScaleToFit(src, 2, 2)
+	if dst.Bounds().Dx() != 2 || dst.Bounds().Dy() != 1 {
+		t.Fatalf("expected 2x1, got %v", dst.Bounds())
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("left pixel = %v, want red", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("right pixel = %v, want blue", got)
+	}
+
+	// Red, blue, blue into two pixels: the middle one is split between them
+	dst = utils.ScaleToFit(src.SubImage(image.Rect(1, 0, 4, 1)), 2, 1)
+	if dst.Bounds().Dx() != 2 {
+		t.Fatalf("expected width 2, got %v", dst.Bounds())
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{170, 0, 85, 255}) {
+		t.Errorf("left pixel = %v, want two thirds red and one third blue", got)
+	}
+	if got := dst.RGBAAt(1, 0); got != (color.RGBA{0, 0, 255, 255}) {
+		t.Errorf("right pixel = %v, want blue", got)
+	}
+}
+
+func TestScaleToFitFlattensTransparency(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 2)) // Fully transparent
+	src.Set(0, 0, color.NRGBA{0, 0, 0, 255})
+
+	dst := utils.ScaleToFit(src, 10, 10)
+	if got := dst.RGBAAt(1, 1); got != (color.RGBA{255, 255, 255, 255}) {
+		t.Errorf("transparent pixel = %v, want white", got)
+	}
+	if got := dst.RGBAAt(0, 0); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("opaque pixel = %v, want black", got)
+	}
+}
+
+// landscape returns a smooth test photo with light and dark areas
+func landscape(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			fx, fy := float64(x)/float64(width), float64(y)/float64(height)
+			v := 128 + 100*math.Sin(fx*7)*math.Cos(fy*5+fx*2)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v * 0.8), uint8(255 - v), 255})
+		}
+	}
+	return img
+}
+
+func TestDifferenceHashMatchesCopies(t *testing.T) {
+	original := landscape(640, 480)
+	hash := utils.DifferenceHash(original)
+
+	// Resized and recompressed copies of a photo hash (almost) the same
+	var buf bytes.Buffer
+	jpeg.Encode(&buf, utils.ScaleToFit(original, 200, 200), &jpeg.Options{Quality: 60})
+	recompressed, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if d := utils.HammingDistance(hash, utils.DifferenceHash(recompressed)); d > 4 {
+		t.Errorf("resized copy differs in %d bits, want at most 4", d)
+	}
+
+	// A different photo does not
+	mirrored := image.NewRGBA(original.Bounds())
+	for y := 0; y < 480; y++ {
+		for x := 0; x < 640; x++ {
+			mirrored.SetRGBA(639-x, y, original.RGBAAt(x, y))
+		}
+	}
+	if d := utils.HammingDistance(hash, utils.DifferenceHash(mirrored)); d < 20 {
+		t.Errorf("different photo differs in only %d bits", d)
+	}
+}
+
+func TestDifferenceHashBlankImage(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 30, 20))
+	for i := range img.Pix {
+		img.Pix[i] = 200
+	}
+	if hash := utils.DifferenceHash(img); hash != 0 {
+		t.Errorf("blank image hash = %#x, want 0", hash)
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0xFF00, 0x00FF, 16},
+		{0, math.MaxUint64, 64},
+	}
+	for _, tt := range tests {
+		if got := utils.HammingDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HammingDistance(%#x, %#x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDuplicatePhotoDistanceIsSearchable(t *testing.T) {
+	// Candidates are found by a shared hash byte, which only guarantees finding close matches
+	if services.DuplicatePhotoMaxDistance > models.MaxDuplicatePhotoDistance {
+		t.Errorf("DuplicatePhotoMaxDistance = %d, but the hash index finds matches within %d bits only",
+			services.DuplicatePhotoMaxDistance, models.MaxDuplicatePhotoDistance)
+	}
+}
+
+func TestScaleFlatToFitMatchesScaleToFit(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 8, 6))
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			src.Set(x, y, color.NRGBA{uint8(x * 30), uint8(y * 40), 100, uint8(128 + x*10)})
+		}
+	}
+
+	flat := utils.FlattenOnWhite(src)
+	for _, size := range [][2]int{{4, 4}, {2, 2}, {8, 6}} {
+		want := utils.ScaleToFit(src, size[0], size[1])
+		got := utils.ScaleFlatToFit(flat, size[0], size[1])
+		if !bytes.Equal(got.Pix, want.Pix) || got.Bounds() != want.Bounds() {
+			t.Errorf("%dx%d: scaling the flattened copy differs from ScaleToFit", size[0], size[1])
+		}
+	}
+}
@@ -155,6 +155,35 @@ func TestExtractClientIP(t *testing.T) {
 	}
 }
 
+func TestExtractClientIPBehindProxies(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.10"}
+	tests := []struct {
+		name          string
+		remoteAddr    string
+		xForwardedFor string
+		xRealIP       string
+		trusted       []string
+		want          string
+	}{
+		{name: "headers ignored from an untrusted peer", remoteAddr: "203.0.113.9:5000", xForwardedFor: "198.51.100.1", xRealIP: "198.51.100.2", trusted: trusted, want: "203.0.113.9"},
+		{name: "headers ignored without trusted proxies", remoteAddr: "10.0.0.100:8080", xForwardedFor: "198.51.100.1", trusted: nil, want: "10.0.0.100"},
+		{name: "X-Real-IP from a trusted proxy", remoteAddr: "10.0.0.100:8080", xForwardedFor: "198.51.100.1", xRealIP: "198.51.100.2", trusted: trusted, want: "198.51.100.2"},
+		{name: "spoofed first hop is skipped", remoteAddr: "10.0.0.100:8080", xForwardedFor: "1.2.3.4, 198.51.100.1", trusted: trusted, want: "198.51.100.1"},
+		{name: "trusted hops are skipped", remoteAddr: "192.168.1.10:443", xForwardedFor: "198.51.100.1, 10.0.0.7", trusted: trusted, want: "198.51.100.1"},
+		{name: "only trusted hops", remoteAddr: "10.0.0.100:8080", xForwardedFor: "10.0.0.7", trusted: trusted, want: "10.0.0.100"},
+		{name: "invalid hop stops the walk", remoteAddr: "10.0.0.100:8080", xForwardedFor: "198.51.100.1, junk, 10.0.0.7", trusted: trusted, want: "10.0.0.100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := utils.ExtractClientIPBehindProxies(tt.remoteAddr, tt.xForwardedFor, tt.xRealIP, tt.trusted)
+			if got != tt.want {
+				t.Errorf("utils.ExtractClientIPBehindProxies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNormalizeIPAddress(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -0,0 +1,275 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+func TestParseMarketPriceCSV(t *testing.T) {
+	data := "\ufeffBrand,Model,Sub Model,Year,Price\n" +
+		"toyota,Yaris,1.2  Sport,2018-2020,\"350,000-450,000\"\n" +
+		"HONDA,CITY,,2019,500000\n" +
+		",,,,\n" +
+		"TOYOTA,YARIS,1.2 SPORT,2018 - 2020,400000\n" +
+		"MAZDA,2,,2020-2018,abc\n"
+
+	prices, rowErrors, err := services.ParseMarketPriceCSV(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []services.MarketPrice{
+		{Brand: "TOYOTA", Model: "YARIS", SubModel: "1.2 SPORT", YearStart: 2018, YearEnd: 2020, PriceMin: 350000, PriceMax: 450000},
+		{Brand: "HONDA", Model: "CITY", SubModel: "", YearStart: 2019, YearEnd: 2019, PriceMin: 500000, PriceMax: 500000},
+	}
+	if !reflect.DeepEqual(prices, want) {
+		t.Errorf("prices = %+v, want %+v", prices, want)
+	}
+
+	if len(rowErrors) != 2 {
+		t.Fatalf("got row errors %+v, want rows 5 and 6", rowErrors)
+	}
+	if rowErrors[0].Row != 5 || len(rowErrors[0].Errors) != 1 || !strings.Contains(rowErrors[0].Errors[0], "duplicates row 2") {
+		t.Errorf("row 5: got %+v, want a duplicate of row 2", rowErrors[0])
+	}
+	if rowErrors[1].Row != 6 || len(rowErrors[1].Errors) != 2 {
+		t.Errorf("row 6: got %+v, want year and price errors", rowErrors[1])
+	}
+}
+
+func TestParseMarketPriceCSVSplitColumns(t *testing.T) {
+	data := "brand,model,year_start,year_end,price_min_thb,price_max_thb\n" +
+		"TOYOTA,VIOS 1.5 E,2018,2020,450000,350000\n"
+
+	prices, rowErrors, err := services.ParseMarketPriceCSV(strings.NewReader(data))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("unexpected errors: %v %+v", err, rowErrors)
+	}
+	// Without a sub_model column the model is split like PDF rows; reversed prices are swapped
+	want := services.MarketPrice{Brand: "TOYOTA", Model: "VIOS", SubModel: "1.5 E", YearStart: 2018, YearEnd: 2020, PriceMin: 350000, PriceMax: 450000}
+	if len(prices) != 1 || prices[0] != want {
+		t.Errorf("prices = %+v, want %+v", prices, want)
+	}
+}
+
+func TestParseMarketPriceCSVRejectsFile(t *testing.T) {
+	tests := map[string]string{
+		"empty":            "",
+		"header only":      "brand,model,year,price\n",
+		"unknown column":   "brand,model,year,price,colour\nTOYOTA,YARIS,2019,1,white\n",
+		"duplicate column": "brand,Brand,model,year,price\nA,B,C,2019,1\n",
+		"missing model":    "brand,year,price\nTOYOTA,2019,1\n",
+		"missing year end": "brand,model,year_start,price\nTOYOTA,YARIS,2019,1\n",
+		"both year forms":  "brand,model,year,year_start,year_end,price\nTOYOTA,YARIS,2019,2019,2019,1\n",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, _, err := services.ParseMarketPriceCSV(strings.NewReader(data))
+			if !errors.Is(err, models.ErrInvalidMarketPriceFile) {
+				t.Errorf("got %v, want ErrInvalidMarketPriceFile", err)
+			}
+		})
+	}
+}
+
+func TestParseMarketPriceXLSX(t *testing.T) {
+	var buf bytes.Buffer
+	xw, err := utils.NewXLSXWriter(&buf, "Prices")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]interface{}{
+		{"Brand", "Model", "Sub Model", "Year Start", "Year End", "Price Min", "Price Max"},
+		{"Toyota", "Yaris", "1.2 Sport", 2018, 2020, int64(350000), 450000.0},
+		{},
+		{"Honda", "City", nil, 2019, 2019, 500000, 520000},
+	}
+	for _, row := range rows {
+		if err := xw.WriteRow(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	prices, rowErrors, err := services.ParseMarketPriceXLSX(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil || len(rowErrors) != 0 {
+		t.Fatalf("unexpected errors: %v %+v", err, rowErrors)
+	}
+	want := []services.MarketPrice{
+		{Brand: "TOYOTA", Model: "YARIS", SubModel: "1.2 SPORT", YearStart: 2018, YearEnd: 2020, PriceMin: 350000, PriceMax: 450000},
+		{Brand: "HONDA", Model: "CITY", SubModel: "", YearStart: 2019, YearEnd: 2019, PriceMin: 500000, PriceMax: 520000},
+	}
+	if !reflect.DeepEqual(prices, want) {
+		t.Errorf("prices = %+v, want %+v", prices, want)
+	}
+
+	if _, _, err := services.ParseMarketPriceXLSX(strings.NewReader("not a zip"), 9); !errors.Is(err, models.ErrInvalidMarketPriceFile) {
+		t.Errorf("got %v, want ErrInvalidMarketPriceFile for a non-XLSX file", err)
+	}
+}
+
+func TestReadXLSXRowsSharedStrings(t *testing.T) {
+	// Spreadsheet apps write text to a shared strings table and may skip empty rows and cells
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Data" sheetId="1" r:id="rId3"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId3" Target="worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>brand</t></si><si><r><t>TOY</t></r><r><t>OTA</t></r></si></sst>`,
+		"xl/worksheets/data.xml": `<worksheet><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="C1" t="b"><v>1</v></c></row>` +
+			`<row r="3"><c r="B3" t="s"><v>1</v></c><c r="C3"><v>3.5E5</v></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+
+	rows, err := utils.ReadXLSXRows(bytes.NewReader(data), int64(len(data)), utils.XLSXLimits{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := [][]string{
+		{"brand", "", "TRUE"},
+		nil,
+		{"", "TOYOTA", "350000"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestReadXLSXRowsLimits(t *testing.T) {
+	sheet := func(sheetData string) []byte {
+		return buildZip(t, map[string]string{
+			"xl/worksheets/sheet1.xml": `<worksheet><sheetData>` + sheetData + `</sheetData></worksheet>`,
+		})
+	}
+	tests := []struct {
+		name   string
+		data   []byte
+		limits utils.XLSXLimits
+	}{
+		{name: "column beyond XFD", data: sheet(`<row r="1"><c r="XFE1"><v>1</v></c></row>`)},
+		{name: "four-letter column", data: sheet(`<row r="1"><c r="AAAA1"><v>1</v></c></row>`)},
+		{name: "row beyond the last", data: sheet(`<row r="1048577"><c><v>1</v></c></row>`)},
+		{name: "too many rows", data: sheet(`<row><c><v>1</v></c></row><row><c><v>2</v></c></row><row><c><v>3</v></c></row>`), limits: utils.XLSXLimits{MaxRows: 2}},
+		{name: "too many columns", data: sheet(`<row r="1"><c r="D1"><v>1</v></c></row>`), limits: utils.XLSXLimits{MaxColumns: 3}},
+		{name: "too large uncompressed", data: sheet(`<row r="1"><c r="A1"><v>` + strings.Repeat("1", 4096) + `</v></c></row>`), limits: utils.XLSXLimits{MaxUncompressed: 1024}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := utils.ReadXLSXRows(bytes.NewReader(tt.data), int64(len(tt.data)), tt.limits); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	// Formatted empty cells don't count against the limits
+	data := sheet(`<row r="1"><c r="A1"><v>1</v></c><c r="XFD1" s="1"/></row><row r="5000"><c r="A5000" s="1"/></row>`)
+	rows, err := utils.ReadXLSXRows(bytes.NewReader(data), int64(len(data)), utils.XLSXLimits{MaxRows: 1, MaxColumns: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := [][]string{{"1"}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+}
+
+func TestParseMarketPriceCSVRowLimit(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("brand,model,year,price\n")
+	for i := 0; i <= services.MaxMarketPriceRows; i++ {
+		fmt.Fprintf(&b, "TOYOTA,MODEL %d,2019,500000\n", i)
+	}
+	if _, _, err := services.ParseMarketPriceCSV(strings.NewReader(b.String())); !errors.Is(err, models.ErrInvalidMarketPriceFile) {
+		t.Errorf("got %v, want ErrInvalidMarketPriceFile for %d rows", err, services.MaxMarketPriceRows+1)
+	}
+}
+
+func buildZip(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMarketPriceRollbackTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		history     []models.MarketPriceActivation
+		wantCurrent int // Activation IDs, 0 for none
+		wantTarget  int
+	}{
+		{
+			name:        "nothing activated before",
+			history:     []models.MarketPriceActivation{{ID: 1, VersionID: 1}},
+			wantCurrent: 1,
+		},
+		{
+			// Version 2 was imported without being activated, so it has no activation
+			name:        "staged version never activated is skipped",
+			history:     []models.MarketPriceActivation{{ID: 1, VersionID: 1}, {ID: 2, VersionID: 3}},
+			wantCurrent: 2,
+			wantTarget:  1,
+		},
+		{
+			name:        "reactivated older version rolls back to the one live before it",
+			history:     []models.MarketPriceActivation{{ID: 1, VersionID: 1}, {ID: 2, VersionID: 2}, {ID: 3, VersionID: 1}},
+			wantCurrent: 3,
+			wantTarget:  2,
+		},
+		{
+			name: "repeated rollback steps further back",
+			history: []models.MarketPriceActivation{
+				{ID: 1, VersionID: 1}, {ID: 2, VersionID: 2}, {ID: 3, VersionID: 3, RolledBack: true},
+			},
+			wantCurrent: 2,
+			wantTarget:  1,
+		},
+		{
+			name: "everything rolled back",
+			history: []models.MarketPriceActivation{
+				{ID: 1, VersionID: 1}, {ID: 2, VersionID: 2, RolledBack: true},
+			},
+			wantCurrent: 1,
+		},
+		{name: "no history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			current, target := models.MarketPriceRollbackTarget(tt.history)
+			if got := activationID(current); got != tt.wantCurrent {
+				t.Errorf("current activation = %d, want %d", got, tt.wantCurrent)
+			}
+			if got := activationID(target); got != tt.wantTarget {
+				t.Errorf("rollback target = %d, want %d", got, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func activationID(a *models.MarketPriceActivation) int {
+	if a == nil {
+		return 0
+	}
+	return a.ID
+}
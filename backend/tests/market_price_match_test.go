@@ -0,0 +1,150 @@
+package tests
+
+import (
+	"math"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+	"github.com/uzimpp/CarJai/backend/services"
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+func TestNormalizeVehicleName(t *testing.T) {
+	tests := map[string]string{
+		"Hi-Lander":       "HILANDER",
+		"HILANDER":        "HILANDER",
+		" 1.5 e (Sport) ": "15ESPORT",
+		"Mercedes-Benz":   "MERCEDESBENZ",
+		"โตโยต้า":         "โตโยต้า", // Thai vowel and tone marks are kept
+		"เมอร์เซเดส-เบนซ์": "เมอร์เซเดสเบนซ์",
+	}
+	for in, want := range tests {
+		if got := utils.NormalizeVehicleName(in); got != want {
+			t.Errorf("NormalizeVehicleName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestCanonicalBrand(t *testing.T) {
+	tests := map[string]string{
+		"Toyota":  "TOYOTA",
+		"โตโยต้า": "TOYOTA",
+		"เมอร์เซเดส-เบนซ์": "MERCEDESBENZ",
+		"MERCEDES BENZ": "MERCEDESBENZ",
+		"Benz":          "MERCEDESBENZ",
+		"vw":            "VOLKSWAGEN",
+		"PEEUGEOT":      "PEUGEOT",
+		"Peugeot":       "PEUGEOT",
+	}
+	for in, want := range tests {
+		if got := utils.CanonicalBrand(in); got != want {
+			t.Errorf("CanonicalBrand(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"word", "word", 1},
+		{"Word", "WORD!", 1},
+		{"word", "two words", 4.0 / 11}, // pg_trgm: SELECT similarity('word', 'two words')
+		{"abc", "xyz", 0},
+		{"", "abc", 0},
+	}
+	for _, tt := range tests {
+		if got := utils.TrigramSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("TrigramSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestMatchMarketPriceBrand(t *testing.T) {
+	brands := []string{"HONDA", "ISUZU", "MERCEDES BENZ", "TOYOTA"}
+	tests := []struct {
+		brand     string
+		want      string
+		wantScore float64
+	}{
+		{"Toyota", "TOYOTA", 1},
+		{"โตโยต้า", "TOYOTA", 1},
+		{"Mercedes-Benz", "MERCEDES BENZ", 1},
+		{"เบนซ์", "MERCEDES BENZ", 1},
+		{"T0Y0TA", "", 0},
+		{"ISUSU", "ISUZU", 0.333},
+		{"Ferrari", "", 0},
+	}
+	for _, tt := range tests {
+		got, score := services.MatchMarketPriceBrand(brands, tt.brand)
+		if got != tt.want || math.Abs(score-tt.wantScore) > 0.001 {
+			t.Errorf("MatchMarketPriceBrand(%q) = %q, %.3f; want %q, %.3f", tt.brand, got, score, tt.want, tt.wantScore)
+		}
+	}
+}
+
+func TestMatchMarketPrice(t *testing.T) {
+	candidates := []models.MarketPrice{
+		{ID: 1, Model: "D-MAX", SubModel: "HI-LANDER 1.9 Z"},
+		{ID: 2, Model: "D-MAX", SubModel: "SPARK 1.9 S"},
+		{ID: 3, Model: "MU-X", SubModel: "1.9 ACTIVE"},
+	}
+	tests := []struct {
+		model, submodel string
+		wantID          int
+		exact           bool
+	}{
+		{"Dmax", "Hilander 1.9Z", 1, true},
+		{"D-MAX HILANDER", "1.9 Z", 1, true}, // Words may be in the model or the submodel
+		{"D-Max", "Hi Lander 1.9 ZP", 1, false},
+		{"MUX", "1.9 Activ", 3, false},
+		{"Hilux", "Revo 2.4", 0, false},
+	}
+	for _, tt := range tests {
+		got, score := services.MatchMarketPrice(candidates, tt.model, tt.submodel)
+		switch {
+		case tt.wantID == 0:
+			if got != nil {
+				t.Errorf("%s %s: matched %+v (%.2f), want no match", tt.model, tt.submodel, got, score)
+			}
+		case got == nil || got.ID != tt.wantID:
+			t.Errorf("%s %s: matched %+v, want candidate %d", tt.model, tt.submodel, got, tt.wantID)
+		case tt.exact && score != 1:
+			t.Errorf("%s %s: score %.2f, want 1 for a normalised match", tt.model, tt.submodel, score)
+		case !tt.exact && (score >= 1 || score < services.MarketPriceMatchThreshold):
+			t.Errorf("%s %s: score %.2f, want a fuzzy score", tt.model, tt.submodel, score)
+		}
+	}
+}
+
+func TestMatchMarketPriceExact(t *testing.T) {
+	candidates := []models.MarketPrice{
+		{ID: 1, Model: "D-MAX", SubModel: "HI-LANDER 1.9 Z"},
+		{ID: 2, Model: "MU-X", SubModel: "1.9 ACTIVE"},
+	}
+	tests := []struct {
+		model, submodel string
+		wantID          int
+	}{
+		{"d-max", "Hi-Lander 1.9 Z", 1}, // Case is ignored
+		{"MU-X", "1.9 ACTIVE", 2},
+		{"%", "%", 0}, // LIKE wildcards are plain text
+		{"D-MAX", "HI-LANDER 1.9 _", 0},
+		{"D_MAX", "HI-LANDER 1.9 Z", 0},
+	}
+	for _, tt := range tests {
+		got := services.MatchMarketPriceExact(candidates, tt.model, tt.submodel)
+		switch {
+		case tt.wantID == 0 && got != nil:
+			t.Errorf("%q %q: matched %+v, want no match", tt.model, tt.submodel, got)
+		case tt.wantID != 0 && (got == nil || got.ID != tt.wantID):
+			t.Errorf("%q %q: matched %+v, want candidate %d", tt.model, tt.submodel, got, tt.wantID)
+		}
+	}
+
+	// Wildcards don't reach the fuzzy match either: they normalise away to nothing
+	if got, _ := services.MatchMarketPrice(candidates, "%", "%"); got != nil {
+		t.Errorf("fuzzy match of %q matched %+v, want no match", "%", got)
+	}
+}
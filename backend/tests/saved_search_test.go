@@ -0,0 +1,58 @@
+package tests
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/uzimpp/CarJai/backend/models"
+)
+
+func TestSavedSearchCriteriaJSON(t *testing.T) {
+	// Criteria use the same names as the /api/cars/search query parameters
+	body := `{"q":"toyota","maxPrice":900000,"bodyType":["SUV","PICKUP"],"transmission":"AT","fuelTypes":["DIESEL"]}`
+
+	var c models.SavedSearchCriteria
+	if err := json.Unmarshal([]byte(body), &c); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if c.Query != "toyota" || c.MaxPrice == nil || *c.MaxPrice != 900000 {
+		t.Errorf("unexpected criteria: %+v", c)
+	}
+	if !reflect.DeepEqual(c.BodyTypeCodes, []string{"SUV", "PICKUP"}) {
+		t.Errorf("BodyTypeCodes = %v", c.BodyTypeCodes)
+	}
+
+	// Unset filters are omitted when stored
+	out, err := json.Marshal(models.SavedSearchCriteria{MinYear: intPtr(2018)})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(out) != `{"minYear":2018}` {
+		t.Errorf("marshal = %s", out)
+	}
+}
+
+func TestSavedSearchCriteriaToSearchRequest(t *testing.T) {
+	c := models.SavedSearchCriteria{
+		Query:            "civic",
+		MinPrice:         intPtr(300000),
+		ProvinceID:       intPtr(10),
+		TransmissionCode: strPtr("MT"),
+		ColorCodes:       []string{"WHITE"},
+	}
+
+	req := c.ToSearchRequest()
+	if req.Status != "active" {
+		t.Errorf("Status = %q, want active", req.Status)
+	}
+	if req.Query != "civic" || *req.MinPrice != 300000 || *req.ProvinceID != 10 || *req.TransmissionCode != "MT" {
+		t.Errorf("unexpected request: %+v", req)
+	}
+	if !reflect.DeepEqual(req.ColorCodes, []string{"WHITE"}) {
+		t.Errorf("ColorCodes = %v", req.ColorCodes)
+	}
+	if req.MaxPrice != nil || req.After != nil {
+		t.Errorf("unset filters should stay nil: %+v", req)
+	}
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uzimpp/CarJai/backend/utils"
+)
+
+func TestWeightedSemaphore(t *testing.T) {
+	sem := utils.NewWeightedSemaphore(10)
+	first := sem.Acquire(6)
+
+	// A weight over the limit is clamped to it and waits until everything is released
+	heavy := make(chan int64)
+	go func() { heavy <- sem.Acquire(100) }()
+
+	// Light weights queue behind the heavy one instead of overtaking it
+	time.Sleep(20 * time.Millisecond)
+	light := make(chan int64)
+	go func() { light <- sem.Acquire(2) }()
+
+	select {
+	case <-heavy:
+		t.Fatal("heavy weight acquired while the first was held")
+	case <-light:
+		t.Fatal("light weight overtook the waiting heavy one")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sem.Release(first)
+	held := <-heavy
+	if held != 10 {
+		t.Errorf("heavy weight held %d, want it clamped to 10", held)
+	}
+	select {
+	case <-light:
+		t.Fatal("light weight acquired while the heavy one held the whole limit")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	sem.Release(held)
+	if got := <-light; got != 2 {
+		t.Errorf("light weight held %d, want 2", got)
+	}
+}
@@ -22,10 +22,12 @@ func DisplayColor(colorCode string) string {
 // DisplayStatus converts internal status to user-friendly display
 func DisplayStatus(status string) string {
 	statusMap := map[string]string{
-		"draft":   "Draft",
-		"active":  "Listed",
-		"sold":    "Sold",
-		"deleted": "Deleted",
+		"draft":    "Draft",
+		"active":   "Listed",
+		"reserved": "Reserved",
+		"sold":     "Sold",
+		"expired":  "Expired",
+		"deleted":  "Deleted",
 	}
 	if display, ok := statusMap[status]; ok {
 		return display
@@ -43,6 +43,22 @@ func WriteError(w http.ResponseWriter, statusCode int, message string) {
 	WriteJSON(w, statusCode, nil, message)
 }
 
+// WriteErrorWithFields writes a standardized JSON error response with extra top-level
+// fields, e.g. the invalid rows of an upload. The standard fields can't be overridden.
+func WriteErrorWithFields(w http.ResponseWriter, statusCode int, message string, fields map[string]interface{}) {
+	body := make(map[string]interface{}, len(fields)+3)
+	for k, v := range fields {
+		body[k] = v
+	}
+	body["success"] = false
+	body["code"] = statusCode
+	body["message"] = message
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(body)
+}
+
 // ExtractIDFromPath extracts an integer ID from a URL path by removing a prefix
 // and truncating at the next slash. Useful for routes like /api/cars/{id} or /api/cars/{id}/images
 func ExtractIDFromPath(path, prefix string) (int, error) {
@@ -66,3 +82,16 @@ func ShouldLogResponseBody(environment string) bool {
 	env := strings.ToLower(strings.TrimSpace(environment))
 	return env != "production" && env != "prod"
 }
+
+// ETagMatches reports whether an If-None-Match header matches etag. Tags are compared
+// weakly (a W/ prefix is ignored), as RFC 9110 requires for If-None-Match.
+func ETagMatches(ifNoneMatch, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"image"
+	"image/draw"
+	"math"
+	"math/bits"
+)
+
+// FitSize returns the size of a width x height image scaled down to fit within
+// maxWidth x maxHeight, keeping its aspect ratio. Images that already fit keep their size.
+func FitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if width <= 0 || height <= 0 {
+		return 0, 0
+	}
+	ratio := math.Min(float64(maxWidth)/float64(width), float64(maxHeight)/float64(height))
+	if ratio >= 1 {
+		return width, height
+	}
+	w := int(math.Round(float64(width) * ratio))
+	h := int(math.Round(float64(height) * ratio))
+	return max(w, 1), max(h, 1)
+}
+
+// ScaleToFit returns src flattened onto a white background and scaled down to fit within
+// maxWidth x maxHeight. Pixels are area-averaged, which keeps downscaled photos sharp
+// without aliasing.
+func ScaleToFit(src image.Image, maxWidth, maxHeight int) *image.RGBA {
+	return ScaleFlatToFit(FlattenOnWhite(src), maxWidth, maxHeight)
+}
+
+// ScaleFlatToFit scales an image returned by FlattenOnWhite down to fit within
+// maxWidth x maxHeight. Use it to build several sizes from one flattened copy; flat is
+// returned as is when it already fits.
+func ScaleFlatToFit(flat *image.RGBA, maxWidth, maxHeight int) *image.RGBA {
+	bounds := flat.Bounds()
+	width, height := FitSize(bounds.Dx(), bounds.Dy(), maxWidth, maxHeight)
+	if width == bounds.Dx() && height == bounds.Dy() {
+		return flat
+	}
+	return scaleRGBA(flat, width, height)
+}
+
+// DifferenceHash returns the 64-bit difference hash (dHash) of an image: it is shrunk to
+// 9x8 grayscale pixels and each bit records whether a pixel is brighter than its right
+// neighbour. Resized, recompressed or lightly edited copies of a photo hash within a few
+// bits of each other. Blank images hash to 0.
+func DifferenceHash(src image.Image) uint64 {
+	if src.Bounds().Empty() {
+		return 0
+	}
+	small := scaleRGBA(FlattenOnWhite(src), 9, 8)
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			hash <<= 1
+			if luma(small, x, y) > luma(small, x+1, y) {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// HammingDistance returns the number of bits that differ between two hashes
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// luma returns the brightness of a pixel (ITU-R BT.601)
+func luma(img *image.RGBA, x, y int) float64 {
+	p := img.Pix[img.PixOffset(x, y):]
+	return 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+}
+
+// FlattenOnWhite draws src onto an opaque white RGBA image of the same size, with its
+// origin at (0, 0)
+func FlattenOnWhite(src image.Image) *image.RGBA {
+	bounds := src.Bounds()
+	flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Over)
+	return flat
+}
+
+// scaleRGBA resamples src to width x height, ignoring its aspect ratio
+func scaleRGBA(src *image.RGBA, width, height int) *image.RGBA {
+	// Scale rows, then columns
+	bounds := src.Bounds()
+	tmp := image.NewRGBA(image.Rect(0, 0, width, bounds.Dy()))
+	rowWeights := areaWeights(bounds.Dx(), width)
+	for y := 0; y < bounds.Dy(); y++ {
+		scaleLine(src.Pix[y*src.Stride:], 4, tmp.Pix[y*tmp.Stride:], 4, rowWeights)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	columnWeights := areaWeights(bounds.Dy(), height)
+	for x := 0; x < width; x++ {
+		scaleLine(tmp.Pix[x*4:], tmp.Stride, dst.Pix[x*4:], dst.Stride, columnWeights)
+	}
+	return dst
+}
+
+// areaWeight is the share of source pixels start, start+1, ... in one destination pixel
+type areaWeight struct {
+	start   int
+	weights []float64
+}
+
+// areaWeights maps srcLen pixels onto dstLen pixels by how much of each source pixel
+// falls inside each destination pixel
+func areaWeights(srcLen, dstLen int) []areaWeight {
+	scale := float64(srcLen) / float64(dstLen)
+	result := make([]areaWeight, dstLen)
+	for i := range result {
+		from, to := float64(i)*scale, float64(i+1)*scale
+		start := int(from)
+		end := min(int(math.Ceil(to)), srcLen)
+		weights := make([]float64, end-start)
+		for j := start; j < end; j++ {
+			weights[j-start] = (math.Min(to, float64(j+1)) - math.Max(from, float64(j))) / scale
+		}
+		result[i] = areaWeight{start: start, weights: weights}
+	}
+	return result
+}
+
+// scaleLine resamples one row or column of RGBA pixels, stepping srcStep and dstStep
+// bytes between pixels
+func scaleLine(src []uint8, srcStep int, dst []uint8, dstStep int, weights []areaWeight) {
+	for i, w := range weights {
+		var r, g, b, a float64
+		for k, weight := range w.weights {
+			p := src[(w.start+k)*srcStep:]
+			r += float64(p[0]) * weight
+			g += float64(p[1]) * weight
+			b += float64(p[2]) * weight
+			a += float64(p[3]) * weight
+		}
+		q := dst[i*dstStep:]
+		q[0], q[1], q[2], q[3] = clampUint8(r), clampUint8(g), clampUint8(b), clampUint8(a)
+	}
+}
+
+func clampUint8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
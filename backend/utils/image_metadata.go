@@ -0,0 +1,457 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register decoders for DecodeConfig
+	"image/jpeg"
+	_ "image/png"
+)
+
+// MaxDecodedImagePixels caps the images decoded into memory (about 200MB as RGBA)
+const MaxDecodedImagePixels = 50_000_000
+
+// orientedJPEGQuality is used when a JPEG has to be re-encoded to apply its orientation
+const orientedJPEGQuality = 92
+
+// orientBytesPerPixel estimates the memory a JPEG needs while it is rotated: the decoded
+// source (up to 4 bytes per pixel for CMYK) plus the oriented RGBA copy
+const orientBytesPerPixel = 4 + 4
+
+// ErrInvalidImage is returned for images that cannot be parsed
+var ErrInvalidImage = errors.New("invalid image file")
+
+// CleanedImage is an image with its metadata removed
+type CleanedImage struct {
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// CleanImage removes the metadata of an image (EXIF including GPS, XMP, IPTC, comments and
+// text chunks) and returns it with its dimensions. JPEGs with an EXIF orientation are
+// rotated to match it and re-encoded, except those over MaxDecodedImagePixels, which keep
+// only the orientation tag; otherwise the encoded pixels are kept as they are.
+// WebP images cannot be rotated without a decoder, so only their metadata is removed.
+func CleanImage(data []byte, contentType string) (*CleanedImage, error) {
+	return CleanImageWithin(data, contentType, nil)
+}
+
+// CleanImageWithin is CleanImage with the memory of rotating a JPEG taken from mem while
+// the image is decoded and oriented (unbounded when mem is nil)
+func CleanImageWithin(data []byte, contentType string, mem *WeightedSemaphore) (*CleanedImage, error) {
+	switch contentType {
+	case "image/jpeg", "image/jpg":
+		return cleanJPEG(data, mem)
+	case "image/png":
+		return cleanDecodable(stripPNG(data))
+	case "image/gif":
+		return cleanDecodable(stripGIF(data))
+	case "image/webp":
+		clean, width, height, err := stripWebP(data)
+		if err != nil {
+			return nil, err
+		}
+		return &CleanedImage{Data: clean, Width: width, Height: height}, nil
+	default:
+		return nil, fmt.Errorf("%w: unsupported type %s", ErrInvalidImage, contentType)
+	}
+}
+
+func invalidImage(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidImage, reason)
+}
+
+// cleanDecodable reads the dimensions of a stripped PNG or GIF
+func cleanDecodable(clean []byte, err error) (*CleanedImage, error) {
+	if err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(clean))
+	if err != nil {
+		return nil, invalidImage(err.Error())
+	}
+	return &CleanedImage{Data: clean, Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// cleanJPEG strips a JPEG and applies its EXIF orientation, holding memory from mem (if
+// any) while it is rotated
+func cleanJPEG(data []byte, mem *WeightedSemaphore) (*CleanedImage, error) {
+	clean, orientation, err := stripJPEG(data)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(clean))
+	if err != nil {
+		return nil, invalidImage(err.Error())
+	}
+	if orientation == 1 {
+		return &CleanedImage{Data: clean, Width: cfg.Width, Height: cfg.Height}, nil
+	}
+	if cfg.Width*cfg.Height > MaxDecodedImagePixels {
+		// Too large to rotate here: keep just the orientation tag so viewers still display it upright
+		width, height := cfg.Width, cfg.Height
+		if orientation >= 5 {
+			width, height = height, width
+		}
+		return &CleanedImage{Data: withJPEGOrientation(clean, orientation), Width: width, Height: height}, nil
+	}
+
+	if mem != nil {
+		held := mem.Acquire(int64(cfg.Width) * int64(cfg.Height) * orientBytesPerPixel)
+		defer mem.Release(held)
+	}
+	src, err := jpeg.Decode(bytes.NewReader(clean))
+	if err != nil {
+		return nil, invalidImage(err.Error())
+	}
+	oriented := OrientImage(src, orientation)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, oriented, &jpeg.Options{Quality: orientedJPEGQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode image: %w", err)
+	}
+	return &CleanedImage{Data: buf.Bytes(), Width: oriented.Bounds().Dx(), Height: oriented.Bounds().Dy()}, nil
+}
+
+// stripJPEG removes metadata segments and anything after the end of the image (phones
+// append extra images there), keeping JFIF, ICC profile and Adobe segments. It also
+// returns the EXIF orientation (1 when there is none).
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, 0, invalidImage("not a JPEG")
+	}
+	var out bytes.Buffer
+	out.Write(data[:2])
+	orientation := 1
+
+	i := 2
+	for {
+		if i >= len(data) || data[i] != 0xFF {
+			return nil, 0, invalidImage("truncated JPEG")
+		}
+		for i < len(data) && data[i] == 0xFF { // Fill bytes
+			i++
+		}
+		if i >= len(data) {
+			return nil, 0, invalidImage("truncated JPEG")
+		}
+		marker := data[i]
+		i++
+
+		switch {
+		case marker == 0xD9: // End of image
+			out.Write([]byte{0xFF, 0xD9})
+			return out.Bytes(), orientation, nil
+		case marker >= 0xD0 && marker <= 0xD7, marker == 0x01: // No length
+			out.Write([]byte{0xFF, marker})
+			continue
+		}
+
+		if i+2 > len(data) {
+			return nil, 0, invalidImage("truncated JPEG")
+		}
+		length := int(data[i])<<8 | int(data[i+1])
+		if length < 2 || i+length > len(data) {
+			return nil, 0, invalidImage("truncated JPEG")
+		}
+		segment := data[i : i+length]
+		payload := segment[2:]
+		i += length
+
+		switch {
+		case marker == 0xE1: // EXIF and XMP
+			if orientation == 1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")) {
+				orientation = exifOrientation(payload[6:])
+			}
+			continue
+		case marker == 0xE2 && !bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")):
+			continue
+		case marker >= 0xE3 && marker <= 0xED, marker == 0xEF, marker == 0xFE: // Other APPn, comments
+			continue
+		}
+		out.Write([]byte{0xFF, marker})
+		out.Write(segment)
+
+		if marker == 0xDA { // Start of scan: copy the entropy-coded data up to the next marker
+			start := i
+			for i < len(data) && !(data[i] == 0xFF && i+1 < len(data) && data[i+1] != 0x00 && (data[i+1] < 0xD0 || data[i+1] > 0xD7)) {
+				i++
+			}
+			out.Write(data[start:i])
+		}
+	}
+}
+
+// exifOrientation reads the orientation tag (1-8) from EXIF TIFF data
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	offset := int(order.Uint32(tiff[4:8]))
+	if offset < 8 || offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for k := 0; k < count; k++ {
+		entry := offset + 2 + 12*k
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) != 0x0112 {
+			continue
+		}
+		if order.Uint16(tiff[entry+2:]) != 3 { // SHORT
+			return 1
+		}
+		if v := int(order.Uint16(tiff[entry+8:])); v >= 1 && v <= 8 {
+			return v
+		}
+		return 1
+	}
+	return 1
+}
+
+// withJPEGOrientation adds an EXIF segment holding only the orientation tag to a stripped
+// JPEG, after its JFIF segment if it has one
+func withJPEGOrientation(clean []byte, orientation int) []byte {
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1)                       // Entries
+	tiff = append(tiff, 0x01, 0x12, 0x00, 0x03, 0x00, 0x00, 0x00, 0x01) // Orientation, one SHORT
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = append(tiff, 0x00, 0x00)             // Value padding
+	tiff = append(tiff, 0x00, 0x00, 0x00, 0x00) // No next IFD
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+
+	at := 2
+	if len(clean) >= 6 && clean[2] == 0xFF && clean[3] == 0xE0 {
+		at = min(4+(int(clean[4])<<8|int(clean[5])), len(clean))
+	}
+	out := make([]byte, 0, len(clean)+4+len(payload))
+	out = append(out, clean[:at]...)
+	out = append(out, 0xFF, 0xE1, byte((len(payload)+2)>>8), byte(len(payload)+2))
+	out = append(out, payload...)
+	return append(out, clean[at:]...)
+}
+
+// OrientImage returns src transformed as EXIF orientation 1-8 describes, so it displays
+// upright without the tag
+func OrientImage(src image.Image, orientation int) *image.RGBA {
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	flat := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(flat, flat.Bounds(), src, bounds.Min, draw.Src)
+	if orientation <= 1 || orientation > 8 {
+		return flat
+	}
+
+	dw, dh := w, h
+	if orientation >= 5 { // Rotated by 90 degrees
+		dw, dh = h, w
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // Mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // Rotated 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // Mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // Transposed
+				dx, dy = y, x
+			case 6: // Rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // Transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // Rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dy*dst.Stride+dx*4:dy*dst.Stride+dx*4+4], flat.Pix[y*flat.Stride+x*4:y*flat.Stride+x*4+4])
+		}
+	}
+	return dst
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// stripPNG removes text, EXIF and timestamp chunks and anything after the end of the image
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, invalidImage("not a PNG")
+	}
+	var out bytes.Buffer
+	out.Write(pngSignature)
+
+	i := len(pngSignature)
+	for i+12 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		chunkType := string(data[i+4 : i+8])
+		end := i + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, invalidImage("truncated PNG")
+		}
+		switch chunkType {
+		case "tEXt", "zTXt", "iTXt", "eXIf", "tIME":
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+	return nil, invalidImage("truncated PNG")
+}
+
+// stripGIF removes comment and application extensions, except animation looping
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || !(bytes.HasPrefix(data, []byte("GIF87a")) || bytes.HasPrefix(data, []byte("GIF89a"))) {
+		return nil, invalidImage("not a GIF")
+	}
+	i := 13
+	if flags := data[10]; flags&0x80 != 0 { // Global color table
+		i += 3 << (flags&0x07 + 1)
+	}
+	if i > len(data) {
+		return nil, invalidImage("truncated GIF")
+	}
+	var out bytes.Buffer
+	out.Write(data[:i])
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x3B: // Trailer
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+		case 0x21: // Extension
+			if i+2 > len(data) {
+				return nil, invalidImage("truncated GIF")
+			}
+			end, err := skipGIFSubBlocks(data, i+2)
+			if err != nil {
+				return nil, err
+			}
+			keep := true
+			switch data[i+1] {
+			case 0xFE: // Comment
+				keep = false
+			case 0xFF: // Application
+				app := ""
+				if i+14 <= len(data) && data[i+2] == 11 {
+					app = string(data[i+3 : i+14])
+				}
+				keep = app == "NETSCAPE2.0" || app == "ANIMEXTS1.0"
+			}
+			if keep {
+				out.Write(data[i:end])
+			}
+			i = end
+		case 0x2C: // Image
+			if i+10 > len(data) {
+				return nil, invalidImage("truncated GIF")
+			}
+			start := i + 10
+			if flags := data[i+9]; flags&0x80 != 0 { // Local color table
+				start += 3 << (flags&0x07 + 1)
+			}
+			end, err := skipGIFSubBlocks(data, start+1) // After the LZW minimum code size
+			if err != nil {
+				return nil, err
+			}
+			out.Write(data[i:end])
+			i = end
+		default:
+			return nil, invalidImage("corrupt GIF")
+		}
+	}
+	// Missing trailer: browsers accept it, so add one
+	out.WriteByte(0x3B)
+	return out.Bytes(), nil
+}
+
+// skipGIFSubBlocks returns the position after the data sub-blocks starting at i
+func skipGIFSubBlocks(data []byte, i int) (int, error) {
+	for {
+		if i >= len(data) {
+			return 0, invalidImage("truncated GIF")
+		}
+		n := int(data[i])
+		i += 1 + n
+		if n == 0 {
+			return i, nil
+		}
+	}
+}
+
+// stripWebP removes EXIF and XMP chunks and returns the canvas size
+func stripWebP(data []byte) ([]byte, int, int, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, 0, 0, invalidImage("not a WebP")
+	}
+	limit := min(len(data), 8+int(binary.LittleEndian.Uint32(data[4:8])))
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	vp8x := -1
+	width, height := 0, 0
+
+	i := 12
+	for i+8 <= limit {
+		fourCC := string(data[i : i+4])
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		if i+8+size > limit {
+			return nil, 0, 0, invalidImage("truncated WebP")
+		}
+		payload := data[i+8 : i+8+size]
+		end := min(i+8+size+size&1, limit) // Chunks are padded to an even size
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			i = end
+			continue
+		case "VP8X":
+			if size >= 10 {
+				width = 1 + (int(payload[4]) | int(payload[5])<<8 | int(payload[6])<<16)
+				height = 1 + (int(payload[7]) | int(payload[8])<<8 | int(payload[9])<<16)
+				vp8x = len(out)
+			}
+		case "VP8 ":
+			if width == 0 && size >= 10 && payload[3] == 0x9D && payload[4] == 0x01 && payload[5] == 0x2A {
+				width = int(binary.LittleEndian.Uint16(payload[6:])) & 0x3FFF
+				height = int(binary.LittleEndian.Uint16(payload[8:])) & 0x3FFF
+			}
+		case "VP8L":
+			if width == 0 && size >= 5 && payload[0] == 0x2F {
+				bits := binary.LittleEndian.Uint32(payload[1:])
+				width = int(bits&0x3FFF) + 1
+				height = int(bits>>14&0x3FFF) + 1
+			}
+		}
+		out = append(out, data[i:end]...)
+		i = end
+	}
+	if width == 0 || height == 0 {
+		return nil, 0, 0, invalidImage("WebP without image data")
+	}
+
+	if vp8x >= 0 {
+		out[vp8x+8] &^= 0x08 | 0x04 // Clear the EXIF and XMP flags
+	}
+	binary.LittleEndian.PutUint32(out[4:8], uint32(len(out)-8))
+	return out, width, height, nil
+}
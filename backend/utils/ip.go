@@ -117,6 +117,43 @@ func ExtractClientIP(remoteAddr, xForwardedFor, xRealIP string) string {
 	return ""
 }
 
+// ExtractClientIPBehindProxies returns the client IP of a request, honouring X-Real-IP and
+// X-Forwarded-For only when the connection comes from one of the trusted proxies (IPs or
+// CIDRs). X-Forwarded-For is read from the right, skipping trusted proxies, since a client
+// can put anything at its start.
+func ExtractClientIPBehindProxies(remoteAddr, xForwardedFor, xRealIP string, trustedProxies []string) string {
+	peer := remoteAddr
+	if host, _, err := net.SplitHostPort(remoteAddr); err == nil {
+		peer = host
+	}
+	if !isTrustedProxy(peer, trustedProxies) {
+		return peer
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(xRealIP)); ip != nil {
+		return ip.String()
+	}
+	hops := strings.Split(xForwardedFor, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			break
+		}
+		if !isTrustedProxy(ip.String(), trustedProxies) {
+			return ip.String()
+		}
+	}
+	return peer
+}
+
+func isTrustedProxy(ip string, trustedProxies []string) bool {
+	if len(trustedProxies) == 0 {
+		return false
+	}
+	trusted, _ := IsIPWhitelisted(ip, trustedProxies)
+	return trusted
+}
+
 // NormalizeIPAddress normalizes an IP address to its canonical form
 func NormalizeIPAddress(ip string) (string, error) {
 	parsedIP := net.ParseIP(ip)
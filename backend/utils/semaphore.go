@@ -0,0 +1,55 @@
+package utils
+
+import "sync"
+
+// WeightedSemaphore limits the total weight (e.g. bytes of memory) held at once. Waiters
+// are served in arrival order, so a heavy request is not starved by a stream of light ones.
+type WeightedSemaphore struct {
+	mu      sync.Mutex
+	limit   int64
+	used    int64
+	waiters []*semaphoreWaiter
+}
+
+type semaphoreWaiter struct {
+	weight int64
+	ready  chan struct{}
+}
+
+// NewWeightedSemaphore creates a semaphore that holds at most limit at once
+func NewWeightedSemaphore(limit int64) *WeightedSemaphore {
+	return &WeightedSemaphore{limit: limit}
+}
+
+// Acquire blocks until weight is available and returns the weight held, which must be
+// passed to Release. Weights above the limit are clamped to it, so they run alone.
+func (s *WeightedSemaphore) Acquire(weight int64) int64 {
+	weight = min(max(weight, 0), s.limit)
+
+	s.mu.Lock()
+	if len(s.waiters) == 0 && s.used+weight <= s.limit {
+		s.used += weight
+		s.mu.Unlock()
+		return weight
+	}
+	w := &semaphoreWaiter{weight: weight, ready: make(chan struct{})}
+	s.waiters = append(s.waiters, w)
+	s.mu.Unlock()
+
+	<-w.ready
+	return weight
+}
+
+// Release returns weight taken by Acquire and wakes the waiters that now fit
+func (s *WeightedSemaphore) Release(weight int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.used -= weight
+	for len(s.waiters) > 0 && s.used+s.waiters[0].weight <= s.limit {
+		w := s.waiters[0]
+		s.waiters = s.waiters[1:]
+		s.used += w.weight
+		close(w.ready)
+	}
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// brandAliases maps normalised alternative brand names (Thai names, abbreviations and the
+// spellings of the DLT price list) to one canonical normalised name
+var brandAliases = map[string]string{
+	"โตโยต้า":         "TOYOTA",
+	"โตโยตา":          "TOYOTA",
+	"ฮอนด้า":          "HONDA",
+	"ฮอนดา":           "HONDA",
+	"อีซูซุ":          "ISUZU",
+	"อิซูซุ":          "ISUZU",
+	"นิสสัน":          "NISSAN",
+	"มิตซูบิชิ":       "MITSUBISHI",
+	"มาสด้า":          "MAZDA",
+	"มาสดา":           "MAZDA",
+	"ฟอร์ด":           "FORD",
+	"เชฟโรเลต":        "CHEVROLET",
+	"เชฟโรเลท":        "CHEVROLET",
+	"CHEVY":           "CHEVROLET",
+	"ซูซูกิ":          "SUZUKI",
+	"เมอร์เซเดสเบนซ์": "MERCEDESBENZ",
+	"เบนซ์":           "MERCEDESBENZ",
+	"BENZ":            "MERCEDESBENZ",
+	"MERCEDES":        "MERCEDESBENZ",
+	"บีเอ็มดับเบิลยู": "BMW",
+	"บีเอ็มดับบลิว":   "BMW",
+	"ฮุนได":        "HYUNDAI",
+	"ฮุนไดย":       "HYUNDAI",
+	"เกีย":         "KIA",
+	"เอ็มจี":       "MG",
+	"ซูบารุ":       "SUBARU",
+	"วอลโว่":       "VOLVO",
+	"วอลโว":        "VOLVO",
+	"ออดี้":        "AUDI",
+	"โฟล์คสวาเกน":  "VOLKSWAGEN",
+	"VW":           "VOLKSWAGEN",
+	"เล็กซัส":      "LEXUS",
+	"ปอร์เช่":      "PORSCHE",
+	"พอร์ช":        "PORSCHE",
+	"เปอโยต์":      "PEUGEOT",
+	"PEEUGEOT":     "PEUGEOT", // Spelling of the DLT price list
+	"แลนด์โรเวอร์": "LANDROVER",
+	"เรนจ์โรเวอร์": "RANGEROVER",
+	"มินิ":         "MINI",
+	"จี๊ป":         "JEEP",
+	"บีวายดี":      "BYD",
+	"เนต้า":        "NETA",
+	"โอร่า":        "ORA",
+	"ฮาวาล":        "HAVAL",
+	"ไดฮัทสุ":      "DAIHATSU",
+	"เทสลา":        "TESLA",
+	"โปรตอน":       "PROTON",
+	"ซันยอง":       "SSANGYONG",
+	"ฮีโน่":        "HINO",
+	"เฟียต":        "FIAT",
+	"ทาทา":         "TATA",
+	"จากัวร์":      "JAGUAR",
+	"เฟอร์รารี่":   "FERRARI",
+	"ลัมโบร์กินี":  "LAMBORGHINI",
+	"เชอรี่":       "CHERY",
+	"ไอออน":        "AION",
+	"ซีเคอร์":      "ZEEKR",
+	"โรลส์รอยซ์":   "ROLLSROYCE",
+	"เบนท์ลีย์":    "BENTLEY",
+	"เกรทวอลล์มอเตอร์": "GWM",
+	"GREATWALL": "GWM",
+}
+
+// NormalizeVehicleName folds case and strips punctuation and spaces from a brand, model or
+// submodel name, so "Hi-Lander" and "HILANDER" compare equal. Thai letters and their
+// vowel and tone marks are kept.
+func NormalizeVehicleName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// CanonicalBrand returns the normalised canonical name of a brand, resolving Thai names and
+// other aliases ("โตโยต้า" and "Toyota" both give "TOYOTA")
+func CanonicalBrand(brand string) string {
+	name := NormalizeVehicleName(brand)
+	if canonical, ok := brandAliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// TrigramSimilarity returns how similar two strings are in [0, 1], computed like pg_trgm's
+// similarity(): the share of distinct three-character sequences of the padded, lower-cased
+// words that the strings have in common
+func TrigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range ta {
+		if tb[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(ta)+len(tb)-common)
+}
+
+// trigrams returns the trigram set of a string. Like pg_trgm, words are runs of letters
+// and digits, each padded with two spaces in front and one behind.
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsMark(r)
+	})
+	for _, word := range words {
+		runes := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(runes); i++ {
+			set[string(runes[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,463 @@
+package utils
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// XLSXWriter streams a single-sheet XLSX workbook row by row. Rows are written straight
+// to the zip entry, so memory use does not grow with the number of rows.
+type XLSXWriter struct {
+	zw    *zip.Writer
+	sheet *bufio.Writer
+	rows  int
+}
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+// NewXLSXWriter writes the workbook parts and opens the sheet for rows.
+// Sheet names are limited to 31 characters by Excel.
+func NewXLSXWriter(w io.Writer, sheetName string) (*XLSXWriter, error) {
+	zw := zip.NewWriter(w)
+
+	var name bytes.Buffer
+	if err := xml.EscapeText(&name, []byte(sheetName)); err != nil {
+		return nil, err
+	}
+	parts := []struct{ path, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, name.String())},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		f, err := zw.Create(part.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", part.path, err)
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", part.path, err)
+		}
+	}
+
+	// The sheet is the last entry so rows can be appended until Close
+	f, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, fmt.Errorf("failed to write sheet: %w", err)
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	return &XLSXWriter{zw: zw, sheet: sheet}, nil
+}
+
+// WriteRow appends a row. Integers and floats become numbers, bools become booleans,
+// times are written as "2006-01-02 15:04:05" text and nil leaves the cell empty.
+// Anything else is written as text.
+func (x *XLSXWriter) WriteRow(cells []interface{}) error {
+	x.rows++
+	fmt.Fprintf(x.sheet, `<row r="%d">`, x.rows)
+	for i, cell := range cells {
+		ref := XLSXColumnName(i) + strconv.Itoa(x.rows)
+		switch v := cell.(type) {
+		case nil:
+			continue
+		case int:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case int64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%d</v></c>`, ref, v)
+		case float64:
+			fmt.Fprintf(x.sheet, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(v, 'f', -1, 64))
+		case bool:
+			b := 0
+			if v {
+				b = 1
+			}
+			fmt.Fprintf(x.sheet, `<c r="%s" t="b"><v>%d</v></c>`, ref, b)
+		case time.Time:
+			x.writeText(ref, v.Format("2006-01-02 15:04:05"))
+		case string:
+			x.writeText(ref, v)
+		default:
+			x.writeText(ref, fmt.Sprint(v))
+		}
+	}
+	_, err := x.sheet.WriteString(`</row>`)
+	return err
+}
+
+// writeText writes an inline string cell (no shared strings table is needed)
+func (x *XLSXWriter) writeText(ref, text string) {
+	fmt.Fprintf(x.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref)
+	xml.EscapeText(x.sheet, []byte(text))
+	x.sheet.WriteString(`</t></is></c>`)
+}
+
+// Close finishes the sheet and the zip archive
+func (x *XLSXWriter) Close() error {
+	if _, err := x.sheet.WriteString(`</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zw.Close()
+}
+
+// XLSXColumnName converts a 0-based column index to its letters (0 → A, 26 → AA)
+func XLSXColumnName(index int) string {
+	name := ""
+	for index >= 0 {
+		name = string(rune('A'+index%26)) + name
+		index = index/26 - 1
+	}
+	return name
+}
+
+// xlsxRichText is the text of a shared or inline string, which is either plain (<t>) or
+// made of formatted runs (<r><t>)
+type xlsxRichText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxRichText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.T)
+	}
+	return b.String()
+}
+
+// xlsxCell is a <c> element of a worksheet
+type xlsxCell struct {
+	Ref       string       `xml:"r,attr"`
+	Type      string       `xml:"t,attr"`
+	Value     string       `xml:"v"`
+	InlineStr xlsxRichText `xml:"is"`
+}
+
+// Largest row number and column index (XFD) a worksheet can have
+const (
+	xlsxMaxRowNumber   = 1048576
+	xlsxMaxColumnIndex = 16383
+)
+
+// XLSXLimits bounds what ReadXLSXRows reads from an untrusted workbook. Zero values leave
+// only the worksheet's own limits (1,048,576 rows and 16,384 columns).
+type XLSXLimits struct {
+	MaxRows         int   // Rows with a non-empty cell
+	MaxColumns      int   // Non-empty cells must be in the first MaxColumns columns
+	MaxUncompressed int64 // Total uncompressed size of the parts read
+}
+
+// ReadXLSXRows reads the first worksheet of an XLSX workbook as text. Missing rows and
+// cells are returned empty, so rows[i][j] is the cell in spreadsheet row i+1, column j;
+// rows end at their last non-empty cell. Numbers are returned in plain notation ("520000",
+// "1.5"), booleans as TRUE or FALSE, and formulas as their last calculated value. Reading
+// stops with an error as soon as the workbook exceeds limits.
+func ReadXLSXRows(r io.ReaderAt, size int64, limits XLSXLimits) ([][]string, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not an XLSX file: %w", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	maxRows := limits.MaxRows
+	if maxRows <= 0 || maxRows > xlsxMaxRowNumber {
+		maxRows = xlsxMaxRowNumber
+	}
+	maxColumns := limits.MaxColumns
+	if maxColumns <= 0 || maxColumns > xlsxMaxColumnIndex+1 {
+		maxColumns = xlsxMaxColumnIndex + 1
+	}
+	budget := &xlsxBudget{remaining: limits.MaxUncompressed, limited: limits.MaxUncompressed > 0}
+
+	sheetPath, err := xlsxFirstSheetPath(files, budget)
+	if err != nil {
+		return nil, err
+	}
+	sheet, ok := files[sheetPath]
+	if !ok {
+		return nil, fmt.Errorf("worksheet %s is missing", sheetPath)
+	}
+
+	var sharedStrings []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxRichText `xml:"si"`
+		}
+		if err := decodeXLSXPart(f, budget, &sst); err != nil {
+			return nil, err
+		}
+		sharedStrings = make([]string, len(sst.Items))
+		for i, item := range sst.Items {
+			sharedStrings[i] = item.String()
+		}
+	}
+
+	rc, err := budget.open(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var rows [][]string
+	rowNum := 0   // Spreadsheet row of the cells being read
+	nonEmpty := 0 // Rows with a non-empty cell
+	counted := -1 // Last row counted in nonEmpty
+	dec := xml.NewDecoder(rc)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read worksheet: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "row":
+			next := rowNum + 1
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "r" {
+					if n, err := strconv.Atoi(attr.Value); err == nil && n >= next {
+						next = n
+					}
+				}
+			}
+			if next > xlsxMaxRowNumber {
+				return nil, fmt.Errorf("row %d is beyond the last worksheet row", next)
+			}
+			rowNum = next
+		case "c":
+			if rowNum == 0 {
+				rowNum = 1
+			}
+			var cell xlsxCell
+			if err := dec.DecodeElement(&cell, &start); err != nil {
+				return nil, fmt.Errorf("failed to read worksheet: %w", err)
+			}
+			text, err := xlsxCellText(cell, sharedStrings)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", cell.Ref, err)
+			}
+			if text == "" {
+				continue // Formatted empty cells can reach the last column
+			}
+
+			if counted != rowNum {
+				counted = rowNum
+				if nonEmpty++; nonEmpty > maxRows {
+					return nil, fmt.Errorf("worksheet has more than %d rows", maxRows)
+				}
+			}
+			for len(rows) < rowNum {
+				rows = append(rows, nil)
+			}
+			row := &rows[rowNum-1]
+			col := len(*row)
+			if cell.Ref != "" {
+				c, ok := xlsxColumnIndex(cell.Ref)
+				if !ok {
+					return nil, fmt.Errorf("invalid cell reference %q", cell.Ref)
+				}
+				if c >= col {
+					col = c
+				}
+			}
+			if col >= maxColumns {
+				return nil, fmt.Errorf("cell %s is beyond the first %d columns", XLSXColumnName(col)+strconv.Itoa(rowNum), maxColumns)
+			}
+			for len(*row) <= col {
+				*row = append(*row, "")
+			}
+			(*row)[col] = text
+		}
+	}
+	return rows, nil
+}
+
+// xlsxBudget is the uncompressed size left for the parts of a workbook, so a small zip
+// can't expand into gigabytes of XML
+type xlsxBudget struct {
+	remaining int64
+	limited   bool
+}
+
+// open opens a part whose reads are charged to the budget
+func (b *xlsxBudget) open(f *zip.File) (io.ReadCloser, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", f.Name, err)
+	}
+	if !b.limited {
+		return rc, nil
+	}
+	return &xlsxBudgetReader{ReadCloser: rc, budget: b}, nil
+}
+
+type xlsxBudgetReader struct {
+	io.ReadCloser
+	budget *xlsxBudget
+}
+
+func (r *xlsxBudgetReader) Read(p []byte) (int, error) {
+	if r.budget.remaining <= 0 {
+		// The budget may end exactly at the end of the part
+		var probe [1]byte
+		if n, err := r.ReadCloser.Read(probe[:]); n == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, fmt.Errorf("workbook is too large when uncompressed")
+	}
+	if int64(len(p)) > r.budget.remaining {
+		p = p[:r.budget.remaining]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.budget.remaining -= int64(n)
+	return n, err
+}
+
+// xlsxFirstSheetPath finds the part of the workbook's first sheet through its relationships
+func xlsxFirstSheetPath(files map[string]*zip.File, budget *xlsxBudget) (string, error) {
+	const fallback = "xl/worksheets/sheet1.xml"
+	workbook, ok := files["xl/workbook.xml"]
+	rels, relsOK := files["xl/_rels/workbook.xml.rels"]
+	if !ok || !relsOK {
+		return fallback, nil
+	}
+
+	var wb struct {
+		Sheets []struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodeXLSXPart(workbook, budget, &wb); err != nil {
+		return "", err
+	}
+	if len(wb.Sheets) == 0 {
+		return "", fmt.Errorf("workbook has no sheets")
+	}
+	relID := ""
+	for _, attr := range wb.Sheets[0].Attrs {
+		if attr.Name.Local == "id" {
+			relID = attr.Value
+		}
+	}
+
+	var rel struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodeXLSXPart(rels, budget, &rel); err != nil {
+		return "", err
+	}
+	for _, r := range rel.Relationships {
+		if r.ID == relID {
+			if strings.HasPrefix(r.Target, "/") {
+				return strings.TrimPrefix(r.Target, "/"), nil
+			}
+			return path.Join("xl", r.Target), nil
+		}
+	}
+	return fallback, nil
+}
+
+func decodeXLSXPart(f *zip.File, budget *xlsxBudget, v interface{}) error {
+	rc, err := budget.open(f)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("failed to read %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+// xlsxCellText converts a cell's value to text according to its type
+func xlsxCellText(cell xlsxCell, sharedStrings []string) (string, error) {
+	switch cell.Type {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+		if err != nil || i < 0 || i >= len(sharedStrings) {
+			return "", fmt.Errorf("invalid shared string %q", cell.Value)
+		}
+		return sharedStrings[i], nil
+	case "inlineStr":
+		return cell.InlineStr.String(), nil
+	case "b":
+		if strings.TrimSpace(cell.Value) == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "", "n":
+		f, err := strconv.ParseFloat(strings.TrimSpace(cell.Value), 64)
+		if err != nil {
+			return cell.Value, nil
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	default: // "str" (formula text), "e" (error), "d" (ISO date)
+		return cell.Value, nil
+	}
+}
+
+// xlsxColumnIndex returns the 0-based column of a cell reference ("B7" → 1), the inverse
+// of XLSXColumnName. References beyond the last column (XFD) are invalid.
+func xlsxColumnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, ch := range ref {
+		if ch < 'A' || ch > 'Z' {
+			break
+		}
+		col = col*26 + int(ch-'A'+1)
+		if n++; n > 3 {
+			return 0, false
+		}
+	}
+	return col - 1, n > 0 && col-1 <= xlsxMaxColumnIndex
+}
@@ -1047,6 +1047,14 @@ export default function AdminCarsPage() {
                         {car.status.charAt(0).toUpperCase() +
                           car.status.slice(1)}
                       </span>
+                      {car.duplicatePhotos > 0 && (
+                        <span
+                          className="ml-1 inline-flex items-center px-2 py-1 rounded-full text-xs font-medium bg-red-100 text-red-800"
+                          title="Photos matching photos on other sellers' listings"
+                        >
+                          {car.duplicatePhotos} duplicate photo(s)
+                        </span>
+                      )}
                     </div>
 
                     {/* Listed Date - hidden on mobile, visible on md+ */}
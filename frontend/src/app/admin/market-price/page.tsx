@@ -3,6 +3,7 @@
 import React, { useState, ChangeEvent, FormEvent, useEffect } from "react";
 import { useAdminAuth } from "@/hooks/useAdminAuth";
 import { adminAPI } from "@/lib/adminAPI";
+import type { MarketPriceImportPreview } from "@/types/admin";
 
 // Interface MarketPrice
 interface MarketPrice {
@@ -23,6 +24,9 @@ interface StatusResponse {
   error?: string;
 }
 
+// File types the market price upload accepts
+const MARKET_PRICE_FILE_EXTENSIONS = [".pdf", ".csv", ".xlsx"];
+
 // Upload Modal Component
 function UploadModal({
   isOpen,
@@ -36,30 +40,56 @@ function UploadModal({
   const [selectedFile, setSelectedFile] = useState<File | null>(null);
   const [uploadStatus, setUploadStatus] = useState<StatusResponse | null>(null);
   const [isUploading, setIsUploading] = useState<boolean>(false);
+  const [preview, setPreview] = useState<MarketPriceImportPreview | null>(
+    null
+  );
 
   const handleFileChange = (event: ChangeEvent<HTMLInputElement>) => {
     setSelectedFile(null);
     setUploadStatus(null);
+    setPreview(null);
 
     if (event.target.files && event.target.files[0]) {
-      if (event.target.files[0].type === "application/pdf") {
+      const name = event.target.files[0].name.toLowerCase();
+      if (MARKET_PRICE_FILE_EXTENSIONS.some((ext) => name.endsWith(ext))) {
         setSelectedFile(event.target.files[0]);
       } else {
         setUploadStatus({
           message: "",
-          error: "Invalid file type. Please select a PDF file.",
+          error: "Invalid file type. Please select a PDF, CSV or XLSX file.",
         });
         event.target.value = "";
       }
     }
   };
 
+  const handlePreview = async () => {
+    if (!selectedFile) return;
+
+    setIsUploading(true);
+    setUploadStatus(null);
+    setPreview(null);
+
+    try {
+      const result = await adminAPI.previewMarketPriceImport(selectedFile);
+      setPreview(result.data);
+    } catch (error) {
+      const errorMessage =
+        error instanceof Error
+          ? error.message
+          : "An unknown network error occurred";
+      setUploadStatus({ message: "", error: `Preview Error: ${errorMessage}` });
+    } finally {
+      setIsUploading(false);
+    }
+  };
+
   const handleUploadSubmit = async (event: FormEvent<HTMLFormElement>) => {
     event.preventDefault();
     if (!selectedFile) {
       setUploadStatus({
         message: "",
-        error: "Please select a PDF file first.",
+        error: "Please select a file first.",
       });
       return;
     }
@@ -71,7 +101,7 @@ function UploadModal({
       const result = await adminAPI.importMarketPrices(selectedFile);
       if (result.success && result.data) {
         setUploadStatus({
-          message: `${result.message} Inserted: ${result.data.inserted_count}, Updated: ${result.data.updated_count}`,
+          message: `${result.message} Version ${result.data.version?.id}. Inserted: ${result.data.inserted_count}, Updated: ${result.data.updated_count}`,
           error: undefined,
         });
       } else {
@@ -79,6 +109,7 @@ function UploadModal({
       }
       // Clear file after successful import
       setSelectedFile(null);
+      setPreview(null);
       const fileInput = document.getElementById(
         "pdf-upload"
       ) as HTMLInputElement;
@@ -95,7 +126,10 @@ function UploadModal({
         error instanceof Error
           ? error.message
           : "An unknown network error occurred";
-      setUploadStatus({ message: "", error: `Import Error: ${errorMessage}` });
+      setUploadStatus({
+        message: "",
+        error: `Import Error: ${errorMessage}. Use Preview to see which rows need fixing.`,
+      });
     } finally {
       setIsUploading(false);
     }
@@ -109,7 +143,7 @@ function UploadModal({
         <div className="p-6">
           <div className="flex items-center justify-between mb-4">
             <h2 className="text-2xl font-bold text-gray-900">
-              Upload Market Price PDF
+              Upload Market Prices
             </h2>
             <button
               onClick={onClose}
@@ -137,17 +171,20 @@ function UploadModal({
                 htmlFor="pdf-upload"
                 className="block text-sm font-medium text-gray-700 mb-2 cursor-pointer"
               >
-                Choose a PDF file
+                Choose a PDF, CSV or XLSX file
               </label>
               <input
                 id="pdf-upload"
-                name="marketPricePdf"
+                name="marketPriceFile"
                 type="file"
-                accept="application/pdf"
+                accept={MARKET_PRICE_FILE_EXTENSIONS.join(",")}
                 onChange={handleFileChange}
                 className="block w-full text-sm text-gray-500 file:mr-4 file:py-2 file:px-4 file:rounded-full file:border-0 file:text-sm file:font-semibold file:bg-red-50 file:text-red-700 hover:file:bg-red-100 cursor-pointer"
               />
-              <p className="mt-1 text-xs text-gray-500">PDF only, up to 50MB</p>
+              <p className="mt-1 text-xs text-gray-500">
+                DLT PDF, or a spreadsheet with brand, model, sub_model, year and
+                price columns. Up to 50MB
+              </p>
               {selectedFile && (
                 <p className="mt-2 text-sm text-green-600 font-medium">
                   Selected: {selectedFile.name}
@@ -155,6 +192,32 @@ function UploadModal({
               )}
             </div>
 
+            {preview && (
+              <div className="mb-4 p-3 rounded-lg text-sm bg-gray-50 border border-gray-200 text-gray-700">
+                <p className="font-medium">
+                  Preview ({preview.format.toUpperCase()}): {preview.rowCount}{" "}
+                  valid prices. {preview.insertCount} new,{" "}
+                  {preview.updateCount} changed, {preview.unchangedCount}{" "}
+                  unchanged.
+                </p>
+                {preview.errors.length > 0 && (
+                  <div className="mt-2 text-red-700">
+                    <p>
+                      {preview.errors.length} rows have errors and must be
+                      fixed before importing:
+                    </p>
+                    <ul className="mt-1 list-disc pl-5 max-h-40 overflow-y-auto">
+                      {preview.errors.map((rowError) => (
+                        <li key={rowError.row}>
+                          Row {rowError.row}: {rowError.errors.join("; ")}
+                        </li>
+                      ))}
+                    </ul>
+                  </div>
+                )}
+              </div>
+            )}
+
             {uploadStatus && (
               <div
                 className={`mb-4 p-3 rounded-lg text-sm ${
@@ -176,6 +239,14 @@ function UploadModal({
               >
                 Cancel
               </button>
+              <button
+                type="button"
+                onClick={handlePreview}
+                disabled={!selectedFile || isUploading}
+                className="flex-1 px-4 py-2 border border-red-600 text-red-600 rounded-lg hover:bg-red-50 transition-colors disabled:opacity-50 disabled:cursor-not-allowed"
+              >
+                Preview
+              </button>
               <button
                 type="submit"
                 disabled={!selectedFile || isUploading}
@@ -303,7 +374,7 @@ export default function MarketPricePage() {
                 d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"
               />
             </svg>
-            Upload PDF
+            Upload File
           </button>
         </div>
       </div>
@@ -421,7 +492,7 @@ export default function MarketPricePage() {
                           d="M7 16a4 4 0 01-.88-7.903A5 5 0 1115.9 6L16 6a5 5 0 011 9.9M15 13l-3-3m0 0l-3 3m3-3v12"
                         />
                       </svg>
-                      Upload PDF
+                      Upload File
                     </button>
                   </div>
                 )}
@@ -385,6 +385,30 @@ export default function AdminReportsPage() {
                       <p className="text-sm font-medium text-gray-900 truncate">
                         {subjectText}
                       </p>
+                      {report.duplicatePhotos &&
+                        report.duplicatePhotos.length > 0 && (
+                          <p
+                            className="text-xs text-red-700 mt-0.5"
+                            title={report.duplicatePhotos
+                              .map(
+                                (m) =>
+                                  `Photo #${m.imageId} matches car #${
+                                    m.matchedCarId
+                                  } (${m.matchedCarStatus}) by ${
+                                    m.matchedSellerName ||
+                                    `seller #${m.matchedSellerId}`
+                                  }`
+                              )
+                              .join("\n")}
+                          >
+                            {
+                              new Set(
+                                report.duplicatePhotos.map((m) => m.imageId)
+                              ).size
+                            }{" "}
+                            photo(s) found on other sellers&apos; listings
+                          </p>
+                        )}
                       <p className="text-xs text-gray-600 md:hidden mt-1">
                         {report.reason}
                       </p>
@@ -17,6 +17,7 @@ import { DEFAULT_CAR_SUBTOPICS } from "@/types/report";
 import { recentAPI } from "@/lib/recentAPI";
 import StarRating from "@/components/ui/StarRating";
 import { useToast } from "@/components/ui/Toast";
+import { carImageUrl } from "@/utils/carImage";
 
 export default function CarListingPage() {
   const params = useParams();
@@ -88,9 +89,7 @@ export default function CarListingPage() {
           .join(" "),
         price: c?.price,
         thumbnailId: first?.id,
-        thumbnailUrl:
-          first?.url ||
-          (first?.id ? `/api/cars/images/${first.id}` : undefined),
+        thumbnailUrl: first?.id ? carImageUrl(first, "thumb") : undefined,
       });
     } catch {
       // Ignore non-critical recent view errors
@@ -333,10 +332,7 @@ export default function CarListingPage() {
               <div className="relative group">
                 <div className="aspect-[16/10] relative bg-gray-100">
                   <Image
-                    src={
-                      images[currentImageIndex]?.url ||
-                      "/api/cars/images/" + images[currentImageIndex]?.id
-                    }
+                    src={carImageUrl(images[currentImageIndex], "full")}
                     alt={`${carData.brandName} ${carData.modelName}`}
                     fill
                     className="object-cover"
@@ -433,7 +429,7 @@ export default function CarListingPage() {
                         }`}
                       >
                         <Image
-                          src={img.url || "/api/cars/images/" + img.id}
+                          src={carImageUrl(img, "thumb")}
                           alt={`Thumbnail ${index + 1}`}
                           fill
                           className="object-cover"
@@ -14,7 +14,12 @@ import Step3PricingForm from "@/components/car/Step3PricingForm";
 import Step4ReviewForm from "@/components/car/Step4ReviewForm";
 import ProgressRestoreModal from "@/components/car/ProgressRestoreModal";
 import DuplicateConflictModal from "@/components/car/DuplicateConflictModal";
-import type { CarFormData, InspectionResult } from "@/types/car";
+import type {
+  CarFormData,
+  InspectionResult,
+  PriceAdjustment,
+  PriceEstimate,
+} from "@/types/car";
 import type { Step } from "@/types/selling";
 
 interface APIErrorData {
@@ -23,6 +28,25 @@ interface APIErrorData {
     redirectToCarID?: number| null;
 }
 
+// describeAdjustment labels one factor of a price estimate
+function describeAdjustment(adj: PriceAdjustment): string {
+  const percent = `${adj.percent >= 0 ? "+" : ""}${adj.percent}%`;
+  switch (adj.factor) {
+    case "condition":
+      return `Condition rating ${adj.value}/5 (${percent})`;
+    case "mileage":
+      return `Mileage ${adj.value?.toLocaleString()} km/year (${percent})`;
+    case "flooded":
+      return `Flood damage (${percent})`;
+    case "heavilyDamaged":
+      return `Heavy damage (${percent})`;
+    case "inspectionPassed":
+      return `All inspection items passed (${percent})`;
+    case "inspectionFailed":
+      return `Inspection failed or missing: ${adj.item} (${percent})`;
+  }
+}
+
 export default function SellWithIdPage() {
   const { showToast, ToastContainer } = useToast();
   
@@ -59,9 +83,10 @@ export default function SellWithIdPage() {
     useState(false);
   const [, setConflictExistingCarId] = useState<number | null>(null);
 
-  const [estimatedPrice, setEstimatedPrice] = useState<number | null>(null);
+  const [estimate, setEstimate] = useState<PriceEstimate | null>(null);
   const [isEstimating, setIsEstimating] = useState(false);
   const [estimationError, setEstimationError] = useState<string | null>(null);
+  const [matchConfirmed, setMatchConfirmed] = useState(false);
 
   // --- New States for Cascading Dropdowns ---
   const [brandOptions, setBrandOptions] = useState<string[]>([]);
@@ -348,15 +373,16 @@ export default function SellWithIdPage() {
   useEffect(() => {
     const fetchEstimate = async () => {
       // Reset state on each attempt
-      setEstimatedPrice(null);
+      setEstimate(null);
       setEstimationError(null);
+      setMatchConfirmed(false);
 
       if (currentStep === "pricing" && carId) {
         setIsEstimating(true);
         try {
           const result = await carsAPI.getPriceEstimate(carId);
           if (result.success && result.data && result.data.estimatedPrice > 0) {
-            setEstimatedPrice(result.data.estimatedPrice);
+            setEstimate(result.data);
           } else if (!result.success && result.message) {
             setEstimationError(result.message);
           } else {
@@ -960,13 +986,46 @@ export default function SellWithIdPage() {
                   Loading estimated price...
                 </div>
               )}
-              {estimatedPrice && !isEstimating && (
+              {estimate &&
+                !isEstimating &&
+                estimate.match?.needsConfirmation &&
+                !matchConfirmed && (
+                  <div className="mb-4 p-4 bg-yellow-50 border border-yellow-200 rounded-md text-sm text-yellow-800">
+                    <p>
+                      We couldn&apos;t find your exact car in the market price
+                      data, so this estimate uses the closest match:{" "}
+                      <span className="font-semibold">
+                        {estimate.marketPrice.brand}{" "}
+                        {estimate.marketPrice.model}{" "}
+                        {estimate.marketPrice.sub_model} (
+                        {estimate.marketPrice.year_start}–
+                        {estimate.marketPrice.year_end})
+                      </span>
+                      , {Math.round(estimate.match.score * 100)}% similar. Is
+                      this your car? If not, go back and check the brand, model
+                      and submodel.
+                    </p>
+                    <button
+                      type="button"
+                      onClick={() => setMatchConfirmed(true)}
+                      className="mt-2 px-3 py-1 bg-yellow-600 text-white rounded-md hover:bg-yellow-700"
+                    >
+                      Yes, this is my car
+                    </button>
+                  </div>
+                )}
+              {estimate && !isEstimating && (
                 <div className="mb-6 p-4 bg-green-50 border border-green-200 rounded-md">
                   <div className="flex items-center justify-between">
                     <div>
                       <h4 className="text-lg font-semibold text-green-800">
-                        Estimated Price: ฿{estimatedPrice.toLocaleString()}
+                        Estimated Price: ฿
+                        {estimate.estimatedPrice.toLocaleString()}
                       </h4>
+                      <p className="text-sm text-green-700">
+                        Likely range: ฿{estimate.lowPrice.toLocaleString()} – ฿
+                        {estimate.highPrice.toLocaleString()}
+                      </p>
                     </div>
                     <Link
                       href="/pricing"
@@ -977,10 +1036,30 @@ export default function SellWithIdPage() {
                       See how we calculate
                     </Link>
                   </div>
+                  <ul className="mt-3 space-y-1 text-sm text-green-800">
+                    <li className="flex justify-between">
+                      <span>
+                        Market price ({estimate.marketPrice.year_start}–
+                        {estimate.marketPrice.year_end}): ฿
+                        {estimate.marketPrice.price_min_thb.toLocaleString()} – ฿
+                        {estimate.marketPrice.price_max_thb.toLocaleString()}
+                      </span>
+                      <span>฿{estimate.basePrice.toLocaleString()}</span>
+                    </li>
+                    {estimate.adjustments.map((adj, i) => (
+                      <li key={i} className="flex justify-between">
+                        <span>{describeAdjustment(adj)}</span>
+                        <span>
+                          {adj.amount >= 0 ? "+" : "−"}฿
+                          {Math.abs(adj.amount).toLocaleString()}
+                        </span>
+                      </li>
+                    ))}
+                  </ul>
                 </div>
               )}
 
-              {estimationError && !isEstimating && !estimatedPrice && (
+              {estimationError && !isEstimating && !estimate && (
                 <div className="mb-6 p-3 bg-gray-100 border border-gray-200 rounded-md">
                   <p className="text-sm text-gray-600">
                     <span className="font-medium">Note:</span> Could not
@@ -5,6 +5,7 @@ import Image from "next/image";
 import { carsAPI } from "@/lib/carsAPI";
 import { InlineAlert } from "@/components/ui/InlineAlert";
 import { MIN_IMAGES, MAX_IMAGES } from "@/constants/car";
+import { carImageUrl } from "@/utils/carImage";
 
 const MAX_FILE_SIZE = 50 * 1024 * 1024; // 50MB
 const ACCEPTED_IMAGE_TYPES = [
@@ -54,7 +55,7 @@ export default function CarImageUploader({
             )
             .map((img: { id: number; displayOrder: number }) => ({
               file: null,
-              preview: `/api/cars/images/${img.id}`, // Backend image URL
+              preview: `/api/cars/images/${img.id}?size=thumb`, // Backend image URL
               order: img.displayOrder,
               status: "uploaded" as const,
               serverId: img.id,
@@ -143,7 +144,7 @@ export default function CarImageUploader({
                 ...img,
                 status: "uploaded" as const,
                 serverId: uploadedImg.id,
-                preview: `/api/cars/images/${uploadedImg.id}`,
+                preview: carImageUrl(uploadedImg, "thumb"),
               };
             }
             return {
@@ -7,6 +7,9 @@ import {
   AdminActionResponse,
   MarketPrice,
   ImportMarketPriceResponse,
+  MarketPriceImportPreview,
+  MarketPriceVersion,
+  MarketPriceVersionDiff,
   DashboardStats,
   ChartDataPoint,
   BrandDataPoint,
@@ -116,9 +119,10 @@ export const adminAPI = {
     return Array.isArray(response?.data) ? response.data : [];
   },
 
+  // Accepts a PDF, CSV or XLSX file
   async importMarketPrices(file: File): Promise<ImportMarketPriceResponse> {
     const form = new FormData();
-    form.append("marketPricePdf", file);
+    form.append("marketPriceFile", file);
     return apiCall<ImportMarketPriceResponse>(
       `${adminPrefix}/market-price/upload`,
       {
@@ -128,6 +132,71 @@ export const adminAPI = {
     );
   },
 
+  // Dry run: reads the file and reports what importing it would change
+  async previewMarketPriceImport(file: File): Promise<{
+    success: boolean;
+    code: number;
+    data: MarketPriceImportPreview;
+    message?: string;
+  }> {
+    const form = new FormData();
+    form.append("marketPriceFile", file);
+    form.append("dryRun", "true");
+    return apiCall(`${adminPrefix}/market-price/upload`, {
+      method: "POST",
+      body: form,
+    });
+  },
+
+  async getMarketPriceVersions(): Promise<MarketPriceVersion[]> {
+    const response = await apiCall<{
+      success: boolean;
+      code: number;
+      data: MarketPriceVersion[];
+      message?: string;
+    }>(`${adminPrefix}/market-price/versions`, {
+      method: "GET",
+    });
+    return Array.isArray(response?.data) ? response.data : [];
+  },
+
+  async activateMarketPriceVersion(versionId: number): Promise<{
+    success: boolean;
+    code: number;
+    data: MarketPriceVersion;
+    message?: string;
+  }> {
+    return apiCall(`${adminPrefix}/market-price/versions/${versionId}/activate`, {
+      method: "POST",
+    });
+  },
+
+  async rollbackMarketPrices(): Promise<{
+    success: boolean;
+    code: number;
+    data: MarketPriceVersion;
+    message?: string;
+  }> {
+    return apiCall(`${adminPrefix}/market-price/versions/rollback`, {
+      method: "POST",
+    });
+  },
+
+  async diffMarketPriceVersions(
+    from: number,
+    to: number
+  ): Promise<MarketPriceVersionDiff | null> {
+    const response = await apiCall<{
+      success: boolean;
+      code: number;
+      data: MarketPriceVersionDiff;
+      message?: string;
+    }>(`${adminPrefix}/market-price/versions/diff?from=${from}&to=${to}`, {
+      method: "GET",
+    });
+    return response?.data ?? null;
+  },
+
   // Ban a user
   async banUser(userId: number): Promise<AdminActionResponse> {
     return apiCall<AdminActionResponse>(`${adminPrefix}/users/${userId}/ban`, {
@@ -7,6 +7,7 @@ import type {
   InspectionResult,
   BookResult,
   CarListing,
+  PriceEstimate,
 } from "@/types/car";
 
 // Type definitions now sourced from types/Car
@@ -216,6 +217,7 @@ export const carsAPI = {
         imageSize: number;
         displayOrder: number;
         uploadedAt: string;
+        url?: string; // Versioned image URL
       }>;
     };
     message?: string;
@@ -236,6 +238,12 @@ export const carsAPI = {
           displayOrder: number;
           uploadedAt: string;
         }>;
+        results: Array<{
+          filename: string;
+          status: "uploaded" | "invalid" | "failed" | "skipped";
+          error?: string;
+          imageId?: number;
+        }>;
       };
       message?: string;
     }>(`/api/cars/${carId}/images`, {
@@ -313,12 +321,12 @@ export const carsAPI = {
    */
   async getPriceEstimate(carId: number): Promise<{
     success: boolean;
-    data: { estimatedPrice: number } | null;
+    data: PriceEstimate | null;
     message?: string;
   }> {
     return apiCall<{
       success: boolean;
-      data: { estimatedPrice: number } | null;
+      data: PriceEstimate | null;
       message?: string;
     }>(`/api/cars/${carId}/estimate`, {
       method: "GET",
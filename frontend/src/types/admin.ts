@@ -119,6 +119,7 @@ interface AdminManagedCar {
   soldBy: string | null;
   price: number | null;
   mileage: number | null;
+  duplicatePhotos: number; // Photos matching photos on other sellers' listings
 }
 
 interface AdminUpdateCarRequest {
@@ -176,10 +177,68 @@ interface ImportMarketPriceResponse {
   data: {
     inserted_count: number;
     updated_count: number;
+    version: MarketPriceVersion;
   };
   message?: string;
 }
 
+// A spreadsheet row that failed validation; the header is row 1
+interface MarketPriceRowError {
+  row: number;
+  errors: string[];
+}
+
+// Dry-run result of a market price upload
+interface MarketPriceImportPreview {
+  format: "pdf" | "csv" | "xlsx";
+  rowCount: number;
+  insertCount: number;
+  updateCount: number;
+  unchangedCount: number;
+  errors: MarketPriceRowError[];
+  prices: MarketPrice[];
+  debugLog?: string[];
+}
+
+// One market price import; exactly one version is active
+interface MarketPriceVersion {
+  id: number;
+  sourceFilename: string | null;
+  sourceHash: string | null;
+  rowCount: number;
+  importedCount: number;
+  insertedCount: number;
+  updatedCount: number;
+  uploadedBy: number | null;
+  uploadedByName: string | null;
+  effectiveFrom: string;
+  isActive: boolean;
+  activatedAt: string | null;
+  createdAt: string;
+}
+
+interface MarketPriceChange {
+  change: "added" | "removed" | "changed";
+  brand: string;
+  model: string;
+  subModel: string;
+  yearStart: number;
+  yearEnd: number;
+  oldPriceMin: number | null;
+  oldPriceMax: number | null;
+  newPriceMin: number | null;
+  newPriceMax: number | null;
+}
+
+interface MarketPriceVersionDiff {
+  from: number;
+  to: number;
+  added: number;
+  removed: number;
+  changed: number;
+  changes: MarketPriceChange[];
+}
+
 interface DashboardStats {
   totalUsers: number;
   activeCars: number;
@@ -235,6 +294,11 @@ export type {
   MarketPrice,
   MarketPriceResponse,
   ImportMarketPriceResponse,
+  MarketPriceRowError,
+  MarketPriceImportPreview,
+  MarketPriceVersion,
+  MarketPriceChange,
+  MarketPriceVersionDiff,
   DashboardStats,
   RecentReport,
   ChartDataPoint,
@@ -19,7 +19,7 @@ export interface CarListing {
   fuelTypes?: string[]; // Display labels (e.g., ["Gasoline", "LPG"])
   colors?: string[]; // Display labels (e.g., ["White", "Gray"])
   conditionRating?: number;
-  thumbnailUrl?: string; // Image URL for thumbnail (e.g., "/api/cars/images/123")
+  thumbnailUrl?: string; // Image URL for thumbnail (e.g., "/api/cars/images/123?size=thumb")
 }
 
 export interface Car {
@@ -69,9 +69,11 @@ export interface ImageMetadata {
   carId: number;
   imageType: string;
   imageSize: number;
+  width: number | null; // Pixels, upright (null for images not yet processed)
+  height: number | null;
   displayOrder: number;
   uploadedAt: string;
-  url: string; // URL to fetch/display the image: /api/cars/images/{id}
+  url: string; // URL to fetch/display the image: /api/cars/images/{id}?v={version}
 }
 
 export interface InspectionData {
@@ -197,3 +199,44 @@ export interface InspectionResult {
   seatbeltResult: boolean;
   wiperResult: boolean;
 }
+
+// One factor's contribution to a price estimate
+export interface PriceAdjustment {
+  factor:
+    | "condition"
+    | "mileage"
+    | "flooded"
+    | "heavilyDamaged"
+    | "inspectionPassed"
+    | "inspectionFailed";
+  item?: string; // Inspection item key, e.g. "brakeResult", for inspectionFailed
+  value?: number; // Condition rating or mileage per year (km)
+  percent: number; // Percentage points
+  amount: number; // THB
+}
+
+// Price estimate for a car, with the market data and adjustments behind it
+export interface PriceEstimate {
+  estimatedPrice: number;
+  lowPrice: number;
+  highPrice: number;
+  basePrice: number;
+  marketPrice: {
+    id: number;
+    brand: string;
+    model: string;
+    sub_model: string;
+    year_start: number;
+    year_end: number;
+    price_min_thb: number;
+    price_max_thb: number;
+  };
+  adjustmentFactor: number;
+  adjustments: PriceAdjustment[];
+  // How the car was matched to marketPrice; fuzzy matches need the seller's confirmation
+  match: {
+    type: "exact" | "normalized" | "fuzzy";
+    score: number;
+    needsConfirmation: boolean;
+  };
+}
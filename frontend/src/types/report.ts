@@ -45,6 +45,22 @@ interface AdminReport {
   createdAt: string;
   resolvedAt?: string;
   resolvedBy?: string;
+  duplicatePhotos?: DuplicatePhotoMatch[]; // Car reports only
+}
+
+// A photo of the reported car that nearly matches a photo on another seller's listing
+interface DuplicatePhotoMatch {
+  imageId: number;
+  imageUrl: string;
+  matchedImageId: number;
+  matchedImageUrl: string;
+  matchedCarId: number;
+  matchedCarStatus: string;
+  matchedSellerId: number;
+  matchedSellerName: string | null;
+  matchedUploadedAt: string;
+  distance: number;
+  detectedAt: string;
 }
 
 interface AdminReportsListResponse {
@@ -67,6 +83,7 @@ export type {
   ReportType,
   ReportStatus,
   AdminReport,
+  DuplicatePhotoMatch,
   AdminReportsListResponse,
   AdminActionResponse,
 };
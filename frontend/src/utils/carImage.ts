@@ -0,0 +1,12 @@
+type CarImageSize = "thumb" | "card" | "full" | "original";
+
+// Returns the URL of a car image at a size. The API's versioned image URLs (?v=) are cached
+// by the browser as immutable; without one, the image is revalidated on each visit.
+export function carImageUrl(
+  image: { id: number; url?: string },
+  size?: CarImageSize
+): string {
+  const base = image.url || `/api/cars/images/${image.id}`;
+  if (!size) return base;
+  return `${base}${base.includes("?") ? "&" : "?"}size=${size}`;
+}
@@ -18,6 +18,8 @@ export {
   isStepCompleted,
 } from "./stepNavigation";
 
+export { carImageUrl } from "./carImage";
+
 export {
   getTimeRemaining,
   isSessionExpiringSoon,